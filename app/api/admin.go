@@ -0,0 +1,259 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	log "github.com/go-pkgz/lgr"
+	"github.com/go-pkgz/rest"
+	"github.com/pkg/errors"
+
+	"github.com/umputun/feed-master/app/config"
+	"github.com/umputun/feed-master/app/youtube"
+)
+
+// POST /api/v1/feeds/{name} - creates feed, PUT /api/v1/feeds/{name} - creates or replaces feed.
+// Request is a feed as shown by /config, i.e. {"title": "...", "sources": [{"name": "src", "url": "http://..."}]}
+func (s *Server) setFeedCtrl(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+	f := config.Feed{}
+	if err := render.DecodeJSON(r.Body, &f); err != nil {
+		rest.SendErrorJSON(w, r, log.Default(), http.StatusBadRequest, err, "failed to parse request")
+		return
+	}
+
+	ok := s.updateConf(w, r, func(conf *config.Conf) (int, error) {
+		if _, found := conf.Feeds[name]; found && r.Method == http.MethodPost {
+			return http.StatusConflict, fmt.Errorf("feed %q already exists", name)
+		}
+		conf.Feeds[name] = f
+		return 0, nil
+	})
+	if ok {
+		log.Printf("[INFO] feed %s set, %d sources", name, len(f.Sources))
+		rest.RenderJSON(w, rest.JSON{"status": "ok", "feed": name})
+	}
+}
+
+// DELETE /api/v1/feeds/{name} - removes feed
+func (s *Server) deleteFeedCtrl(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+	ok := s.updateConf(w, r, func(conf *config.Conf) (int, error) {
+		if _, found := conf.Feeds[name]; !found {
+			return http.StatusNotFound, fmt.Errorf("feed %q not found", name)
+		}
+		delete(conf.Feeds, name)
+		return 0, nil
+	})
+	if ok {
+		log.Printf("[INFO] feed %s removed", name)
+		rest.RenderJSON(w, rest.JSON{"status": "ok", "feed": name})
+	}
+}
+
+// POST /api/v1/feeds/{name}/sources - adds source to the feed, request is {"name": "src", "url": "http://..."}
+// PUT /api/v1/feeds/{name}/sources/{source} - replaces source with the given name or adds it
+func (s *Server) setSourceCtrl(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+	src := config.Source{}
+	if err := render.DecodeJSON(r.Body, &src); err != nil {
+		rest.SendErrorJSON(w, r, log.Default(), http.StatusBadRequest, err, "failed to parse request")
+		return
+	}
+	if srcName := chi.URLParam(r, "source"); srcName != "" {
+		src.Name = srcName
+	}
+	if src.Name == "" {
+		rest.SendErrorJSON(w, r, log.Default(), http.StatusBadRequest, errors.New("empty source name"), "invalid source")
+		return
+	}
+
+	ok := s.updateConf(w, r, func(conf *config.Conf) (int, error) {
+		f, found := conf.Feeds[name]
+		if !found {
+			return http.StatusNotFound, fmt.Errorf("feed %q not found", name)
+		}
+		idx := sourceIndex(f, src.Name)
+		switch {
+		case idx >= 0 && r.Method == http.MethodPost:
+			return http.StatusConflict, fmt.Errorf("source %q already exists in feed %q", src.Name, name)
+		case idx >= 0:
+			f.Sources[idx] = src
+		default:
+			f.Sources = append(f.Sources, src)
+		}
+		conf.Feeds[name] = f
+		return 0, nil
+	})
+	if ok {
+		log.Printf("[INFO] source %s set for feed %s, %s", src.Name, name, src.URL)
+		rest.RenderJSON(w, rest.JSON{"status": "ok", "feed": name, "source": src.Name})
+	}
+}
+
+// DELETE /api/v1/feeds/{name}/sources/{source} - removes source from the feed
+func (s *Server) deleteSourceCtrl(w http.ResponseWriter, r *http.Request) {
+	name, srcName := chi.URLParam(r, "name"), chi.URLParam(r, "source")
+	ok := s.updateConf(w, r, func(conf *config.Conf) (int, error) {
+		f, found := conf.Feeds[name]
+		if !found {
+			return http.StatusNotFound, fmt.Errorf("feed %q not found", name)
+		}
+		idx := sourceIndex(f, srcName)
+		if idx < 0 {
+			return http.StatusNotFound, fmt.Errorf("source %q not found in feed %q", srcName, name)
+		}
+		f.Sources = append(f.Sources[:idx], f.Sources[idx+1:]...)
+		conf.Feeds[name] = f
+		return 0, nil
+	})
+	if ok {
+		log.Printf("[INFO] source %s removed from feed %s", srcName, name)
+		rest.RenderJSON(w, rest.JSON{"status": "ok", "feed": name, "source": srcName})
+	}
+}
+
+// POST /api/v1/yt/channels/{id} - adds youtube channel, PUT /api/v1/yt/channels/{id} - adds or replaces it.
+// Request is {"name": "...", "type": "channel", "keep": 10, "lang": "en", "filter": {"include": "", "exclude": ""}}.
+// Download template and local cover file are set in the config file only, replaced channel keeps its template.
+func (s *Server) setYoutubeChannelCtrl(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	ch := youtube.FeedInfo{}
+	if err := render.DecodeJSON(r.Body, &ch); err != nil {
+		rest.SendErrorJSON(w, r, log.Default(), http.StatusBadRequest, err, "failed to parse request")
+		return
+	}
+	ch.ID = id
+
+	ok := s.updateConf(w, r, func(conf *config.Conf) (int, error) {
+		idx := channelIndex(conf, id)
+		prev := youtube.FeedInfo{}
+		if idx >= 0 {
+			prev = conf.YouTube.Channels[idx]
+		}
+		if ch.DlTemplate != "" && ch.DlTemplate != prev.DlTemplate {
+			return http.StatusBadRequest, errors.New("dl_template can be set in the config file only")
+		}
+		if !isURL(ch.Cover) && ch.Cover != prev.Cover {
+			return http.StatusBadRequest, errors.New("cover should be http(s) url, local files set in the config file only")
+		}
+		ch.DlTemplate = prev.DlTemplate
+		switch {
+		case idx >= 0 && r.Method == http.MethodPost:
+			return http.StatusConflict, fmt.Errorf("youtube channel %q already exists", id)
+		case idx >= 0:
+			conf.YouTube.Channels[idx] = ch
+		default:
+			conf.YouTube.Channels = append(conf.YouTube.Channels, ch)
+		}
+		return 0, nil
+	})
+	if ok {
+		log.Printf("[INFO] youtube channel %s set, %s", id, ch.Name)
+		rest.RenderJSON(w, rest.JSON{"status": "ok", "channel": id})
+	}
+}
+
+// DELETE /api/v1/yt/channels/{id} - removes youtube channel, downloaded entries are kept
+func (s *Server) deleteYoutubeChannelCtrl(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	ok := s.updateConf(w, r, func(conf *config.Conf) (int, error) {
+		idx := channelIndex(conf, id)
+		if idx < 0 {
+			return http.StatusNotFound, fmt.Errorf("youtube channel %q not found", id)
+		}
+		conf.YouTube.Channels = append(conf.YouTube.Channels[:idx], conf.YouTube.Channels[idx+1:]...)
+		return 0, nil
+	})
+	if ok {
+		log.Printf("[INFO] youtube channel %s removed", id)
+		rest.RenderJSON(w, rest.JSON{"status": "ok", "channel": id})
+	}
+}
+
+// POST /api/v1/yt/queue/{channel}/{video}/retry - resets failed or skipped entry, it is downloaded on the next update
+// POST /api/v1/yt/queue/{channel}/{video}/give-up - marks failed or pending entry as skipped, it won't be downloaded
+func (s *Server) updateYoutubeQueueCtrl(w http.ResponseWriter, r *http.Request) {
+	if s.YoutubeQueue == nil {
+		rest.SendErrorJSON(w, r, log.Default(), http.StatusServiceUnavailable, errors.New("no youtube queue"),
+			"youtube processing is not enabled")
+		return
+	}
+	chanID, videoID := chi.URLParam(r, "channel"), chi.URLParam(r, "video")
+	update := s.YoutubeQueue.RetryQueued
+	if strings.HasSuffix(r.URL.Path, "/give-up") {
+		update = s.YoutubeQueue.GiveUpQueued
+	}
+
+	item, err := update(chanID, videoID)
+	switch {
+	case errors.Is(err, youtube.ErrNotQueued):
+		rest.SendErrorJSON(w, r, log.Default(), http.StatusNotFound, err, "entry is not queued")
+		return
+	case errors.Is(err, youtube.ErrQueueState):
+		rest.SendErrorJSON(w, r, log.Default(), http.StatusConflict, err, err.Error())
+		return
+	case err != nil:
+		rest.SendErrorJSON(w, r, log.Default(), http.StatusInternalServerError, err, "failed to update queue")
+		return
+	}
+	rest.RenderJSON(w, item)
+}
+
+// updateConf changes config with fn returning status code for its errors. Invalid config rejected with
+// bad request status. Sends error response and returns false if config wasn't changed.
+func (s *Server) updateConf(w http.ResponseWriter, r *http.Request, fn func(conf *config.Conf) (int, error)) bool {
+	if s.ConfEditor == nil {
+		rest.SendErrorJSON(w, r, log.Default(), http.StatusServiceUnavailable, errors.New("no config editor"),
+			"config changes are not available")
+		return false
+	}
+
+	status := 0
+	err := s.ConfEditor.Update(func(conf *config.Conf) error {
+		code, err := fn(conf)
+		status = code
+		return err
+	})
+	if err == nil {
+		return true
+	}
+	if status == 0 {
+		status = http.StatusInternalServerError
+		if errors.As(err, &config.CheckError{}) {
+			status = http.StatusBadRequest
+		}
+	}
+	msg := "can't change config"
+	if status < http.StatusInternalServerError { // client errors reported with details, i.e. config problems
+		msg = fmt.Sprintf("%s, %v", msg, err)
+	}
+	rest.SendErrorJSON(w, r, log.Default(), status, err, msg)
+	return false
+}
+
+func sourceIndex(f config.Feed, name string) int {
+	for i, src := range f.Sources {
+		if src.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func channelIndex(conf *config.Conf, id string) int {
+	for i, ch := range conf.YouTube.Channels {
+		if ch.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// isURL checks if src is empty or http(s) url
+func isURL(src string) bool {
+	return src == "" || strings.HasPrefix(src, "http://") || strings.HasPrefix(src, "https://")
+}
@@ -0,0 +1,283 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/umputun/feed-master/app/api/mocks"
+	"github.com/umputun/feed-master/app/config"
+	"github.com/umputun/feed-master/app/youtube"
+	ytfeed "github.com/umputun/feed-master/app/youtube/feed"
+)
+
+func TestServer_adminCtrl(t *testing.T) {
+	conf := &config.Conf{Feeds: map[string]config.Feed{"feed1": {
+		Title:   "feed1",
+		Sources: []config.Source{{Name: "src1", URL: "http://example.com/1"}},
+	}}}
+	conf.YouTube.Channels = []youtube.FeedInfo{{ID: "ch1", Name: "name1"}}
+
+	editor := &mocks.ConfEditorMock{
+		UpdateFunc: func(fn func(conf *config.Conf) error) error {
+			upd := *conf
+			upd.Feeds = map[string]config.Feed{}
+			for k, v := range conf.Feeds {
+				v.Sources = append([]config.Source(nil), v.Sources...)
+				upd.Feeds[k] = v
+			}
+			upd.YouTube.Channels = append([]youtube.FeedInfo(nil), conf.YouTube.Channels...)
+			if err := fn(&upd); err != nil {
+				return err
+			}
+			for name, f := range upd.Feeds {
+				if f.Title == "invalid" {
+					return multierror.Append(nil, config.CheckError{Path: "feeds." + name + ".title", Msg: "invalid"})
+				}
+			}
+			conf = &upd
+			return nil
+		},
+	}
+
+	s := Server{Version: "1.0", ConfEditor: editor, AdminPasswd: "123456"}
+	ts := httptest.NewServer(s.router())
+	defer ts.Close()
+
+	reqNum := 0
+	send := func(method, url, body string) (int, string) {
+		req, err := http.NewRequest(method, ts.URL+url, bytes.NewBufferString(body))
+		require.NoError(t, err)
+		req.SetBasicAuth("admin", "123456")
+		reqNum++
+		req.Header.Set("X-Real-IP", fmt.Sprintf("10.0.0.%d", reqNum)) // avoid rate limiter
+
+		resp, err := ts.Client().Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close() // nolint
+		respBody, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, string(respBody)
+	}
+
+	t.Run("auth", func(t *testing.T) {
+		req, err := http.NewRequest("DELETE", ts.URL+"/api/v1/feeds/feed1", http.NoBody)
+		require.NoError(t, err)
+		req.SetBasicAuth("admin", "bad")
+		resp, err := ts.Client().Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close() // nolint
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+		assert.Equal(t, 0, len(editor.UpdateCalls()))
+	})
+
+	t.Run("feeds", func(t *testing.T) {
+		code, body := send("POST", "/api/v1/feeds/feed1", `{"title": "other"}`)
+		assert.Equal(t, http.StatusConflict, code)
+		assert.Contains(t, body, `feed \"feed1\" already exists`)
+		assert.Equal(t, "feed1", conf.Feeds["feed1"].Title)
+
+		code, body = send("POST", "/api/v1/feeds/feed2", `{"title": "feed2", "sources": [{"name": "s1", "url": "http://example.com/2"}]}`)
+		assert.Equal(t, http.StatusOK, code)
+		assert.JSONEq(t, `{"status": "ok", "feed": "feed2"}`, body)
+		assert.Equal(t, []config.Source{{Name: "s1", URL: "http://example.com/2"}}, conf.Feeds["feed2"].Sources)
+
+		code, _ = send("PUT", "/api/v1/feeds/feed2", `{"title": "feed2 updated", "telegram_channel": "chan",
+			"ext_date": "yyyymmdd", "owner_email": "me@example.com", "filter": {"title": "junk", "invert": true}}`)
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, "feed2 updated", conf.Feeds["feed2"].Title)
+		assert.Equal(t, "chan", conf.Feeds["feed2"].TelegramChannel)
+		assert.Equal(t, "yyyymmdd", conf.Feeds["feed2"].ExtendDateTitle)
+		assert.Equal(t, "me@example.com", conf.Feeds["feed2"].OwnerEmail)
+		assert.Equal(t, config.Filter{Title: "junk", Invert: true}, conf.Feeds["feed2"].Filter)
+
+		code, body = send("PUT", "/api/v1/feeds/feed2", `{"title": "invalid"}`)
+		assert.Equal(t, http.StatusBadRequest, code)
+		assert.Contains(t, body, "feeds.feed2.title: invalid")
+		assert.Equal(t, "feed2 updated", conf.Feeds["feed2"].Title)
+
+		code, _ = send("PUT", "/api/v1/feeds/feed2", `bad json`)
+		assert.Equal(t, http.StatusBadRequest, code)
+
+		code, _ = send("DELETE", "/api/v1/feeds/feed2", "")
+		assert.Equal(t, http.StatusOK, code)
+		_, ok := conf.Feeds["feed2"]
+		assert.False(t, ok)
+
+		code, _ = send("DELETE", "/api/v1/feeds/feed2", "")
+		assert.Equal(t, http.StatusNotFound, code)
+	})
+
+	t.Run("sources", func(t *testing.T) {
+		code, _ := send("POST", "/api/v1/feeds/feed1/sources", `{"name": "src1", "url": "http://example.com/other"}`)
+		assert.Equal(t, http.StatusConflict, code)
+		code, _ = send("POST", "/api/v1/feeds/feed1/sources", `{"url": "http://example.com/other"}`)
+		assert.Equal(t, http.StatusBadRequest, code)
+		code, _ = send("POST", "/api/v1/feeds/unknown/sources", `{"name": "src2", "url": "http://example.com/2"}`)
+		assert.Equal(t, http.StatusNotFound, code)
+
+		code, body := send("POST", "/api/v1/feeds/feed1/sources", `{"name": "src2", "url": "http://example.com/2"}`)
+		assert.Equal(t, http.StatusOK, code)
+		assert.JSONEq(t, `{"status": "ok", "feed": "feed1", "source": "src2"}`, body)
+
+		code, _ = send("PUT", "/api/v1/feeds/feed1/sources/src1", `{"url": "http://example.com/1a"}`)
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, []config.Source{{Name: "src1", URL: "http://example.com/1a"}, {Name: "src2", URL: "http://example.com/2"}},
+			conf.Feeds["feed1"].Sources)
+
+		code, _ = send("DELETE", "/api/v1/feeds/feed1/sources/src1", "")
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, []config.Source{{Name: "src2", URL: "http://example.com/2"}}, conf.Feeds["feed1"].Sources)
+		code, _ = send("DELETE", "/api/v1/feeds/feed1/sources/src1", "")
+		assert.Equal(t, http.StatusNotFound, code)
+	})
+
+	t.Run("youtube channels", func(t *testing.T) {
+		code, _ := send("POST", "/api/v1/yt/channels/ch1", `{"name": "other"}`)
+		assert.Equal(t, http.StatusConflict, code)
+
+		code, body := send("POST", "/api/v1/yt/channels/ch2", `{"name": "name2", "type": "playlist", "keep": 7}`)
+		assert.Equal(t, http.StatusOK, code)
+		assert.JSONEq(t, `{"status": "ok", "channel": "ch2"}`, body)
+		require.Equal(t, 2, len(conf.YouTube.Channels))
+		assert.Equal(t, youtube.FeedInfo{ID: "ch2", Name: "name2", Type: "playlist", Keep: 7}, conf.YouTube.Channels[1])
+
+		code, _ = send("PUT", "/api/v1/yt/channels/ch1", `{"id": "ignored", "name": "name1 updated"}`)
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, youtube.FeedInfo{ID: "ch1", Name: "name1 updated"}, conf.YouTube.Channels[0])
+
+		code, body = send("PUT", "/api/v1/yt/channels/ch1", `{"name": "name1", "lang": "en", "cover": "https://example.com/c.jpg",
+			"skip_segments": ["sponsor"], "skip_postprocess": ["all"], "filter": {"include": "news"}}`)
+		assert.Equal(t, http.StatusOK, code, body)
+		assert.Equal(t, youtube.FeedInfo{ID: "ch1", Name: "name1", Language: "en", Cover: "https://example.com/c.jpg",
+			SkipSegments: []string{"sponsor"}, SkipPostProcess: []string{"all"}, Filter: youtube.FeedFilter{Include: "news"}},
+			conf.YouTube.Channels[0])
+
+		code, body = send("PUT", "/api/v1/yt/channels/ch1", `{"name": "name1", "dl_template": "touch /tmp/x; yt-dlp {{.ID}}"}`)
+		assert.Equal(t, http.StatusBadRequest, code)
+		assert.Contains(t, body, "dl_template can be set in the config file only")
+		code, _ = send("POST", "/api/v1/yt/channels/ch3", `{"name": "name3", "dl_template": "yt-dlp {{.ID}}"}`)
+		assert.Equal(t, http.StatusBadRequest, code)
+		code, body = send("PUT", "/api/v1/yt/channels/ch1", `{"name": "name1", "cover": "/etc/passwd"}`)
+		assert.Equal(t, http.StatusBadRequest, code)
+		assert.Contains(t, body, "cover should be http(s) url")
+		assert.Equal(t, "name1", conf.YouTube.Channels[0].Name)
+
+		conf.YouTube.Channels[0].DlTemplate, conf.YouTube.Channels[0].Cover = "yt-dlp {{.ID}}", "/srv/cover.jpg"
+		code, body = send("PUT", "/api/v1/yt/channels/ch1", `{"name": "name1 again", "cover": "/srv/cover.jpg"}`)
+		assert.Equal(t, http.StatusOK, code, body)
+		assert.Equal(t, youtube.FeedInfo{ID: "ch1", Name: "name1 again", Cover: "/srv/cover.jpg", DlTemplate: "yt-dlp {{.ID}}"},
+			conf.YouTube.Channels[0], "template from the config file kept")
+
+		code, _ = send("DELETE", "/api/v1/yt/channels/ch1", "")
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, []youtube.FeedInfo{{ID: "ch2", Name: "name2", Type: "playlist", Keep: 7}}, conf.YouTube.Channels)
+		code, _ = send("DELETE", "/api/v1/yt/channels/ch1", "")
+		assert.Equal(t, http.StatusNotFound, code)
+	})
+}
+
+func TestServer_adminCtrlNoEditor(t *testing.T) {
+	s := Server{Version: "1.0", AdminPasswd: "123456"}
+	ts := httptest.NewServer(s.router())
+	defer ts.Close()
+
+	req, err := http.NewRequest("DELETE", ts.URL+"/api/v1/feeds/feed1", http.NoBody)
+	require.NoError(t, err)
+	req.SetBasicAuth("admin", "123456")
+	resp, err := ts.Client().Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close() // nolint
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+}
+
+func TestServer_youtubeQueueCtrl(t *testing.T) {
+	item := ytfeed.QueueItem{Entry: ytfeed.Entry{ChannelID: "ch1", VideoID: "vid1"}, State: ytfeed.QueueFailed,
+		Attempts: 2, Error: "network error"}
+	queue := &mocks.YoutubeQueueMock{
+		QueueFunc: func(state ytfeed.QueueState) ([]ytfeed.QueueItem, error) {
+			return []ytfeed.QueueItem{item}, nil
+		},
+		RetryQueuedFunc: func(chanID, videoID string) (ytfeed.QueueItem, error) {
+			switch videoID {
+			case "vid1":
+				return ytfeed.QueueItem{Entry: item.Entry, State: ytfeed.QueuePending}, nil
+			case "done":
+				return ytfeed.QueueItem{}, fmt.Errorf("entry done is done: %w", youtube.ErrQueueState)
+			}
+			return ytfeed.QueueItem{}, fmt.Errorf("entry: %w", youtube.ErrNotQueued)
+		},
+		GiveUpQueuedFunc: func(chanID, videoID string) (ytfeed.QueueItem, error) {
+			return ytfeed.QueueItem{Entry: item.Entry, State: ytfeed.QueueSkipped, Error: "given up"}, nil
+		},
+	}
+	s := Server{Version: "1.0", AdminPasswd: "123456", YoutubeQueue: queue}
+	ts := httptest.NewServer(s.router())
+	defer ts.Close()
+
+	reqNum := 0
+	send := func(method, path string, auth bool) (code int, body string) {
+		reqNum++
+		req, err := http.NewRequest(method, ts.URL+path, http.NoBody)
+		require.NoError(t, err)
+		if auth {
+			req.SetBasicAuth("admin", "123456")
+		}
+		req.Header.Set("X-Real-IP", fmt.Sprintf("10.0.1.%d", reqNum)) // avoid rate limiter
+		resp, err := ts.Client().Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close() // nolint
+		b, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, string(b)
+	}
+
+	code, _ := send("GET", "/api/v1/yt/queue", false)
+	assert.Equal(t, http.StatusUnauthorized, code)
+
+	code, body := send("GET", "/api/v1/yt/queue?state=failed", true)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, `"state":"failed","attempts":2,"error":"network error"`)
+	require.Equal(t, 1, len(queue.QueueCalls()))
+	assert.Equal(t, ytfeed.QueueFailed, queue.QueueCalls()[0].State)
+
+	code, _ = send("GET", "/api/v1/yt/queue?state=bad", true)
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	code, _ = send("POST", "/api/v1/yt/queue/ch1/vid1/retry", false)
+	assert.Equal(t, http.StatusUnauthorized, code)
+
+	code, body = send("POST", "/api/v1/yt/queue/ch1/vid1/retry", true)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, `"state":"pending"`)
+	require.Equal(t, 1, len(queue.RetryQueuedCalls()))
+	assert.Equal(t, "ch1", queue.RetryQueuedCalls()[0].ChanID)
+	assert.Equal(t, "vid1", queue.RetryQueuedCalls()[0].VideoID)
+
+	code, _ = send("POST", "/api/v1/yt/queue/ch1/done/retry", true)
+	assert.Equal(t, http.StatusConflict, code)
+	code, _ = send("POST", "/api/v1/yt/queue/ch1/unknown/retry", true)
+	assert.Equal(t, http.StatusNotFound, code)
+
+	code, body = send("POST", "/api/v1/yt/queue/ch1/vid1/give-up", true)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, `"state":"skipped","attempts":0,"error":"given up"`)
+	assert.Equal(t, 1, len(queue.GiveUpQueuedCalls()))
+
+	code, _ = send("POST", "/api/v1/yt/queue/ch1/vid1/other", true)
+	assert.Equal(t, http.StatusNotFound, code)
+	assert.Equal(t, 3, len(queue.RetryQueuedCalls()))
+
+	s.YoutubeQueue = nil
+	code, _ = send("GET", "/api/v1/yt/queue", true)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	code, _ = send("POST", "/api/v1/yt/queue/ch1/vid1/retry", true)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+}
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5/middleware"
+	log "github.com/go-pkgz/lgr"
+)
+
+// auth requires either bearer token with the scope or basic auth with admin password, which grants all scopes.
+// Requests passed authentication are logged with the token name for audit. Unauthorized responses ask for
+// basic auth if admin password is set.
+func (s *Server) auth(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			actor, status := s.authenticate(r, scope)
+			if status != http.StatusOK {
+				if status == http.StatusUnauthorized && s.AdminPasswd != "" {
+					w.Header().Set("WWW-Authenticate", `Basic realm="feed-master"`)
+				}
+				w.WriteHeader(status)
+				return
+			}
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r)
+			log.Printf("[INFO] audit: %s %s by %s, status %d", r.Method, r.URL.Path, actor, ww.Status())
+		}
+		return http.HandlerFunc(fn)
+	}
+}
+
+// authenticate returns the name of the actor or error status, unauthorized for missing or unknown credentials
+// and forbidden for wrong password or token without the scope
+func (s *Server) authenticate(r *http.Request, scope string) (actor string, status int) {
+	if h := r.Header.Get("Authorization"); strings.HasPrefix(h, "Bearer ") {
+		if s.Tokens == nil {
+			return "", http.StatusUnauthorized
+		}
+		tkn, found, err := s.Tokens.Find(strings.TrimSpace(strings.TrimPrefix(h, "Bearer ")))
+		if err != nil {
+			log.Printf("[WARN] can't check api token, %v", err)
+			return "", http.StatusInternalServerError
+		}
+		if !found {
+			return "", http.StatusUnauthorized
+		}
+		actor = fmt.Sprintf("token %q", tkn.Name)
+		if !tkn.HasScope(scope) {
+			log.Printf("[WARN] %s has no scope %s for %s %s", actor, scope, r.Method, r.URL.Path)
+			return "", http.StatusForbidden
+		}
+		return actor, http.StatusOK
+	}
+
+	user, passwd, ok := r.BasicAuth()
+	if !ok {
+		return "", http.StatusUnauthorized
+	}
+	if s.AdminPasswd == "" || (subtle.ConstantTimeCompare([]byte(s.AdminPasswd), []byte(passwd))+
+		subtle.ConstantTimeCompare([]byte("admin"), []byte(user))) != 2 {
+		return "", http.StatusForbidden
+	}
+	return "admin", http.StatusOK
+}
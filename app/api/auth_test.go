@@ -0,0 +1,75 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/umputun/feed-master/app/api/mocks"
+	"github.com/umputun/feed-master/app/token"
+)
+
+func TestServer_auth(t *testing.T) {
+	tokens := &mocks.TokenStoreMock{FindFunc: func(secret string) (token.Token, bool, error) {
+		switch secret {
+		case "fm_feeds":
+			return token.Token{Name: "ops", Scopes: []string{token.ScopeRead, token.ScopeFeedsAdmin}}, true, nil
+		case "fm_broken":
+			return token.Token{}, false, errors.New("db error")
+		}
+		return token.Token{}, false, nil
+	}}
+
+	tbl := []struct {
+		name   string
+		srv    *Server
+		scope  string
+		setReq func(r *http.Request)
+		status int
+	}{
+		{"bearer with scope", &Server{Tokens: tokens}, token.ScopeFeedsAdmin,
+			func(r *http.Request) { r.Header.Set("Authorization", "Bearer fm_feeds") }, http.StatusOK},
+		{"bearer without scope", &Server{Tokens: tokens}, token.ScopeYTAdmin,
+			func(r *http.Request) { r.Header.Set("Authorization", "Bearer fm_feeds") }, http.StatusForbidden},
+		{"unknown bearer", &Server{Tokens: tokens}, token.ScopeRead,
+			func(r *http.Request) { r.Header.Set("Authorization", "Bearer fm_bad") }, http.StatusUnauthorized},
+		{"unknown bearer with admin password", &Server{AdminPasswd: "123456", Tokens: tokens}, token.ScopeRead,
+			func(r *http.Request) { r.Header.Set("Authorization", "Bearer fm_bad") }, http.StatusUnauthorized},
+		{"bearer store error", &Server{Tokens: tokens}, token.ScopeRead,
+			func(r *http.Request) { r.Header.Set("Authorization", "Bearer fm_broken") }, http.StatusInternalServerError},
+		{"bearer without store", &Server{}, token.ScopeRead,
+			func(r *http.Request) { r.Header.Set("Authorization", "Bearer fm_feeds") }, http.StatusUnauthorized},
+		{"basic admin", &Server{AdminPasswd: "123456", Tokens: tokens}, token.ScopeYTAdmin,
+			func(r *http.Request) { r.SetBasicAuth("admin", "123456") }, http.StatusOK},
+		{"basic wrong password", &Server{AdminPasswd: "123456"}, token.ScopeRead,
+			func(r *http.Request) { r.SetBasicAuth("admin", "bad") }, http.StatusForbidden},
+		{"basic without admin password", &Server{}, token.ScopeRead,
+			func(r *http.Request) { r.SetBasicAuth("admin", "") }, http.StatusForbidden},
+		{"no credentials", &Server{AdminPasswd: "123456", Tokens: tokens}, token.ScopeRead,
+			func(r *http.Request) {}, http.StatusUnauthorized},
+	}
+
+	for _, tt := range tbl {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			h := tt.srv.auth(tt.scope)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				called = true
+			}))
+			req := httptest.NewRequest("POST", "/api/v1/feeds/f1", http.NoBody)
+			tt.setReq(req)
+			rr := httptest.NewRecorder()
+			h.ServeHTTP(rr, req)
+			assert.Equal(t, tt.status, rr.Code)
+			assert.Equal(t, tt.status == http.StatusOK, called)
+			if tt.status == http.StatusUnauthorized && tt.srv.AdminPasswd != "" {
+				assert.Equal(t, `Basic realm="feed-master"`, rr.Header().Get("WWW-Authenticate"))
+			} else {
+				assert.Empty(t, rr.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
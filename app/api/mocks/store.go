@@ -11,23 +11,35 @@ import (
 
 // StoreMock is a mock implementation of api.Store.
 //
-// 	func TestSomethingThatUsesStore(t *testing.T) {
+//	func TestSomethingThatUsesStore(t *testing.T) {
 //
-// 		// make and configure a mocked api.Store
-// 		mockedStore := &StoreMock{
-// 			LoadFunc: func(fmFeed string, max int, skipJunk bool) ([]feed.Item, error) {
-// 				panic("mock out the Load method")
-// 			},
-// 		}
+//		// make and configure a mocked api.Store
+//		mockedStore := &StoreMock{
+//			LoadFunc: func(fmFeed string, max int, skipJunk bool) ([]feed.Item, error) {
+//				panic("mock out the Load method")
+//			},
+//			LoadPageFunc: func(fmFeed string, cursor string, limit int, filter func(feed.Item) bool) ([]feed.Item, string, error) {
+//				panic("mock out the LoadPage method")
+//			},
+//			VersionFunc: func() (uint64, error) {
+//				panic("mock out the Version method")
+//			},
+//		}
 //
-// 		// use mockedStore in code that requires api.Store
-// 		// and then make assertions.
+//		// use mockedStore in code that requires api.Store
+//		// and then make assertions.
 //
-// 	}
+//	}
 type StoreMock struct {
 	// LoadFunc mocks the Load method.
 	LoadFunc func(fmFeed string, max int, skipJunk bool) ([]feed.Item, error)
 
+	// LoadPageFunc mocks the LoadPage method.
+	LoadPageFunc func(fmFeed string, cursor string, limit int, filter func(feed.Item) bool) ([]feed.Item, string, error)
+
+	// VersionFunc mocks the Version method.
+	VersionFunc func() (uint64, error)
+
 	// calls tracks calls to the methods.
 	calls struct {
 		// Load holds details about calls to the Load method.
@@ -39,8 +51,24 @@ type StoreMock struct {
 			// SkipJunk is the skipJunk argument value.
 			SkipJunk bool
 		}
+		// LoadPage holds details about calls to the LoadPage method.
+		LoadPage []struct {
+			// FmFeed is the fmFeed argument value.
+			FmFeed string
+			// Cursor is the cursor argument value.
+			Cursor string
+			// Limit is the limit argument value.
+			Limit int
+			// Filter is the filter argument value.
+			Filter func(feed.Item) bool
+		}
+		// Version holds details about calls to the Version method.
+		Version []struct {
+		}
 	}
-	lockLoad sync.RWMutex
+	lockLoad     sync.RWMutex
+	lockLoadPage sync.RWMutex
+	lockVersion  sync.RWMutex
 }
 
 // Load calls LoadFunc.
@@ -65,7 +93,8 @@ func (mock *StoreMock) Load(fmFeed string, max int, skipJunk bool) ([]feed.Item,
 
 // LoadCalls gets all the calls that were made to Load.
 // Check the length with:
-//     len(mockedStore.LoadCalls())
+//
+//	len(mockedStore.LoadCalls())
 func (mock *StoreMock) LoadCalls() []struct {
 	FmFeed   string
 	Max      int
@@ -81,3 +110,74 @@ func (mock *StoreMock) LoadCalls() []struct {
 	mock.lockLoad.RUnlock()
 	return calls
 }
+
+// LoadPage calls LoadPageFunc.
+func (mock *StoreMock) LoadPage(fmFeed string, cursor string, limit int, filter func(feed.Item) bool) ([]feed.Item, string, error) {
+	if mock.LoadPageFunc == nil {
+		panic("StoreMock.LoadPageFunc: method is nil but Store.LoadPage was just called")
+	}
+	callInfo := struct {
+		FmFeed string
+		Cursor string
+		Limit  int
+		Filter func(feed.Item) bool
+	}{
+		FmFeed: fmFeed,
+		Cursor: cursor,
+		Limit:  limit,
+		Filter: filter,
+	}
+	mock.lockLoadPage.Lock()
+	mock.calls.LoadPage = append(mock.calls.LoadPage, callInfo)
+	mock.lockLoadPage.Unlock()
+	return mock.LoadPageFunc(fmFeed, cursor, limit, filter)
+}
+
+// LoadPageCalls gets all the calls that were made to LoadPage.
+// Check the length with:
+//
+//	len(mockedStore.LoadPageCalls())
+func (mock *StoreMock) LoadPageCalls() []struct {
+	FmFeed string
+	Cursor string
+	Limit  int
+	Filter func(feed.Item) bool
+} {
+	var calls []struct {
+		FmFeed string
+		Cursor string
+		Limit  int
+		Filter func(feed.Item) bool
+	}
+	mock.lockLoadPage.RLock()
+	calls = mock.calls.LoadPage
+	mock.lockLoadPage.RUnlock()
+	return calls
+}
+
+// Version calls VersionFunc.
+func (mock *StoreMock) Version() (uint64, error) {
+	if mock.VersionFunc == nil {
+		panic("StoreMock.VersionFunc: method is nil but Store.Version was just called")
+	}
+	callInfo := struct {
+	}{}
+	mock.lockVersion.Lock()
+	mock.calls.Version = append(mock.calls.Version, callInfo)
+	mock.lockVersion.Unlock()
+	return mock.VersionFunc()
+}
+
+// VersionCalls gets all the calls that were made to Version.
+// Check the length with:
+//
+//	len(mockedStore.VersionCalls())
+func (mock *StoreMock) VersionCalls() []struct {
+} {
+	var calls []struct {
+	}
+	mock.lockVersion.RLock()
+	calls = mock.calls.Version
+	mock.lockVersion.RUnlock()
+	return calls
+}
@@ -12,26 +12,32 @@ import (
 
 // YoutubeSvcMock is a mock implementation of api.YoutubeSvc.
 //
-// 	func TestSomethingThatUsesYoutubeSvc(t *testing.T) {
+//	func TestSomethingThatUsesYoutubeSvc(t *testing.T) {
 //
-// 		// make and configure a mocked api.YoutubeSvc
-// 		mockedYoutubeSvc := &YoutubeSvcMock{
-// 			RSSFeedFunc: func(cinfo youtube.FeedInfo) (string, error) {
-// 				panic("mock out the RSSFeed method")
-// 			},
-// 			RemoveEntryFunc: func(entry ytfeed.Entry) error {
-// 				panic("mock out the RemoveEntry method")
-// 			},
-// 			StoreRSSFunc: func(chanID string, rss string) error {
-// 				panic("mock out the StoreRSS method")
-// 			},
-// 		}
+//		// make and configure a mocked api.YoutubeSvc
+//		mockedYoutubeSvc := &YoutubeSvcMock{
+//			FilteredRSSFeedFunc: func(cinfo youtube.FeedInfo, skip func(entry ytfeed.Entry) bool) (string, error) {
+//				panic("mock out the FilteredRSSFeed method")
+//			},
+//			RSSFeedFunc: func(cinfo youtube.FeedInfo) (string, error) {
+//				panic("mock out the RSSFeed method")
+//			},
+//			RemoveEntryFunc: func(entry ytfeed.Entry) error {
+//				panic("mock out the RemoveEntry method")
+//			},
+//			StoreRSSFunc: func(chanID string, rss string) error {
+//				panic("mock out the StoreRSS method")
+//			},
+//		}
 //
-// 		// use mockedYoutubeSvc in code that requires api.YoutubeSvc
-// 		// and then make assertions.
+//		// use mockedYoutubeSvc in code that requires api.YoutubeSvc
+//		// and then make assertions.
 //
-// 	}
+//	}
 type YoutubeSvcMock struct {
+	// FilteredRSSFeedFunc mocks the FilteredRSSFeed method.
+	FilteredRSSFeedFunc func(cinfo youtube.FeedInfo, skip func(entry ytfeed.Entry) bool) (string, error)
+
 	// RSSFeedFunc mocks the RSSFeed method.
 	RSSFeedFunc func(cinfo youtube.FeedInfo) (string, error)
 
@@ -43,6 +49,13 @@ type YoutubeSvcMock struct {
 
 	// calls tracks calls to the methods.
 	calls struct {
+		// FilteredRSSFeed holds details about calls to the FilteredRSSFeed method.
+		FilteredRSSFeed []struct {
+			// Cinfo is the cinfo argument value.
+			Cinfo youtube.FeedInfo
+			// Skip is the skip argument value.
+			Skip func(entry ytfeed.Entry) bool
+		}
 		// RSSFeed holds details about calls to the RSSFeed method.
 		RSSFeed []struct {
 			// Cinfo is the cinfo argument value.
@@ -61,9 +74,46 @@ type YoutubeSvcMock struct {
 			Rss string
 		}
 	}
-	lockRSSFeed     sync.RWMutex
-	lockRemoveEntry sync.RWMutex
-	lockStoreRSS    sync.RWMutex
+	lockFilteredRSSFeed sync.RWMutex
+	lockRSSFeed         sync.RWMutex
+	lockRemoveEntry     sync.RWMutex
+	lockStoreRSS        sync.RWMutex
+}
+
+// FilteredRSSFeed calls FilteredRSSFeedFunc.
+func (mock *YoutubeSvcMock) FilteredRSSFeed(cinfo youtube.FeedInfo, skip func(entry ytfeed.Entry) bool) (string, error) {
+	if mock.FilteredRSSFeedFunc == nil {
+		panic("YoutubeSvcMock.FilteredRSSFeedFunc: method is nil but YoutubeSvc.FilteredRSSFeed was just called")
+	}
+	callInfo := struct {
+		Cinfo youtube.FeedInfo
+		Skip  func(entry ytfeed.Entry) bool
+	}{
+		Cinfo: cinfo,
+		Skip:  skip,
+	}
+	mock.lockFilteredRSSFeed.Lock()
+	mock.calls.FilteredRSSFeed = append(mock.calls.FilteredRSSFeed, callInfo)
+	mock.lockFilteredRSSFeed.Unlock()
+	return mock.FilteredRSSFeedFunc(cinfo, skip)
+}
+
+// FilteredRSSFeedCalls gets all the calls that were made to FilteredRSSFeed.
+// Check the length with:
+//
+//	len(mockedYoutubeSvc.FilteredRSSFeedCalls())
+func (mock *YoutubeSvcMock) FilteredRSSFeedCalls() []struct {
+	Cinfo youtube.FeedInfo
+	Skip  func(entry ytfeed.Entry) bool
+} {
+	var calls []struct {
+		Cinfo youtube.FeedInfo
+		Skip  func(entry ytfeed.Entry) bool
+	}
+	mock.lockFilteredRSSFeed.RLock()
+	calls = mock.calls.FilteredRSSFeed
+	mock.lockFilteredRSSFeed.RUnlock()
+	return calls
 }
 
 // RSSFeed calls RSSFeedFunc.
@@ -84,7 +134,8 @@ func (mock *YoutubeSvcMock) RSSFeed(cinfo youtube.FeedInfo) (string, error) {
 
 // RSSFeedCalls gets all the calls that were made to RSSFeed.
 // Check the length with:
-//     len(mockedYoutubeSvc.RSSFeedCalls())
+//
+//	len(mockedYoutubeSvc.RSSFeedCalls())
 func (mock *YoutubeSvcMock) RSSFeedCalls() []struct {
 	Cinfo youtube.FeedInfo
 } {
@@ -115,7 +166,8 @@ func (mock *YoutubeSvcMock) RemoveEntry(entry ytfeed.Entry) error {
 
 // RemoveEntryCalls gets all the calls that were made to RemoveEntry.
 // Check the length with:
-//     len(mockedYoutubeSvc.RemoveEntryCalls())
+//
+//	len(mockedYoutubeSvc.RemoveEntryCalls())
 func (mock *YoutubeSvcMock) RemoveEntryCalls() []struct {
 	Entry ytfeed.Entry
 } {
@@ -148,7 +200,8 @@ func (mock *YoutubeSvcMock) StoreRSS(chanID string, rss string) error {
 
 // StoreRSSCalls gets all the calls that were made to StoreRSS.
 // Check the length with:
-//     len(mockedYoutubeSvc.StoreRSSCalls())
+//
+//	len(mockedYoutubeSvc.StoreRSSCalls())
 func (mock *YoutubeSvcMock) StoreRSSCalls() []struct {
 	ChanID string
 	Rss    string
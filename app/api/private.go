@@ -0,0 +1,261 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	log "github.com/go-pkgz/lgr"
+	"github.com/go-pkgz/rest"
+	"github.com/pkg/errors"
+
+	"github.com/umputun/feed-master/app/config"
+	"github.com/umputun/feed-master/app/token"
+	ytfeed "github.com/umputun/feed-master/app/youtube/feed"
+)
+
+// GET /private/{token}/rss/{name} - returns rss of private feed for subscriber, urls of youtube media
+// are rewritten to private media urls with the same token
+func (s *Server) getPrivateFeedCtrl(w http.ResponseWriter, r *http.Request) {
+	conf := s.conf()
+	feedName, secret := chi.URLParam(r, "name"), chi.URLParam(r, "token")
+	sub, ok := s.subscriber(w, r, secret, feedName, "")
+	if !ok {
+		return
+	}
+
+	mediaURL := strings.TrimSuffix(conf.YouTube.BaseURL, "/") + "/"
+	privateURL := strings.TrimSuffix(conf.System.BaseURL, "/") + "/private/" + secret + "/media/"
+	data, err := s.cache.Get("private::"+feedName+"::"+secret, func() ([]byte, error) {
+		return s.feedRSS(conf, feedName, func(u string) string {
+			if conf.YouTube.BaseURL == "" || !strings.HasPrefix(u, mediaURL) {
+				return u
+			}
+			return privateURL + path.Base(u)
+		})
+	})
+	if err != nil {
+		rest.SendErrorJSON(w, r, log.Default(), http.StatusBadRequest, err, "failed to get feed")
+		return
+	}
+
+	log.Printf("[INFO] private feed %s requested by %s", feedName, sub.Name)
+	w.Header().Set("Content-Type", "application/xml; charset=UTF-8")
+	_, _ = fmt.Fprintf(w, "%s", data)
+}
+
+// GET /private/{token}/media/{file} - returns youtube media file of private feed for subscriber,
+// the media itself or files derived from it, i.e. cached thumbnail and chapters
+func (s *Server) getPrivateMediaCtrl(w http.ResponseWriter, r *http.Request) {
+	conf := s.conf()
+	file := path.Base(chi.URLParam(r, "file"))
+	sub, ok := s.subscriber(w, r, chi.URLParam(r, "token"), "", file)
+	if !ok {
+		return
+	}
+	if !s.feedMedia(conf, sub.Feed)[file] {
+		rest.SendErrorJSON(w, r, log.Default(), http.StatusNotFound, fmt.Errorf("file %q not found in %s", file, sub.Feed),
+			"media not found")
+		return
+	}
+	log.Printf("[DEBUG] private media %s of %s requested by %s", file, sub.Feed, sub.Name)
+	http.ServeFile(w, r, filepath.Join(conf.YouTube.FilesLocation, file))
+}
+
+// subscriber checks the token of private feed request, feedName is optional and file is empty for rss requests.
+// Sends error response and returns false if the token is unknown, or it is not for the feed,
+// or the feed is not private (anymore).
+func (s *Server) subscriber(w http.ResponseWriter, r *http.Request, secret, feedName, file string) (token.Subscriber, bool) {
+	if s.Subscribers == nil {
+		rest.SendErrorJSON(w, r, log.Default(), http.StatusNotFound, errors.New("no subscribers store"), "feed not found")
+		return token.Subscriber{}, false
+	}
+	sub, found, err := s.Subscribers.Access(secret, file)
+	if err != nil {
+		rest.SendErrorJSON(w, r, log.Default(), http.StatusInternalServerError, err, "can't check subscriber")
+		return token.Subscriber{}, false
+	}
+	if f, ok := s.conf().Feeds[sub.Feed]; !found || !ok || !f.Private || (feedName != "" && sub.Feed != feedName) {
+		rest.SendErrorJSON(w, r, log.Default(), http.StatusNotFound, errors.New("unknown subscriber token"), "feed not found")
+		return token.Subscriber{}, false
+	}
+	return sub, true
+}
+
+// hidePrivateMedia rejects public requests of youtube media files belonging to private feeds
+func (s *Server) hidePrivateMedia(next http.Handler) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		if s.privateMedia(s.conf())[path.Base(r.URL.Path)] {
+			http.NotFound(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	}
+	return http.HandlerFunc(fn)
+}
+
+// privateMedia returns youtube media files of all private feeds, see feedMedia. The result is cached
+// until the store is changed or config reloaded, so it's not loaded on each media request.
+func (s *Server) privateMedia(conf config.Conf) map[string]bool {
+	version, err := s.Store.Version()
+	if err != nil {
+		log.Printf("[WARN] can't get store version, %v", err)
+		return s.loadPrivateMedia(conf)
+	}
+	s.private.Lock()
+	defer s.private.Unlock()
+	if s.private.files == nil || s.private.version != version {
+		s.private.files, s.private.version = s.loadPrivateMedia(conf), version
+	}
+	return s.private.files
+}
+
+// loadPrivateMedia loads youtube media files of all private feeds from the store
+func (s *Server) loadPrivateMedia(conf config.Conf) map[string]bool {
+	res := map[string]bool{}
+	for name, f := range conf.Feeds {
+		if !f.Private {
+			continue
+		}
+		for file := range s.feedMedia(conf, name) {
+			res[file] = true
+		}
+	}
+	return res
+}
+
+// hiddenEntries returns filter of youtube entries belonging to private feeds for requests without read scope,
+// nil for authenticated requests
+func (s *Server) hiddenEntries(r *http.Request, conf config.Conf) func(entry ytfeed.Entry) bool {
+	if _, status := s.authenticate(r, token.ScopeRead); status == http.StatusOK {
+		return nil
+	}
+	private := s.privateMedia(conf)
+	return func(entry ytfeed.Entry) bool { return private[path.Base(entry.File)] }
+}
+
+// feedMedia returns youtube media files of the feed items, enclosures and the files derived from them,
+// cached thumbnails and chapters
+func (s *Server) feedMedia(conf config.Conf, feedName string) map[string]bool {
+	res := map[string]bool{}
+	if conf.YouTube.BaseURL == "" {
+		return res
+	}
+	items, err := s.Store.Load(feedName, conf.System.MaxTotal, false)
+	if err != nil {
+		log.Printf("[WARN] can't load items of %s, %v", feedName, err)
+		return res
+	}
+	mediaURL := strings.TrimSuffix(conf.YouTube.BaseURL, "/") + "/"
+	for _, item := range items {
+		if !strings.HasPrefix(item.Enclosure.URL, mediaURL) {
+			continue
+		}
+		file := path.Base(item.Enclosure.URL)
+		res[file], res[ytfeed.ImageFile(file)], res[ytfeed.ChaptersFile(file)] = true, true, true
+	}
+	return res
+}
+
+// GET /api/v1/feeds/{name}/subscribers - returns subscribers of the feed with access counts
+func (s *Server) listSubscribersCtrl(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+	if !s.checkSubscribersFeed(w, r, name) {
+		return
+	}
+	subs, err := s.Subscribers.Subscribers(name)
+	if err != nil {
+		rest.SendErrorJSON(w, r, log.Default(), http.StatusInternalServerError, err, "can't load subscribers")
+		return
+	}
+	rest.RenderJSON(w, rest.JSON{"feed": name, "subscribers": subs})
+}
+
+// POST /api/v1/feeds/{name}/subscribers - issues subscriber token, request is {"name": "subscriber"}.
+// Response has the token and private rss url, the token can't be retrieved later.
+func (s *Server) addSubscriberCtrl(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+	req := struct {
+		Name string `json:"name"`
+	}{}
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		rest.SendErrorJSON(w, r, log.Default(), http.StatusBadRequest, err, "failed to parse request")
+		return
+	}
+	if req.Name == "" {
+		rest.SendErrorJSON(w, r, log.Default(), http.StatusBadRequest, errors.New("empty subscriber name"), "invalid subscriber")
+		return
+	}
+	if !s.checkSubscribersFeed(w, r, name) {
+		return
+	}
+	if _, found := s.findSubscriber(name, req.Name); found {
+		rest.SendErrorJSON(w, r, log.Default(), http.StatusConflict,
+			fmt.Errorf("subscriber %q of feed %q already exists", req.Name, name), "can't add subscriber")
+		return
+	}
+
+	secret, err := s.Subscribers.AddSubscriber(name, req.Name)
+	if err != nil {
+		rest.SendErrorJSON(w, r, log.Default(), http.StatusInternalServerError, err, "can't add subscriber")
+		return
+	}
+	rssURL := strings.TrimSuffix(s.conf().System.BaseURL, "/") + "/private/" + secret + "/rss/" + name
+	rest.RenderJSON(w, rest.JSON{"feed": name, "name": req.Name, "token": secret, "rss": rssURL})
+}
+
+// DELETE /api/v1/feeds/{name}/subscribers/{subscriber} - revokes subscriber token
+func (s *Server) deleteSubscriberCtrl(w http.ResponseWriter, r *http.Request) {
+	name, subName := chi.URLParam(r, "name"), chi.URLParam(r, "subscriber")
+	if !s.checkSubscribersFeed(w, r, name) {
+		return
+	}
+	if _, found := s.findSubscriber(name, subName); !found {
+		rest.SendErrorJSON(w, r, log.Default(), http.StatusNotFound,
+			fmt.Errorf("subscriber %q of feed %q not found", subName, name), "can't revoke subscriber")
+		return
+	}
+	if err := s.Subscribers.RevokeSubscriber(name, subName); err != nil {
+		rest.SendErrorJSON(w, r, log.Default(), http.StatusInternalServerError, err, "can't revoke subscriber")
+		return
+	}
+	rest.RenderJSON(w, rest.JSON{"status": "ok", "feed": name, "subscriber": subName})
+}
+
+// checkSubscribersFeed sends error response and returns false if subscribers are not available or the feed is unknown
+func (s *Server) checkSubscribersFeed(w http.ResponseWriter, r *http.Request, name string) bool {
+	if s.Subscribers == nil {
+		rest.SendErrorJSON(w, r, log.Default(), http.StatusServiceUnavailable, errors.New("no subscribers store"),
+			"subscribers are not available")
+		return false
+	}
+	if _, ok := s.conf().Feeds[name]; !ok {
+		rest.SendErrorJSON(w, r, log.Default(), http.StatusNotFound, fmt.Errorf("feed %q not found", name), "unknown feed")
+		return false
+	}
+	return true
+}
+
+func (s *Server) findSubscriber(fmFeed, name string) (token.Subscriber, bool) {
+	subs, err := s.Subscribers.Subscribers(fmFeed)
+	if err != nil {
+		log.Printf("[WARN] can't load subscribers of %s, %v", fmFeed, err)
+		return token.Subscriber{}, false
+	}
+	for _, sub := range subs {
+		if sub.Name == name {
+			return sub, true
+		}
+	}
+	return token.Subscriber{}, false
+}
+
+// publicFeed checks if the feed exists and is not private
+func publicFeed(conf config.Conf, name string) bool {
+	f, ok := conf.Feeds[name]
+	return ok && !f.Private
+}
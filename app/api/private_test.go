@@ -0,0 +1,300 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/go-pkgz/lcw/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/umputun/feed-master/app/api/mocks"
+	"github.com/umputun/feed-master/app/config"
+	"github.com/umputun/feed-master/app/feed"
+	"github.com/umputun/feed-master/app/token"
+	"github.com/umputun/feed-master/app/youtube"
+	ytfeed "github.com/umputun/feed-master/app/youtube/feed"
+)
+
+func TestServer_privateFeed(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "f1.mp3"), []byte("private media"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "f2.mp3"), []byte("public media"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "f1.jpg"), []byte("private image"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "f1.chapters.json"), []byte("private chapters"), 0o600))
+
+	store := &mocks.StoreMock{LoadFunc: func(fmFeed string, _ int, _ bool) ([]feed.Item, error) {
+		switch fmFeed {
+		case "internal":
+			return []feed.Item{
+				{GUID: "guid1", Title: "title1", Enclosure: feed.Enclosure{URL: "http://localhost/yt/media/f1.mp3"},
+					Image:    &feed.ItemImage{URL: "http://localhost/yt/media/f1.jpg"},
+					Chapters: &feed.PodcastChapters{URL: "http://localhost/yt/media/f1.chapters.json"}},
+				{GUID: "guid2", Title: "title2", Enclosure: feed.Enclosure{URL: "http://example.com/other.mp3"}},
+			}, nil
+		case "pub":
+			return []feed.Item{{GUID: "guid3", Title: "title3", Enclosure: feed.Enclosure{URL: "http://localhost/yt/media/f2.mp3"}}}, nil
+		}
+		return nil, fmt.Errorf("unknown feed %s", fmFeed)
+	}}
+	version := atomic.Uint64{}
+	store.VersionFunc = func() (uint64, error) { return version.Load(), nil }
+	subs := &mocks.SubscriberStoreMock{AccessFunc: func(secret, file string) (token.Subscriber, bool, error) {
+		switch secret {
+		case "fms_alice":
+			return token.Subscriber{Feed: "internal", Name: "alice"}, true, nil
+		case "fms_pub":
+			return token.Subscriber{Feed: "pub", Name: "bob"}, true, nil
+		}
+		return token.Subscriber{}, false, nil
+	}}
+	yt := &mocks.YoutubeSvcMock{FilteredRSSFeedFunc: func(_ youtube.FeedInfo, skip func(ytfeed.Entry) bool) (string, error) {
+		res := []string{}
+		for _, file := range []string{"/srv/f1.mp3", "/srv/f2.mp3"} {
+			if skip == nil || !skip(ytfeed.Entry{File: file}) {
+				res = append(res, file)
+			}
+		}
+		return strings.Join(res, ","), nil
+	}}
+
+	s := Server{
+		Version:       "1.0",
+		TemplLocation: "../webapp/templates/*",
+		Store:         store,
+		Subscribers:   subs,
+		YoutubeSvc:    yt,
+		AdminPasswd:   "123456",
+		cache:         lcw.NewNopCache[[]byte](),
+		Conf: config.Conf{
+			Feeds: map[string]config.Feed{
+				"internal": {Title: "internal feed", Private: true},
+				"pub":      {Title: "public feed"},
+			},
+		},
+	}
+	s.Conf.System.BaseURL = "http://example.com/"
+	s.Conf.YouTube.BaseURL = "http://localhost/yt/media"
+	s.Conf.YouTube.FilesLocation = dir
+
+	ts := httptest.NewServer(s.router())
+	defer ts.Close()
+
+	reqNum := 0
+	get := func(path string) (int, string) {
+		reqNum++
+		req, err := http.NewRequest("GET", ts.URL+path, http.NoBody)
+		require.NoError(t, err)
+		req.Header.Set("X-Real-IP", fmt.Sprintf("10.0.1.%d", reqNum)) // avoid rate limiter
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close() // nolint
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, string(body)
+	}
+
+	t.Run("private rss", func(t *testing.T) {
+		code, body := get("/private/fms_alice/rss/internal")
+		require.Equal(t, http.StatusOK, code, body)
+		assert.Contains(t, body, `<enclosure url="http://example.com/private/fms_alice/media/f1.mp3"`)
+		assert.Contains(t, body, `<enclosure url="http://example.com/other.mp3"`)
+		assert.Contains(t, body, `<podcast:chapters url="http://example.com/private/fms_alice/media/f1.chapters.json"`)
+		assert.Contains(t, body, `<itunes:image href="http://example.com/private/fms_alice/media/f1.jpg"`)
+		assert.NotContains(t, body, "http://localhost/yt/media")
+	})
+
+	t.Run("private rss with wrong token", func(t *testing.T) {
+		for _, path := range []string{"/private/fms_bad/rss/internal", "/private/fms_pub/rss/internal",
+			"/private/fms_pub/rss/pub", "/private/fms_alice/rss/pub"} {
+			code, _ := get(path)
+			assert.Equal(t, http.StatusNotFound, code, path)
+		}
+	})
+
+	t.Run("private feed hidden from public", func(t *testing.T) {
+		code, _ := get("/rss/internal")
+		assert.Equal(t, http.StatusNotFound, code)
+		code, _ = get("/api/v1/feeds/internal/items")
+		assert.Equal(t, http.StatusNotFound, code)
+		code, body := get("/api/v1/feeds")
+		assert.Equal(t, http.StatusOK, code)
+		assert.NotContains(t, body, "internal")
+		code, body = get("/list")
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, `["pub"]`+"\n", body)
+	})
+
+	t.Run("media", func(t *testing.T) {
+		code, body := get("/private/fms_alice/media/f1.mp3")
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, "private media", body)
+
+		code, body = get("/private/fms_alice/media/f1.jpg")
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, "private image", body)
+
+		for _, file := range []string{"f1.mp3", "f1.jpg", "f1.chapters.json"} {
+			code, _ = get("/yt/media/" + file)
+			assert.Equal(t, http.StatusNotFound, code, "private media is not public, %s", file)
+		}
+		code, body = get("/yt/media/f2.mp3")
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, "public media", body)
+
+		code, _ = get("/private/fms_alice/media/f2.mp3")
+		assert.Equal(t, http.StatusNotFound, code, "not in subscriber's feed")
+		code, _ = get("/private/fms_bad/media/f1.mp3")
+		assert.Equal(t, http.StatusNotFound, code)
+	})
+
+	t.Run("media range", func(t *testing.T) {
+		for path, exp := range map[string]string{"/yt/media/f2.mp3": "media", "/private/fms_alice/media/f1.mp3": "media"} {
+			reqNum++
+			req, err := http.NewRequest("GET", ts.URL+path, http.NoBody)
+			require.NoError(t, err)
+			req.Header.Set("X-Real-IP", fmt.Sprintf("10.0.1.%d", reqNum))
+			req.Header.Set("Range", "bytes=-5") // players seek with range requests
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			require.NoError(t, resp.Body.Close())
+			assert.Equal(t, http.StatusPartialContent, resp.StatusCode, path)
+			assert.Equal(t, exp, string(body), path)
+			assert.Equal(t, "bytes", resp.Header.Get("Accept-Ranges"), path)
+		}
+	})
+
+	t.Run("private media cached", func(t *testing.T) {
+		loads := func() (res int) {
+			for _, c := range store.LoadCalls() {
+				if c.FmFeed == "internal" {
+					res++
+				}
+			}
+			return res
+		}
+		code, _ := get("/yt/media/f2.mp3")
+		require.Equal(t, http.StatusOK, code)
+		before := loads()
+		for i := 0; i < 3; i++ {
+			code, _ = get("/yt/media/f1.mp3")
+			assert.Equal(t, http.StatusNotFound, code)
+		}
+		assert.Equal(t, before, loads(), "not reloaded")
+
+		version.Add(1)
+		code, _ = get("/yt/media/f1.mp3")
+		assert.Equal(t, http.StatusNotFound, code)
+		assert.Equal(t, before+1, loads(), "reloaded on store change")
+
+		s.SetConf(s.conf())
+		code, _ = get("/yt/media/f2.mp3")
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, before+2, loads(), "reloaded on config change")
+	})
+
+	t.Run("youtube rss", func(t *testing.T) {
+		req, err := http.NewRequest("GET", ts.URL+"/yt/rss/chan1", http.NoBody)
+		require.NoError(t, err)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		b, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Contains(t, string(b), "\n/srv/f2.mp3", "private entry skipped")
+		assert.Equal(t, "1", resp.Header.Get(feed.HiddenEntriesHeader))
+
+		req.SetBasicAuth("admin", "123456")
+		resp, err = http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		b, err = io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		assert.Contains(t, string(b), "\n/srv/f1.mp3,/srv/f2.mp3", "all entries for authenticated request")
+		assert.Equal(t, "", resp.Header.Get(feed.HiddenEntriesHeader))
+	})
+
+	require.Equal(t, 10, len(subs.AccessCalls()))
+	assert.Equal(t, "", subs.AccessCalls()[0].File)
+	assert.Equal(t, "f1.mp3", subs.AccessCalls()[5].File)
+}
+
+func TestServer_subscribersCtrl(t *testing.T) {
+	subs := []token.Subscriber{{Feed: "internal", Name: "alice", RSSHits: 5}}
+	store := &mocks.SubscriberStoreMock{
+		AddSubscriberFunc: func(fmFeed, name string) (string, error) {
+			subs = append(subs, token.Subscriber{Feed: fmFeed, Name: name})
+			return "fms_secret", nil
+		},
+		SubscribersFunc: func(fmFeed string) ([]token.Subscriber, error) { return subs, nil },
+		RevokeSubscriberFunc: func(fmFeed, name string) error {
+			subs = subs[:1]
+			return nil
+		},
+	}
+	s := Server{
+		Version:     "1.0",
+		Subscribers: store,
+		AdminPasswd: "123456",
+		cache:       lcw.NewNopCache[[]byte](),
+		Conf:        config.Conf{Feeds: map[string]config.Feed{"internal": {Title: "internal feed", Private: true}}},
+	}
+	s.Conf.System.BaseURL = "http://example.com"
+	ts := httptest.NewServer(s.router())
+	defer ts.Close()
+
+	reqNum := 0
+	send := func(method, path, body string) (int, string) {
+		reqNum++
+		req, err := http.NewRequest(method, ts.URL+path, bytes.NewBufferString(body))
+		require.NoError(t, err)
+		req.SetBasicAuth("admin", "123456")
+		req.Header.Set("X-Real-IP", fmt.Sprintf("10.0.2.%d", reqNum)) // avoid rate limiter
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close() // nolint
+		b, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, string(b)
+	}
+
+	code, body := send("POST", "/api/v1/feeds/internal/subscribers", `{"name": "bob"}`)
+	require.Equal(t, http.StatusOK, code, body)
+	res := map[string]string{}
+	require.NoError(t, json.Unmarshal([]byte(body), &res))
+	assert.Equal(t, map[string]string{"feed": "internal", "name": "bob", "token": "fms_secret",
+		"rss": "http://example.com/private/fms_secret/rss/internal"}, res)
+
+	code, _ = send("POST", "/api/v1/feeds/internal/subscribers", `{"name": "bob"}`)
+	assert.Equal(t, http.StatusConflict, code)
+	code, _ = send("POST", "/api/v1/feeds/internal/subscribers", `{}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = send("POST", "/api/v1/feeds/unknown/subscribers", `{"name": "bob"}`)
+	assert.Equal(t, http.StatusNotFound, code)
+
+	code, body = send("GET", "/api/v1/feeds/internal/subscribers", "")
+	require.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, `"name":"alice"`)
+	assert.Contains(t, body, `"rss_hits":5`)
+	assert.Contains(t, body, `"name":"bob"`)
+
+	code, _ = send("DELETE", "/api/v1/feeds/internal/subscribers/bob", "")
+	assert.Equal(t, http.StatusOK, code)
+	code, _ = send("DELETE", "/api/v1/feeds/internal/subscribers/bob", "")
+	assert.Equal(t, http.StatusNotFound, code)
+	require.Equal(t, 1, len(store.AddSubscriberCalls()))
+	require.Equal(t, 1, len(store.RevokeSubscriberCalls()))
+	assert.Equal(t, "bob", store.RevokeSubscriberCalls()[0].Name)
+}
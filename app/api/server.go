@@ -3,7 +3,6 @@ package api
 
 import (
 	"context"
-	"crypto/subtle"
 	"encoding/xml"
 	"fmt"
 	"html/template"
@@ -11,6 +10,8 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -28,12 +29,25 @@ import (
 
 	"github.com/umputun/feed-master/app/config"
 	"github.com/umputun/feed-master/app/feed"
+	"github.com/umputun/feed-master/app/health"
+	"github.com/umputun/feed-master/app/metrics"
+	"github.com/umputun/feed-master/app/proc"
+	"github.com/umputun/feed-master/app/token"
 	"github.com/umputun/feed-master/app/youtube"
 	ytfeed "github.com/umputun/feed-master/app/youtube/feed"
 )
 
 //go:generate moq -out mocks/yt_service.go -pkg mocks -skip-ensure -fmt goimports . YoutubeSvc
 //go:generate moq -out mocks/store.go -pkg mocks -skip-ensure -fmt goimports . Store
+//go:generate moq -out mocks/processor.go -pkg mocks -skip-ensure -fmt goimports . Processor
+//go:generate moq -out mocks/conf_editor.go -pkg mocks -skip-ensure -fmt goimports . ConfEditor
+//go:generate moq -out mocks/yt_store.go -pkg mocks -skip-ensure -fmt goimports . YoutubeStore
+//go:generate moq -out mocks/token_store.go -pkg mocks -skip-ensure -fmt goimports . TokenStore
+//go:generate moq -out mocks/yt_queue.go -pkg mocks -skip-ensure -fmt goimports . YoutubeQueue
+//go:generate moq -out mocks/subscriber_store.go -pkg mocks -skip-ensure -fmt goimports . SubscriberStore
+
+var httpDuration = metrics.NewHistogram("feed_master_http_request_duration_seconds",
+	"Latency of http requests by method, route and status", metrics.DefBuckets, "method", "route", "status")
 
 // Server provides HTTP API
 type Server struct {
@@ -42,29 +56,77 @@ type Server struct {
 	Store         Store
 	YoutubeStore  YoutubeStore
 	YoutubeSvc    YoutubeSvc
+	YoutubeQueue  YoutubeQueue
+	Processor     Processor
+	ConfEditor    ConfEditor
+	Tokens        TokenStore
+	Subscribers   SubscriberStore
+	HealthChecks  []health.Check
 	TemplLocation string
 	AdminPasswd   string
+	MetricsAuth   bool // require read scope for /metrics, public otherwise
 
 	httpServer *http.Server
 	cache      lcw.LoadingCache[[]byte]
 	templates  *template.Template
+	lock       sync.RWMutex
+
+	private struct { // media files of private feeds, reloaded on store changes and dropped on config reload
+		sync.Mutex
+		version uint64
+		files   map[string]bool
+	}
 }
 
 // YoutubeSvc provides access to youtube's audio rss
 type YoutubeSvc interface {
 	RSSFeed(cinfo youtube.FeedInfo) (string, error)
+	FilteredRSSFeed(cinfo youtube.FeedInfo, skip func(entry ytfeed.Entry) bool) (string, error)
 	StoreRSS(chanID, rss string) error
 	RemoveEntry(entry ytfeed.Entry) error
 }
 
+// YoutubeQueue provides access to download states of youtube entries
+type YoutubeQueue interface {
+	Queue(state ytfeed.QueueState) ([]ytfeed.QueueItem, error)
+	RetryQueued(chanID, videoID string) (ytfeed.QueueItem, error)
+	GiveUpQueued(chanID, videoID string) (ytfeed.QueueItem, error)
+}
+
 // Store provides access to feed data
 type Store interface {
 	Load(fmFeed string, max int, skipJunk bool) ([]feed.Item, error)
+	LoadPage(fmFeed, cursor string, limit int, filter func(feed.Item) bool) (items []feed.Item, next string, err error)
+	Version() (uint64, error)
+}
+
+// Processor provides access to feeds processing
+type Processor interface {
+	Preview(name string, fm config.Feed) []proc.PreviewSource
+}
+
+// ConfEditor changes feeds and youtube channels at runtime, fn gets a copy of the current config to change
+type ConfEditor interface {
+	Update(fn func(conf *config.Conf) error) error
+}
+
+// TokenStore finds api tokens by secret
+type TokenStore interface {
+	Find(secret string) (tkn token.Token, found bool, err error)
+}
+
+// SubscriberStore manages subscriber tokens of private feeds
+type SubscriberStore interface {
+	AddSubscriber(fmFeed, name string) (secret string, err error)
+	Subscribers(fmFeed string) ([]token.Subscriber, error)
+	RevokeSubscriber(fmFeed, name string) error
+	Access(secret, file string) (sub token.Subscriber, found bool, err error)
 }
 
 // YoutubeStore provides access to YouTube channel data
 type YoutubeStore interface {
 	Load(channelID string, max int) ([]ytfeed.Entry, error)
+	LoadPage(channelID, cursor string, limit int) (entries []ytfeed.Entry, next string, err error)
 }
 
 // Run starts http server for API with all routes
@@ -108,12 +170,33 @@ func (s *Server) Run(ctx context.Context, port int) {
 	log.Printf("[WARN] http server terminated, %s", err)
 }
 
+// SetConf replaces configuration and drops cached responses, used on config reload.
+// Changes of youtube media location require restart
+func (s *Server) SetConf(conf config.Conf) {
+	s.lock.Lock()
+	s.Conf = conf
+	s.lock.Unlock()
+	if s.cache != nil {
+		s.cache.Purge()
+	}
+	s.private.Lock()
+	s.private.files = nil
+	s.private.Unlock()
+}
+
+func (s *Server) conf() config.Conf {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.Conf
+}
+
 func (s *Server) router() *chi.Mux {
+	conf := s.conf()
 	router := chi.NewRouter()
 	router.Use(middleware.RealIP, rest.Recoverer(log.Default()), middleware.GetHead)
 	router.Use(middleware.Throttle(1000), middleware.Timeout(60*time.Second))
 	router.Use(rest.AppInfo("feed-master", "umputun", s.Version), rest.Ping)
-	router.Use(tollbooth_chi.LimitHandler(tollbooth.NewLimiter(5, nil)))
+	router.Use(tollbooth_chi.LimitHandler(tollbooth.NewLimiter(5, nil)), httpMetrics)
 
 	router.Group(func(rimg chi.Router) {
 		l := logger.New(logger.Log(log.Default()), logger.Prefix("[DEBUG]"), logger.IPfn(logger.AnonymizeIP))
@@ -135,36 +218,81 @@ func (s *Server) router() *chi.Mux {
 		rrss.Get("/feeds", s.getFeedsPageCtrl)
 	})
 
-	router.Get("/config", func(w http.ResponseWriter, _ *http.Request) { rest.RenderJSON(w, s.Conf) })
+	router.Route("/private/{token}", func(rprv chi.Router) {
+		rprv.Get("/rss/{name}", s.getPrivateFeedCtrl)
+		rprv.Head("/rss/{name}", s.getPrivateFeedCtrl)
+		rprv.Get("/media/{file}", s.getPrivateMediaCtrl)
+	})
 
-	router.Route("/yt", func(r chi.Router) {
+	if s.MetricsAuth {
+		router.With(s.auth(token.ScopeRead)).Get("/metrics", metrics.Handler().ServeHTTP)
+	} else {
+		router.Get("/metrics", metrics.Handler().ServeHTTP)
+	}
+	router.Get("/config/public", s.getPublicConfCtrl)
+	router.Get("/health", s.healthCtrl)
+	router.With(s.auth(token.ScopeFeedsAdmin)).Get("/config", s.getConfCtrl)
+
+	router.Group(func(radm chi.Router) {
+		l := logger.New(logger.Log(log.Default()), logger.Prefix("[INFO]"), logger.IPfn(logger.AnonymizeIP))
+		radm.Use(l.Handler, s.auth(token.ScopeFeedsAdmin)) // preview fetches arbitrary urls
+		radm.Post("/preview", s.previewCtrl)
+	})
 
-		auth := rest.BasicAuth(func(user, passwd string) bool {
-			return (subtle.ConstantTimeCompare([]byte(s.AdminPasswd), []byte(passwd)) +
-				subtle.ConstantTimeCompare([]byte("admin"), []byte(user))) == 2
+	router.Route("/api/v1", func(rapi chi.Router) {
+		l := logger.New(logger.Log(log.Default()), logger.Prefix("[INFO]"), logger.IPfn(logger.AnonymizeIP))
+		rapi.Use(l.Handler)
+		rapi.Get("/openapi.json", s.openAPICtrl)
+		rapi.Get("/feeds", s.listFeedsCtrl)
+		rapi.Get("/feeds/{name}/items", s.listItemsCtrl)
+		rapi.Get("/yt/channels", s.listYoutubeChannelsCtrl)
+		rapi.Get("/yt/channels/{id}/entries", s.listYoutubeEntriesCtrl)
+		rapi.With(s.auth(token.ScopeRead)).Get("/feeds/{name}/subscribers", s.listSubscribersCtrl)
+		rapi.With(s.auth(token.ScopeRead)).Get("/yt/queue", s.listYoutubeQueueCtrl)
+
+		rapi.Group(func(radm chi.Router) {
+			radm.Use(s.auth(token.ScopeFeedsAdmin))
+			radm.Post("/feeds/{name}", s.setFeedCtrl)
+			radm.Put("/feeds/{name}", s.setFeedCtrl)
+			radm.Delete("/feeds/{name}", s.deleteFeedCtrl)
+			radm.Post("/feeds/{name}/sources", s.setSourceCtrl)
+			radm.Put("/feeds/{name}/sources/{source}", s.setSourceCtrl)
+			radm.Delete("/feeds/{name}/sources/{source}", s.deleteSourceCtrl)
+			radm.Post("/feeds/{name}/subscribers", s.addSubscriberCtrl)
+			radm.Delete("/feeds/{name}/subscribers/{subscriber}", s.deleteSubscriberCtrl)
 		})
+		rapi.Group(func(radm chi.Router) {
+			radm.Use(s.auth(token.ScopeYTAdmin))
+			radm.Post("/yt/channels/{id}", s.setYoutubeChannelCtrl)
+			radm.Put("/yt/channels/{id}", s.setYoutubeChannelCtrl)
+			radm.Delete("/yt/channels/{id}", s.deleteYoutubeChannelCtrl)
+			radm.Post("/yt/queue/{channel}/{video}/retry", s.updateYoutubeQueueCtrl)
+			radm.Post("/yt/queue/{channel}/{video}/give-up", s.updateYoutubeQueueCtrl)
+		})
+	})
 
+	router.Route("/yt", func(r chi.Router) {
 		l := logger.New(logger.Log(log.Default()), logger.Prefix("[INFO]"), logger.IPfn(logger.AnonymizeIP))
 		r.Use(l.Handler)
 		r.Get("/rss/{channel}", s.getYoutubeFeedCtrl)
 		r.Get("/channels", s.getYoutubeChannelsPageCtrl)
-		r.With(auth).Post("/rss/generate", s.regenerateRSSCtrl)
-		r.With(auth).Delete("/entry/{channel}/{video}", s.removeEntryCtrl)
+		r.With(s.auth(token.ScopeYTAdmin)).Post("/rss/generate", s.regenerateRSSCtrl)
+		r.With(s.auth(token.ScopeYTAdmin)).Delete("/entry/{channel}/{video}", s.removeEntryCtrl)
 	})
 
-	if s.Conf.YouTube.BaseURL != "" {
-		baseYtURL, parseErr := url.Parse(s.Conf.YouTube.BaseURL)
+	if conf.YouTube.BaseURL != "" {
+		baseYtURL, parseErr := url.Parse(conf.YouTube.BaseURL)
 		if parseErr != nil {
-			log.Printf("[ERROR] failed to parse base url %s, %v", s.Conf.YouTube.BaseURL, parseErr)
+			log.Printf("[ERROR] failed to parse base url %s, %v", conf.YouTube.BaseURL, parseErr)
 		}
 
-		if mkdirErr := os.MkdirAll(s.Conf.YouTube.FilesLocation, 0o750); mkdirErr != nil {
-			log.Printf("[ERROR] failed to create directory %s, %v", s.Conf.YouTube.FilesLocation, mkdirErr)
+		if mkdirErr := os.MkdirAll(conf.YouTube.FilesLocation, 0o750); mkdirErr != nil {
+			log.Printf("[ERROR] failed to create directory %s, %v", conf.YouTube.FilesLocation, mkdirErr)
 		}
 
-		ytfs, fsErr := rest.NewFileServer(baseYtURL.Path, s.Conf.YouTube.FilesLocation)
+		ytfs, fsErr := rest.NewFileServer(baseYtURL.Path, conf.YouTube.FilesLocation)
 		if fsErr == nil {
-			router.Mount(baseYtURL.Path, ytfs)
+			router.Mount(baseYtURL.Path, s.hidePrivateMedia(ytfs))
 		} else {
 			log.Printf("[WARN] can't start static file server for yt, %v", fsErr)
 		}
@@ -181,81 +309,153 @@ func (s *Server) router() *chi.Mux {
 
 // GET /rss/{name} - returns rss for given feeds set
 func (s *Server) getFeedCtrl(w http.ResponseWriter, r *http.Request) {
+	conf := s.conf()
 	feedName := chi.URLParam(r, "name")
+	if !publicFeed(conf, feedName) {
+		rest.SendErrorJSON(w, r, log.Default(), http.StatusNotFound, fmt.Errorf("feed %q not found", feedName), "failed to get feed")
+		return
+	}
 
 	data, err := s.cache.Get("feed::"+feedName, func() ([]byte, error) {
-		items, err := s.Store.Load(feedName, s.Conf.System.MaxTotal, true)
-		if err != nil {
-			return nil, err
-		}
+		return s.feedRSS(conf, feedName, nil)
+	})
+
+	if err != nil {
+		rest.SendErrorJSON(w, r, log.Default(), http.StatusBadRequest, err, "failed to get feed")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/xml; charset=UTF-8")
+	_, _ = fmt.Fprintf(w, "%s", data)
+}
+
+// feedRSS makes rss of the feed, mediaFn is optional and changes enclosure, chapters and image urls of items
+func (s *Server) feedRSS(conf config.Conf, feedName string, mediaFn func(u string) string) ([]byte, error) {
+	items, err := s.Store.Load(feedName, conf.System.MaxTotal, true)
+	if err != nil {
+		return nil, err
+	}
 
-		for i, itm := range items {
-			// add ts suffix to titles
-			switch s.Conf.Feeds[feedName].ExtendDateTitle {
-			case "yyyyddmm":
-				items[i].Title = fmt.Sprintf("%s (%s)", itm.Title, itm.DT.Format("2006-02-01")) // nolint
-			case "yyyymmdd":
-				items[i].Title = fmt.Sprintf("%s (%s)", itm.Title, itm.DT.Format("2006-01-02"))
+	hasChapters := false
+	for i, itm := range items {
+		hasChapters = hasChapters || itm.Chapters != nil
+		// add ts suffix to titles
+		switch conf.Feeds[feedName].ExtendDateTitle {
+		case "yyyyddmm":
+			items[i].Title = fmt.Sprintf("%s (%s)", itm.Title, itm.DT.Format("2006-02-01")) // nolint
+		case "yyyymmdd":
+			items[i].Title = fmt.Sprintf("%s (%s)", itm.Title, itm.DT.Format("2006-01-02"))
+		}
+		if mediaFn != nil {
+			items[i].Enclosure.URL = mediaFn(itm.Enclosure.URL)
+			if itm.Chapters != nil {
+				chapters := *itm.Chapters
+				chapters.URL = mediaFn(chapters.URL)
+				items[i].Chapters = &chapters
+			}
+			if itm.Image != nil {
+				image := *itm.Image
+				image.URL = mediaFn(image.URL)
+				items[i].Image = &image
 			}
 		}
+	}
 
-		rss := feed.Rss2{
-			Version:        "2.0",
-			ItemList:       items,
-			Title:          s.Conf.Feeds[feedName].Title,
-			Description:    s.Conf.Feeds[feedName].Description,
-			Language:       s.Conf.Feeds[feedName].Language,
-			Link:           s.Conf.Feeds[feedName].Link,
-			PubDate:        items[0].PubDate,
-			LastBuildDate:  time.Now().Format(time.RFC822Z),
-			ItunesAuthor:   s.Conf.Feeds[feedName].Author,
-			ItunesExplicit: "no",
-			ItunesOwner: &feed.ItunesOwner{
-				Name:  "Feed Master",
-				Email: s.Conf.Feeds[feedName].OwnerEmail,
-			},
-			NsItunes: "http://www.itunes.com/dtds/podcast-1.0.dtd",
-			NsMedia:  "http://search.yahoo.com/mrss/",
-		}
+	rss := feed.Rss2{
+		Version:        "2.0",
+		ItemList:       items,
+		Title:          conf.Feeds[feedName].Title,
+		Description:    conf.Feeds[feedName].Description,
+		Language:       conf.Feeds[feedName].Language,
+		Link:           conf.Feeds[feedName].Link,
+		PubDate:        items[0].PubDate,
+		LastBuildDate:  time.Now().Format(time.RFC822Z),
+		ItunesAuthor:   conf.Feeds[feedName].Author,
+		ItunesExplicit: "no",
+		ItunesOwner: &feed.ItunesOwner{
+			Name:  "Feed Master",
+			Email: conf.Feeds[feedName].OwnerEmail,
+		},
+		NsItunes: "http://www.itunes.com/dtds/podcast-1.0.dtd",
+		NsMedia:  "http://search.yahoo.com/mrss/",
+	}
+	if hasChapters {
+		rss.NsPodcast = "https://podcastindex.org/namespace/1.0"
+	}
 
-		// replace link to UI page
-		if s.Conf.System.BaseURL != "" {
-			baseURL := strings.TrimSuffix(s.Conf.System.BaseURL, "/")
-			rss.Link = baseURL + "/feed/" + feedName
-			imagesURL := baseURL + "/images/" + feedName
-			rss.ItunesImage = &feed.ItunesImg{URL: imagesURL}
-			rss.MediaThumbnail = &feed.MediaThumbnail{URL: imagesURL}
-		}
+	// replace link to UI page
+	if conf.System.BaseURL != "" {
+		baseURL := strings.TrimSuffix(conf.System.BaseURL, "/")
+		rss.Link = baseURL + "/feed/" + feedName
+		imagesURL := baseURL + "/images/" + feedName
+		rss.ItunesImage = &feed.ItunesImg{URL: imagesURL}
+		rss.MediaThumbnail = &feed.MediaThumbnail{URL: imagesURL}
+	}
 
-		b, err := xml.MarshalIndent(&rss, "", "  ")
-		if err != nil {
-			rest.SendErrorJSON(w, r, log.Default(), http.StatusInternalServerError, err, "failed to marshal rss")
-			return nil, errors.Wrapf(err, "failed to marshal rss for %s", feedName)
-		}
+	b, err := xml.MarshalIndent(&rss, "", "  ")
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to marshal rss for %s", feedName)
+	}
 
-		res := `<?xml version="1.0" encoding="UTF-8"?>` + "\n" + string(b)
+	res := `<?xml version="1.0" encoding="UTF-8"?>` + "\n" + string(b)
 
-		// this hack to avoid having different items for marshal and unmarshal due to "itunes" namespace
-		res = strings.Replace(res, "<duration>", "<itunes:duration>", -1)
-		res = strings.Replace(res, "</duration>", "</itunes:duration>", -1)
+	// this hack to avoid having different items for marshal and unmarshal due to "itunes" namespace
+	res = strings.Replace(res, "<duration>", "<itunes:duration>", -1)
+	res = strings.Replace(res, "</duration>", "</itunes:duration>", -1)
+	res = strings.Replace(res, "<chapters ", "<podcast:chapters ", -1)
+	res = strings.Replace(res, "</chapters>", "</podcast:chapters>", -1)
+	res = strings.Replace(res, "<image href=", "<itunes:image href=", -1)
+	res = strings.Replace(res, "</image>", "</itunes:image>", -1)
 
-		return []byte(res), nil
-	})
+	return []byte(res), nil
+}
 
-	if err != nil {
-		rest.SendErrorJSON(w, r, log.Default(), http.StatusBadRequest, err, "failed to get feed")
-		return
+// GET /config - returns the whole config with sensitive fields redacted
+func (s *Server) getConfCtrl(w http.ResponseWriter, _ *http.Request) {
+	conf := s.conf()
+	rest.RenderJSON(w, conf.Redacted())
+}
+
+// GET /config/public - returns update intervals and titles with links of public feeds
+func (s *Server) getPublicConfCtrl(w http.ResponseWriter, _ *http.Request) {
+	conf := s.conf()
+	type feedLink struct {
+		Name  string `json:"name"`
+		Title string `json:"title"`
+		Link  string `json:"link"`
+		RSS   string `json:"rss"`
+	}
+	feeds := []feedLink{}
+	for _, name := range s.feeds() {
+		f := conf.Feeds[name]
+		feeds = append(feeds, feedLink{Name: name, Title: f.Title, Link: f.Link,
+			RSS: strings.TrimSuffix(conf.System.BaseURL, "/") + "/rss/" + name})
 	}
+	sort.Slice(feeds, func(i, j int) bool { return feeds[i].Name < feeds[j].Name })
+	rest.RenderJSON(w, rest.JSON{
+		"update_interval":         conf.System.UpdateInterval.String(),
+		"youtube_update_interval": conf.YouTube.UpdateInterval.String(),
+		"feeds":                   feeds,
+	})
+}
 
-	w.Header().Set("Content-Type", "application/xml; charset=UTF-8")
-	_, _ = fmt.Fprintf(w, "%s", data)
+// GET /health - returns results of all health checks, responds with 503 if any of them failed
+func (s *Server) healthCtrl(w http.ResponseWriter, r *http.Request) {
+	res, ok := health.Run(s.HealthChecks)
+	status := "ok"
+	if !ok {
+		status = "failed"
+		render.Status(r, http.StatusServiceUnavailable)
+	}
+	render.JSON(w, r, rest.JSON{"status": status, "checks": res})
 }
 
 // GET /image/{name}
 func (s *Server) getImageCtrl(w http.ResponseWriter, r *http.Request) {
+	conf := s.conf()
 	fm := chi.URLParam(r, "name")
 	fm = strings.TrimSuffix(fm, ".png")
-	feedConf, found := s.Conf.Feeds[fm]
+	feedConf, found := conf.Feeds[fm]
 	if !found {
 		rest.SendErrorJSON(w, r, log.Default(), http.StatusBadRequest,
 			fmt.Errorf("image %s not found", fm), "failed to load image")
@@ -280,24 +480,41 @@ func (s *Server) getListCtrl(w http.ResponseWriter, r *http.Request) {
 	render.JSON(w, r, feeds)
 }
 
-// GET /yt/rss/{channel} - returns rss for given youtube channel
+// GET /yt/rss/{channel} - returns rss for given youtube channel. Entries of private feeds' items are returned
+// to authenticated requests only, so private feeds can use it as a source with credentials. Number of hidden
+// entries is reported in X-Hidden-Entries header.
 func (s *Server) getYoutubeFeedCtrl(w http.ResponseWriter, r *http.Request) {
+	conf := s.conf()
 	channel := chi.URLParam(r, "channel")
 
 	fi := youtube.FeedInfo{ID: channel}
-	for _, f := range s.Conf.YouTube.Channels {
+	for _, f := range conf.YouTube.Channels {
 		if f.ID == channel {
 			fi = f
 			break
 		}
 	}
 
-	res, err := s.YoutubeSvc.RSSFeed(fi)
+	hidden, skip := 0, s.hiddenEntries(r, conf)
+	if skip != nil {
+		hiddenEntry := skip
+		skip = func(entry ytfeed.Entry) bool {
+			if hiddenEntry(entry) {
+				hidden++
+				return true
+			}
+			return false
+		}
+	}
+	res, err := s.YoutubeSvc.FilteredRSSFeed(fi, skip)
 	if err != nil {
 		rest.SendErrorJSON(w, r, log.Default(), http.StatusInternalServerError, err, "failed to read yt list")
 		return
 	}
 
+	if hidden > 0 { // lets feed-master's own sources tell hidden entries from removed ones
+		w.Header().Set(feed.HiddenEntriesHeader, strconv.Itoa(hidden))
+	}
 	w.Header().Set("Content-Type", "application/xml; charset=UTF-8")
 	res = `<?xml version="1.0" encoding="UTF-8"?>` + "\n" + res
 	_, _ = fmt.Fprintf(w, "%s", res)
@@ -305,8 +522,9 @@ func (s *Server) getYoutubeFeedCtrl(w http.ResponseWriter, r *http.Request) {
 
 // POST /yt/rss/generate - generates rss for all (each) youtube channels
 func (s *Server) regenerateRSSCtrl(w http.ResponseWriter, r *http.Request) {
+	conf := s.conf()
 
-	for _, f := range s.Conf.YouTube.Channels {
+	for _, f := range conf.YouTube.Channels {
 		res, err := s.YoutubeSvc.RSSFeed(youtube.FeedInfo{ID: f.ID})
 		if err != nil {
 			rest.SendErrorJSON(w, r, log.Default(), http.StatusInternalServerError, err, "failed to read yt rss for "+f.ID)
@@ -317,7 +535,7 @@ func (s *Server) regenerateRSSCtrl(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 	}
-	rest.RenderJSON(w, rest.JSON{"status": "ok", "feeds": len(s.Conf.YouTube.Channels)})
+	rest.RenderJSON(w, rest.JSON{"status": "ok", "feeds": len(conf.YouTube.Channels)})
 }
 
 // DELETE /yt/entry/{channel}/{video} - deletes entry from youtube channel and videID
@@ -330,9 +548,61 @@ func (s *Server) removeEntryCtrl(w http.ResponseWriter, r *http.Request) {
 	rest.RenderJSON(w, rest.JSON{"status": "ok", "removed": chi.URLParam(r, "video")})
 }
 
+// POST /preview - fetches sources and shows what would be saved, junked or notified, without saving or notifying.
+// Request: {"feed": "name", "sources": [{"name": "src", "url": "http://..."}], "filter": {"title": "regex"}},
+// sources and filter are optional and override the configured ones of the feed.
+func (s *Server) previewCtrl(w http.ResponseWriter, r *http.Request) {
+	conf := s.conf()
+	req := struct {
+		Feed    string          `json:"feed"`
+		Sources []config.Source `json:"sources"`
+		Filter  *config.Filter  `json:"filter"`
+	}{}
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		rest.SendErrorJSON(w, r, log.Default(), http.StatusBadRequest, err, "failed to parse request")
+		return
+	}
+
+	fm, ok := conf.Feeds[req.Feed]
+	if !ok {
+		rest.SendErrorJSON(w, r, log.Default(), http.StatusBadRequest, fmt.Errorf("feed %q not found", req.Feed), "unknown feed")
+		return
+	}
+	if len(req.Sources) > 0 {
+		fm.Sources = req.Sources
+	}
+	if req.Filter != nil {
+		fm.Filter = *req.Filter
+	}
+	if s.Processor == nil {
+		rest.SendErrorJSON(w, r, log.Default(), http.StatusServiceUnavailable, errors.New("no processor"), "preview is not available")
+		return
+	}
+	rest.RenderJSON(w, rest.JSON{"feed": req.Feed, "sources": s.Processor.Preview(req.Feed, fm)})
+}
+
+// httpMetrics measures latency of requests, labeled with route pattern to keep the number of series bounded
+func httpMetrics(next http.Handler) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		st := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+		route := "unknown"
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			route = rctx.RoutePattern()
+		}
+		httpDuration.Observe(time.Since(st).Seconds(), r.Method, route, strconv.Itoa(ww.Status()))
+	}
+	return http.HandlerFunc(fn)
+}
+
 func (s *Server) feeds() []string {
-	feeds := make([]string, 0, len(s.Conf.Feeds))
-	for k := range s.Conf.Feeds {
+	conf := s.conf()
+	feeds := make([]string, 0, len(conf.Feeds))
+	for k, f := range conf.Feeds {
+		if f.Private {
+			continue
+		}
 		feeds = append(feeds, k)
 	}
 	return feeds
@@ -3,11 +3,13 @@ package api
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"math/rand"
 	"net/http"
 	"net/http/httptest"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -18,6 +20,9 @@ import (
 	"github.com/umputun/feed-master/app/api/mocks"
 	"github.com/umputun/feed-master/app/config"
 	"github.com/umputun/feed-master/app/feed"
+	"github.com/umputun/feed-master/app/health"
+	"github.com/umputun/feed-master/app/proc"
+	"github.com/umputun/feed-master/app/token"
 	"github.com/umputun/feed-master/app/youtube"
 	ytfeed "github.com/umputun/feed-master/app/youtube/feed"
 )
@@ -66,6 +71,8 @@ func TestServer_getFeedCtrl(t *testing.T) {
 						Type:   "audio/mpeg",
 						Length: 12346,
 					},
+					Chapters: &feed.PodcastChapters{URL: "http://example.com/2.chapters.json", Type: "application/json+chapters"},
+					Image:    &feed.ItemImage{URL: "http://example.com/2.jpg"},
 				},
 			}, nil
 		},
@@ -116,6 +123,10 @@ func TestServer_getFeedCtrl(t *testing.T) {
 	assert.Contains(t, body, "<link>http://example.com/link1</link>")
 	assert.Contains(t, body, "<description>some description1</description>")
 	assert.Contains(t, body, `<enclosure url="http://example.com/enclosure1" length="12345" type="audio/mpeg"></enclosure>`)
+	assert.Contains(t, body, `xmlns:podcast="https://podcastindex.org/namespace/1.0"`)
+	assert.Contains(t, body,
+		`<podcast:chapters url="http://example.com/2.chapters.json" type="application/json+chapters"></podcast:chapters>`)
+	assert.Contains(t, body, `<itunes:image href="http://example.com/2.jpg"></itunes:image>`)
 	assert.NotContains(t, body, `<itunes:image href=""></itunes:image>`)
 	assert.NotContains(t, body, `<media:thumbnail url=""></media:thumbnail>`)
 
@@ -365,6 +376,99 @@ func TestServer_removeEntryCtrl(t *testing.T) {
 	require.Equal(t, "vid1", yt.RemoveEntryCalls()[0].Entry.VideoID)
 }
 
+func TestServer_previewCtrl(t *testing.T) {
+	prc := &mocks.ProcessorMock{
+		PreviewFunc: func(name string, fm config.Feed) []proc.PreviewSource {
+			return []proc.PreviewSource{{Name: fm.Sources[0].Name, URL: fm.Sources[0].URL,
+				Items: []proc.PreviewItem{{GUID: "g1", Title: "title1", Action: proc.PreviewSave}}}}
+		},
+	}
+
+	s := Server{
+		Version:       "1.0",
+		TemplLocation: "../webapp/templates/*",
+		Processor:     prc,
+		Conf: config.Conf{Feeds: map[string]config.Feed{"feed1": {
+			Filter:  config.Filter{Title: "junk"},
+			Sources: []config.Source{{Name: "src1", URL: "http://example.com/1"}},
+		}}},
+		AdminPasswd: "123456",
+	}
+	ts := httptest.NewServer(s.router())
+	defer ts.Close()
+
+	post := func(body, passwd string) (int, string) {
+		req, err := http.NewRequest("POST", ts.URL+"/preview", bytes.NewBufferString(body))
+		require.NoError(t, err)
+		req.SetBasicAuth("admin", passwd)
+		resp, err := ts.Client().Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close() // nolint
+		respBody, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, string(respBody)
+	}
+
+	code, _ := post(`{"feed": "feed1"}`, "bad")
+	assert.Equal(t, http.StatusForbidden, code)
+
+	code, body := post(`{"feed": "feed1"}`, "123456")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, `"action":"save"`)
+	require.Equal(t, 1, len(prc.PreviewCalls()))
+	assert.Equal(t, "feed1", prc.PreviewCalls()[0].Name)
+	assert.Equal(t, "http://example.com/1", prc.PreviewCalls()[0].Fm.Sources[0].URL, "configured sources")
+
+	code, _ = post(`{"feed": "feed1", "sources": [{"name": "new", "url": "http://example.com/new"}], "filter": {"title": "other"}}`, "123456")
+	assert.Equal(t, http.StatusOK, code)
+	require.Equal(t, 2, len(prc.PreviewCalls()))
+	assert.Equal(t, []config.Source{{Name: "new", URL: "http://example.com/new"}}, prc.PreviewCalls()[1].Fm.Sources)
+	assert.Equal(t, config.Filter{Title: "other"}, prc.PreviewCalls()[1].Fm.Filter)
+
+	code, _ = post(`{"feed": "bad"}`, "123456")
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = post(`not json`, "123456")
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, 2, len(prc.PreviewCalls()))
+}
+
+func TestServer_feedsAdminScope(t *testing.T) {
+	prc := &mocks.ProcessorMock{PreviewFunc: func(name string, fm config.Feed) []proc.PreviewSource { return nil }}
+	tokens := &mocks.TokenStoreMock{FindFunc: func(secret string) (token.Token, bool, error) {
+		switch secret {
+		case "fm_read":
+			return token.Token{Name: "reader", Scopes: []string{token.ScopeRead}}, true, nil
+		case "fm_feeds":
+			return token.Token{Name: "ops", Scopes: []string{token.ScopeFeedsAdmin}}, true, nil
+		}
+		return token.Token{}, false, nil
+	}}
+	s := Server{Version: "1.0", TemplLocation: "../webapp/templates/*", Processor: prc, Tokens: tokens,
+		Conf: config.Conf{Feeds: map[string]config.Feed{"feed1": {}}}}
+	ts := httptest.NewServer(s.router())
+	defer ts.Close()
+
+	tbl := []struct {
+		method, path, secret string
+		status               int
+	}{
+		{"POST", "/preview", "fm_read", http.StatusForbidden},
+		{"POST", "/preview", "fm_feeds", http.StatusOK},
+		{"GET", "/config", "fm_read", http.StatusForbidden},
+		{"GET", "/config", "fm_feeds", http.StatusOK},
+	}
+	for _, tt := range tbl {
+		req, err := http.NewRequest(tt.method, ts.URL+tt.path, bytes.NewBufferString(`{"feed": "feed1"}`))
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer "+tt.secret)
+		resp, err := ts.Client().Do(req)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		assert.Equal(t, tt.status, resp.StatusCode, tt.path+" with "+tt.secret)
+	}
+	assert.Equal(t, 1, len(prc.PreviewCalls()))
+}
+
 func TestServer_configCtrl(t *testing.T) {
 
 	store := &mocks.StoreMock{}
@@ -373,6 +477,7 @@ func TestServer_configCtrl(t *testing.T) {
 		Version:       "1.0",
 		TemplLocation: "../webapp/templates/*",
 		Store:         store,
+		AdminPasswd:   "123456",
 		cache:         lcw.NewNopCache[[]byte](),
 		Conf: config.Conf{
 			Feeds: map[string]config.Feed{
@@ -383,6 +488,7 @@ func TestServer_configCtrl(t *testing.T) {
 					Link:        "http://example.com/feed1",
 					Author:      "Feed Master",
 					OwnerEmail:  "test@email.com",
+					Sources:     []config.Source{{Name: "src1", URL: "http://example.com/rss?key=secret"}},
 				},
 				"feed2": {
 					Title: "feed2",
@@ -395,6 +501,14 @@ func TestServer_configCtrl(t *testing.T) {
 
 	resp, err := ts.Client().Get(ts.URL + "/config")
 	require.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	require.NoError(t, resp.Body.Close())
+
+	req, err := http.NewRequest("GET", ts.URL+"/config", http.NoBody)
+	require.NoError(t, err)
+	req.SetBasicAuth("admin", "123456")
+	resp, err = ts.Client().Do(req)
+	require.NoError(t, err)
 	defer resp.Body.Close() // nolint
 	assert.Equal(t, http.StatusOK, resp.StatusCode)
 
@@ -406,4 +520,151 @@ func TestServer_configCtrl(t *testing.T) {
 	assert.Contains(t, body, "feed2")
 	assert.Contains(t, body, "this is feed1")
 	assert.Contains(t, body, "http://example.com/feed1")
+	assert.Contains(t, body, "http://example.com/rss?key=REDACTED")
+	assert.NotContains(t, body, "secret")
+	assert.Contains(t, body, `"system":{"update":0,"max_per_feed":0`, "names of config file")
+	assert.Contains(t, body, `"youtube":{"dl_template":""`)
+}
+
+func TestServer_publicConfigCtrl(t *testing.T) {
+	s := Server{
+		Version: "1.0",
+		cache:   lcw.NewNopCache[[]byte](),
+		Conf: config.Conf{Feeds: map[string]config.Feed{
+			"feed1": {Title: "feed1 title", Link: "http://example.com/feed1", OwnerEmail: "test@email.com",
+				TelegramChannel: "chan1", Sources: []config.Source{{Name: "src1", URL: "http://example.com/rss"}}},
+			"feed2":    {Title: "feed2 title"},
+			"internal": {Title: "internal", Private: true},
+		}},
+	}
+	s.Conf.System.UpdateInterval = 5 * time.Minute
+	s.Conf.System.BaseURL = "http://fm.example.com"
+	s.Conf.YouTube.DlTemplate = "yt-dlp {{.ID}}"
+	ts := httptest.NewServer(s.router())
+	defer ts.Close()
+
+	resp, err := ts.Client().Get(ts.URL + "/config/public")
+	require.NoError(t, err)
+	defer resp.Body.Close() // nolint
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	respBody, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"update_interval": "5m0s", "youtube_update_interval": "0s", "feeds": [
+		{"name": "feed1", "title": "feed1 title", "link": "http://example.com/feed1", "rss": "http://fm.example.com/rss/feed1"},
+		{"name": "feed2", "title": "feed2 title", "link": "", "rss": "http://fm.example.com/rss/feed2"}]}`, string(respBody))
+}
+
+func TestServer_healthCtrl(t *testing.T) {
+	failed := atomic.Bool{}
+	s := Server{
+		Version: "1.0",
+		cache:   lcw.NewNopCache[[]byte](),
+		HealthChecks: []health.Check{
+			{Name: "c1", Fn: func() (string, error) { return "fine", nil }},
+			{Name: "c2", Fn: func() (string, error) {
+				if failed.Load() {
+					return "", errors.New("broken")
+				}
+				return "", nil
+			}},
+		},
+	}
+	ts := httptest.NewServer(s.router())
+	defer ts.Close()
+
+	get := func() (code int, body string) {
+		resp, err := ts.Client().Get(ts.URL + "/health")
+		require.NoError(t, err)
+		defer resp.Body.Close() // nolint
+		respBody, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, string(respBody)
+	}
+
+	code, body := get()
+	assert.Equal(t, http.StatusOK, code)
+	assert.JSONEq(t, `{"status": "ok", "checks": [{"name": "c1", "ok": true, "status": "fine"}, {"name": "c2", "ok": true}]}`, body)
+
+	failed.Store(true)
+	code, body = get()
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.JSONEq(t, `{"status": "failed", "checks": [{"name": "c1", "ok": true, "status": "fine"},
+		{"name": "c2", "ok": false, "error": "broken"}]}`, body)
+}
+
+func TestServer_SetConf(t *testing.T) {
+	s := Server{
+		Version:       "1.0",
+		TemplLocation: "../webapp/templates/*",
+		AdminPasswd:   "123456",
+		cache:         lcw.NewNopCache[[]byte](),
+		Conf:          config.Conf{Feeds: map[string]config.Feed{"feed1": {Title: "feed1"}}},
+	}
+	ts := httptest.NewServer(s.router())
+	defer ts.Close()
+
+	s.SetConf(config.Conf{Feeds: map[string]config.Feed{"feed2": {Title: "feed2"}}})
+
+	req, err := http.NewRequest("GET", ts.URL+"/config", http.NoBody)
+	require.NoError(t, err)
+	req.SetBasicAuth("admin", "123456")
+	resp, err := ts.Client().Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close() // nolint
+	respBody, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(respBody), "feed2")
+	assert.NotContains(t, string(respBody), "feed1")
+}
+
+func TestServer_metrics(t *testing.T) {
+	s := Server{
+		Version:     "1.0",
+		AdminPasswd: "123456",
+		cache:       lcw.NewNopCache[[]byte](),
+		Conf:        config.Conf{Feeds: map[string]config.Feed{"feed1": {Title: "feed1"}}},
+	}
+	ts := httptest.NewServer(s.router())
+	defer ts.Close()
+
+	resp, err := ts.Client().Get(ts.URL + "/config/public")
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+
+	resp, err = ts.Client().Get(ts.URL + "/metrics")
+	require.NoError(t, err)
+	defer resp.Body.Close() // nolint
+	require.Equal(t, http.StatusOK, resp.StatusCode, "public by default")
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), "# TYPE feed_master_http_request_duration_seconds histogram")
+	assert.Contains(t, string(body), `feed_master_http_request_duration_seconds_count{method="GET",route="/config/public",status="200"}`)
+}
+
+func TestServer_metricsAuth(t *testing.T) {
+	s := Server{
+		Version:     "1.0",
+		AdminPasswd: "123456",
+		MetricsAuth: true,
+		cache:       lcw.NewNopCache[[]byte](),
+		Conf:        config.Conf{Feeds: map[string]config.Feed{"feed1": {Title: "feed1"}}},
+	}
+	ts := httptest.NewServer(s.router())
+	defer ts.Close()
+
+	resp, err := ts.Client().Get(ts.URL + "/metrics")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	require.NoError(t, resp.Body.Close())
+
+	req, err := http.NewRequest("GET", ts.URL+"/metrics", http.NoBody)
+	require.NoError(t, err)
+	req.SetBasicAuth("admin", "123456")
+	resp, err = ts.Client().Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close() // nolint
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), `route="/metrics",status="401"`)
 }
@@ -0,0 +1,180 @@
+package api
+
+import (
+	_ "embed" // for openapi document
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	log "github.com/go-pkgz/lgr"
+	"github.com/go-pkgz/rest"
+	"github.com/pkg/errors"
+
+	"github.com/umputun/feed-master/app/config"
+	"github.com/umputun/feed-master/app/feed"
+	ytfeed "github.com/umputun/feed-master/app/youtube/feed"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+//go:embed openapi.json
+var openAPIDoc []byte
+
+// feedInfo is a feed description returned by /api/v1/feeds
+type feedInfo struct {
+	Name            string          `json:"name"`
+	Title           string          `json:"title"`
+	Description     string          `json:"description"`
+	Link            string          `json:"link"`
+	Language        string          `json:"language"`
+	Author          string          `json:"author"`
+	TelegramChannel string          `json:"telegram_channel,omitempty"`
+	RSS             string          `json:"rss"`
+	Sources         []config.Source `json:"sources"`
+}
+
+// GET /api/v1/feeds - returns all public feeds sorted by name
+func (s *Server) listFeedsCtrl(w http.ResponseWriter, _ *http.Request) {
+	conf := s.conf()
+	conf = conf.Redacted() // source urls may have credentials, telegram channel may be private
+	res := make([]feedInfo, 0, len(conf.Feeds))
+	for name, f := range conf.Feeds {
+		if f.Private {
+			continue
+		}
+		res = append(res, feedInfo{Name: name, Title: f.Title, Description: f.Description, Link: f.Link,
+			Language: f.Language, Author: f.Author, TelegramChannel: f.TelegramChannel,
+			RSS: strings.TrimSuffix(conf.System.BaseURL, "/") + "/rss/" + name, Sources: f.Sources})
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+	rest.RenderJSON(w, res)
+}
+
+// GET /api/v1/feeds/{name}/items?limit=20&before=cursor&source=name&include_junk=true - returns stored items
+// of the feed, newest first. Response has "next" cursor to pass as "before" for the next page.
+func (s *Server) listItemsCtrl(w http.ResponseWriter, r *http.Request) {
+	conf := s.conf()
+	name := chi.URLParam(r, "name")
+	if !publicFeed(conf, name) {
+		rest.SendErrorJSON(w, r, log.Default(), http.StatusNotFound, fmt.Errorf("feed %q not found", name), "unknown feed")
+		return
+	}
+	limit, err := pageLimit(r)
+	if err != nil {
+		rest.SendErrorJSON(w, r, log.Default(), http.StatusBadRequest, err, "invalid limit")
+		return
+	}
+
+	source, includeJunk := r.URL.Query().Get("source"), r.URL.Query().Get("include_junk") == "true"
+	filter := func(item feed.Item) bool {
+		return (includeJunk || !item.Junk) && (source == "" || item.Source == source)
+	}
+	items, next, err := s.Store.LoadPage(name, r.URL.Query().Get("before"), limit, filter)
+	if err != nil {
+		rest.SendErrorJSON(w, r, log.Default(), http.StatusInternalServerError, err, "failed to load items")
+		return
+	}
+	if items == nil {
+		items = []feed.Item{}
+	}
+	rest.RenderJSON(w, rest.JSON{"feed": name, "items": items, "next": next})
+}
+
+// GET /api/v1/yt/channels - returns configured youtube channels, download templates are redacted
+func (s *Server) listYoutubeChannelsCtrl(w http.ResponseWriter, _ *http.Request) {
+	conf := s.conf()
+	rest.RenderJSON(w, conf.Redacted().YouTube.Channels)
+}
+
+// GET /api/v1/yt/channels/{id}/entries?limit=20&before=cursor - returns stored entries of youtube channel,
+// newest first. Response has "next" cursor to pass as "before" for the next page. Entries of private feeds
+// are skipped unless authenticated, so the page may be shorter than the limit.
+func (s *Server) listYoutubeEntriesCtrl(w http.ResponseWriter, r *http.Request) {
+	conf := s.conf()
+	id := chi.URLParam(r, "id")
+	found := false
+	for _, ch := range conf.YouTube.Channels {
+		found = found || ch.ID == id
+	}
+	if !found {
+		rest.SendErrorJSON(w, r, log.Default(), http.StatusNotFound, fmt.Errorf("youtube channel %q not found", id), "unknown channel")
+		return
+	}
+	if s.YoutubeStore == nil {
+		rest.SendErrorJSON(w, r, log.Default(), http.StatusServiceUnavailable, errors.New("no youtube store"),
+			"youtube processing is not enabled")
+		return
+	}
+	limit, err := pageLimit(r)
+	if err != nil {
+		rest.SendErrorJSON(w, r, log.Default(), http.StatusBadRequest, err, "invalid limit")
+		return
+	}
+
+	entries, next, err := s.YoutubeStore.LoadPage(id, r.URL.Query().Get("before"), limit)
+	if err != nil {
+		rest.SendErrorJSON(w, r, log.Default(), http.StatusInternalServerError, err, "failed to load entries")
+		return
+	}
+	res := []ytfeed.Entry{}
+	hidden := s.hiddenEntries(r, conf)
+	for _, entry := range entries {
+		if hidden == nil || !hidden(entry) {
+			res = append(res, entry)
+		}
+	}
+	rest.RenderJSON(w, rest.JSON{"channel": id, "entries": res, "next": next})
+}
+
+// GET /api/v1/yt/queue?state=failed - returns download states of youtube entries, the most recently updated first.
+// Optional state is one of pending, downloading, failed, done or skipped.
+func (s *Server) listYoutubeQueueCtrl(w http.ResponseWriter, r *http.Request) {
+	if s.YoutubeQueue == nil {
+		rest.SendErrorJSON(w, r, log.Default(), http.StatusServiceUnavailable, errors.New("no youtube queue"),
+			"youtube processing is not enabled")
+		return
+	}
+	state := ytfeed.QueueState(r.URL.Query().Get("state"))
+	switch state {
+	case "", ytfeed.QueuePending, ytfeed.QueueDownloading, ytfeed.QueueFailed, ytfeed.QueueDone, ytfeed.QueueSkipped:
+	default:
+		rest.SendErrorJSON(w, r, log.Default(), http.StatusBadRequest, fmt.Errorf("unknown state %q", state), "invalid state")
+		return
+	}
+	items, err := s.YoutubeQueue.Queue(state)
+	if err != nil {
+		rest.SendErrorJSON(w, r, log.Default(), http.StatusInternalServerError, err, "failed to load queue")
+		return
+	}
+	rest.RenderJSON(w, rest.JSON{"items": items})
+}
+
+// GET /api/v1/openapi.json - returns OpenAPI document
+func (s *Server) openAPICtrl(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	if _, err := w.Write(openAPIDoc); err != nil {
+		log.Printf("[WARN] failed to send openapi document, %v", err)
+	}
+}
+
+// pageLimit returns "limit" query parameter, default if not set and capped by max
+func pageLimit(r *http.Request) (int, error) {
+	v := r.URL.Query().Get("limit")
+	if v == "" {
+		return defaultPageLimit, nil
+	}
+	limit, err := strconv.Atoi(v)
+	if err != nil || limit <= 0 {
+		return 0, fmt.Errorf("limit should be a positive number, got %q", v)
+	}
+	if limit > maxPageLimit {
+		limit = maxPageLimit
+	}
+	return limit, nil
+}
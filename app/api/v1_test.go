@@ -0,0 +1,199 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/umputun/feed-master/app/api/mocks"
+	"github.com/umputun/feed-master/app/config"
+	"github.com/umputun/feed-master/app/feed"
+	"github.com/umputun/feed-master/app/youtube"
+	ytfeed "github.com/umputun/feed-master/app/youtube/feed"
+)
+
+func TestServer_listFeedsCtrl(t *testing.T) {
+	conf := config.Conf{Feeds: map[string]config.Feed{
+		"feed2": {Title: "title2", Sources: []config.Source{{Name: "src2", URL: "http://example.com/2"}}},
+		"feed1": {Title: "title1", TelegramChannel: "chan1"},
+	}}
+	conf.System.BaseURL = "http://example.com/"
+	s := Server{Version: "1.0", Conf: conf}
+	ts := httptest.NewServer(s.router())
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/api/v1/feeds")
+	require.NoError(t, err)
+	defer resp.Body.Close() // nolint
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.JSONEq(t, `[
+		{"name": "feed1", "title": "title1", "description": "", "link": "", "language": "", "author": "",
+		 "telegram_channel": "REDACTED", "rss": "http://example.com/rss/feed1", "sources": null},
+		{"name": "feed2", "title": "title2", "description": "", "link": "", "language": "", "author": "",
+		 "rss": "http://example.com/rss/feed2", "sources": [{"name": "src2", "url": "http://example.com/2"}]}
+	]`, string(body))
+}
+
+func TestServer_listItemsCtrl(t *testing.T) {
+	items := []feed.Item{
+		{GUID: "1", Title: "title1", Source: "src1"},
+		{GUID: "2", Title: "title2", Source: "src2"},
+		{GUID: "3", Title: "title3", Source: "src1", Junk: true},
+	}
+	store := &mocks.StoreMock{
+		LoadPageFunc: func(fmFeed, cursor string, limit int, filter func(feed.Item) bool) ([]feed.Item, string, error) {
+			if fmFeed == "failed" {
+				return nil, "", errors.New("some error")
+			}
+			res := []feed.Item{}
+			for _, item := range items {
+				if filter(item) {
+					res = append(res, item)
+				}
+			}
+			return res, "next-cursor", nil
+		},
+	}
+	s := Server{Version: "1.0", Store: store, Conf: config.Conf{Feeds: map[string]config.Feed{"feed1": {}, "failed": {}}}}
+	ts := httptest.NewServer(s.router())
+	defer ts.Close()
+
+	get := func(url string) (int, []string, string) {
+		resp, err := http.Get(ts.URL + url)
+		require.NoError(t, err)
+		defer resp.Body.Close() // nolint
+		res := struct {
+			Items []feed.Item `json:"items"`
+			Next  string      `json:"next"`
+		}{}
+		if resp.StatusCode == http.StatusOK {
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&res))
+		}
+		guids := []string{}
+		for _, item := range res.Items {
+			guids = append(guids, item.GUID)
+		}
+		return resp.StatusCode, guids, res.Next
+	}
+
+	code, guids, next := get("/api/v1/feeds/feed1/items")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []string{"1", "2"}, guids)
+	assert.Equal(t, "next-cursor", next)
+	require.Equal(t, 1, len(store.LoadPageCalls()))
+	assert.Equal(t, "", store.LoadPageCalls()[0].Cursor)
+	assert.Equal(t, 20, store.LoadPageCalls()[0].Limit)
+
+	code, guids, _ = get("/api/v1/feeds/feed1/items?limit=500&before=abc&source=src1&include_junk=true")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []string{"1", "3"}, guids)
+	assert.Equal(t, "abc", store.LoadPageCalls()[1].Cursor)
+	assert.Equal(t, 100, store.LoadPageCalls()[1].Limit, "capped")
+
+	code, _, _ = get("/api/v1/feeds/feed1/items?limit=-1")
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _, _ = get("/api/v1/feeds/unknown/items")
+	assert.Equal(t, http.StatusNotFound, code)
+	code, _, _ = get("/api/v1/feeds/failed/items")
+	assert.Equal(t, http.StatusInternalServerError, code)
+}
+
+func TestServer_listYoutubeEntriesCtrl(t *testing.T) {
+	ytStore := &mocks.YoutubeStoreMock{
+		LoadPageFunc: func(channelID, cursor string, limit int) ([]ytfeed.Entry, string, error) {
+			return []ytfeed.Entry{{ChannelID: channelID, VideoID: "vid1", File: "/srv/yt/vid1.mp3"},
+				{ChannelID: channelID, VideoID: "vid2", File: "/srv/yt/vid2.mp3"}}, "", nil
+		},
+	}
+	store := &mocks.StoreMock{LoadFunc: func(fmFeed string, max int, skipJunk bool) ([]feed.Item, error) {
+		return []feed.Item{{Enclosure: feed.Enclosure{URL: "http://example.com/yt/media/vid2.mp3"}}}, nil
+	}, VersionFunc: func() (uint64, error) { return 1, nil }}
+	conf := config.Conf{Feeds: map[string]config.Feed{"priv": {Private: true}}}
+	conf.YouTube.BaseURL = "http://example.com/yt/media"
+	conf.YouTube.Channels = []youtube.FeedInfo{{ID: "ch1", Name: "name1", DlTemplate: "yt-dlp --cookies c.txt {{.ID}}"}}
+	s := Server{Version: "1.0", YoutubeStore: ytStore, Store: store, Conf: conf, AdminPasswd: "123456"}
+	ts := httptest.NewServer(s.router())
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/api/v1/yt/channels/ch1/entries?limit=5&before=abc")
+	require.NoError(t, err)
+	defer resp.Body.Close() // nolint
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	res := struct {
+		Channel string         `json:"channel"`
+		Entries []ytfeed.Entry `json:"entries"`
+		Next    string         `json:"next"`
+	}{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&res))
+	assert.Equal(t, "ch1", res.Channel)
+	require.Equal(t, 1, len(res.Entries), "entry of private feed skipped")
+	assert.Equal(t, "vid1", res.Entries[0].VideoID)
+	require.Equal(t, 1, len(ytStore.LoadPageCalls()))
+	assert.Equal(t, "abc", ytStore.LoadPageCalls()[0].Cursor)
+	assert.Equal(t, 5, ytStore.LoadPageCalls()[0].Limit)
+
+	req, err := http.NewRequest("GET", ts.URL+"/api/v1/yt/channels/ch1/entries", http.NoBody)
+	require.NoError(t, err)
+	req.SetBasicAuth("admin", "123456")
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close() // nolint
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&res))
+	assert.Equal(t, 2, len(res.Entries), "all entries for authenticated request")
+
+	resp, err = http.Get(ts.URL + "/api/v1/yt/channels/unknown/entries")
+	require.NoError(t, err)
+	defer resp.Body.Close() // nolint
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp, err = http.Get(ts.URL + "/api/v1/yt/channels")
+	require.NoError(t, err)
+	defer resp.Body.Close() // nolint
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), `"id":"ch1"`)
+	assert.Contains(t, string(body), `"dl_template":"REDACTED"`)
+	assert.NotContains(t, string(body), "cookies")
+}
+
+func TestServer_openAPICtrl(t *testing.T) {
+	s := Server{Version: "1.0"}
+	router := s.router()
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/api/v1/openapi.json")
+	require.NoError(t, err)
+	defer resp.Body.Close() // nolint
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	doc := struct {
+		OpenAPI string                                `json:"openapi"`
+		Paths   map[string]map[string]json.RawMessage `json:"paths"`
+	}{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&doc))
+	assert.Equal(t, "3.0.3", doc.OpenAPI)
+
+	// all api routes documented
+	routes := 0
+	err = chi.Walk(router, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		if !strings.HasPrefix(route, "/api/v1/") || method == "HEAD" {
+			return nil
+		}
+		routes++
+		_, ok := doc.Paths[route][strings.ToLower(method)]
+		assert.True(t, ok, "%s %s is not documented", method, route)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 20, routes)
+}
@@ -21,10 +21,15 @@ import (
 
 // GET /feed/{name} - renders page with list of items
 func (s *Server) getFeedPageCtrl(w http.ResponseWriter, r *http.Request) {
+	conf := s.conf()
 	feedName := chi.URLParam(r, "name")
+	if !publicFeed(conf, feedName) {
+		s.renderErrorPage(w, r, fmt.Errorf("feed %s not found", feedName), 404)
+		return
+	}
 
 	data, err := s.cache.Get(feedName, func() ([]byte, error) {
-		items, err := s.Store.Load(feedName, s.Conf.System.MaxTotal, false)
+		items, err := s.Store.Load(feedName, conf.System.MaxTotal, false)
 		if err != nil {
 			return nil, err
 		}
@@ -55,16 +60,16 @@ func (s *Server) getFeedPageCtrl(w http.ResponseWriter, r *http.Request) {
 			TelegramChannel string
 		}{
 			Items:           items,
-			Name:            s.Conf.Feeds[feedName].Title,
-			Description:     s.Conf.Feeds[feedName].Description,
-			Link:            s.Conf.Feeds[feedName].Link,
+			Name:            conf.Feeds[feedName].Title,
+			Description:     conf.Feeds[feedName].Description,
+			Link:            conf.Feeds[feedName].Link,
 			LastUpdate:      items[0].DT.In(time.UTC),
 			SinceLastUpdate: humanize.Time(items[0].DT),
-			Feeds:           len(s.Conf.Feeds[feedName].Sources),
+			Feeds:           len(conf.Feeds[feedName].Sources),
 			Version:         s.Version,
-			RSSLink:         s.Conf.System.BaseURL + "/rss/" + feedName,
-			SourcesLink:     s.Conf.System.BaseURL + "/feed/" + feedName + "/sources",
-			TelegramChannel: s.Conf.Feeds[feedName].TelegramChannel,
+			RSSLink:         conf.System.BaseURL + "/rss/" + feedName,
+			SourcesLink:     conf.System.BaseURL + "/feed/" + feedName + "/sources",
+			TelegramChannel: conf.Feeds[feedName].TelegramChannel,
 		}
 
 		res := bytes.NewBuffer(nil)
@@ -84,6 +89,7 @@ func (s *Server) getFeedPageCtrl(w http.ResponseWriter, r *http.Request) {
 
 // GET /feed/{name}/source/{source} - renders feed's source page with list of items
 func (s *Server) getFeedSourceCtrl(w http.ResponseWriter, r *http.Request) {
+	conf := s.conf()
 	feedName := chi.URLParam(r, "name")
 	sourceNameRaw := chi.URLParam(r, "source")
 	var err error
@@ -95,12 +101,12 @@ func (s *Server) getFeedSourceCtrl(w http.ResponseWriter, r *http.Request) {
 	}
 
 	data, err := s.cache.Get(feedName+sourceName, func() ([]byte, error) {
-		if _, ok := s.Conf.Feeds[feedName]; !ok {
+		if !publicFeed(conf, feedName) {
 			return nil, fmt.Errorf("feed %s not found", feedName)
 		}
 
 		var feedInfo youtube.FeedInfo
-		for _, k := range s.Conf.YouTube.Channels {
+		for _, k := range conf.YouTube.Channels {
 			if k.Name == sourceName {
 				feedInfo = k
 				break
@@ -110,7 +116,7 @@ func (s *Server) getFeedSourceCtrl(w http.ResponseWriter, r *http.Request) {
 			return nil, fmt.Errorf("feed %s does not have source %s", feedName, sourceName)
 		}
 
-		items, er := s.YoutubeStore.Load(feedInfo.ID, s.Conf.YouTube.MaxItems)
+		items, er := s.YoutubeStore.Load(feedInfo.ID, conf.YouTube.MaxItems)
 		if er != nil {
 			return nil, er
 		}
@@ -122,7 +128,7 @@ func (s *Server) getFeedSourceCtrl(w http.ResponseWriter, r *http.Request) {
 			}
 			d := time.Duration(int(time.Second) * item.Duration)
 			items[i].DurationFmt = d.String()
-			items[i].File = s.Conf.YouTube.BaseURL + "/" + path.Base(item.File)
+			items[i].File = conf.YouTube.BaseURL + "/" + path.Base(item.File)
 		}
 
 		tmplData := struct {
@@ -142,7 +148,7 @@ func (s *Server) getFeedSourceCtrl(w http.ResponseWriter, r *http.Request) {
 			SinceLastUpdate: humanize.Time(items[0].Published),
 			Feeds:           len(items),
 			Version:         s.Version,
-			RSSLink:         s.Conf.System.BaseURL + "/yt/rss/" + feedInfo.ID,
+			RSSLink:         conf.System.BaseURL + "/yt/rss/" + feedInfo.ID,
 		}
 		if feedInfo.Type == ytfeed.FTPlaylist {
 			tmplData.Link = "https://www.youtube.com/playlist?list=" + feedInfo.ID
@@ -165,6 +171,7 @@ func (s *Server) getFeedSourceCtrl(w http.ResponseWriter, r *http.Request) {
 
 // GET /feeds - renders page with list of feeds
 func (s *Server) getFeedsPageCtrl(w http.ResponseWriter, r *http.Request) {
+	conf := s.conf()
 	data, err := s.cache.Get("feeds", func() ([]byte, error) {
 
 		feeds := s.feeds()
@@ -178,16 +185,16 @@ func (s *Server) getFeedsPageCtrl(w http.ResponseWriter, r *http.Request) {
 		}
 		var feedItems []feedItem
 		for _, f := range feeds {
-			items, loadErr := s.Store.Load(f, s.Conf.System.MaxTotal, true)
+			items, loadErr := s.Store.Load(f, conf.System.MaxTotal, true)
 			if loadErr != nil {
 				continue
 			}
-			feedConf := s.Conf.Feeds[f]
+			feedConf := conf.Feeds[f]
 			item := feedItem{
 				Feed:        feedConf,
-				FeedURL:     s.Conf.System.BaseURL + "/feed/" + f,
+				FeedURL:     conf.System.BaseURL + "/feed/" + f,
 				Sources:     len(feedConf.Sources),
-				SourcesLink: s.Conf.System.BaseURL + "/feed/" + f + "/sources",
+				SourcesLink: conf.System.BaseURL + "/feed/" + f + "/sources",
 				LastUpdated: items[0].DT.In(time.UTC),
 			}
 			feedItems = append(feedItems, item)
@@ -218,6 +225,7 @@ func (s *Server) getFeedsPageCtrl(w http.ResponseWriter, r *http.Request) {
 
 // GET /yt/channels - renders page with list of YouTube channels
 func (s *Server) getYoutubeChannelsPageCtrl(w http.ResponseWriter, r *http.Request) {
+	conf := s.conf()
 	data, err := s.cache.Get("channels", func() ([]byte, error) {
 		type channelItem struct {
 			youtube.FeedInfo
@@ -227,19 +235,19 @@ func (s *Server) getYoutubeChannelsPageCtrl(w http.ResponseWriter, r *http.Reque
 		}
 		var channelItems []channelItem
 
-		for _, k := range s.Conf.YouTube.Channels {
+		for _, k := range conf.YouTube.Channels {
 			items, loadErr := s.YoutubeStore.Load(k.ID, 1)
 			if loadErr != nil {
 				continue
 			}
 			item := channelItem{
 				FeedInfo:    k,
-				RssURL:      s.Conf.YouTube.BaseChanURL + k.ID,
+				RssURL:      conf.YouTube.BaseChanURL + k.ID,
 				ChannelURL:  "https://youtube.com/channel/" + k.ID,
 				LastUpdated: items[0].Published.In(time.UTC),
 			}
 			if k.Type == ytfeed.FTPlaylist {
-				item.RssURL = s.Conf.YouTube.BasePlaylistURL + k.ID
+				item.RssURL = conf.YouTube.BasePlaylistURL + k.ID
 				item.ChannelURL = "https://www.youtube.com/playlist?list=" + k.ID
 			}
 			channelItems = append(channelItems, item)
@@ -270,12 +278,13 @@ func (s *Server) getYoutubeChannelsPageCtrl(w http.ResponseWriter, r *http.Reque
 
 // GET /feed/{name}/sources - renders page with feed's list of sources
 func (s *Server) getSourcesPageCtrl(w http.ResponseWriter, r *http.Request) {
+	conf := s.conf()
 	feedName := chi.URLParam(r, "name")
 	data, err := s.cache.Get(feedName+"-sources", func() ([]byte, error) {
-		if _, ok := s.Conf.Feeds[feedName]; !ok {
+		if !publicFeed(conf, feedName) {
 			return nil, fmt.Errorf("feed %s not found", feedName)
 		}
-		feedConf := s.Conf.Feeds[feedName]
+		feedConf := conf.Feeds[feedName]
 
 		type Source struct {
 			Name string
@@ -290,7 +299,7 @@ func (s *Server) getSourcesPageCtrl(w http.ResponseWriter, r *http.Request) {
 		for _, source := range feedConf.Sources {
 			src := Source{
 				Name: source.Name,
-				URL:  s.Conf.System.BaseURL + "/feed/" + feedName + "/source/" + source.Name,
+				URL:  conf.System.BaseURL + "/feed/" + feedName + "/source/" + source.Name,
 			}
 			tmplData.Sources = append(tmplData.Sources, src)
 		}
@@ -0,0 +1,352 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	"gopkg.in/yaml.v3"
+
+	"github.com/umputun/feed-master/app/youtube"
+	ytfeed "github.com/umputun/feed-master/app/youtube/feed"
+	"github.com/umputun/feed-master/app/youtube/postproc"
+	"github.com/umputun/feed-master/app/youtube/sponsor"
+)
+
+// CheckError is a config problem with its position in the config file
+type CheckError struct {
+	File   string // set for included files only
+	Line   int
+	Column int
+	Path   string // path of the offending key, i.e. "feeds.name.filter.title"
+	Msg    string
+}
+
+func (e CheckError) Error() string {
+	file := ""
+	if e.File != "" {
+		file = e.File + ", "
+	}
+	if e.Line == 0 {
+		return fmt.Sprintf("%s%s: %s", file, e.Path, e.Msg)
+	}
+	return fmt.Sprintf("%sline %d, column %d: %s: %s", file, e.Line, e.Column, e.Path, e.Msg)
+}
+
+// Check loads config file and validates it strictly. Reports unknown keys, invalid regexes, sources without url,
+// duplicate youtube channels, feeds referencing unknown youtube channels, missing images and base urls mismatch.
+// All problems are returned as multierror of CheckError, each one with the position in the file.
+func Check(fname string) (*Conf, error) {
+	res, files, err := load(fname)
+	if err != nil {
+		return nil, err
+	}
+
+	c := checker{nodes: map[string]nodeRef{}, errs: new(multierror.Error)}
+	for _, f := range files {
+		if len(f.root.Content) == 0 {
+			continue
+		}
+		if !f.included {
+			c.walk(f.root.Content[0], reflect.TypeOf(Conf{}), "")
+			continue
+		}
+		c.file = f.name
+		c.walk(f.root.Content[0], reflect.TypeOf(included{}), "")
+	}
+	c.checkFeeds(res)
+	c.checkNotify(res)
+	c.checkYouTube(res)
+	c.checkBaseURL(res)
+
+	sort.SliceStable(c.errs.Errors, func(i, j int) bool {
+		ei, ej := c.errs.Errors[i].(CheckError), c.errs.Errors[j].(CheckError) // nolint
+		if ei.File != ej.File {
+			return ei.File < ej.File
+		}
+		if ei.Line != ej.Line {
+			return ei.Line < ej.Line
+		}
+		return ei.Column < ej.Column
+	})
+	return res, c.errs.ErrorOrNil()
+}
+
+// validate checks values of config changed at runtime, reported problems have no positions
+func validate(conf *Conf) error {
+	c := checker{nodes: map[string]nodeRef{}, errs: new(multierror.Error)}
+	c.checkFeeds(conf)
+	c.checkNotify(conf)
+	c.checkYouTube(conf)
+	sort.SliceStable(c.errs.Errors, func(i, j int) bool {
+		return c.errs.Errors[i].(CheckError).Path < c.errs.Errors[j].(CheckError).Path // nolint
+	})
+	return c.errs.ErrorOrNil()
+}
+
+// checker collects config problems, keeps value nodes by path to report positions
+type checker struct {
+	nodes    map[string]nodeRef
+	errs     *multierror.Error
+	file     string // included file being walked, empty for the main one
+	channels int    // number of youtube channels walked, included channels are appended to the main ones
+}
+
+// nodeRef is a yaml node with the file it is defined in
+type nodeRef struct {
+	file string
+	node *yaml.Node
+}
+
+// walk goes over yaml nodes along with the type they are decoded to and reports unknown keys
+func (c *checker) walk(node *yaml.Node, t reflect.Type, path string) {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	if _, ok := c.nodes[path]; !ok { // top-level keys shared by files, keep the main file's ones
+		c.nodes[path] = nodeRef{file: c.file, node: node}
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode || t == reflect.TypeOf(time.Time{}) {
+			return
+		}
+		fields := yamlFields(t)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, val := node.Content[i], node.Content[i+1]
+			ft, ok := fields[key.Value]
+			if !ok {
+				c.errs = multierror.Append(c.errs, CheckError{File: c.file, Line: key.Line, Column: key.Column,
+					Path: c.join(path, key.Value), Msg: "unknown key"})
+				continue
+			}
+			c.walk(val, ft, c.join(path, key.Value))
+		}
+	case reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			c.walk(node.Content[i+1], t.Elem(), c.join(path, node.Content[i].Value))
+		}
+	case reflect.Slice:
+		if node.Kind != yaml.SequenceNode {
+			return
+		}
+		offset := 0
+		if path == "youtube.channels" {
+			offset = c.channels
+			c.channels += len(node.Content)
+		}
+		for i, n := range node.Content {
+			c.walk(n, t.Elem(), c.join(path, strconv.Itoa(i+offset)))
+		}
+	}
+}
+
+func (c *checker) checkFeeds(conf *Conf) {
+	ytChannels := map[string]bool{}
+	for _, ch := range conf.YouTube.Channels {
+		ytChannels[ch.ID] = true
+	}
+	ytPrefix := ""
+	if conf.System.BaseURL != "" {
+		ytPrefix = strings.TrimSuffix(conf.System.BaseURL, "/") + "/yt/rss/"
+	}
+
+	for name, f := range conf.Feeds {
+		path := c.join("feeds", name)
+		for i, src := range f.Sources {
+			srcPath := c.join(path, "sources", strconv.Itoa(i))
+			if src.URL == "" {
+				c.add(srcPath, "source %q has no url", src.Name)
+				continue
+			}
+			if u, err := url.Parse(src.URL); err != nil || u.Scheme == "" || u.Host == "" {
+				c.add(c.join(srcPath, "url"), "invalid url %q", src.URL)
+				continue
+			}
+			if ytPrefix != "" && strings.HasPrefix(src.URL, ytPrefix) {
+				id := strings.Trim(strings.TrimPrefix(src.URL, ytPrefix), "/")
+				if !ytChannels[id] {
+					c.add(c.join(srcPath, "url"), "youtube channel %q is not configured", id)
+				}
+			}
+		}
+		if f.Filter.Title != "" {
+			if _, err := regexp.Compile(f.Filter.Title); err != nil {
+				c.add(c.join(path, "filter", "title"), "invalid regex %q, %v", f.Filter.Title, err)
+			}
+		}
+		if f.Image != "" {
+			if _, err := os.Stat(f.Image); err != nil {
+				c.add(c.join(path, "image"), "can't access image, %v", err)
+			}
+		}
+	}
+}
+
+func (c *checker) checkNotify(conf *Conf) {
+	for ch, l := range conf.Notify {
+		if _, err := l.Quiet(time.Now()); err != nil {
+			c.add(c.join("notify", ch), "%v", err)
+		}
+	}
+}
+
+func (c *checker) checkYouTube(conf *Conf) {
+	steps := map[string]bool{"all": true}
+	for i, step := range conf.YouTube.PostProcess {
+		path := c.join("youtube", "postprocess", strconv.Itoa(i))
+		if step.Name == "" {
+			c.add(path, "step has no name")
+			continue
+		}
+		if steps[step.Name] {
+			c.add(c.join(path, "name"), "duplicate step %q", step.Name)
+		}
+		steps[step.Name] = true
+		if _, err := postproc.Args(step, "", ytfeed.DefaultFormat); err != nil {
+			c.add(c.join(path, "args"), "invalid step, %v", err)
+		}
+	}
+
+	seen := map[string]int{}
+	for i, ch := range conf.YouTube.Channels {
+		path := c.join("youtube", "channels", strconv.Itoa(i))
+		if ch.ID == "" {
+			c.add(path, "channel %q has no id", ch.Name)
+			continue
+		}
+		if prev, ok := seen[ch.ID]; ok {
+			c.add(c.join(path, "id"), "duplicate channel id %q, already defined in channel #%d", ch.ID, prev+1)
+		}
+		seen[ch.ID] = i
+		if ch.Type != ytfeed.FTDefault && ch.Type != ytfeed.FTChannel && ch.Type != ytfeed.FTPlaylist {
+			c.add(c.join(path, "type"), "invalid type %q, should be %q or %q", ch.Type, ytfeed.FTChannel, ytfeed.FTPlaylist)
+		}
+		if ch.Format != "" && !ytfeed.ValidFormat(ch.Format) {
+			c.add(c.join(path, "format"), "invalid format %q, should be file extension like mp3 or m4a", ch.Format)
+		}
+		if ch.Mode != "" && ch.Mode != "audio" && ch.Mode != youtube.ModeVideo {
+			c.add(c.join(path, "mode"), "invalid mode %q, should be %q or %q", ch.Mode, "audio", youtube.ModeVideo)
+		}
+		if ch.Resolution < 0 {
+			c.add(c.join(path, "resolution"), "negative resolution %d", ch.Resolution)
+		}
+		known := map[string]bool{}
+		for _, cat := range sponsor.Categories {
+			known[cat] = true
+		}
+		for j, cat := range ch.SkipSegments {
+			if !known[cat] {
+				c.add(c.join(path, "skip_segments", strconv.Itoa(j)), "unknown category %q, should be one of %s",
+					cat, strings.Join(sponsor.Categories, ", "))
+			}
+		}
+		for j, name := range ch.SkipPostProcess {
+			if !steps[name] {
+				c.add(c.join(path, "skip_postprocess", strconv.Itoa(j)), "unknown post-processing step %q", name)
+			}
+		}
+		if ch.DlTemplate != "" {
+			if _, err := template.New("dl").Parse(ch.DlTemplate); err != nil {
+				c.add(c.join(path, "dl_template"), "invalid template, %v", err)
+			}
+		}
+		for key, re := range map[string]string{"include": ch.Filter.Include, "exclude": ch.Filter.Exclude} {
+			if re == "" {
+				continue
+			}
+			if _, err := regexp.Compile(re); err != nil {
+				c.add(c.join(path, "filter", key), "invalid regex %q, %v", re, err)
+			}
+		}
+	}
+}
+
+// checkBaseURL verifies base urls are absolute and youtube media is served by the same server
+func (c *checker) checkBaseURL(conf *Conf) {
+	valid := true
+	for path, val := range map[string]string{"system.base_url": conf.System.BaseURL, "youtube.base_url": conf.YouTube.BaseURL} {
+		if _, ok := c.nodes[path]; !ok || val == "" {
+			continue // not set explicitly
+		}
+		if u, err := url.Parse(val); err != nil || u.Scheme == "" || u.Host == "" {
+			c.add(path, "invalid url %q, should be absolute", val)
+			valid = false
+		}
+	}
+
+	if _, ok := c.nodes["youtube.base_url"]; !ok || conf.System.BaseURL == "" || !valid {
+		return // youtube.base_url defaults to system.base_url + "/yt/media"
+	}
+	if !strings.HasPrefix(conf.YouTube.BaseURL, strings.TrimSuffix(conf.System.BaseURL, "/")+"/") {
+		c.add("youtube.base_url", "%q doesn't match system.base_url %q", conf.YouTube.BaseURL, conf.System.BaseURL)
+	}
+}
+
+// add reports problem for the value at path, position taken from the closest existing node
+func (c *checker) add(path, format string, args ...interface{}) {
+	e := CheckError{Path: path, Msg: fmt.Sprintf(format, args...)}
+	for p := path; p != ""; p = p[:max(strings.LastIndex(p, "."), 0)] {
+		if ref, ok := c.nodes[p]; ok {
+			e.File, e.Line, e.Column = ref.file, ref.node.Line, ref.node.Column
+			break
+		}
+	}
+	c.errs = multierror.Append(c.errs, e)
+}
+
+func (c *checker) join(elems ...string) string {
+	res := make([]string, 0, len(elems))
+	for _, e := range elems {
+		if e != "" {
+			res = append(res, e)
+		}
+	}
+	return strings.Join(res, ".")
+}
+
+// yamlFields returns types of struct fields by their yaml names, including inlined ones
+func yamlFields(t reflect.Type) map[string]reflect.Type {
+	res := map[string]reflect.Type{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" && !f.Anonymous {
+			continue // unexported
+		}
+		tag := strings.Split(f.Tag.Get("yaml"), ",")
+		name := tag[0]
+		if name == "-" {
+			continue
+		}
+		inline := false
+		for _, opt := range tag[1:] {
+			inline = inline || opt == "inline"
+		}
+		if inline && f.Type.Kind() == reflect.Struct {
+			for k, v := range yamlFields(f.Type) {
+				res[k] = v
+			}
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		res[name] = f.Type
+	}
+	return res
+}
@@ -0,0 +1,71 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheck(t *testing.T) {
+	conf, err := Check("testdata/config.yml")
+	require.NoError(t, err)
+	assert.Equal(t, 4, len(conf.Feeds))
+	assert.Equal(t, 5, conf.System.MaxItems, "defaults set")
+
+	conf, err = Check("testdata/config_bad.yml")
+	require.Error(t, err)
+	require.NotNil(t, conf, "config returned with validation errors")
+	merr, ok := err.(*multierror.Error)
+	require.True(t, ok)
+
+	res := make([]string, 0, len(merr.Errors))
+	for _, e := range merr.Errors {
+		res = append(res, e.Error())
+	}
+	assert.Equal(t, []string{
+		`line 4, column 12: feeds.first.image: can't access image, stat testdata/no-such-image.png: no such file or directory`,
+		"line 6, column 14: feeds.first.filter.title: invalid regex \"(bad\", error parsing regexp: missing closing ): `(bad`",
+		`line 8, column 27: feeds.first.sources.0.url: youtube channel "unknown" is not configured`,
+		`line 9, column 9: feeds.first.sources.1: source "src2" has no url`,
+		`line 12, column 5: feeds.second.titel: unknown key`,
+		`line 14, column 27: feeds.second.sources.0.url: invalid url "not-url"`,
+		`line 17, column 10: notify.chan1: invalid quiet hours "23:00", expected hh:mm-hh:mm`,
+		`line 21, column 3: system.max_per_chanel: unknown key`,
+		`line 24, column 13: youtube.base_url: "http://other.com/yt/media" doesn't match system.base_url "http://example.com"`,
+		`line 27, column 12: youtube.channels.1.id: duplicate channel id "ch1", already defined in channel #1`,
+		`line 27, column 36: youtube.channels.1.type: invalid type "video", should be "channel" or "playlist"`,
+		`line 28, column 7: youtube.channels.2: channel "no-id" has no id`,
+		"line 29, column 48: youtube.channels.3.filter.include: invalid regex \"[a\", error parsing regexp: missing closing ]: `[a`",
+		`line 30, column 38: youtube.channels.4.format: invalid format "mp3; rm -rf /", should be file extension like mp3 or m4a`,
+		`line 30, column 68: youtube.channels.4.dl_template: invalid template, template: dl:1: unclosed action`,
+		`line 31, column 36: youtube.channels.5.mode: invalid mode "podcast", should be "audio" or "video"`,
+		`line 31, column 57: youtube.channels.5.resolution: negative resolution -1`,
+		`line 32, column 55: youtube.channels.6.skip_segments.1: unknown category "ads", should be one of sponsor, selfpromo, interaction, intro, outro, preview, music_offtopic, filler`,
+		`line 33, column 64: youtube.channels.7.skip_postprocess.2: unknown post-processing step "mono"`,
+		`line 36, column 14: youtube.postprocess.1.name: duplicate step "loudnorm"`,
+		`line 36, column 30: youtube.postprocess.1.args: invalid step, failed to parse args of step "loudnorm": template: loudnorm:1: unclosed action`,
+		`line 37, column 7: youtube.postprocess.2.args: invalid step, no args for step "custom"`,
+		`line 38, column 7: youtube.postprocess.3: step has no name`,
+	}, res)
+}
+
+func TestCheck_failed(t *testing.T) {
+	_, err := Check("/tmp/29e28b3c-e1a4-4269-a10b-3e9a89a08d45.txt")
+	assert.EqualError(t, err, "open /tmp/29e28b3c-e1a4-4269-a10b-3e9a89a08d45.txt: no such file or directory")
+
+	conf, err := Check("testdata/file.txt")
+	assert.Nil(t, conf)
+	assert.Error(t, err)
+
+	fname := filepath.Join(t.TempDir(), "fm.yml")
+	require.NoError(t, os.WriteFile(fname, []byte("system:\n  base_url: example.com\nyoutube:\n  base_url: /yt/media\n"), 0o600))
+	_, err = Check(fname)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `line 2, column 13: system.base_url: invalid url "example.com", should be absolute`)
+	assert.Contains(t, err.Error(), `line 4, column 13: youtube.base_url: invalid url "/yt/media", should be absolute`)
+	assert.NotContains(t, err.Error(), "doesn't match")
+}
@@ -2,68 +2,80 @@
 package config
 
 import (
-	"os"
+	"fmt"
 	"regexp"
+	"strings"
 	"time"
 
-	"gopkg.in/yaml.v3"
-
 	"github.com/umputun/feed-master/app/feed"
 	"github.com/umputun/feed-master/app/youtube"
+	"github.com/umputun/feed-master/app/youtube/postproc"
 )
 
 // Conf for feeds config yml
 type Conf struct {
-	Feeds  map[string]Feed `yaml:"feeds"`
-	System struct {
-		UpdateInterval time.Duration `yaml:"update"`
-		MaxItems       int           `yaml:"max_per_feed"`
-		MaxTotal       int           `yaml:"max_total"`
-		MaxKeepInDB    int           `yaml:"max_keep"`
-		Concurrent     int           `yaml:"concurrent"`
-		BaseURL        string        `yaml:"base_url"`
-	} `yaml:"system"`
+	Include []string                `yaml:"include" json:"include"` // globs of files with additional feeds and youtube channels
+	Feeds   map[string]Feed         `yaml:"feeds" json:"feeds"`
+	Notify  map[string]NotifyLimits `yaml:"notify" json:"notify"` // key is telegram channel
+	System  struct {
+		UpdateInterval time.Duration `yaml:"update" json:"update"`
+		MaxItems       int           `yaml:"max_per_feed" json:"max_per_feed"`
+		MaxTotal       int           `yaml:"max_total" json:"max_total"`
+		MaxKeepInDB    int           `yaml:"max_keep" json:"max_keep"`
+		Concurrent     int           `yaml:"concurrent" json:"concurrent"`
+		BaseURL        string        `yaml:"base_url" json:"base_url"`
+	} `yaml:"system" json:"system"`
 
 	YouTube struct {
-		DlTemplate      string             `yaml:"dl_template"`
-		BaseChanURL     string             `yaml:"base_chan_url"`
-		BasePlaylistURL string             `yaml:"base_playlist_url"`
-		Channels        []youtube.FeedInfo `yaml:"channels"`
-		BaseURL         string             `yaml:"base_url"`
-		UpdateInterval  time.Duration      `yaml:"update"`
-		MaxItems        int                `yaml:"max_per_channel"`
-		FilesLocation   string             `yaml:"files_location"`
-		RSSLocation     string             `yaml:"rss_location"`
-		SkipShorts      time.Duration      `yaml:"skip_shorts"`
-		DisableUpdates  bool               `yaml:"disable_updates"`
-	} `yaml:"youtube"`
+		DlTemplate      string             `yaml:"dl_template" json:"dl_template" redact:"true"`       // may have credentials or cookies
+		VideoTemplate   string             `yaml:"video_template" json:"video_template" redact:"true"` // used for channels in video mode
+		BaseChanURL     string             `yaml:"base_chan_url" json:"base_chan_url"`
+		BasePlaylistURL string             `yaml:"base_playlist_url" json:"base_playlist_url"`
+		Channels        []youtube.FeedInfo `yaml:"channels" json:"channels"`
+		BaseURL         string             `yaml:"base_url" json:"base_url"`
+		UpdateInterval  time.Duration      `yaml:"update" json:"update"`
+		MaxItems        int                `yaml:"max_per_channel" json:"max_per_channel"`
+		FilesLocation   string             `yaml:"files_location" json:"files_location"`
+		RSSLocation     string             `yaml:"rss_location" json:"rss_location"`
+		SkipShorts      time.Duration      `yaml:"skip_shorts" json:"skip_shorts"`
+		DisableUpdates  bool               `yaml:"disable_updates" json:"disable_updates"`
+		Concurrent      int                `yaml:"concurrent" json:"concurrent"` // channels processed in parallel
+		RetryAttempts   int                `yaml:"retry_attempts" json:"retry_attempts"`
+		RetryDelay      time.Duration      `yaml:"retry_delay" json:"retry_delay"`
+		SponsorBlockURL string             `yaml:"sponsorblock_url" json:"sponsorblock_url" redact:"url"` // skip segments api, public one if not set
+		PostProcess     []postproc.Step    `yaml:"postprocess" json:"postprocess"`                        // steps applied to downloaded files
+	} `yaml:"youtube" json:"youtube"`
+
+	files   []string        // all loaded config files, the main one first
+	secrets map[string]bool // values read from "_file" keys, redacted wherever found
 }
 
 // Source defines config section for source
 type Source struct {
-	Name string `yaml:"name"`
-	URL  string `yaml:"url"`
+	Name string `yaml:"name" json:"name"`
+	URL  string `yaml:"url" json:"url" redact:"url"` // may have credentials or api key in query
 }
 
 // Feed defines config section for a feed~
 type Feed struct {
-	Title           string   `yaml:"title"`
-	Description     string   `yaml:"description"`
-	Link            string   `yaml:"link"`
-	Image           string   `yaml:"image"`
-	Language        string   `yaml:"language"`
-	TelegramChannel string   `yaml:"telegram_channel"`
-	Filter          Filter   `yaml:"filter"`
-	Sources         []Source `yaml:"sources"`
-	ExtendDateTitle string   `yaml:"ext_date"`
-	Author          string   `yaml:"author"`
-	OwnerEmail      string   `yaml:"owner_email"`
+	Title           string   `yaml:"title" json:"title"`
+	Description     string   `yaml:"description" json:"description"`
+	Link            string   `yaml:"link" json:"link"`
+	Image           string   `yaml:"image" json:"image"`
+	Language        string   `yaml:"language" json:"language"`
+	TelegramChannel string   `yaml:"telegram_channel" json:"telegram_channel" redact:"true"`
+	Filter          Filter   `yaml:"filter" json:"filter"`
+	Sources         []Source `yaml:"sources" json:"sources"`
+	ExtendDateTitle string   `yaml:"ext_date" json:"ext_date"`
+	Author          string   `yaml:"author" json:"author"`
+	OwnerEmail      string   `yaml:"owner_email" json:"owner_email" redact:"true"`
+	Private         bool     `yaml:"private" json:"private"` // rss and media available with subscriber token only
 }
 
 // Filter defines feed section for a feed filter~
 type Filter struct {
-	Title  string `yaml:"title"`
-	Invert bool   `yaml:"invert"`
+	Title  string `yaml:"title" json:"title"`
+	Invert bool   `yaml:"invert" json:"invert"`
 }
 
 // Skip items with this regexp
@@ -85,25 +97,60 @@ func (filter *Filter) Skip(item feed.Item) (bool, error) {
 	return false, nil
 }
 
+// NotifyLimits defines rate limit and quiet hours for notifications sent to a destination
+type NotifyLimits struct {
+	MaxPerHour int    `yaml:"max_per_hour" json:"max_per_hour"` // 0 means unlimited
+	QuietHours string `yaml:"quiet_hours" json:"quiet_hours"`   // i.e. "23:00-08:00", no notifications sent in this period
+	Timezone   string `yaml:"timezone" json:"timezone"`         // timezone for quiet hours, local if empty
+	Digest     bool   `yaml:"digest" json:"digest"`             // collapse deferred notifications into a single digest message
+}
+
+// Quiet checks if the given time is within quiet hours
+func (l NotifyLimits) Quiet(t time.Time) (bool, error) {
+	if l.QuietHours == "" {
+		return false, nil
+	}
+
+	loc := time.Local
+	if l.Timezone != "" {
+		var err error
+		if loc, err = time.LoadLocation(l.Timezone); err != nil {
+			return false, fmt.Errorf("invalid timezone %q: %w", l.Timezone, err)
+		}
+	}
+
+	elems := strings.Split(l.QuietHours, "-")
+	if len(elems) != 2 {
+		return false, fmt.Errorf("invalid quiet hours %q, expected hh:mm-hh:mm", l.QuietHours)
+	}
+	from, err := time.Parse("15:04", strings.TrimSpace(elems[0]))
+	if err != nil {
+		return false, fmt.Errorf("invalid quiet hours start %q: %w", elems[0], err)
+	}
+	to, err := time.Parse("15:04", strings.TrimSpace(elems[1]))
+	if err != nil {
+		return false, fmt.Errorf("invalid quiet hours end %q: %w", elems[1], err)
+	}
+
+	t = t.In(loc)
+	minutes := t.Hour()*60 + t.Minute()
+	fromMin, toMin := from.Hour()*60+from.Minute(), to.Hour()*60+to.Minute()
+	if fromMin <= toMin {
+		return minutes >= fromMin && minutes < toMin, nil
+	}
+	return minutes >= fromMin || minutes < toMin, nil // over midnight, i.e. 23:00-08:00
+}
+
 // YTChannel defines youtube channel config
 type YTChannel struct {
 	ID   string
 	Name string
 }
 
-// Load config from file
+// Load config from file with included files, environment variables and "_file" values interpolated
 func Load(fname string) (res *Conf, err error) {
-	res = &Conf{}
-	data, err := os.ReadFile(fname) // nolint
-	if err != nil {
-		return nil, err
-	}
-
-	if err := yaml.Unmarshal(data, res); err != nil {
-		return nil, err
-	}
-	res.setDefaults()
-	return res, nil
+	res, _, err = load(fname)
+	return res, err
 }
 
 // SingleFeed returns single feed "fake" config for no-config mode
@@ -167,7 +214,11 @@ func (c *Conf) setDefaults() {
 	}
 
 	if c.YouTube.DlTemplate == "" {
-		c.YouTube.DlTemplate = `yt-dlp --extract-audio --audio-format=mp3 --audio-quality=0 -f m4a/bestaudio "https://www.youtube.com/watch?v={{.ID}}" --no-progress -o {{.FileName}} --match-filter "!is_live & availability=public"`
+		c.YouTube.DlTemplate = `yt-dlp --extract-audio --audio-format={{.Format}} --audio-quality=0 -f m4a/bestaudio "https://www.youtube.com/watch?v={{.ID}}" --no-progress --write-info-json -o {{.FileName}} --match-filter "!is_live & availability=public"`
+	}
+
+	if c.YouTube.VideoTemplate == "" {
+		c.YouTube.VideoTemplate = `yt-dlp -f "bv*[height<={{.Resolution}}][ext=mp4]+ba[ext=m4a]/b[height<={{.Resolution}}][ext=mp4]/b[height<={{.Resolution}}]" --merge-output-format {{.Format}} "https://www.youtube.com/watch?v={{.ID}}" --no-progress --write-info-json -o {{.FileName}}.%(ext)s --match-filter "!is_live & availability=public"`
 	}
 
 	if c.YouTube.BaseChanURL == "" {
@@ -178,6 +229,18 @@ func (c *Conf) setDefaults() {
 		c.YouTube.BasePlaylistURL = "https://www.youtube.com/feeds/videos.xml?playlist_id="
 	}
 
+	if c.YouTube.Concurrent == 0 {
+		c.YouTube.Concurrent = 1
+	}
+
+	if c.YouTube.RetryAttempts == 0 {
+		c.YouTube.RetryAttempts = 5
+	}
+
+	if c.YouTube.RetryDelay == 0 {
+		c.YouTube.RetryDelay = 10 * time.Minute
+	}
+
 	if c.YouTube.FilesLocation == "" {
 		c.YouTube.FilesLocation = "var/yt"
 	}
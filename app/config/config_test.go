@@ -11,6 +11,7 @@ import (
 
 	rssfeed "github.com/umputun/feed-master/app/feed"
 	ytfdeed "github.com/umputun/feed-master/app/youtube"
+	"github.com/umputun/feed-master/app/youtube/postproc"
 )
 
 func TestLoad(t *testing.T) {
@@ -26,8 +27,9 @@ func TestLoad(t *testing.T) {
 	assert.Equal(t, "^filterme*", r.Feeds["filtered"].Filter.Title)
 	assert.Equal(t, time.Second*600, r.System.UpdateInterval)
 	assert.Equal(t, []ytfdeed.FeedInfo{{Name: "name1", ID: "id1", Type: "playlist", Keep: 15},
-		{Name: "name2", ID: "id2", Type: "channel", Language: "ru-ru", Keep: 5}},
+		{Name: "name2", ID: "id2", Type: "channel", Language: "ru-ru", Keep: 5, SkipPostProcess: []string{"mono"}}},
 		r.YouTube.Channels, "2 yt")
+	assert.Equal(t, []postproc.Step{{Name: "loudnorm"}, {Name: "mono", Args: "-ac 1 -b:a 64k"}}, r.YouTube.PostProcess)
 	assert.Equal(t, "yt-dlp --extract-audio --audio-format=mp3 -f m4a/bestaudio \"https://www.youtube.com/watch?v={{.ID}}\" --no-progress -o {{.Filename}}", r.YouTube.DlTemplate)
 	assert.Equal(t, "https://www.youtube.com/videos.xml?channel_id=", r.YouTube.BaseChanURL)
 	assert.Equal(t, "https://www.youtube.com/videos.xml?playlist_id=", r.YouTube.BasePlaylistURL)
@@ -90,12 +92,12 @@ func TestSetDefault(t *testing.T) {
 
 	expectedConf := Conf{
 		System: struct {
-			UpdateInterval time.Duration `yaml:"update"`
-			MaxItems       int           `yaml:"max_per_feed"`
-			MaxTotal       int           `yaml:"max_total"`
-			MaxKeepInDB    int           `yaml:"max_keep"`
-			Concurrent     int           `yaml:"concurrent"`
-			BaseURL        string        `yaml:"base_url"`
+			UpdateInterval time.Duration `yaml:"update" json:"update"`
+			MaxItems       int           `yaml:"max_per_feed" json:"max_per_feed"`
+			MaxTotal       int           `yaml:"max_total" json:"max_total"`
+			MaxKeepInDB    int           `yaml:"max_keep" json:"max_keep"`
+			Concurrent     int           `yaml:"concurrent" json:"concurrent"`
+			BaseURL        string        `yaml:"base_url" json:"base_url"`
 		}{UpdateInterval: time.Minute * 5, MaxItems: 5, MaxTotal: 100, MaxKeepInDB: 5000, Concurrent: 8, BaseURL: ""},
 	}
 
@@ -103,8 +105,13 @@ func TestSetDefault(t *testing.T) {
 	assert.Equal(t, time.Minute*5, c.YouTube.UpdateInterval)
 	assert.Equal(t, "/yt/media", c.YouTube.BaseURL)
 	assert.Equal(t, "var/yt", c.YouTube.FilesLocation)
+	assert.Equal(t, 1, c.YouTube.Concurrent)
+	assert.Equal(t, 5, c.YouTube.RetryAttempts)
+	assert.Equal(t, 10*time.Minute, c.YouTube.RetryDelay)
 	assert.Equal(t, "var/rss", c.YouTube.RSSLocation)
-	assert.Equal(t, "yt-dlp --extract-audio --audio-format=mp3 --audio-quality=0 -f m4a/bestaudio \"https://www.youtube.com/watch?v={{.ID}}\" --no-progress -o {{.FileName}} --match-filter \"!is_live & availability=public\"", c.YouTube.DlTemplate)
+	assert.Equal(t, "yt-dlp --extract-audio --audio-format={{.Format}} --audio-quality=0 -f m4a/bestaudio \"https://www.youtube.com/watch?v={{.ID}}\" --no-progress --write-info-json -o {{.FileName}} --match-filter \"!is_live & availability=public\"", c.YouTube.DlTemplate)
+	assert.Contains(t, c.YouTube.VideoTemplate, "[height<={{.Resolution}}]")
+	assert.Contains(t, c.YouTube.VideoTemplate, "-o {{.FileName}}.%(ext)s")
 	assert.Equal(t, "https://www.youtube.com/feeds/videos.xml?channel_id=", c.YouTube.BaseChanURL)
 	assert.Equal(t, "https://www.youtube.com/feeds/videos.xml?playlist_id=", c.YouTube.BasePlaylistURL)
 }
@@ -181,3 +188,42 @@ func TestFilter(t *testing.T) {
 		})
 	}
 }
+
+func TestNotifyLimits_Quiet(t *testing.T) {
+	tbl := []struct {
+		limits NotifyLimits
+		ts     string
+		quiet  bool
+		err    string
+	}{
+		{NotifyLimits{}, "2023-01-01T23:30:00Z", false, ""},
+		{NotifyLimits{QuietHours: "23:00-08:00", Timezone: "UTC"}, "2023-01-01T23:30:00Z", true, ""},
+		{NotifyLimits{QuietHours: "23:00-08:00", Timezone: "UTC"}, "2023-01-01T07:59:00Z", true, ""},
+		{NotifyLimits{QuietHours: "23:00-08:00", Timezone: "UTC"}, "2023-01-01T08:00:00Z", false, ""},
+		{NotifyLimits{QuietHours: "13:00-14:00", Timezone: "UTC"}, "2023-01-01T13:10:00Z", true, ""},
+		{NotifyLimits{QuietHours: "13:00-14:00", Timezone: "UTC"}, "2023-01-01T12:10:00Z", false, ""},
+		{NotifyLimits{QuietHours: "23:00-08:00", Timezone: "Europe/Berlin"}, "2023-01-01T22:30:00Z", true, ""},
+		{NotifyLimits{QuietHours: "23:00-08:00", Timezone: "Europe/Berlin"}, "2023-01-01T07:30:00Z", false, ""},
+		{NotifyLimits{QuietHours: "23:00", Timezone: "UTC"}, "2023-01-01T07:30:00Z", false,
+			`invalid quiet hours "23:00", expected hh:mm-hh:mm`},
+		{NotifyLimits{QuietHours: "25:00-08:00", Timezone: "UTC"}, "2023-01-01T07:30:00Z", false,
+			`invalid quiet hours start "25:00": parsing time "25:00": hour out of range`},
+		{NotifyLimits{QuietHours: "23:00-08:00", Timezone: "Bad/Zone"}, "2023-01-01T07:30:00Z", false,
+			`invalid timezone "Bad/Zone": unknown time zone Bad/Zone`},
+	}
+
+	for i, tt := range tbl {
+		tt := tt
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			ts, err := time.Parse(time.RFC3339, tt.ts)
+			require.NoError(t, err)
+			quiet, err := tt.limits.Quiet(ts)
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.quiet, quiet)
+		})
+	}
+}
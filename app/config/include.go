@@ -0,0 +1,198 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/umputun/feed-master/app/youtube"
+)
+
+// included is a part of config defined in the included file, only feeds and youtube channels allowed
+type included struct {
+	Feeds   map[string]Feed `yaml:"feeds"`
+	YouTube struct {
+		Channels []youtube.FeedInfo `yaml:"channels"`
+	} `yaml:"youtube"`
+}
+
+// parsedFile is a config file parsed to yaml nodes
+type parsedFile struct {
+	name     string
+	root     *yaml.Node
+	included bool
+	secrets  []string // values read from "_file" keys
+}
+
+// load reads config file with all included files, merges them and sets defaults.
+// Returns parsed yaml of all files, the main file first.
+func load(fname string) (*Conf, []parsedFile, error) {
+	root, secrets, err := parseFile(fname)
+	if err != nil {
+		return nil, nil, err
+	}
+	res := &Conf{}
+	if err = root.Decode(res); err != nil {
+		return nil, nil, err
+	}
+	files := []parsedFile{{name: fname, root: root, secrets: secrets}}
+
+	incFiles, err := res.includes(fname)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	feedsFrom, chansFrom := map[string]string{}, map[string]string{}
+	for name := range res.Feeds {
+		feedsFrom[name] = fname
+	}
+	for _, ch := range res.YouTube.Channels {
+		chansFrom[ch.ID] = fname
+	}
+	for _, incFile := range incFiles {
+		incRoot, incSecrets, err := parseFile(incFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", incFile, err)
+		}
+		inc := included{}
+		if err = incRoot.Decode(&inc); err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", incFile, err)
+		}
+		files = append(files, parsedFile{name: incFile, root: incRoot, included: true, secrets: incSecrets})
+
+		names := make([]string, 0, len(inc.Feeds))
+		for name := range inc.Feeds {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if from, ok := feedsFrom[name]; ok {
+				return nil, nil, fmt.Errorf("%s, %s: feed %q already defined in %s",
+					incFile, position(incRoot, "feeds", name), name, from)
+			}
+			if res.Feeds == nil {
+				res.Feeds = map[string]Feed{}
+			}
+			res.Feeds[name], feedsFrom[name] = inc.Feeds[name], incFile
+		}
+		for i, ch := range inc.YouTube.Channels {
+			if from, ok := chansFrom[ch.ID]; ok && ch.ID != "" {
+				return nil, nil, fmt.Errorf("%s, %s: youtube channel %q already defined in %s",
+					incFile, position(incRoot, "youtube", "channels", strconv.Itoa(i)), ch.ID, from)
+			}
+			res.YouTube.Channels, chansFrom[ch.ID] = append(res.YouTube.Channels, ch), incFile
+		}
+	}
+
+	res.setDefaults()
+	res.files = make([]string, 0, len(files))
+	for _, f := range files {
+		res.files = append(res.files, f.name)
+		for _, s := range f.secrets {
+			if s == "" {
+				continue
+			}
+			if res.secrets == nil {
+				res.secrets = map[string]bool{}
+			}
+			res.secrets[s] = true
+		}
+	}
+	return res, files, nil
+}
+
+// includes returns sorted list of files matching include globs, relative globs are relative to config's directory
+func (c *Conf) includes(fname string) ([]string, error) {
+	res := []string{}
+	seen := map[string]bool{fname: true}
+	for _, pattern := range c.Include {
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(filepath.Dir(fname), pattern)
+		}
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid include %q: %w", pattern, err)
+		}
+		sort.Strings(matches)
+		for _, m := range matches {
+			if seen[m] {
+				continue
+			}
+			seen[m] = true
+			res = append(res, m)
+		}
+	}
+	return res, nil
+}
+
+// Files returns the list of config files loaded, the main file first and then included ones
+func (c *Conf) Files() []string {
+	return c.files
+}
+
+// Watched returns files to watch for config changes, loaded files and files matching include globs now.
+// Added and removed included files are detected this way without loading the config.
+func (c *Conf) Watched() []string {
+	if len(c.files) == 0 {
+		return nil
+	}
+	res := append([]string{}, c.files...)
+	incFiles, err := c.includes(c.files[0])
+	if err != nil {
+		return res
+	}
+	seen := map[string]bool{}
+	for _, f := range res {
+		seen[f] = true
+	}
+	for _, f := range incFiles {
+		if !seen[f] {
+			res = append(res, f)
+		}
+	}
+	return res
+}
+
+// position returns "line N, column M" of the node at the path of mapping keys or sequence indexes
+func position(root *yaml.Node, path ...string) string {
+	node := root
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+	for n, elem := range path {
+		var next *yaml.Node
+		switch node.Kind {
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				if node.Content[i].Value == elem {
+					next = node.Content[i+1]
+					if n == len(path)-1 {
+						next = node.Content[i] // report key position for the last element
+					}
+					break
+				}
+			}
+		case yaml.SequenceNode:
+			if idx, err := strconv.Atoi(elem); err == nil && idx < len(node.Content) {
+				next = node.Content[idx]
+			}
+		}
+		if next == nil {
+			break
+		}
+		node = next
+	}
+	return fmt.Sprintf("line %d, column %d", node.Line, node.Column)
+}
+
+func parseFile(fname string) (*yaml.Node, []string, error) {
+	data, err := os.ReadFile(fname) // nolint
+	if err != nil {
+		return nil, nil, err
+	}
+	return parse(data, filepath.Dir(fname))
+}
@@ -0,0 +1,131 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad_include(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "conf.d"), 0o700))
+	write := func(name, data string) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(data), 0o600))
+	}
+	write("fm.yml", `
+include: ["conf.d/*.yml", "conf.d/b.yml"]
+feeds:
+  feed1:
+    sources:
+      - {name: src1, url: http://example.com/1}
+youtube:
+  channels:
+    - {id: ch1, name: "name1"}
+`)
+	write("conf.d/b.yml", `
+feeds:
+  feed3:
+    sources:
+      - {name: src3, url: http://example.com/3}
+`)
+	write("conf.d/a.yml", `
+feeds:
+  feed2:
+    sources:
+      - {name: src2, url: http://example.com/2}
+youtube:
+  channels:
+    - {id: ch2, name: "name2"}
+`)
+	write("conf.d/c.txt", "not matched")
+
+	conf, err := Check(filepath.Join(dir, "fm.yml"))
+	require.NoError(t, err)
+	assert.Equal(t, 3, len(conf.Feeds))
+	assert.Equal(t, "src2", conf.Feeds["feed2"].Sources[0].Name)
+	assert.Equal(t, "src3", conf.Feeds["feed3"].Sources[0].Name)
+	require.Equal(t, 2, len(conf.YouTube.Channels))
+	assert.Equal(t, "ch2", conf.YouTube.Channels[1].ID)
+	assert.Equal(t, 5, conf.System.MaxItems, "defaults set")
+	assert.Equal(t, []string{filepath.Join(dir, "fm.yml"), filepath.Join(dir, "conf.d/a.yml"),
+		filepath.Join(dir, "conf.d/b.yml")}, conf.Files(), "sorted and deduplicated")
+
+	write("conf.d/0.yml", "feeds: {}\n")
+	require.NoError(t, os.Remove(filepath.Join(dir, "conf.d/a.yml")))
+	assert.Equal(t, []string{filepath.Join(dir, "fm.yml"), filepath.Join(dir, "conf.d/a.yml"),
+		filepath.Join(dir, "conf.d/b.yml"), filepath.Join(dir, "conf.d/0.yml")}, conf.Watched(),
+		"loaded and newly matched files")
+	assert.Empty(t, (&Conf{}).Watched())
+}
+
+func TestLoad_includeDuplicates(t *testing.T) {
+	dir := t.TempDir()
+	fname := filepath.Join(dir, "fm.yml")
+	inc := filepath.Join(dir, "inc.yml")
+	require.NoError(t, os.WriteFile(fname, []byte("include: [inc.yml]\nfeeds:\n  feed1:\n    title: t1\n"+
+		"youtube:\n  channels:\n    - {id: ch1}\n"), 0o600))
+
+	require.NoError(t, os.WriteFile(inc, []byte("feeds:\n  feed2:\n    title: t2\n  feed1:\n    title: t1\n"), 0o600))
+	_, err := Load(fname)
+	require.Error(t, err)
+	assert.Equal(t, inc+`, line 4, column 3: feed "feed1" already defined in `+fname, err.Error())
+
+	require.NoError(t, os.WriteFile(inc, []byte("youtube:\n  channels:\n    - {id: ch2}\n    - {id: ch1}\n"), 0o600))
+	_, err = Load(fname)
+	require.Error(t, err)
+	assert.Equal(t, inc+`, line 4, column 7: youtube channel "ch1" already defined in `+fname, err.Error())
+}
+
+func TestCheck_include(t *testing.T) {
+	dir := t.TempDir()
+	fname := filepath.Join(dir, "fm.yml")
+	inc := filepath.Join(dir, "inc.yml")
+	require.NoError(t, os.WriteFile(fname, []byte("include: [inc.yml]\n"+
+		"youtube:\n  channels:\n    - {id: ch1}\n"), 0o600))
+	require.NoError(t, os.WriteFile(inc, []byte(`
+system:
+  max_per_feed: 10
+feeds:
+  feed1:
+    titel: t1
+youtube:
+  channels:
+    - {id: ch2, type: video}
+`), 0o600))
+
+	_, err := Check(fname)
+	require.Error(t, err)
+	merr, ok := err.(*multierror.Error)
+	require.True(t, ok)
+	res := make([]string, 0, len(merr.Errors))
+	for _, e := range merr.Errors {
+		res = append(res, e.Error())
+	}
+	assert.Equal(t, []string{
+		inc + `, line 2, column 1: system: unknown key`,
+		inc + `, line 6, column 5: feeds.feed1.titel: unknown key`,
+		inc + `, line 9, column 23: youtube.channels.1.type: invalid type "video", should be "channel" or "playlist"`,
+	}, res)
+}
+
+func TestPosition(t *testing.T) {
+	root, _, err := parse([]byte("feeds:\n  feeds:\n    sources: []\nyoutube:\n  channels:\n    - {id: ch1}\n"), "")
+	require.NoError(t, err)
+	tbl := []struct {
+		path []string
+		res  string
+	}{
+		{[]string{"feeds"}, "line 1, column 1"},
+		{[]string{"feeds", "feeds"}, "line 2, column 3"},
+		{[]string{"feeds", "feeds", "sources"}, "line 3, column 5"},
+		{[]string{"youtube", "channels", "0"}, "line 6, column 7"},
+		{[]string{"youtube", "channels", "1"}, "line 6, column 5"}, // closest existing node
+	}
+	for _, tt := range tbl {
+		assert.Equal(t, tt.res, position(root, tt.path...), tt.path)
+	}
+}
@@ -0,0 +1,104 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/hashicorp/go-multierror"
+	"gopkg.in/yaml.v3"
+)
+
+// fileSuffix marks keys with values read from the file, i.e. "token_file: /run/secrets/token" sets "token"
+const fileSuffix = "_file"
+
+var reEnvVar = regexp.MustCompile(`\$\$|\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// parse unmarshals yaml into node tree and interpolates it, returns values read from files as well.
+// Relative paths of "_file" keys are relative to dir, the directory of the config file.
+func parse(data []byte, dir string) (*yaml.Node, []string, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, nil, err
+	}
+	secrets, err := interpolate(&root, dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &root, secrets, nil
+}
+
+// interpolate expands ${VAR} and ${VAR:-default} in all scalar values with environment variables,
+// "$$" is an escaped "$". Keys with "_file" suffix replaced by the keys without suffix and the content of the file,
+// relative file paths are relative to dir. Unset variables without default are errors.
+// Returns values read from files, as these are secrets usually.
+func interpolate(root *yaml.Node, dir string) (secrets []string, err error) {
+	errs := new(multierror.Error)
+
+	var walk func(node *yaml.Node)
+	walk = func(node *yaml.Node) {
+		switch node.Kind {
+		case yaml.DocumentNode, yaml.SequenceNode:
+			for _, n := range node.Content {
+				walk(n)
+			}
+		case yaml.MappingNode:
+			keys := map[string]bool{}
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				keys[node.Content[i].Value] = true
+			}
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				key, val := node.Content[i], node.Content[i+1]
+				walk(val)
+				if !strings.HasSuffix(key.Value, fileSuffix) || val.Kind != yaml.ScalarNode {
+					continue
+				}
+				name := strings.TrimSuffix(key.Value, fileSuffix)
+				if keys[name] {
+					errs = multierror.Append(errs, fmt.Errorf("line %d, column %d: both %s and %s are set",
+						key.Line, key.Column, name, key.Value))
+					continue
+				}
+				fname := val.Value
+				if !filepath.IsAbs(fname) {
+					fname = filepath.Join(dir, fname)
+				}
+				data, err := os.ReadFile(fname) // nolint
+				if err != nil {
+					errs = multierror.Append(errs, fmt.Errorf("line %d, column %d: can't read %s, %w",
+						val.Line, val.Column, key.Value, err))
+					continue
+				}
+				key.Value = name
+				val.Value, val.Tag, val.Style = strings.TrimRight(string(data), "\r\n"), "!!str", 0
+				secrets = append(secrets, val.Value)
+			}
+		case yaml.ScalarNode:
+			if !strings.Contains(node.Value, "$") {
+				return
+			}
+			res := reEnvVar.ReplaceAllStringFunc(node.Value, func(s string) string {
+				if s == "$$" {
+					return "$"
+				}
+				m := reEnvVar.FindStringSubmatch(s)
+				if v, ok := os.LookupEnv(m[1]); ok && (v != "" || m[2] == "") {
+					return v
+				}
+				if m[2] != "" {
+					return m[3]
+				}
+				errs = multierror.Append(errs, fmt.Errorf("line %d, column %d: environment variable %s is not set",
+					node.Line, node.Column, m[1]))
+				return ""
+			})
+			if res != node.Value {
+				node.Value, node.Tag, node.Style = res, "", 0 // resolve type by the expanded value
+			}
+		}
+	}
+	walk(root)
+	return secrets, errs.ErrorOrNil()
+}
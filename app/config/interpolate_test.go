@@ -0,0 +1,71 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad_interpolate(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "url"), []byte("http://example.com/secret\n"), 0o600))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "secrets"), 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "secrets", "link"), []byte("http://example.com/link"), 0o600))
+	t.Setenv("FM_TEST_TITLE", "title from env")
+	t.Setenv("FM_TEST_MAX", "7")
+	t.Setenv("FM_TEST_EMPTY", "")
+
+	fname := filepath.Join(dir, "fm.yml")
+	data := `
+feeds:
+  feed1:
+    title: ${FM_TEST_TITLE}
+    description: "${FM_TEST_NOT_SET:-default desc}, ${FM_TEST_EMPTY:-empty}, cost $$5"
+    author: "pre-${FM_TEST_EMPTY}-post"
+    link_file: secrets/link
+    sources:
+      - name: src1
+        url_file: ` + filepath.Join(dir, "url") + `
+system:
+  max_per_feed: ${FM_TEST_MAX}
+`
+	require.NoError(t, os.WriteFile(fname, []byte(data), 0o600))
+
+	conf, err := Load(fname)
+	require.NoError(t, err)
+	f := conf.Feeds["feed1"]
+	assert.Equal(t, "title from env", f.Title)
+	assert.Equal(t, "default desc, empty, cost $5", f.Description)
+	assert.Equal(t, "pre--post", f.Author)
+	assert.Equal(t, "http://example.com/secret", f.Sources[0].URL)
+	assert.Equal(t, "http://example.com/link", f.Link, "relative to config file")
+	assert.Equal(t, 7, conf.System.MaxItems)
+
+	_, err = Check(fname)
+	assert.NoError(t, err, "url_file is not an unknown key")
+}
+
+func TestLoad_interpolateErrors(t *testing.T) {
+	dir := t.TempDir()
+	fname := filepath.Join(dir, "fm.yml")
+	data := `
+feeds:
+  feed1:
+    title: ${FM_TEST_NOT_SET}
+    image: img.png
+    image_file: /some/file
+    sources:
+      - name: src1
+        url_file: /no/such/file
+`
+	require.NoError(t, os.WriteFile(fname, []byte(data), 0o600))
+
+	_, err := Load(fname)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "line 4, column 12: environment variable FM_TEST_NOT_SET is not set")
+	assert.Contains(t, err.Error(), "line 6, column 5: both image and image_file are set")
+	assert.Contains(t, err.Error(), "line 9, column 19: can't read url_file, open /no/such/file: no such file or directory")
+}
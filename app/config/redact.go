@@ -0,0 +1,98 @@
+package config
+
+import (
+	"net/url"
+	"reflect"
+	"sort"
+)
+
+const redacted = "REDACTED"
+
+// Redacted returns a copy of config with sensitive fields masked. Fields are marked with "redact" tag,
+// `redact:"true"` masks the whole value and `redact:"url"` masks password and query values of url only.
+// Values read from "_file" keys are masked in any field.
+func (c *Conf) Redacted() Conf {
+	return redact(reflect.ValueOf(*c), "", c.secrets).Interface().(Conf)
+}
+
+// redact makes a copy of v with tagged fields and secret values masked, maps and slices are copied too
+func redact(v reflect.Value, tag string, secrets map[string]bool) reflect.Value {
+	switch {
+	case v.Kind() == reflect.String && secrets[v.String()]:
+		res := reflect.New(v.Type()).Elem()
+		res.SetString(redacted)
+		return res
+	case tag != "" && v.Kind() == reflect.String:
+		res := reflect.New(v.Type()).Elem()
+		res.SetString(redactString(v.String(), tag))
+		return res
+	case tag == "true": // non-string values are dropped
+		return reflect.Zero(v.Type())
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		res := reflect.New(v.Type()).Elem()
+		res.Set(v) // copies unexported fields as is
+		for i := 0; i < v.NumField(); i++ {
+			if !res.Field(i).CanSet() {
+				continue
+			}
+			res.Field(i).Set(redact(v.Field(i), v.Type().Field(i).Tag.Get("redact"), secrets))
+		}
+		return res
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		res := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			res.SetMapIndex(iter.Key(), redact(iter.Value(), tag, secrets))
+		}
+		return res
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		res := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			res.Index(i).Set(redact(v.Index(i), tag, secrets))
+		}
+		return res
+	}
+	return v
+}
+
+func redactString(s, tag string) string {
+	if s == "" {
+		return s
+	}
+	if tag != "url" {
+		return redacted
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		return redacted
+	}
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), redacted)
+	}
+	if u.RawQuery != "" {
+		q := u.Query()
+		keys := make([]string, 0, len(q))
+		for k := range q {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		raw := ""
+		for i, k := range keys {
+			if i > 0 {
+				raw += "&"
+			}
+			raw += url.QueryEscape(k) + "=" + redacted
+		}
+		u.RawQuery = raw
+	}
+	return u.String()
+}
@@ -0,0 +1,195 @@
+package config
+
+import (
+	"reflect"
+	"sort"
+	"sync"
+
+	log "github.com/go-pkgz/lgr"
+	"github.com/pkg/errors"
+
+	"github.com/umputun/feed-master/app/youtube"
+)
+
+// Overrides are feeds and youtube channels changed at runtime, applied on top of the config file.
+// Nil value marks an entry removed at runtime.
+type Overrides struct {
+	Feeds    map[string]*Feed             `json:"feeds,omitempty"`
+	Channels map[string]*youtube.FeedInfo `json:"channels,omitempty"`
+}
+
+// OverridesStore persists runtime overrides
+type OverridesStore interface {
+	LoadOverrides() (Overrides, error)
+	SaveOverrides(o Overrides) error
+}
+
+// Runtime keeps the config file merged with overrides made at runtime. Each change is validated,
+// persisted to the store and passed to Apply.
+type Runtime struct {
+	Apply func(conf *Conf) // called with the new merged config on each change, optional
+
+	store     OverridesStore
+	lock      sync.Mutex
+	base      *Conf // loaded from config file
+	conf      *Conf // base with overrides
+	overrides Overrides
+}
+
+// NewRuntime makes Runtime for the config loaded from file, with overrides from the store applied
+func NewRuntime(base *Conf, store OverridesStore) (*Runtime, error) {
+	o, err := store.LoadOverrides()
+	if err != nil {
+		return nil, errors.Wrap(err, "can't load config overrides")
+	}
+	res := &Runtime{store: store, base: base, overrides: o}
+	res.conf = merge(base, o)
+	if len(o.Feeds)+len(o.Channels) > 0 {
+		log.Printf("[INFO] runtime config overrides applied, feeds=%d, youtube channels=%d", len(o.Feeds), len(o.Channels))
+	}
+	return res, nil
+}
+
+// Conf returns the current config with overrides
+func (r *Runtime) Conf() *Conf {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.conf
+}
+
+// SetBase replaces config loaded from file, used on config reload. Overrides are applied on top of the new config.
+func (r *Runtime) SetBase(base *Conf) {
+	r.lock.Lock()
+	r.base = base
+	r.conf = merge(base, r.overrides)
+	conf := r.conf
+	r.lock.Unlock()
+	r.apply(conf)
+}
+
+// Update calls fn with a copy of the current config to change feeds and youtube channels.
+// Changes of other sections are ignored. The changed config is validated and the difference
+// with the config file is persisted as overrides.
+func (r *Runtime) Update(fn func(conf *Conf) error) error {
+	r.lock.Lock()
+	changed := r.conf.clone()
+	if err := fn(changed); err != nil {
+		r.lock.Unlock()
+		return err
+	}
+	upd := r.conf.clone() // take feeds and channels only
+	upd.Feeds, upd.YouTube.Channels = changed.Feeds, changed.YouTube.Channels
+	upd.setDefaults()
+	if err := validate(upd); err != nil {
+		r.lock.Unlock()
+		return err
+	}
+
+	o := diff(r.base, upd)
+	if err := r.store.SaveOverrides(o); err != nil {
+		r.lock.Unlock()
+		return errors.Wrap(err, "can't save config overrides")
+	}
+	r.overrides = o
+	r.conf = merge(r.base, o)
+	conf := r.conf
+	r.lock.Unlock()
+
+	r.apply(conf)
+	return nil
+}
+
+func (r *Runtime) apply(conf *Conf) {
+	if r.Apply != nil {
+		r.Apply(conf)
+	}
+}
+
+// merge applies overrides to the copy of base config. Changed youtube channels keep their position,
+// added ones are appended sorted by id.
+func merge(base *Conf, o Overrides) *Conf {
+	res := base.clone()
+	for name, f := range o.Feeds {
+		if f == nil {
+			delete(res.Feeds, name)
+			continue
+		}
+		if res.Feeds == nil {
+			res.Feeds = map[string]Feed{}
+		}
+		res.Feeds[name] = *f
+	}
+
+	channels := make([]youtube.FeedInfo, 0, len(res.YouTube.Channels)+len(o.Channels))
+	seen := map[string]bool{}
+	for _, ch := range res.YouTube.Channels {
+		seen[ch.ID] = true
+		upd, ok := o.Channels[ch.ID]
+		switch {
+		case !ok:
+			channels = append(channels, ch)
+		case upd != nil:
+			channels = append(channels, *upd)
+		}
+	}
+	added := []string{}
+	for id, ch := range o.Channels {
+		if ch != nil && !seen[id] {
+			added = append(added, id)
+		}
+	}
+	sort.Strings(added)
+	for _, id := range added {
+		channels = append(channels, *o.Channels[id])
+	}
+	res.YouTube.Channels = channels
+	res.setDefaults()
+	return res
+}
+
+// diff returns overrides making conf from base, for feeds and youtube channels only
+func diff(base, conf *Conf) Overrides {
+	res := Overrides{Feeds: map[string]*Feed{}, Channels: map[string]*youtube.FeedInfo{}}
+	for name, f := range conf.Feeds {
+		if bf, ok := base.Feeds[name]; !ok || !reflect.DeepEqual(bf, f) {
+			f := f
+			res.Feeds[name] = &f
+		}
+	}
+	for name := range base.Feeds {
+		if _, ok := conf.Feeds[name]; !ok {
+			res.Feeds[name] = nil
+		}
+	}
+
+	baseChannels := map[string]youtube.FeedInfo{}
+	for _, ch := range base.YouTube.Channels {
+		baseChannels[ch.ID] = ch
+	}
+	channels := map[string]bool{}
+	for _, ch := range conf.YouTube.Channels {
+		channels[ch.ID] = true
+		if bch, ok := baseChannels[ch.ID]; !ok || !reflect.DeepEqual(bch, ch) {
+			ch := ch
+			res.Channels[ch.ID] = &ch
+		}
+	}
+	for id := range baseChannels {
+		if !channels[id] {
+			res.Channels[id] = nil
+		}
+	}
+	return res
+}
+
+// clone makes a copy of config safe to change feeds and youtube channels
+func (c *Conf) clone() *Conf {
+	res := *c
+	res.Feeds = make(map[string]Feed, len(c.Feeds))
+	for name, f := range c.Feeds {
+		f.Sources = append([]Source(nil), f.Sources...)
+		res.Feeds[name] = f
+	}
+	res.YouTube.Channels = append([]youtube.FeedInfo(nil), c.YouTube.Channels...)
+	return &res
+}
@@ -0,0 +1,127 @@
+package config
+
+import (
+	"errors"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/umputun/feed-master/app/youtube"
+)
+
+// memOverrides is in-memory OverridesStore
+type memOverrides struct {
+	o   Overrides
+	err error
+}
+
+func (m *memOverrides) LoadOverrides() (Overrides, error) { return m.o, m.err }
+func (m *memOverrides) SaveOverrides(o Overrides) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.o = o
+	return nil
+}
+
+func TestRuntime(t *testing.T) {
+	base := &Conf{Feeds: map[string]Feed{
+		"feed1": {Title: "feed1", Sources: []Source{{Name: "src1", URL: "http://example.com/1"}}},
+		"feed2": {Title: "feed2", Sources: []Source{{Name: "src2", URL: "http://example.com/2"}}},
+	}}
+	base.YouTube.Channels = []youtube.FeedInfo{{ID: "ch1", Name: "name1"}, {ID: "ch2", Name: "name2"}}
+	base.setDefaults()
+
+	store := &memOverrides{}
+	rt, err := NewRuntime(base, store)
+	require.NoError(t, err)
+	assert.Equal(t, base, rt.Conf(), "nothing overridden")
+
+	var applied []*Conf
+	rt.Apply = func(conf *Conf) { applied = append(applied, conf) }
+
+	err = rt.Update(func(conf *Conf) error {
+		f := conf.Feeds["feed1"]
+		f.Sources = append(f.Sources, Source{Name: "src1a", URL: "http://example.com/1a"})
+		conf.Feeds["feed1"] = f
+		delete(conf.Feeds, "feed2")
+		conf.Feeds["feed3"] = Feed{Title: "feed3"}
+		conf.YouTube.Channels = []youtube.FeedInfo{{ID: "ch2", Name: "name2 updated"}, {ID: "ch3"}}
+		conf.System.MaxItems = 100 // ignored
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, len(base.Feeds["feed1"].Sources), "base config not changed")
+
+	conf := rt.Conf()
+	require.Equal(t, 1, len(applied))
+	assert.Equal(t, conf, applied[0])
+	assert.Equal(t, 2, len(conf.Feeds))
+	assert.Equal(t, 2, len(conf.Feeds["feed1"].Sources))
+	assert.Equal(t, "Feed Master", conf.Feeds["feed3"].Author, "defaults set")
+	assert.Equal(t, []youtube.FeedInfo{{ID: "ch2", Name: "name2 updated", Keep: 5}, {ID: "ch3", Keep: 5}}, conf.YouTube.Channels)
+	assert.Equal(t, 5, conf.System.MaxItems)
+
+	assert.Equal(t, 3, len(store.o.Feeds), "feed1 changed, feed2 removed, feed3 added")
+	assert.Nil(t, store.o.Feeds["feed2"])
+	assert.Equal(t, 3, len(store.o.Channels), "ch1 removed, ch2 changed, ch3 added")
+	assert.Nil(t, store.o.Channels["ch1"])
+
+	// invalid change rejected
+	err = rt.Update(func(conf *Conf) error {
+		conf.Feeds["feed1"] = Feed{Filter: Filter{Title: "("}}
+		return nil
+	})
+	require.Error(t, err)
+	assert.True(t, errors.As(err, &CheckError{}))
+	assert.Contains(t, err.Error(), "feeds.feed1.filter.title: invalid regex")
+	assert.Equal(t, conf, rt.Conf())
+	assert.Equal(t, 1, len(applied))
+
+	// error from fn
+	err = rt.Update(func(conf *Conf) error { return errors.New("some error") })
+	assert.EqualError(t, err, "some error")
+
+	// reload keeps overrides on top of the new config file
+	newBase := &Conf{Feeds: map[string]Feed{"feed4": {Title: "feed4"}}}
+	newBase.YouTube.Channels = []youtube.FeedInfo{{ID: "ch1"}, {ID: "ch4"}}
+	newBase.setDefaults()
+	rt.SetBase(newBase)
+	require.Equal(t, 2, len(applied))
+	conf = rt.Conf()
+	assert.Equal(t, []string{"feed1", "feed3", "feed4"}, feedNames(conf))
+	assert.Equal(t, []youtube.FeedInfo{{ID: "ch4", Keep: 5}, {ID: "ch2", Name: "name2 updated", Keep: 5}, {ID: "ch3", Keep: 5}},
+		conf.YouTube.Channels, "removed ch1 stays removed, added appended sorted")
+
+	// restart with stored overrides
+	rt, err = NewRuntime(newBase, store)
+	require.NoError(t, err)
+	assert.Equal(t, conf, rt.Conf())
+}
+
+func TestRuntime_storeErrors(t *testing.T) {
+	_, err := NewRuntime(&Conf{}, &memOverrides{err: errors.New("failed")})
+	assert.EqualError(t, err, "can't load config overrides: failed")
+
+	store := &memOverrides{}
+	rt, err := NewRuntime(&Conf{}, store)
+	require.NoError(t, err)
+	store.err = errors.New("failed")
+	err = rt.Update(func(conf *Conf) error {
+		conf.Feeds["feed1"] = Feed{}
+		return nil
+	})
+	assert.EqualError(t, err, "can't save config overrides: failed")
+	assert.Equal(t, 0, len(rt.Conf().Feeds))
+}
+
+func feedNames(conf *Conf) []string {
+	res := []string{}
+	for name := range conf.Feeds {
+		res = append(res, name)
+	}
+	sort.Strings(res)
+	return res
+}
@@ -4,16 +4,26 @@ package duration
 import (
 	"io"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
 
 	log "github.com/go-pkgz/lgr"
 	"github.com/tcolgate/mp3"
 )
 
 // Service provides duration of audio from file or reader
-type Service struct{}
+type Service struct {
+	FFProbe string // ffprobe command for non-mp3 files, "ffprobe" if not set
+}
 
-// File scans MP3 file from provided file and returns its duration in seconds, ignoring possible errors
+// File scans MP3 file from provided file and returns its duration in seconds, ignoring possible errors.
+// Other formats are probed with ffprobe.
 func (s *Service) File(fname string) int {
+	if !strings.EqualFold(filepath.Ext(fname), ".mp3") {
+		return s.probe(fname)
+	}
 	fh, err := os.Open(fname) //nolint:gosec // this is not an inclusion as file was created by us
 	if err != nil {
 		log.Printf("[WARN] can't get duration, failed to open file %s: %v", fname, err)
@@ -23,6 +33,26 @@ func (s *Service) File(fname string) int {
 	return s.reader(fh)
 }
 
+// probe gets duration of any media file supported by ffprobe
+func (s *Service) probe(fname string) int {
+	cmd := s.FFProbe
+	if cmd == "" {
+		cmd = "ffprobe"
+	}
+	out, err := exec.Command(cmd, "-v", "error", "-show_entries", "format=duration", // nolint
+		"-of", "default=noprint_wrappers=1:nokey=1", fname).Output()
+	if err != nil {
+		log.Printf("[WARN] can't get duration of %s with %s: %v", fname, cmd, err)
+		return 0
+	}
+	res, err := strconv.ParseFloat(strings.TrimSpace(string(out)), 64)
+	if err != nil {
+		log.Printf("[WARN] can't get duration of %s, unexpected %s output %q", fname, cmd, string(out))
+		return 0
+	}
+	return int(res)
+}
+
 // reader scans MP3 from provided file and returns its duration in seconds, ignoring possible errors
 func (s *Service) reader(r io.Reader) int {
 	d := mp3.NewDecoder(r)
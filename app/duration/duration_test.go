@@ -2,9 +2,12 @@ package duration
 
 import (
 	"bytes"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestService_File(t *testing.T) {
@@ -28,3 +31,17 @@ func TestService_Reader(t *testing.T) {
 	svc := Service{}
 	assert.Zero(t, svc.reader(reader))
 }
+
+func TestService_FileProbe(t *testing.T) {
+	probe := filepath.Join(t.TempDir(), "ffprobe")
+	require.NoError(t, os.WriteFile(probe, []byte("#!/bin/sh\necho \"123.6\"\n"), 0o700)) // nolint
+	svc := Service{FFProbe: probe}
+	assert.Equal(t, 123, svc.File("testdata/audio.m4a"))
+	assert.Equal(t, 47, svc.File("testdata/audio.mp3"), "mp3 scanned")
+
+	require.NoError(t, os.WriteFile(probe, []byte("#!/bin/sh\necho \"N/A\"\n"), 0o700)) // nolint
+	assert.Equal(t, 0, svc.File("testdata/audio.m4a"), "unexpected output")
+
+	svc = Service{FFProbe: filepath.Join(t.TempDir(), "not-found")}
+	assert.Equal(t, 0, svc.File("testdata/audio.opus"))
+}
@@ -22,15 +22,18 @@ type Item struct {
 	Enclosure   Enclosure     `xml:"enclosure"`
 	GUID        string        `xml:"guid"`
 	// Optional
-	Content  template.HTML `xml:"encoded,omitempty"`
-	PubDate  string        `xml:"pubDate,omitempty"`
-	Comments string        `xml:"comments,omitempty"`
-	Author   string        `xml:"author,omitempty"`
-	Duration string        `xml:"duration,omitempty"`
+	Content  template.HTML    `xml:"encoded,omitempty"`
+	PubDate  string           `xml:"pubDate,omitempty"`
+	Comments string           `xml:"comments,omitempty"`
+	Author   string           `xml:"author,omitempty"`
+	Duration string           `xml:"duration,omitempty"`
+	Chapters *PodcastChapters `xml:"chapters,omitempty"`
+	Image    *ItemImage       `xml:"image,omitempty"`
 	// Internal
 	DT          time.Time `xml:"-"`
 	Junk        bool      `xml:"-"`
 	DurationFmt string    `xml:"-"` // used for ui only in
+	Source      string    `xml:"-"` // name of the source the item came from, empty for items saved by old versions
 }
 
 // DownloadAudio return httpBody for Item's Enclosure.URL
@@ -9,11 +9,23 @@ import (
 	"html/template"
 	"io"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
 	log "github.com/go-pkgz/lgr"
 	"github.com/pkg/errors"
+
+	"github.com/umputun/feed-master/app/metrics"
+)
+
+var (
+	fetchTotal = metrics.NewCounter("feed_master_source_fetch_total",
+		"Source fetches by host and http status, error for failed requests", "host", "status")
+	fetchDuration = metrics.NewHistogram("feed_master_source_fetch_duration_seconds",
+		"Source fetch latency", metrics.DefBuckets, "host")
+	fetchBytes = metrics.NewCounter("feed_master_source_fetch_bytes_total", "Bytes fetched from sources", "host")
 )
 
 // Rss2 feed
@@ -22,6 +34,7 @@ type Rss2 struct {
 	Version        string          `xml:"version,attr"`
 	NsItunes       string          `xml:"xmlns:itunes,attr"`
 	NsMedia        string          `xml:"xmlns:media,attr"`
+	NsPodcast      string          `xml:"xmlns:podcast,attr,omitempty"`
 	Title          string          `xml:"channel>title"`
 	Language       string          `xml:"channel>language"`
 	Link           string          `xml:"channel>link"`
@@ -34,8 +47,14 @@ type Rss2 struct {
 	ItunesExplicit string          `xml:"channel>itunes:explicit"`
 	ItunesOwner    *ItunesOwner    `xml:"channel>itunes:owner"`
 	ItemList       []Item          `xml:"channel>item"`
+
+	Partial bool `xml:"-"` // some items are hidden by the server, see HiddenEntriesHeader
 }
 
+// HiddenEntriesHeader is the response header with the number of youtube entries hidden from
+// unauthenticated request of feed-master's own youtube rss
+const HiddenEntriesHeader = "X-Hidden-Entries"
+
 // ItunesImg image element for iTunes
 type ItunesImg struct {
 	XMLName xml.Name `xml:"itunes:image,omitempty"`
@@ -54,6 +73,17 @@ type MediaThumbnail struct {
 	URL     string   `xml:"url,attr"`
 }
 
+// ItemImage element from item, itunes:image of the episode
+type ItemImage struct {
+	URL string `xml:"href,attr"`
+}
+
+// PodcastChapters element from item, link to podcasting 2.0 chapters file
+type PodcastChapters struct {
+	URL  string `xml:"url,attr"`
+	Type string `xml:"type,attr"`
+}
+
 // Enclosure element from item
 type Enclosure struct {
 	URL    string `xml:"url,attr"`
@@ -99,11 +129,19 @@ type Entry struct {
 
 // Parse gets url to rss feed and returns Rss2 items
 func Parse(uri string) (result Rss2, err error) {
+	host := uri
+	if u, e := url.Parse(uri); e == nil {
+		host = u.Host
+	}
+	defer func(st time.Time) { fetchDuration.Observe(time.Since(st).Seconds(), host) }(time.Now())
+
 	client := http.Client{Timeout: time.Minute * 2}
 	resp, err := client.Get(uri) // nolint
 	if err != nil {
+		fetchTotal.Inc(host, "error")
 		return result, err
 	}
+	fetchTotal.Inc(host, strconv.Itoa(resp.StatusCode))
 	defer func() {
 		if e := resp.Body.Close(); e != nil {
 			log.Printf("[WARN] failed to close body, %s", e)
@@ -115,6 +153,7 @@ func Parse(uri string) (result Rss2, err error) {
 	}
 
 	body, err := io.ReadAll(resp.Body)
+	fetchBytes.Add(float64(len(body)), host)
 	if err != nil {
 		return result, errors.Wrapf(err, "failed to read body, url: %s", uri)
 	}
@@ -122,6 +161,7 @@ func Parse(uri string) (result Rss2, err error) {
 	if err != nil {
 		return Rss2{}, errors.Wrap(err, "parsing error")
 	}
+	result.Partial = resp.Header.Get(HiddenEntriesHeader) != ""
 
 	return result.Normalize()
 }
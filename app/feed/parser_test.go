@@ -1,16 +1,20 @@
 package feed
 
 import (
+	"bytes"
 	"fmt"
 	"html/template"
 	"net/http"
 	"net/http/httptest"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/umputun/feed-master/app/metrics"
 )
 
 func TestFeedParse(t *testing.T) {
@@ -81,6 +85,18 @@ func TestFeedParse(t *testing.T) {
 	assert.Equal(t, "Еженедельные импровизации на хай–тек темы", r.Description)
 	require.Equal(t, 1, len(r.ItemList))
 	assert.Equal(t, "podcast@radio-t.com (Umputun, Bobuk, Gray, Ksenks, Alek.sys)", r.ItemList[0].Author)
+	assert.False(t, r.Partial)
+
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set(HiddenEntriesHeader, "2")
+		_, err := w.Write([]byte(testFeed))
+		assert.NoError(t, err)
+	}))
+	defer ts.Close()
+	r, err = Parse(ts.URL)
+	require.NoError(t, err)
+	assert.True(t, r.Partial)
+	assert.Equal(t, 1, len(r.ItemList))
 }
 
 func TestFeedParseBadBody(t *testing.T) {
@@ -94,6 +110,23 @@ func TestFeedParseBadBody(t *testing.T) {
 	assert.Empty(t, r)
 }
 
+func TestFeedParseMetrics(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, err := w.Write([]byte("not found"))
+		assert.NoError(t, err)
+	}))
+	defer ts.Close()
+	_, err := Parse(ts.URL)
+	require.Error(t, err)
+
+	host := strings.TrimPrefix(ts.URL, "http://")
+	buf := bytes.Buffer{}
+	require.NoError(t, metrics.Write(&buf))
+	assert.Contains(t, buf.String(), fmt.Sprintf(`feed_master_source_fetch_total{host=%q,status="404"} 1`, host))
+	assert.Contains(t, buf.String(), fmt.Sprintf(`feed_master_source_fetch_duration_seconds_count{host=%q} 1`, host))
+}
+
 func TestFeedParseHttpError(t *testing.T) {
 	var ts *httptest.Server
 	ts = httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
@@ -271,3 +304,49 @@ func TestParseFeedContentIfRSSVersionEmptyContent(t *testing.T) {
 	assert.Equal(t, got.ItemList[0].Content, template.HTML("Content"))
 	assert.Equal(t, got.ItemList[0].Description, template.HTML("Content"))
 }
+
+func TestParseFeedContentPodcastChapters(t *testing.T) {
+	rss := `<?xml version="1.0" encoding="UTF-8"?>
+<rss xmlns:itunes="http://www.itunes.com/dtds/podcast-1.0.dtd" xmlns:podcast="https://podcastindex.org/namespace/1.0" version="2.0">
+  <channel>
+    <title>Example</title>
+	<item>
+	  <title>With chapters</title>
+	  <podcast:chapters url="https://example.com/1.chapters.json" type="application/json+chapters"></podcast:chapters>
+	</item>
+	<item>
+	  <title>No chapters</title>
+	</item>
+  </channel>
+</rss>`
+
+	got, err := parseFeedContent([]byte(rss))
+	require.NoError(t, err)
+	require.Equal(t, 2, len(got.ItemList))
+	assert.Equal(t, &PodcastChapters{URL: "https://example.com/1.chapters.json", Type: "application/json+chapters"},
+		got.ItemList[0].Chapters)
+	assert.Nil(t, got.ItemList[1].Chapters)
+}
+
+func TestParseFeedContentItemImage(t *testing.T) {
+	rss := `<?xml version="1.0" encoding="UTF-8"?>
+<rss xmlns:itunes="http://www.itunes.com/dtds/podcast-1.0.dtd" version="2.0">
+  <channel>
+    <title>Example</title>
+    <itunes:image href="https://example.com/channel.jpg"></itunes:image>
+	<item>
+	  <title>With image</title>
+	  <itunes:image href="https://example.com/1.jpg"></itunes:image>
+	</item>
+	<item>
+	  <title>No image</title>
+	</item>
+  </channel>
+</rss>`
+
+	got, err := parseFeedContent([]byte(rss))
+	require.NoError(t, err)
+	require.Equal(t, 2, len(got.ItemList))
+	assert.Equal(t, &ItemImage{URL: "https://example.com/1.jpg"}, got.ItemList[0].Image)
+	assert.Nil(t, got.ItemList[1].Image)
+}
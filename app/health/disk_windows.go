@@ -0,0 +1,7 @@
+package health
+
+import "errors"
+
+func diskFree(string) (uint64, error) {
+	return 0, errors.New("not supported on windows")
+}
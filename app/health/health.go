@@ -0,0 +1,114 @@
+// Package health provides checks of the application components for health and readiness probes
+package health
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Check is a named check of a component, Fn returns short status description and error if component is unhealthy
+type Check struct {
+	Name string
+	Fn   func() (status string, err error)
+}
+
+// Result of a check
+type Result struct {
+	Name   string `json:"name"`
+	OK     bool   `json:"ok"`
+	Status string `json:"status,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Run runs all checks, ok is false if any of them failed
+func Run(checks []Check) (res []Result, ok bool) {
+	ok = true
+	res = make([]Result, 0, len(checks))
+	for _, c := range checks {
+		status, err := c.Fn()
+		r := Result{Name: c.Name, OK: err == nil, Status: status}
+		if err != nil {
+			r.Error = err.Error()
+			ok = false
+		}
+		res = append(res, r)
+	}
+	return res, ok
+}
+
+// Fresh checks the last success time returned by last is within maxAge. Zero time means no success yet,
+// in this case the age is counted from the check creation, to give the component time to start.
+func Fresh(name string, last func() time.Time, maxAge time.Duration) Check {
+	created := time.Now()
+	return Check{Name: name, Fn: func() (string, error) {
+		ts := last()
+		if ts.IsZero() {
+			if age := time.Since(created); age > maxAge {
+				return "never", fmt.Errorf("no success in %v", age.Truncate(time.Second))
+			}
+			return "starting", nil
+		}
+		age := time.Since(ts)
+		status := ts.Format(time.RFC3339)
+		if age > maxAge {
+			return status, fmt.Errorf("last success %v ago, more than %v", age.Truncate(time.Second), maxAge)
+		}
+		return status, nil
+	}}
+}
+
+// Bolt checks the db is readable
+func Bolt(name string, db *bolt.DB) Check {
+	return Check{Name: name, Fn: func() (string, error) {
+		buckets := 0
+		err := db.View(func(tx *bolt.Tx) error {
+			return tx.ForEach(func([]byte, *bolt.Bucket) error {
+				buckets++
+				return nil
+			})
+		})
+		if err != nil {
+			return "", fmt.Errorf("can't read db: %w", err)
+		}
+		return fmt.Sprintf("%d buckets", buckets), nil
+	}}
+}
+
+// DiskFree checks free space of the file system with the path is at least minFree bytes.
+// Path may not exist yet, i.e. before the first download, in this case the closest existing parent is checked.
+func DiskFree(name, path string, minFree uint64) Check {
+	return Check{Name: name, Fn: func() (string, error) {
+		dir := filepath.Clean(path)
+		for {
+			if _, err := os.Stat(dir); err == nil || filepath.Dir(dir) == dir {
+				break
+			}
+			dir = filepath.Dir(dir)
+		}
+		free, err := diskFree(dir)
+		if err != nil {
+			return "", fmt.Errorf("can't get free space of %s: %w", path, err)
+		}
+		status := fmt.Sprintf("%d MB free", free>>20)
+		if free < minFree {
+			return status, fmt.Errorf("less than %d MB free", minFree>>20)
+		}
+		return status, nil
+	}}
+}
+
+// Command checks the executable is available in PATH
+func Command(name, cmd string) Check {
+	return Check{Name: name, Fn: func() (string, error) {
+		p, err := exec.LookPath(cmd)
+		if err != nil {
+			return "", fmt.Errorf("%s is not available: %w", cmd, err)
+		}
+		return p, nil
+	}}
+}
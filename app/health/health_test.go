@@ -0,0 +1,93 @@
+package health
+
+import (
+	"errors"
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestRun(t *testing.T) {
+	res, ok := Run([]Check{
+		{Name: "c1", Fn: func() (string, error) { return "fine", nil }},
+		{Name: "c2", Fn: func() (string, error) { return "bad", errors.New("failed") }},
+	})
+	assert.False(t, ok)
+	assert.Equal(t, []Result{{Name: "c1", OK: true, Status: "fine"}, {Name: "c2", Status: "bad", Error: "failed"}}, res)
+
+	res, ok = Run(nil)
+	assert.True(t, ok)
+	assert.Empty(t, res)
+}
+
+func TestFresh(t *testing.T) {
+	var last time.Time
+	c := Fresh("proc", func() time.Time { return last }, time.Minute)
+	assert.Equal(t, "proc", c.Name)
+
+	status, err := c.Fn()
+	require.NoError(t, err, "not completed yet, but just started")
+	assert.Equal(t, "starting", status)
+
+	last = time.Now().Add(-30 * time.Second)
+	status, err = c.Fn()
+	require.NoError(t, err)
+	assert.Equal(t, last.Format(time.RFC3339), status)
+
+	last = time.Now().Add(-2 * time.Minute)
+	_, err = c.Fn()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "last success 2m0s ago, more than 1m0s")
+
+	c = Fresh("yt", func() time.Time { return time.Time{} }, -time.Second)
+	status, err = c.Fn()
+	require.Error(t, err, "never completed")
+	assert.Equal(t, "never", status)
+}
+
+func TestBolt(t *testing.T) {
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "test.db"), 0o600, &bolt.Options{Timeout: 1 * time.Second})
+	require.NoError(t, err)
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, e := tx.CreateBucket([]byte("b1"))
+		return e
+	})
+	require.NoError(t, err)
+
+	c := Bolt("db", db)
+	status, err := c.Fn()
+	require.NoError(t, err)
+	assert.Equal(t, "1 buckets", status)
+
+	require.NoError(t, db.Close())
+	_, err = c.Fn()
+	assert.Error(t, err)
+}
+
+func TestDiskFree(t *testing.T) {
+	dir := t.TempDir()
+	status, err := DiskFree("disk", dir, 1).Fn()
+	require.NoError(t, err)
+	assert.Contains(t, status, "MB free")
+
+	_, err = DiskFree("disk", filepath.Join(dir, "not", "created"), 1).Fn()
+	require.NoError(t, err, "closest existing parent checked")
+
+	_, err = DiskFree("disk", dir, math.MaxUint64).Fn()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "MB free")
+}
+
+func TestCommand(t *testing.T) {
+	status, err := Command("sh", "sh").Fn()
+	require.NoError(t, err)
+	assert.Contains(t, status, "sh")
+
+	_, err = Command("dl", "not-existing-command-12345").Fn()
+	assert.Error(t, err)
+}
@@ -6,15 +6,18 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"os/signal"
 	"path"
+	"reflect"
+	"sort"
 	"strings"
+	"syscall"
 	"text/template"
 	"time"
 
 	"github.com/ChimeraCoder/anaconda"
 
 	log "github.com/go-pkgz/lgr"
-	"github.com/google/uuid"
 	"github.com/jessevdk/go-flags"
 	bolt "go.etcd.io/bbolt"
 
@@ -22,9 +25,16 @@ import (
 	"github.com/umputun/feed-master/app/config"
 	"github.com/umputun/feed-master/app/duration"
 	rssfeed "github.com/umputun/feed-master/app/feed"
+	"github.com/umputun/feed-master/app/health"
+	"github.com/umputun/feed-master/app/metrics"
 	"github.com/umputun/feed-master/app/proc"
+	"github.com/umputun/feed-master/app/token"
 	"github.com/umputun/feed-master/app/youtube"
+	"github.com/umputun/feed-master/app/youtube/cover"
 	ytfeed "github.com/umputun/feed-master/app/youtube/feed"
+	"github.com/umputun/feed-master/app/youtube/mp4"
+	"github.com/umputun/feed-master/app/youtube/postproc"
+	"github.com/umputun/feed-master/app/youtube/sponsor"
 	"github.com/umputun/feed-master/app/youtube/store"
 )
 
@@ -33,6 +43,8 @@ type options struct {
 	DB   string `short:"c" long:"db" env:"FM_DB" default:"var/feed-master.bdb" description:"bolt db file"`
 	Conf string `short:"f" long:"conf" env:"FM_CONF" default:"feed-master.yml" description:"config file (yml)"`
 
+	ConfWatch time.Duration `long:"conf-watch" env:"FM_CONF_WATCH" description:"interval to check config file for changes, disabled by default"`
+
 	// single feed overrides
 	Feed            string        `long:"feed" env:"FM_FEED" description:"single feed, overrides config"`
 	TelegramChannel string        `long:"telegram_chan" env:"TELEGRAM_CHAN" description:"single telegram channel, overrides config"`
@@ -41,19 +53,36 @@ type options struct {
 	TelegramServer        string        `long:"telegram_server" env:"TELEGRAM_SERVER" default:"https://api.telegram.org" description:"telegram bot api server"`
 	TelegramToken         string        `long:"telegram_token" env:"TELEGRAM_TOKEN" description:"telegram token"`
 	TelegramTimeout       time.Duration `long:"telegram_timeout" env:"TELEGRAM_TIMEOUT" default:"1m" description:"telegram timeout"`
+	TelegramBot           bool          `long:"telegram_bot" env:"TELEGRAM_BOT" description:"answer telegram bot commands"`
+	TelegramAdmins        []int64       `long:"telegram_admins" env:"TELEGRAM_ADMINS" env-delim:"," description:"telegram user ids allowed to run admin bot commands"`
 	TwitterConsumerKey    string        `long:"consumer-key" env:"TWI_CONSUMER_KEY" description:"twitter consumer key"`
 	TwitterConsumerSecret string        `long:"consumer-secret" env:"TWI_CONSUMER_SECRET" description:"twitter consumer secret"`
 	TwitterAccessToken    string        `long:"access-token" env:"TWI_ACCESS_TOKEN" description:"twitter access token"`
 	TwitterAccessSecret   string        `long:"access-secret" env:"TWI_ACCESS_SECRET" description:"twitter access secret"`
 	TwitterTemplate       string        `long:"template" env:"TEMPLATE" default:"{{.Title}} - {{.Link}}" description:"twitter message template"`
 
-	AdminPasswd string `long:"admin-passwd" env:"ADMIN_PASSWD" description:"admin password for protected endpoints"`
+	AdminPasswd string `long:"admin-passwd" env:"ADMIN_PASSWD" description:"admin password for protected endpoints, grants all scopes"`
+	DryRun      bool   `long:"dry-run" env:"DRY_RUN" description:"show what would be saved and notified for all feeds and exit"`
+	CheckConfig bool   `long:"check-config" description:"validate config file and exit"`
+
+	MetricsAuth bool `long:"metrics-auth" env:"METRICS_AUTH" description:"require read scope for /metrics, public by default"`
+
+	HealthMaxAge  time.Duration `long:"health-max-age" env:"HEALTH_MAX_AGE" description:"max age of the last processor sweep and youtube run for /health, 3 update intervals by default"`
+	HealthMinFree int           `long:"health-min-free" env:"HEALTH_MIN_FREE" default:"500" description:"min free space under youtube files location for /health, in MB"`
+
+	// api tokens management, works with db directly and requires feed-master to be stopped
+	TokenAdd    string   `long:"token-add" description:"add api token with the name, print its secret and exit"`
+	TokenScopes []string `long:"token-scope" description:"scope of the added token, read, feeds:admin or yt:admin"`
+	TokenList   bool     `long:"token-list" description:"list api tokens and exit"`
+	TokenRevoke string   `long:"token-revoke" description:"revoke api token with the name and exit"`
 
 	Dbg bool `long:"dbg" env:"DEBUG" description:"debug mode"`
 }
 
 var revision = "local"
 
+var boltBucketKeys = metrics.NewGauge("feed_master_bolt_bucket_keys", "Number of keys in bolt db buckets", "bucket")
+
 func main() {
 	fmt.Printf("feed-master %s\n", revision)
 	var opts options
@@ -67,12 +96,37 @@ func main() {
 		conf = config.SingleFeed(opts.Feed, opts.TelegramChannel, opts.UpdateInterval)
 	}
 
+	if opts.CheckConfig {
+		if _, err := config.Check(opts.Conf); err != nil {
+			fmt.Printf("config %s is invalid, %v\n", opts.Conf, err)
+			os.Exit(1)
+		}
+		fmt.Printf("config %s is valid\n", opts.Conf)
+		return
+	}
+
+	if opts.TokenAdd != "" || opts.TokenList || opts.TokenRevoke != "" {
+		if err := manageTokens(opts); err != nil {
+			fmt.Printf("%v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	var err error
 	if opts.Feed == "" {
-		conf, err = config.Load(opts.Conf)
-		if err != nil {
+		conf, err = config.Check(opts.Conf)
+		if conf == nil {
 			log.Fatalf("[ERROR] can't load config %s, %v", opts.Conf, err)
 		}
+		if err != nil {
+			log.Fatalf("[ERROR] invalid config %s, %v", opts.Conf, err)
+		}
+	}
+
+	if opts.DryRun {
+		dryRun(conf, opts)
+		return
 	}
 
 	db, err := makeBoltDB(opts.DB)
@@ -80,6 +134,15 @@ func main() {
 		log.Fatalf("[ERROR] can't open db %s, %v", opts.DB, err)
 	}
 	procStore := &proc.BoltDB{DB: db}
+	collectBoltMetrics(db)
+
+	var confRuntime *config.Runtime
+	if opts.Feed == "" { // feeds and youtube channels changed by admin api are kept on top of config file
+		if confRuntime, err = config.NewRuntime(conf, procStore); err != nil {
+			log.Fatalf("[ERROR] can't make runtime config, %v", err)
+		}
+		conf = confRuntime.Conf()
+	}
 
 	telegramNotif, err := proc.NewTelegramClient(opts.TelegramToken, opts.TelegramServer, opts.TelegramTimeout,
 		&duration.Service{}, &proc.TelegramSenderImpl{})
@@ -124,7 +187,16 @@ func main() {
 				Enabled:  conf.YouTube.RSSLocation != "",
 			},
 			DurationService: &duration.Service{},
+			CoverService:    &cover.Loader{Client: &http.Client{Timeout: 30 * time.Second}},
+			MP4TagsService:  &mp4.Tagger{},
+			VideoTemplate:   conf.YouTube.VideoTemplate,
+			SponsorService:  &sponsor.Service{URL: conf.YouTube.SponsorBlockURL, Client: &http.Client{Timeout: 30 * time.Second}},
+			PostProcService: &postproc.Service{},
+			PostProcess:     conf.YouTube.PostProcess,
 			SkipShorts:      conf.YouTube.SkipShorts,
+			Concurrent:      conf.YouTube.Concurrent,
+			RetryAttempts:   conf.YouTube.RetryAttempts,
+			RetryDelay:      conf.YouTube.RetryDelay,
 		}
 		go func() {
 			if conf.YouTube.DisableUpdates {
@@ -137,22 +209,179 @@ func main() {
 		}()
 	}
 
+	var tgBot *proc.TelegramBot
+	if opts.TelegramBot && opts.TelegramToken != "" {
+		tgBot, err = proc.NewTelegramBot(opts.TelegramToken, opts.TelegramServer, opts.TelegramTimeout)
+		if err != nil {
+			log.Fatalf("[ERROR] failed to initialize telegram bot, %v", err)
+		}
+		tgBot.Conf, tgBot.Store, tgBot.Processor, tgBot.Admins = conf, procStore, p, opts.TelegramAdmins
+		if len(conf.YouTube.Channels) > 0 {
+			tgBot.YoutubeSvc = &ytSvc
+		}
+		go tgBot.Run(context.Background())
+	}
+
 	if opts.AdminPasswd == "" {
-		log.Printf("[WARN] admin password is not set, protected endpoints are disabled")
-		opts.AdminPasswd = uuid.New().String() // generate random (uuid) password
+		log.Printf("[WARN] admin password is not set, protected endpoints are available with api tokens only")
 	}
 
+	subscribers := &token.Counter{BoltDB: token.BoltDB{DB: db}, Window: time.Hour}
+	go subscribers.Run(context.Background(), time.Minute)
+
 	server := api.Server{
-		Version:      revision,
-		Conf:         *conf,
-		Store:        procStore,
-		YoutubeStore: ytStore,
-		YoutubeSvc:   &ytSvc,
-		AdminPasswd:  opts.AdminPasswd,
+		Version:     revision,
+		Conf:        *conf,
+		Store:       procStore,
+		YoutubeSvc:  &ytSvc,
+		Processor:   p,
+		AdminPasswd: opts.AdminPasswd,
+		MetricsAuth: opts.MetricsAuth,
+		Tokens:      token.BoltDB{DB: db},
+		Subscribers: subscribers,
+	}
+	if ytStore != nil {
+		server.YoutubeStore = ytStore
+		server.YoutubeQueue = &ytSvc
+	}
+	server.HealthChecks = makeHealthChecks(conf, opts, db, p, &ytSvc)
+
+	if confRuntime != nil { // config reload makes no sense in single feed mode
+		confRuntime.Apply = func(newConf *config.Conf) {
+			p.SetConf(newConf)
+			if tgBot != nil {
+				tgBot.SetConf(newConf)
+			}
+			if ytStore != nil {
+				channels := []string{}
+				for _, c := range newConf.YouTube.Channels {
+					channels = append(channels, c.ID)
+				}
+				if err := ytStore.SetChannels(channels); err != nil {
+					log.Printf("[WARN] failed to update youtube channels, %v", err)
+				}
+				ytSvc.SetFeeds(newConf.YouTube.Channels)
+				if ytSettingsChanged(conf, newConf) {
+					log.Printf("[WARN] youtube settings other than channels changed, restart required to apply them")
+				}
+			} else if len(newConf.YouTube.Channels) > 0 {
+				log.Printf("[WARN] youtube processing was not enabled on start, restart required to enable it")
+			}
+			server.SetConf(*newConf)
+			log.Printf("[INFO] config applied, feeds=%d, youtube channels=%d", len(newConf.Feeds), len(newConf.YouTube.Channels))
+		}
+		server.ConfEditor = confRuntime
+		go watchConf(context.Background(), opts.Conf, opts.ConfWatch, confRuntime.SetBase)
 	}
+
 	server.Run(context.Background(), opts.Port)
 }
 
+// watchConf reloads config on SIGHUP and, if interval set, on modification of config or any of included files.
+// The new config is passed to apply only if it is loaded and validated successfully.
+func watchConf(ctx context.Context, fname string, interval time.Duration, apply func(conf *config.Conf)) {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGHUP)
+	defer signal.Stop(sigCh)
+
+	var tick <-chan time.Time
+	if interval > 0 {
+		log.Printf("[INFO] watch config %s for changes every %v", fname, interval)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	// modState returns names and modification times of the files of the last loaded config and files matching
+	// its include globs now, to catch added and removed files. Only the main file watched if config can't be loaded.
+	// Files are not parsed, config loaded on change only.
+	watched, _ := config.Load(fname)
+	modState := func() string {
+		files := []string{fname}
+		if watched != nil {
+			files = watched.Watched()
+		}
+		res := make([]string, 0, len(files))
+		for _, f := range files {
+			fi, err := os.Stat(f)
+			if err != nil {
+				res = append(res, f)
+				continue
+			}
+			res = append(res, fmt.Sprintf("%s:%d", f, fi.ModTime().UnixNano()))
+		}
+		return strings.Join(res, ",")
+	}
+	lastState := modState()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sigCh:
+			log.Printf("[INFO] SIGHUP received, reload config %s", fname)
+		case <-tick:
+			if modState() == lastState {
+				continue
+			}
+			log.Printf("[INFO] config %s changed, reload", fname)
+		}
+		lastState = modState()
+
+		conf, err := config.Check(fname)
+		if conf != nil { // loaded, even if invalid, the list of files is known
+			watched = conf
+		}
+		if err != nil {
+			log.Printf("[WARN] can't reload config %s, keep the current one, %v", fname, err)
+			continue
+		}
+		apply(conf)
+	}
+}
+
+// ytSettingsChanged checks if youtube settings other than the list of channels differ. The youtube service is made
+// with these settings on start, so they are not applied on config reload.
+func ytSettingsChanged(prev, next *config.Conf) bool {
+	p, n := prev.YouTube, next.YouTube
+	p.Channels, n.Channels = nil, nil
+	return !reflect.DeepEqual(p, n)
+}
+
+// manageTokens adds, lists or revokes api tokens. The db is locked by running feed-master, so it should be stopped.
+func manageTokens(opts options) error {
+	db, err := makeBoltDB(opts.DB)
+	if err != nil {
+		return fmt.Errorf("can't open db %s, stop feed-master to manage tokens, %w", opts.DB, err)
+	}
+	defer db.Close() // nolint
+	tokens := token.BoltDB{DB: db}
+
+	switch {
+	case opts.TokenAdd != "":
+		secret, err := tokens.Add(opts.TokenAdd, opts.TokenScopes)
+		if err != nil {
+			return fmt.Errorf("can't add token, %w", err)
+		}
+		fmt.Printf("token %s added, scopes %s\n", opts.TokenAdd, strings.Join(opts.TokenScopes, ", "))
+		fmt.Printf("secret (shown once): %s\n", secret)
+	case opts.TokenRevoke != "":
+		if err := tokens.Revoke(opts.TokenRevoke); err != nil {
+			return fmt.Errorf("can't revoke token, %w", err)
+		}
+		fmt.Printf("token %s revoked\n", opts.TokenRevoke)
+	default:
+		list, err := tokens.List()
+		if err != nil {
+			return fmt.Errorf("can't list tokens, %w", err)
+		}
+		for _, t := range list {
+			fmt.Printf("%-20s %-30s %s\n", t.Name, strings.Join(t.Scopes, ","), t.Created.Format(time.RFC3339))
+		}
+	}
+	return nil
+}
+
 func makeBoltDB(dbFile string) (*bolt.DB, error) {
 	log.Printf("[INFO] bolt (persistent) store, %s", dbFile)
 	if dbFile == "" {
@@ -169,6 +398,102 @@ func makeBoltDB(dbFile string) (*bolt.DB, error) {
 	return db, err
 }
 
+// collectBoltMetrics reports number of keys in each bucket of the db on metrics collection
+func collectBoltMetrics(db *bolt.DB) {
+	metrics.OnCollect(func() {
+		boltBucketKeys.Reset()
+		err := db.View(func(tx *bolt.Tx) error {
+			return tx.ForEach(func(name []byte, b *bolt.Bucket) error {
+				boltBucketKeys.Set(float64(b.Stats().KeyN), string(name))
+				return nil
+			})
+		})
+		if err != nil {
+			log.Printf("[WARN] can't collect db metrics, %v", err)
+		}
+	})
+}
+
+// makeHealthChecks makes checks for /health. Youtube checks are added only if youtube processing is enabled,
+// the default max age is 3 update intervals, but not less than 10 minutes for feeds and an hour for youtube downloads
+func makeHealthChecks(conf *config.Conf, opts options, db *bolt.DB, p *proc.Processor, ytSvc *youtube.Service) []health.Check {
+	maxAge := func(interval, minAge time.Duration) time.Duration {
+		if opts.HealthMaxAge > 0 {
+			return opts.HealthMaxAge
+		}
+		if age := 3 * interval; age > minAge {
+			return age
+		}
+		return minAge
+	}
+
+	res := []health.Check{
+		health.Bolt("db", db),
+		health.Fresh("processor", p.LastSweep, maxAge(conf.System.UpdateInterval, 10*time.Minute)),
+	}
+	if len(conf.YouTube.Channels) == 0 {
+		return res
+	}
+	if !conf.YouTube.DisableUpdates {
+		res = append(res, health.Fresh("youtube", ytSvc.LastProgress, maxAge(conf.YouTube.UpdateInterval, time.Hour)))
+	}
+	res = append(res, health.DiskFree("disk", conf.YouTube.FilesLocation, uint64(opts.HealthMinFree)<<20))
+	if fields := strings.Fields(conf.YouTube.DlTemplate); len(fields) > 0 {
+		res = append(res, health.Command("yt-dlp", fields[0]))
+	}
+	return res
+}
+
+// dryRun prints what would be done with items of all feeds, without saving anything and sending notifications.
+// The db is opened read-only to check already saved items, if it is not available all items are considered new.
+func dryRun(conf *config.Conf, opts options) {
+	p := &proc.Processor{Conf: conf, TwitterNotif: makeTwitter(opts)}
+	if _, err := os.Stat(opts.DB); err == nil {
+		db, err := bolt.Open(opts.DB, 0o600, &bolt.Options{Timeout: 1 * time.Second, ReadOnly: true}) // nolint
+		if err != nil {
+			log.Printf("[WARN] can't open db %s read-only, all items considered new, %v", opts.DB, err)
+		} else {
+			defer db.Close() // nolint
+			p.Store = &proc.BoltDB{DB: db}
+			if opts.Feed == "" {
+				rt, rtErr := config.NewRuntime(conf, &proc.BoltDB{DB: db})
+				if rtErr != nil {
+					log.Printf("[WARN] can't apply runtime config changes, %v", rtErr)
+				} else {
+					conf = rt.Conf()
+					p.Conf = conf
+				}
+			}
+		}
+	}
+
+	names := make([]string, 0, len(conf.Feeds))
+	for name := range conf.Feeds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		for _, src := range p.Preview(name, conf.Feeds[name]) {
+			fmt.Printf("%s/%s (%s)\n", name, src.Name, src.URL)
+			if src.Error != "" {
+				fmt.Printf("  failed: %s\n", src.Error)
+				continue
+			}
+			for _, item := range src.Items {
+				line := fmt.Sprintf("  %-6s %s (%s)", item.Action, item.Title, item.GUID)
+				if item.Reason != "" {
+					line += ", " + item.Reason
+				}
+				if len(item.Notify) > 0 {
+					line += ", notify " + strings.Join(item.Notify, ", ")
+				}
+				fmt.Println(line)
+			}
+		}
+	}
+}
+
 func makeTwitter(opts options) *proc.TwitterClient {
 	twitterFmtFn := func(item rssfeed.Item) string {
 		b1 := bytes.Buffer{}
@@ -1,9 +1,24 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/umputun/feed-master/app/config"
+	"github.com/umputun/feed-master/app/health"
+	"github.com/umputun/feed-master/app/metrics"
+	"github.com/umputun/feed-master/app/proc"
+	"github.com/umputun/feed-master/app/youtube"
 )
 
 func TestMakeTwitter(t *testing.T) {
@@ -21,3 +36,184 @@ func TestMakeTwitter(t *testing.T) {
 	assert.Equal(t, client.AccessToken, "c")
 	assert.Equal(t, client.AccessSecret, "d")
 }
+
+func TestWatchConf(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "fm.yml")
+	write := func(data string, ts time.Time) {
+		require.NoError(t, os.WriteFile(fname, []byte(data), 0o600))
+		require.NoError(t, os.Chtimes(fname, ts, ts))
+	}
+	write("feeds:\n  feed1:\n    sources:\n      - {name: src1, url: http://example.com/1}\n", time.Now().Add(-time.Hour))
+
+	var lock sync.Mutex
+	var applied []*config.Conf
+	last := func() (int, *config.Conf) {
+		lock.Lock()
+		defer lock.Unlock()
+		if len(applied) == 0 {
+			return 0, nil
+		}
+		return len(applied), applied[len(applied)-1]
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go watchConf(ctx, fname, 10*time.Millisecond, func(conf *config.Conf) {
+		lock.Lock()
+		defer lock.Unlock()
+		applied = append(applied, conf)
+	})
+
+	time.Sleep(50 * time.Millisecond)
+	n, _ := last()
+	assert.Equal(t, 0, n, "not changed, not applied")
+
+	write("feeds:\n  feed1:\n    sources:\n      - {name: src1, url: http://example.com/1}\n  feed2:\n"+
+		"    sources:\n      - {name: src2, url: http://example.com/2}\n", time.Now().Add(-time.Minute))
+	require.Eventually(t, func() bool { n, _ := last(); return n == 1 }, time.Second, 10*time.Millisecond)
+	_, conf := last()
+	assert.Equal(t, 2, len(conf.Feeds))
+	assert.Equal(t, 5, conf.System.MaxItems, "defaults set")
+
+	ts := time.Now()
+	write("feeds:\n  feed1:\n    filter: {title: \"(\"}\n    sources:\n      - {name: src1, url: http://example.com/1}\n", ts)
+	time.Sleep(50 * time.Millisecond)
+	n, _ = last()
+	assert.Equal(t, 1, n, "invalid config not applied")
+
+	write("feeds:\n  feed3:\n    sources:\n      - {name: src3, url: http://example.com/3}\n", ts) // same mtime, reloaded by signal only
+	require.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGHUP))
+	require.Eventually(t, func() bool { n, _ := last(); return n == 2 }, time.Second, 10*time.Millisecond)
+	_, conf = last()
+	_, ok := conf.Feeds["feed3"]
+	assert.True(t, ok)
+}
+
+func TestWatchConf_Include(t *testing.T) {
+	dir := t.TempDir()
+	fname := filepath.Join(dir, "fm.yml")
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "conf.d"), 0o700))
+	write := func(name, data string) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(data), 0o600))
+		ts := time.Now().Add(-time.Hour)
+		require.NoError(t, os.Chtimes(filepath.Join(dir, name), ts, ts))
+	}
+	write("fm.yml", "include: [\"conf.d/*.yml\"]\nfeeds:\n  feed1:\n    sources:\n      - {name: src1, url: http://example.com/1}\n")
+	write("conf.d/a.yml", "feeds:\n  feed2:\n    sources:\n      - {name: src2, url: http://example.com/2}\n")
+
+	var lock sync.Mutex
+	var applied []*config.Conf
+	last := func() (int, *config.Conf) {
+		lock.Lock()
+		defer lock.Unlock()
+		if len(applied) == 0 {
+			return 0, nil
+		}
+		return len(applied), applied[len(applied)-1]
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go watchConf(ctx, fname, 10*time.Millisecond, func(conf *config.Conf) {
+		lock.Lock()
+		defer lock.Unlock()
+		applied = append(applied, conf)
+	})
+
+	time.Sleep(50 * time.Millisecond)
+	n, _ := last()
+	assert.Equal(t, 0, n, "not changed, not applied")
+
+	// new included file added
+	write("conf.d/b.yml", "feeds:\n  feed3:\n    sources:\n      - {name: src3, url: http://example.com/3}\n")
+	require.Eventually(t, func() bool { n, _ := last(); return n == 1 }, time.Second, 10*time.Millisecond)
+	_, conf := last()
+	assert.Equal(t, 3, len(conf.Feeds))
+
+	// included file changed
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "conf.d/a.yml"),
+		[]byte("feeds:\n  feed4:\n    sources:\n      - {name: src4, url: http://example.com/4}\n"), 0o600))
+	require.Eventually(t, func() bool { n, _ := last(); return n == 2 }, time.Second, 10*time.Millisecond)
+	_, conf = last()
+	_, ok := conf.Feeds["feed4"]
+	assert.True(t, ok)
+	_, ok = conf.Feeds["feed2"]
+	assert.False(t, ok)
+}
+
+func TestYtSettingsChanged(t *testing.T) {
+	prev, next := &config.Conf{}, &config.Conf{}
+	prev.YouTube.DlTemplate, next.YouTube.DlTemplate = "yt-dlp {{.ID}}", "yt-dlp {{.ID}}"
+	next.YouTube.Channels = []youtube.FeedInfo{{ID: "ch1"}}
+	assert.False(t, ytSettingsChanged(prev, next), "channels only")
+	assert.Empty(t, prev.YouTube.Channels)
+	assert.Equal(t, 1, len(next.YouTube.Channels), "not modified")
+
+	next.YouTube.DlTemplate = "yt-dlp -x {{.ID}}"
+	assert.True(t, ytSettingsChanged(prev, next))
+}
+
+func TestManageTokens(t *testing.T) {
+	opts := options{DB: filepath.Join(t.TempDir(), "test.bdb"), TokenAdd: "ci", TokenScopes: []string{"read"}}
+	require.NoError(t, manageTokens(opts))
+	assert.EqualError(t, manageTokens(opts), `can't add token, token "ci" already exists`)
+
+	opts = options{DB: opts.DB, TokenAdd: "bad", TokenScopes: []string{"admin"}}
+	assert.Error(t, manageTokens(opts))
+
+	require.NoError(t, manageTokens(options{DB: opts.DB, TokenList: true}))
+	require.NoError(t, manageTokens(options{DB: opts.DB, TokenRevoke: "ci"}))
+	assert.EqualError(t, manageTokens(options{DB: opts.DB, TokenRevoke: "ci"}), `can't revoke token, token "ci" not found`)
+}
+
+func TestCollectBoltMetrics(t *testing.T) {
+	db, err := makeBoltDB(filepath.Join(t.TempDir(), "test.bdb"))
+	require.NoError(t, err)
+	defer db.Close() // nolint
+	err = db.Update(func(tx *bolt.Tx) error {
+		b, e := tx.CreateBucketIfNotExists([]byte("feed1"))
+		if e != nil {
+			return e
+		}
+		if e = b.Put([]byte("k1"), []byte("v1")); e != nil {
+			return e
+		}
+		return b.Put([]byte("k2"), []byte("v2"))
+	})
+	require.NoError(t, err)
+
+	collectBoltMetrics(db)
+	buf := bytes.Buffer{}
+	require.NoError(t, metrics.Write(&buf))
+	assert.Contains(t, buf.String(), `feed_master_bolt_bucket_keys{bucket="feed1"} 2`)
+}
+
+func TestMakeHealthChecks(t *testing.T) {
+	db, err := makeBoltDB(filepath.Join(t.TempDir(), "test.bdb"))
+	require.NoError(t, err)
+	defer db.Close() // nolint
+
+	names := func(checks []health.Check) (res []string) {
+		for _, c := range checks {
+			res = append(res, c.Name)
+		}
+		return res
+	}
+
+	conf := &config.Conf{}
+	checks := makeHealthChecks(conf, options{}, db, &proc.Processor{}, &youtube.Service{})
+	assert.Equal(t, []string{"db", "processor"}, names(checks))
+
+	conf.YouTube.Channels = []youtube.FeedInfo{{ID: "ch1"}}
+	conf.YouTube.DlTemplate = "yt-dlp {{.ID}}"
+	conf.YouTube.FilesLocation = t.TempDir()
+	checks = makeHealthChecks(conf, options{HealthMinFree: 1}, db, &proc.Processor{}, &youtube.Service{})
+	assert.Equal(t, []string{"db", "processor", "youtube", "disk", "yt-dlp"}, names(checks))
+
+	conf.YouTube.DisableUpdates = true
+	checks = makeHealthChecks(conf, options{HealthMinFree: 1}, db, &proc.Processor{}, &youtube.Service{})
+	assert.Equal(t, []string{"db", "processor", "disk", "yt-dlp"}, names(checks))
+
+	res, ok := health.Run(checks[:3])
+	assert.True(t, ok, "%+v", res)
+}
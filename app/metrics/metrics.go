@@ -0,0 +1,233 @@
+// Package metrics provides counters, gauges and histograms with labels, exposed in prometheus text format.
+// Metrics are registered globally on creation, so they are declared as package-level variables of
+// instrumented packages.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	log "github.com/go-pkgz/lgr"
+)
+
+// DefBuckets are default histogram buckets, in seconds
+var DefBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120}
+
+var registry = struct {
+	lock     sync.Mutex
+	metrics  map[string]*vec
+	collects []func()
+}{metrics: map[string]*vec{}}
+
+// Counter is a monotonically increasing value
+type Counter struct{ v *vec }
+
+// Gauge is a value which can go up and down
+type Gauge struct{ v *vec }
+
+// Histogram counts observations in buckets
+type Histogram struct{ v *vec }
+
+// NewCounter makes and registers counter with label names
+func NewCounter(name, help string, labels ...string) *Counter {
+	return &Counter{v: register(name, help, "counter", nil, labels)}
+}
+
+// NewGauge makes and registers gauge with label names
+func NewGauge(name, help string, labels ...string) *Gauge {
+	return &Gauge{v: register(name, help, "gauge", nil, labels)}
+}
+
+// NewHistogram makes and registers histogram with label names, buckets are upper bounds in increasing order
+func NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	return &Histogram{v: register(name, help, "histogram", buckets, labels)}
+}
+
+// Inc increments counter by 1, label values should be passed in the order of label names
+func (c *Counter) Inc(labelValues ...string) { c.Add(1, labelValues...) }
+
+// Add increments counter by v
+func (c *Counter) Add(v float64, labelValues ...string) {
+	c.v.update(labelValues, func(s *series) { s.value += v })
+}
+
+// Set sets gauge value
+func (g *Gauge) Set(v float64, labelValues ...string) {
+	g.v.update(labelValues, func(s *series) { s.value = v })
+}
+
+// Reset drops all series of the gauge, used to remove stale label values before setting the current ones
+func (g *Gauge) Reset() {
+	g.v.lock.Lock()
+	g.v.series = map[string]*series{}
+	g.v.lock.Unlock()
+}
+
+// Observe adds observation to histogram
+func (h *Histogram) Observe(v float64, labelValues ...string) {
+	h.v.update(labelValues, func(s *series) {
+		if s.buckets == nil {
+			s.buckets = make([]uint64, len(h.v.buckets))
+		}
+		for i, b := range h.v.buckets {
+			if v <= b {
+				s.buckets[i]++
+			}
+		}
+		s.value += v
+		s.count++
+	})
+}
+
+// OnCollect adds fn called before metrics are written, used to set gauges of current state, i.e. db sizes
+func OnCollect(fn func()) {
+	registry.lock.Lock()
+	defer registry.lock.Unlock()
+	registry.collects = append(registry.collects, fn)
+}
+
+// Handler returns http handler writing all metrics in prometheus text format
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		if err := Write(w); err != nil {
+			log.Printf("[WARN] failed to write metrics, %v", err)
+		}
+	})
+}
+
+// Write writes all metrics in prometheus text format, sorted by name
+func Write(w io.Writer) error {
+	registry.lock.Lock()
+	collects := append([]func(){}, registry.collects...)
+	names := make([]string, 0, len(registry.metrics))
+	for name := range registry.metrics {
+		names = append(names, name)
+	}
+	registry.lock.Unlock()
+
+	for _, fn := range collects {
+		fn()
+	}
+
+	sort.Strings(names)
+	bw := bufio.NewWriter(w)
+	for _, name := range names {
+		registry.lock.Lock()
+		v := registry.metrics[name]
+		registry.lock.Unlock()
+		v.write(bw)
+	}
+	return bw.Flush()
+}
+
+type vec struct {
+	name, help, kind string
+	buckets          []float64
+	labels           []string
+
+	lock   sync.Mutex
+	series map[string]*series // key is formatted labels
+}
+
+type series struct {
+	value   float64 // counter and gauge value, histogram sum
+	count   uint64
+	buckets []uint64
+}
+
+func register(name, help, kind string, buckets []float64, labels []string) *vec {
+	registry.lock.Lock()
+	defer registry.lock.Unlock()
+	if _, ok := registry.metrics[name]; ok {
+		panic(fmt.Sprintf("metric %s already registered", name))
+	}
+	v := &vec{name: name, help: help, kind: kind, buckets: buckets, labels: labels, series: map[string]*series{}}
+	registry.metrics[name] = v
+	return v
+}
+
+func (v *vec) update(labelValues []string, fn func(s *series)) {
+	key := v.labelsKey(labelValues)
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	s, ok := v.series[key]
+	if !ok {
+		s = &series{}
+		v.series[key] = s
+	}
+	fn(s)
+}
+
+// labelsKey formats labels as name="value" pairs, missing values are empty
+func (v *vec) labelsKey(labelValues []string) string {
+	pairs := make([]string, len(v.labels))
+	for i, l := range v.labels {
+		val := ""
+		if i < len(labelValues) {
+			val = labelValues[i]
+		}
+		pairs[i] = l + `="` + escape(val) + `"`
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (v *vec) write(w io.Writer) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	if len(v.series) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(v.series))
+	for k := range v.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	_, _ = fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", v.name, v.help, v.name, v.kind)
+	for _, k := range keys {
+		s := v.series[k]
+		if v.kind != "histogram" {
+			_, _ = fmt.Fprintf(w, "%s%s %s\n", v.name, braces(k), formatFloat(s.value))
+			continue
+		}
+		for i, b := range v.buckets {
+			_, _ = fmt.Fprintf(w, "%s_bucket%s %d\n", v.name, braces(join(k, `le="`+formatFloat(b)+`"`)), s.buckets[i])
+		}
+		_, _ = fmt.Fprintf(w, "%s_bucket%s %d\n", v.name, braces(join(k, `le="+Inf"`)), s.count)
+		_, _ = fmt.Fprintf(w, "%s_sum%s %s\n", v.name, braces(k), formatFloat(s.value))
+		_, _ = fmt.Fprintf(w, "%s_count%s %d\n", v.name, braces(k), s.count)
+	}
+}
+
+func braces(labels string) string {
+	if labels == "" {
+		return ""
+	}
+	return "{" + labels + "}"
+}
+
+func join(labels, l string) string {
+	if labels == "" {
+		return l
+	}
+	return labels + "," + l
+}
+
+func formatFloat(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func escape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
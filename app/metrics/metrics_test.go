@@ -0,0 +1,59 @@
+package metrics
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetrics(t *testing.T) {
+	c := NewCounter("test_requests_total", "requests count", "method", "path")
+	g := NewGauge("test_size", "size of something", "name")
+	h := NewHistogram("test_latency_seconds", "request latency", []float64{0.1, 1}, "method")
+	NewCounter("test_unused_total", "not written without values")
+
+	c.Inc("GET", "/a")
+	c.Inc("GET", "/a")
+	c.Add(3, "POST", `/b"c`)
+	h.Observe(0.05, "GET")
+	h.Observe(0.5, "GET")
+	h.Observe(5, "GET")
+	collected := 0
+	OnCollect(func() {
+		collected++
+		g.Reset()
+		g.Set(42, "bkt")
+	})
+
+	buf := bytes.Buffer{}
+	require.NoError(t, Write(&buf))
+	assert.Equal(t, `# HELP test_latency_seconds request latency
+# TYPE test_latency_seconds histogram
+test_latency_seconds_bucket{method="GET",le="0.1"} 1
+test_latency_seconds_bucket{method="GET",le="1"} 2
+test_latency_seconds_bucket{method="GET",le="+Inf"} 3
+test_latency_seconds_sum{method="GET"} 5.55
+test_latency_seconds_count{method="GET"} 3
+# HELP test_requests_total requests count
+# TYPE test_requests_total counter
+test_requests_total{method="GET",path="/a"} 2
+test_requests_total{method="POST",path="/b\"c"} 3
+# HELP test_size size of something
+# TYPE test_size gauge
+test_size{name="bkt"} 42
+`, buf.String())
+	assert.Equal(t, 1, collected)
+
+	rr := httptest.NewRecorder()
+	Handler().ServeHTTP(rr, httptest.NewRequest("GET", "/metrics", http.NoBody))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Header().Get("Content-Type"), "text/plain")
+	assert.Contains(t, rr.Body.String(), `test_size{name="bkt"} 42`)
+	assert.Equal(t, 2, collected)
+
+	assert.Panics(t, func() { NewGauge("test_size", "duplicate") })
+}
@@ -11,25 +11,68 @@ import (
 
 // TelegramNotifMock is a mock implementation of proc.TelegramNotif.
 //
-// 	func TestSomethingThatUsesTelegramNotif(t *testing.T) {
+//	func TestSomethingThatUsesTelegramNotif(t *testing.T) {
 //
-// 		// make and configure a mocked proc.TelegramNotif
-// 		mockedTelegramNotif := &TelegramNotifMock{
-// 			SendFunc: func(chanID string, item feed.Item) error {
-// 				panic("mock out the Send method")
-// 			},
-// 		}
+//		// make and configure a mocked proc.TelegramNotif
+//		mockedTelegramNotif := &TelegramNotifMock{
+//			DeleteFunc: func(ref string) error {
+//				panic("mock out the Delete method")
+//			},
+//			EditFunc: func(ref string, item feed.Item) error {
+//				panic("mock out the Edit method")
+//			},
+//			EditDigestFunc: func(ref string, items []feed.Item) error {
+//				panic("mock out the EditDigest method")
+//			},
+//			SendFunc: func(chanID string, item feed.Item) (string, error) {
+//				panic("mock out the Send method")
+//			},
+//			SendDigestFunc: func(chanID string, items []feed.Item) ([]string, error) {
+//				panic("mock out the SendDigest method")
+//			},
+//		}
 //
-// 		// use mockedTelegramNotif in code that requires proc.TelegramNotif
-// 		// and then make assertions.
+//		// use mockedTelegramNotif in code that requires proc.TelegramNotif
+//		// and then make assertions.
 //
-// 	}
+//	}
 type TelegramNotifMock struct {
+	// DeleteFunc mocks the Delete method.
+	DeleteFunc func(ref string) error
+
+	// EditFunc mocks the Edit method.
+	EditFunc func(ref string, item feed.Item) error
+
+	// EditDigestFunc mocks the EditDigest method.
+	EditDigestFunc func(ref string, items []feed.Item) error
+
 	// SendFunc mocks the Send method.
-	SendFunc func(chanID string, item feed.Item) error
+	SendFunc func(chanID string, item feed.Item) (string, error)
+
+	// SendDigestFunc mocks the SendDigest method.
+	SendDigestFunc func(chanID string, items []feed.Item) ([]string, error)
 
 	// calls tracks calls to the methods.
 	calls struct {
+		// Delete holds details about calls to the Delete method.
+		Delete []struct {
+			// Ref is the ref argument value.
+			Ref string
+		}
+		// Edit holds details about calls to the Edit method.
+		Edit []struct {
+			// Ref is the ref argument value.
+			Ref string
+			// Item is the item argument value.
+			Item feed.Item
+		}
+		// EditDigest holds details about calls to the EditDigest method.
+		EditDigest []struct {
+			// Ref is the ref argument value.
+			Ref string
+			// Items is the items argument value.
+			Items []feed.Item
+		}
 		// Send holds details about calls to the Send method.
 		Send []struct {
 			// ChanID is the chanID argument value.
@@ -37,12 +80,127 @@ type TelegramNotifMock struct {
 			// Item is the item argument value.
 			Item feed.Item
 		}
+		// SendDigest holds details about calls to the SendDigest method.
+		SendDigest []struct {
+			// ChanID is the chanID argument value.
+			ChanID string
+			// Items is the items argument value.
+			Items []feed.Item
+		}
 	}
-	lockSend sync.RWMutex
+	lockDelete     sync.RWMutex
+	lockEdit       sync.RWMutex
+	lockEditDigest sync.RWMutex
+	lockSend       sync.RWMutex
+	lockSendDigest sync.RWMutex
+}
+
+// Delete calls DeleteFunc.
+func (mock *TelegramNotifMock) Delete(ref string) error {
+	if mock.DeleteFunc == nil {
+		panic("TelegramNotifMock.DeleteFunc: method is nil but TelegramNotif.Delete was just called")
+	}
+	callInfo := struct {
+		Ref string
+	}{
+		Ref: ref,
+	}
+	mock.lockDelete.Lock()
+	mock.calls.Delete = append(mock.calls.Delete, callInfo)
+	mock.lockDelete.Unlock()
+	return mock.DeleteFunc(ref)
+}
+
+// DeleteCalls gets all the calls that were made to Delete.
+// Check the length with:
+//
+//	len(mockedTelegramNotif.DeleteCalls())
+func (mock *TelegramNotifMock) DeleteCalls() []struct {
+	Ref string
+} {
+	var calls []struct {
+		Ref string
+	}
+	mock.lockDelete.RLock()
+	calls = mock.calls.Delete
+	mock.lockDelete.RUnlock()
+	return calls
+}
+
+// Edit calls EditFunc.
+func (mock *TelegramNotifMock) Edit(ref string, item feed.Item) error {
+	if mock.EditFunc == nil {
+		panic("TelegramNotifMock.EditFunc: method is nil but TelegramNotif.Edit was just called")
+	}
+	callInfo := struct {
+		Ref  string
+		Item feed.Item
+	}{
+		Ref:  ref,
+		Item: item,
+	}
+	mock.lockEdit.Lock()
+	mock.calls.Edit = append(mock.calls.Edit, callInfo)
+	mock.lockEdit.Unlock()
+	return mock.EditFunc(ref, item)
+}
+
+// EditCalls gets all the calls that were made to Edit.
+// Check the length with:
+//
+//	len(mockedTelegramNotif.EditCalls())
+func (mock *TelegramNotifMock) EditCalls() []struct {
+	Ref  string
+	Item feed.Item
+} {
+	var calls []struct {
+		Ref  string
+		Item feed.Item
+	}
+	mock.lockEdit.RLock()
+	calls = mock.calls.Edit
+	mock.lockEdit.RUnlock()
+	return calls
+}
+
+// EditDigest calls EditDigestFunc.
+func (mock *TelegramNotifMock) EditDigest(ref string, items []feed.Item) error {
+	if mock.EditDigestFunc == nil {
+		panic("TelegramNotifMock.EditDigestFunc: method is nil but TelegramNotif.EditDigest was just called")
+	}
+	callInfo := struct {
+		Ref   string
+		Items []feed.Item
+	}{
+		Ref:   ref,
+		Items: items,
+	}
+	mock.lockEditDigest.Lock()
+	mock.calls.EditDigest = append(mock.calls.EditDigest, callInfo)
+	mock.lockEditDigest.Unlock()
+	return mock.EditDigestFunc(ref, items)
+}
+
+// EditDigestCalls gets all the calls that were made to EditDigest.
+// Check the length with:
+//
+//	len(mockedTelegramNotif.EditDigestCalls())
+func (mock *TelegramNotifMock) EditDigestCalls() []struct {
+	Ref   string
+	Items []feed.Item
+} {
+	var calls []struct {
+		Ref   string
+		Items []feed.Item
+	}
+	mock.lockEditDigest.RLock()
+	calls = mock.calls.EditDigest
+	mock.lockEditDigest.RUnlock()
+	return calls
 }
 
 // Send calls SendFunc.
-func (mock *TelegramNotifMock) Send(chanID string, item feed.Item) error {
+func (mock *TelegramNotifMock) Send(chanID string, item feed.Item) (string, error) {
 	if mock.SendFunc == nil {
 		panic("TelegramNotifMock.SendFunc: method is nil but TelegramNotif.Send was just called")
 	}
@@ -61,7 +219,8 @@ func (mock *TelegramNotifMock) Send(chanID string, item feed.Item) error {
 
 // SendCalls gets all the calls that were made to Send.
 // Check the length with:
-//     len(mockedTelegramNotif.SendCalls())
+//
+//	len(mockedTelegramNotif.SendCalls())
 func (mock *TelegramNotifMock) SendCalls() []struct {
 	ChanID string
 	Item   feed.Item
@@ -75,3 +234,39 @@ func (mock *TelegramNotifMock) SendCalls() []struct {
 	mock.lockSend.RUnlock()
 	return calls
 }
+
+// SendDigest calls SendDigestFunc.
+func (mock *TelegramNotifMock) SendDigest(chanID string, items []feed.Item) ([]string, error) {
+	if mock.SendDigestFunc == nil {
+		panic("TelegramNotifMock.SendDigestFunc: method is nil but TelegramNotif.SendDigest was just called")
+	}
+	callInfo := struct {
+		ChanID string
+		Items  []feed.Item
+	}{
+		ChanID: chanID,
+		Items:  items,
+	}
+	mock.lockSendDigest.Lock()
+	mock.calls.SendDigest = append(mock.calls.SendDigest, callInfo)
+	mock.lockSendDigest.Unlock()
+	return mock.SendDigestFunc(chanID, items)
+}
+
+// SendDigestCalls gets all the calls that were made to SendDigest.
+// Check the length with:
+//
+//	len(mockedTelegramNotif.SendDigestCalls())
+func (mock *TelegramNotifMock) SendDigestCalls() []struct {
+	ChanID string
+	Items  []feed.Item
+} {
+	var calls []struct {
+		ChanID string
+		Items  []feed.Item
+	}
+	mock.lockSendDigest.RLock()
+	calls = mock.calls.SendDigest
+	mock.lockSendDigest.RUnlock()
+	return calls
+}
@@ -0,0 +1,107 @@
+package proc
+
+import (
+	"time"
+
+	log "github.com/go-pkgz/lgr"
+
+	"github.com/umputun/feed-master/app/config"
+	"github.com/umputun/feed-master/app/feed"
+)
+
+// preview actions
+const (
+	PreviewSave   = "save"   // new item, will be saved and notified
+	PreviewJunk   = "junk"   // new item filtered out, will be saved as junk
+	PreviewExists = "exists" // already saved, may be updated
+	PreviewSkip   = "skip"   // ignored, too old or over max items per source
+)
+
+// PreviewSource is the result of the source processing preview
+type PreviewSource struct {
+	Name  string        `json:"name"`
+	URL   string        `json:"url"`
+	Error string        `json:"error,omitempty"`
+	Items []PreviewItem `json:"items"`
+}
+
+// PreviewItem describes what would be done with the source's item
+type PreviewItem struct {
+	GUID   string    `json:"guid"`
+	Title  string    `json:"title"`
+	Link   string    `json:"link"`
+	DT     time.Time `json:"dt"`
+	Action string    `json:"action"`
+	Reason string    `json:"reason,omitempty"`
+	Notify []string  `json:"notify,omitempty"` // destinations to be notified, i.e. "telegram:@chan"
+}
+
+// Preview fetches all sources of the feed and reports what would be done with their items,
+// applying the feed's filter and limits the same way as the regular processing does.
+// Nothing is saved and no notifications are sent.
+func (p *Processor) Preview(name string, fm config.Feed) []PreviewSource {
+	res := make([]PreviewSource, 0, len(fm.Sources))
+	for _, src := range fm.Sources {
+		ps := PreviewSource{Name: src.Name, URL: src.URL, Items: []PreviewItem{}}
+		rss, err := feed.Parse(src.URL)
+		if err != nil {
+			ps.Error = err.Error()
+			res = append(res, ps)
+			continue
+		}
+		for i, item := range rss.ItemList {
+			ps.Items = append(ps.Items, p.previewItem(name, fm, i, item))
+		}
+		res = append(res, ps)
+	}
+	return res
+}
+
+// previewItem decides what processFeed would do with the item at position idx of the source
+func (p *Processor) previewItem(name string, fm config.Feed, idx int, item feed.Item) PreviewItem {
+	res := PreviewItem{GUID: item.GUID, Title: item.Title, Link: item.Link, DT: item.DT}
+	conf := p.conf()
+
+	check := checkItem(idx, conf.System.MaxItems, fm.Filter, item)
+	if check.skip != "" {
+		res.Action, res.Reason = PreviewSkip, check.skip
+		return res
+	}
+	if check.err != nil {
+		res.Reason = "filter failed, " + check.err.Error()
+	}
+
+	if p.Store != nil {
+		exists, e := p.Store.Exists(name, item)
+		if e != nil {
+			log.Printf("[WARN] can't check %s (%s) in %s, %v", item.GUID, item.PubDate, name, e)
+			res.Action, res.Reason = PreviewSkip, "can't be saved, "+e.Error()
+			return res
+		}
+		if exists {
+			res.Action = PreviewExists
+			if check.junk {
+				res.Reason = "filtered"
+			}
+			return res
+		}
+	}
+
+	if check.junk {
+		res.Action, res.Reason = PreviewJunk, "filtered"
+		return res
+	}
+
+	res.Action = PreviewSave
+	if fm.TelegramChannel != "" {
+		dest := "telegram:" + fm.TelegramChannel
+		if _, limited := conf.Notify[fm.TelegramChannel]; limited {
+			dest += " (queued)"
+		}
+		res.Notify = append(res.Notify, dest)
+	}
+	if tc, ok := p.TwitterNotif.(*TwitterClient); ok && tc.enabled() {
+		res.Notify = append(res.Notify, "twitter")
+	}
+	return res
+}
@@ -0,0 +1,85 @@
+package proc
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/umputun/feed-master/app/config"
+	"github.com/umputun/feed-master/app/feed"
+	"github.com/umputun/feed-master/app/proc/mocks"
+)
+
+func TestProcessor_Preview(t *testing.T) {
+	now := time.Now()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/bad" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		item := func(guid, title string, dt time.Time) string {
+			return fmt.Sprintf("<item><guid>%s</guid><title>%s</title><pubDate>%s</pubDate></item>",
+				guid, title, dt.Format(time.RFC1123Z))
+		}
+		_, _ = fmt.Fprintf(w, `<rss version="2.0"><channel><title>test</title>%s%s%s%s%s</channel></rss>`,
+			item("g1", "new one", now), item("g2", "some junk", now.Add(-time.Hour)),
+			item("g3", "saved one", now.Add(-2*time.Hour)), item("g4", "old one", now.AddDate(-2, 0, 0)),
+			item("g5", "too many", now.Add(-3*time.Hour)))
+	}))
+	defer ts.Close()
+
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "test.db"), 0o600, &bolt.Options{Timeout: 1 * time.Second})
+	require.NoError(t, err)
+	store := &BoltDB{DB: db}
+	_, err = store.Save("feed1", feed.Item{GUID: "g3", Title: "saved one", PubDate: now.Add(-2 * time.Hour).Format(time.RFC1123Z)})
+	require.NoError(t, err)
+
+	tgNotif := &mocks.TelegramNotifMock{}
+	conf := &config.Conf{Notify: map[string]config.NotifyLimits{"chan": {MaxPerHour: 1}}}
+	conf.System.MaxItems = 4
+	p := Processor{Conf: conf, Store: store, TelegramNotif: tgNotif, TwitterNotif: &TwitterClient{}}
+
+	fm := config.Feed{
+		TelegramChannel: "chan",
+		Filter:          config.Filter{Title: "junk"},
+		Sources:         []config.Source{{Name: "src1", URL: ts.URL}, {Name: "src2", URL: ts.URL + "/bad"}},
+	}
+	res := p.Preview("feed1", fm)
+	require.Equal(t, 2, len(res))
+
+	assert.Equal(t, "src1", res[0].Name)
+	assert.Empty(t, res[0].Error)
+	require.Equal(t, 5, len(res[0].Items))
+	exp := []struct{ guid, action, reason string }{
+		{"g1", PreviewSave, ""},
+		{"g2", PreviewJunk, "filtered"},
+		{"g3", PreviewExists, ""},
+		{"g4", PreviewSkip, "older than 1 year"},
+		{"g5", PreviewSkip, "over max items per source"},
+	}
+	for i, e := range exp {
+		assert.Equal(t, e.guid, res[0].Items[i].GUID)
+		assert.Equal(t, e.action, res[0].Items[i].Action, e.guid)
+		assert.Equal(t, e.reason, res[0].Items[i].Reason, e.guid)
+	}
+	assert.Equal(t, []string{"telegram:chan (queued)"}, res[0].Items[0].Notify, "twitter disabled without credentials")
+
+	assert.Equal(t, "src2", res[1].Name)
+	assert.NotEmpty(t, res[1].Error)
+
+	// nothing saved or sent
+	items, err := store.Load("feed1", 10, false)
+	require.NoError(t, err)
+	assert.Equal(t, 1, len(items))
+	assert.Equal(t, 0, len(tgNotif.SendCalls()))
+	list, err := store.ListQueued("chan")
+	require.NoError(t, err)
+	assert.Empty(t, list)
+}
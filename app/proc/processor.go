@@ -4,6 +4,9 @@ package proc
 
 import (
 	"context"
+	"math"
+	"sort"
+	"sync"
 	"time"
 
 	log "github.com/go-pkgz/lgr"
@@ -12,6 +15,14 @@ import (
 
 	"github.com/umputun/feed-master/app/config"
 	"github.com/umputun/feed-master/app/feed"
+	"github.com/umputun/feed-master/app/metrics"
+)
+
+var (
+	itemsTotal = metrics.NewCounter("feed_master_items_total",
+		"New items by feed and result, saved or junk", "feed", "result")
+	notificationsTotal = metrics.NewCounter("feed_master_notifications_total",
+		"Notifications by notifier and result, sent or failed", "notifier", "result")
 )
 
 //go:generate moq -out mocks/telegram_notif.go -pkg mocks -skip-ensure -fmt goimports . TelegramNotif
@@ -19,7 +30,17 @@ import (
 
 // TelegramNotif is interface to send messages to telegram
 type TelegramNotif interface {
-	Send(chanID string, item feed.Item) error
+	Send(chanID string, item feed.Item) (ref string, err error)
+	SendDigest(chanID string, items []feed.Item) (refs []string, err error)
+	EditDigest(ref string, items []feed.Item) error
+	EditableNotif
+}
+
+// EditableNotif is interface for notifiers able to change or remove already published messages.
+// The ref is notifier-specific reference returned on sending
+type EditableNotif interface {
+	Edit(ref string, item feed.Item) error
+	Delete(ref string) error
 }
 
 // TwitterNotif is interface to send message to twitter
@@ -33,11 +54,30 @@ type Processor struct {
 	Store         *BoltDB
 	TelegramNotif TelegramNotif
 	TwitterNotif  TwitterNotif
+
+	once      sync.Once
+	refreshCh chan struct{}
+
+	lock    sync.Mutex
+	sources map[string]SourceStatus // key is feed name + source url
+	sweep   time.Time               // completion time of the last full sweep of all feeds
+}
+
+// SourceStatus describes the result of the last attempts to get source's items
+type SourceStatus struct {
+	Feed        string    `json:"feed"`
+	Name        string    `json:"name"`
+	URL         string    `json:"url"`
+	LastAttempt time.Time `json:"last_attempt"`
+	LastSuccess time.Time `json:"last_success"`
+	Error       string    `json:"error,omitempty"`
+	Items       int       `json:"items"`
 }
 
 // Do activate loop of goroutine for each feed, concurrency limited by p.Conf.Concurrent
 func (p *Processor) Do(ctx context.Context) error {
-	log.Printf("[INFO] activate processor, feeds=%d, %+v", len(p.Conf.Feeds), p.Conf)
+	conf := p.conf()
+	log.Printf("[INFO] activate processor, feeds=%d, %+v", len(conf.Feeds), conf)
 
 	for {
 		select {
@@ -46,49 +86,145 @@ func (p *Processor) Do(ctx context.Context) error {
 		default:
 			p.processFeeds(ctx)
 		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(p.conf().System.UpdateInterval):
+		case <-p.refresh():
+			log.Printf("[INFO] refresh requested")
+		}
+	}
+}
+
+// SetConf replaces configuration, used on config reload. Applied starting from the next update,
+// statuses of removed sources are dropped
+func (p *Processor) SetConf(conf *config.Conf) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.Conf = conf
+
+	active := map[string]bool{}
+	for name, fm := range conf.Feeds {
+		for _, src := range fm.Sources {
+			active[name+src.URL] = true
+		}
+	}
+	for k := range p.sources {
+		if !active[k] {
+			delete(p.sources, k)
+		}
+	}
+}
+
+// Refresh requests immediate processing of all feeds, doesn't wait for it
+func (p *Processor) Refresh() {
+	select {
+	case p.refresh() <- struct{}{}:
+	default: // refresh already requested
+	}
+}
+
+// Status returns statuses of all sources processed so far, sorted by feed and source names
+func (p *Processor) Status() []SourceStatus {
+	p.lock.Lock()
+	res := make([]SourceStatus, 0, len(p.sources))
+	for _, st := range p.sources {
+		res = append(res, st)
+	}
+	p.lock.Unlock()
+
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Feed != res[j].Feed {
+			return res[i].Feed < res[j].Feed
+		}
+		return res[i].Name < res[j].Name
+	})
+	return res
+}
+
+// LastSweep returns completion time of the last full sweep of all feeds, zero if not completed yet
+func (p *Processor) LastSweep() time.Time {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.sweep
+}
+
+func (p *Processor) conf() *config.Conf {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.Conf
+}
+
+func (p *Processor) refresh() chan struct{} {
+	p.once.Do(func() { p.refreshCh = make(chan struct{}, 1) })
+	return p.refreshCh
+}
+
+// setStatus records the result of the attempt to get source's items
+func (p *Processor) setStatus(name string, src config.Source, items int, err error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.sources == nil {
+		p.sources = map[string]SourceStatus{}
 	}
+
+	st := p.sources[name+src.URL]
+	st.Feed, st.Name, st.URL = name, src.Name, src.URL
+	st.LastAttempt = time.Now()
+	st.Error = ""
+	if err != nil {
+		st.Error = err.Error()
+		p.sources[name+src.URL] = st
+		return
+	}
+	st.LastSuccess = st.LastAttempt
+	st.Items = items
+	p.sources[name+src.URL] = st
 }
 
 func (p *Processor) processFeeds(ctx context.Context) {
 	log.Printf("[DEBUG] refresh started")
-	swg := syncs.NewSizedGroup(p.Conf.System.Concurrent, syncs.Preemptive, syncs.Context(ctx))
-	for name, fm := range p.Conf.Feeds {
+	conf := p.conf()
+	swg := syncs.NewSizedGroup(conf.System.Concurrent, syncs.Preemptive, syncs.Context(ctx))
+	for name, fm := range conf.Feeds {
 		for _, src := range fm.Sources {
 			name, src, fm := name, src, fm
 			swg.Go(func(context.Context) {
-				p.processFeed(name, src.URL, fm.TelegramChannel, p.Conf.System.MaxItems, fm.Filter)
+				p.processFeed(name, src, fm.TelegramChannel, conf.System.MaxItems, fm.Filter)
 			})
 		}
 	}
 	swg.Wait()
+	p.deliverQueued(time.Now())
+	if ctx.Err() == nil {
+		p.lock.Lock()
+		p.sweep = time.Now()
+		p.lock.Unlock()
+	}
 	log.Printf("[DEBUG] refresh completed")
-	time.Sleep(p.Conf.System.UpdateInterval)
 }
 
-func (p *Processor) processFeed(name, url, telegramChannel string, max int, filter config.Filter) {
+func (p *Processor) processFeed(name string, src config.Source, telegramChannel string, max int, filter config.Filter) {
+	url := src.URL
+	conf := p.conf()
 	rss, err := feed.Parse(url)
+	p.setStatus(name, src, len(rss.ItemList), err)
 	if err != nil {
 		log.Printf("[WARN] failed to parse %s, %v", url, err)
 		return
 	}
 
-	// up to MaxItems (5) items from each feed
-	upto := max
-	if len(rss.ItemList) <= max {
-		upto = len(rss.ItemList)
-	}
-
-	for _, item := range rss.ItemList[:upto] {
-		// skip 1y and older
-		if item.DT.Before(time.Now().AddDate(-1, 0, 0)) {
+	for i, item := range rss.ItemList {
+		item.Source = src.Name
+		check := checkItem(i, max, filter, item)
+		if check.skip != "" {
 			continue
 		}
-
-		skip, err := filter.Skip(item)
-		if err != nil {
-			log.Printf("[WARN] failed to filter %s (%s) to %s, save as is, %v", item.GUID, item.PubDate, name, err)
+		if check.err != nil {
+			log.Printf("[WARN] failed to filter %s (%s) to %s, save as is, %v", item.GUID, item.PubDate, name, check.err)
 		}
-		if skip {
+		if check.junk {
 			item.Junk = true
 			log.Printf("[INFO] filtered %s (%s), %s %s", item.GUID, item.PubDate, name, item.Title)
 		}
@@ -98,24 +234,28 @@ func (p *Processor) processFeed(name, url, telegramChannel string, max int, filt
 			log.Printf("[WARN] failed to save %s (%s) to %s, %v", item.GUID, item.PubDate, name, err)
 		}
 
-		// don't attempt to send anything if the entry was already saved
-		// or in case it was filtered out
-		if !created || item.Junk {
+		// don't attempt to send anything if the entry was already saved, update published messages instead.
+		// in case it was filtered out, nothing to send as well
+		if err == nil && !created {
+			p.updateItem(name, item)
+			continue
+		}
+		if !created {
+			continue
+		}
+		if item.Junk {
+			itemsTotal.Inc(name, "junk")
 			continue
 		}
+		itemsTotal.Inc(name, "saved")
 
-		rptr := repeater.NewDefault(3, 5*time.Second)
-		err = rptr.Do(context.Background(), func() error {
-			if e := p.TelegramNotif.Send(telegramChannel, item); e != nil {
-				log.Printf("[WARN] failed attempt to send telegram message, url=%s to channel=%s, %v",
-					item.Enclosure.URL, telegramChannel, e)
-				return err
+		if _, limited := conf.Notify[telegramChannel]; limited && telegramChannel != "" {
+			q := Queued{Feed: name, Source: url, ChanID: telegramChannel, Item: item, TS: time.Now()}
+			if e := p.Store.Enqueue(q); e != nil {
+				log.Printf("[WARN] failed to queue telegram message, url=%s to channel=%s, %v", item.Enclosure.URL, telegramChannel, e)
 			}
-			return nil
-		})
-		if err != nil {
-			log.Printf("[WARN] failed to send telegram message, url=%s to channel=%s, %v",
-				item.Enclosure.URL, telegramChannel, err)
+		} else {
+			_ = p.sendTelegram(name, url, telegramChannel, item)
 		}
 
 		if err := p.TwitterNotif.Send(item); err != nil {
@@ -123,8 +263,15 @@ func (p *Processor) processFeed(name, url, telegramChannel string, max int, filt
 		}
 	}
 
+	if rss.Partial {
+		log.Printf("[WARN] some items of %s are hidden by the server, removed items are not detected. "+
+			"Source of private feed should have credentials", url)
+	} else {
+		p.removeDeleted(name, url, rss.ItemList)
+	}
+
 	// keep up to MaxKeepInDB items in bucket
-	if removed, err := p.Store.removeOld(name, p.Conf.System.MaxKeepInDB); err == nil {
+	if removed, err := p.Store.removeOld(name, conf.System.MaxKeepInDB); err == nil {
 		if removed > 0 {
 			log.Printf("[DEBUG] removed %d from %s", removed, name)
 		}
@@ -132,3 +279,359 @@ func (p *Processor) processFeed(name, url, telegramChannel string, max int, filt
 		log.Printf("[WARN] failed to remove, %v", err)
 	}
 }
+
+// itemCheck is the decision made on the source's item before saving it
+type itemCheck struct {
+	skip string // reason to ignore the item, empty if the item is saved
+	junk bool   // filtered out, saved as junk
+	err  error  // filter failed, saved as is
+}
+
+// checkItem decides what to do with the item at position idx of the source. Only up to max items (5 by default)
+// from each source are used, items of 1y and older are skipped, items matched by the filter are junk.
+func checkItem(idx, max int, filter config.Filter, item feed.Item) itemCheck {
+	if idx >= max {
+		return itemCheck{skip: "over max items per source"}
+	}
+	if item.DT.Before(time.Now().AddDate(-1, 0, 0)) {
+		return itemCheck{skip: "older than 1 year"}
+	}
+	junk, err := filter.Skip(item)
+	return itemCheck{junk: junk, err: err}
+}
+
+// sendTelegram sends item to telegram channel with retries and saves reference to the published message
+func (p *Processor) sendTelegram(name, url, telegramChannel string, item feed.Item) error {
+	var ref string
+	rptr := repeater.NewDefault(3, 5*time.Second)
+	err := rptr.Do(context.Background(), func() error {
+		var e error
+		if ref, e = p.TelegramNotif.Send(telegramChannel, item); e != nil {
+			log.Printf("[WARN] failed attempt to send telegram message, url=%s to channel=%s, %v",
+				item.Enclosure.URL, telegramChannel, e)
+			return e
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("[WARN] failed to send telegram message, url=%s to channel=%s, %v",
+			item.Enclosure.URL, telegramChannel, err)
+		return err
+	}
+	p.markSent(telegramChannel, time.Now())
+	if ref != "" {
+		refs := []NotifRef{{Notifier: telegramNotifier, ChanID: telegramChannel, Ref: ref}}
+		if e := p.Store.SaveNotified(name, Notified{Item: item, Source: url, Refs: refs}); e != nil {
+			log.Printf("[WARN] failed to save published message reference for %s, %v", item.GUID, e)
+		}
+	}
+	return nil
+}
+
+// deliverQueued sends deferred notifications to telegram channels out of quiet hours and within rate limits.
+// With digest enabled, all deferred notifications of the channel are collapsed into a single message.
+func (p *Processor) deliverQueued(now time.Time) {
+	for chanID, limits := range p.conf().Notify {
+		quiet, err := limits.Quiet(now)
+		if err != nil {
+			log.Printf("[WARN] can't check quiet hours for %s, ignored, %v", chanID, err)
+		}
+		if quiet {
+			continue
+		}
+
+		queued, err := p.Store.ListQueued(chanID)
+		if err != nil {
+			log.Printf("[WARN] failed to list queued messages for %s, %v", chanID, err)
+			continue
+		}
+		if len(queued) == 0 {
+			continue
+		}
+
+		allowed := len(queued)
+		if limits.MaxPerHour > 0 {
+			allowed = limits.MaxPerHour - p.sentSince(chanID, now.Add(-time.Hour))
+		}
+		if allowed <= 0 {
+			log.Printf("[DEBUG] rate limit reached for %s, %d messages deferred", chanID, len(queued))
+			continue
+		}
+
+		if limits.Digest && len(queued) > 1 {
+			items := make([]feed.Item, 0, len(queued))
+			for _, q := range queued {
+				items = append(items, q.Item)
+			}
+			refs, err := p.TelegramNotif.SendDigest(chanID, items)
+			sent, messages := min(len(refs), len(items)), 0
+			for start := 0; start < sent; messages++ { // items of each message have the same ref
+				end := start + 1
+				for end < sent && refs[end] == refs[start] {
+					end++
+				}
+				p.markSent(chanID, now)
+				if refs[start] != "" {
+					p.saveDigest(Digest{Ref: refs[start], ChanID: chanID, Items: items[start:end], TS: now}, queued[start:end])
+				}
+				start = end
+			}
+			if err != nil {
+				log.Printf("[WARN] failed to send digest of %d items to %s, %v", len(items)-sent, chanID, err)
+				queued = queued[:sent] // keep not sent ones queued, try again on the next update
+			} else {
+				log.Printf("[INFO] digest of %d items sent to %s in %d messages", len(items), chanID, messages)
+			}
+			if len(queued) == 0 {
+				continue
+			}
+			if err := p.Store.Dequeue(queued...); err != nil {
+				log.Printf("[WARN] failed to remove queued messages for %s, %v", chanID, err)
+			}
+			continue
+		}
+
+		if allowed < len(queued) {
+			queued = queued[:allowed]
+		}
+		for _, q := range queued {
+			if err := p.sendTelegram(q.Feed, q.Source, chanID, q.Item); err != nil {
+				break // keep it queued, try again on the next update
+			}
+			if err := p.Store.Dequeue(q); err != nil {
+				log.Printf("[WARN] failed to remove queued message %s for %s, %v", q.Item.GUID, chanID, err)
+			}
+		}
+	}
+}
+
+// saveDigest stores published digest and references to it for each of its items, so the digest can be edited
+// or deleted when one of the items changes
+func (p *Processor) saveDigest(d Digest, queued []Queued) {
+	if err := p.Store.SaveDigest(d); err != nil {
+		log.Printf("[WARN] failed to save digest %s for %s, %v", d.Ref, d.ChanID, err)
+		return
+	}
+	for _, q := range queued {
+		refs := []NotifRef{{Notifier: digestNotifier, ChanID: d.ChanID, Ref: d.Ref}}
+		if err := p.Store.SaveNotified(q.Feed, Notified{Item: q.Item, Source: q.Source, Refs: refs}); err != nil {
+			log.Printf("[WARN] failed to save digest reference for %s, %v", q.Item.GUID, err)
+		}
+	}
+}
+
+// markSent records sending time for the channel in the store
+func (p *Processor) markSent(chanID string, ts time.Time) {
+	if err := p.Store.MarkSent(chanID, ts); err != nil {
+		log.Printf("[WARN] failed to record sending time for %s, %v", chanID, err)
+	}
+}
+
+// sentSince returns number of messages sent to the channel after the given time. Messages are counted as sent
+// if the store fails, so rate limit is not exceeded.
+func (p *Processor) sentSince(chanID string, ts time.Time) int {
+	count, err := p.Store.SentSince(chanID, ts)
+	if err != nil {
+		log.Printf("[WARN] failed to count messages sent to %s, %v", chanID, err)
+		return math.MaxInt
+	}
+	return count
+}
+
+// updateItem updates stored item and edits or deletes messages published for it,
+// if the item's title or description changed or if it got filtered out
+func (p *Processor) updateItem(name string, item feed.Item) {
+	prev, changed, err := p.Store.Update(name, item)
+	if err != nil {
+		log.Printf("[WARN] failed to update %s (%s) in %s, %v", item.GUID, item.PubDate, name, err)
+		return
+	}
+	if !changed {
+		return
+	}
+	p.updateQueued(name, item)
+
+	n, found, err := p.Store.LoadNotified(name, item)
+	if err != nil {
+		log.Printf("[WARN] failed to load published messages for %s, %v", item.GUID, err)
+		return
+	}
+	if !found {
+		return
+	}
+
+	if item.Junk && !prev.Junk {
+		log.Printf("[INFO] item %s (%s) in %s filtered out, remove published messages", item.GUID, item.Title, name)
+		p.deleteNotified(name, n)
+		return
+	}
+
+	log.Printf("[INFO] item %s in %s changed, %q -> %q, update published messages", item.GUID, name, prev.Title, item.Title)
+	for _, ref := range n.Refs {
+		if ref.Notifier == digestNotifier {
+			p.editDigest(ref, item, false)
+			continue
+		}
+		notif := p.editableNotif(ref.Notifier)
+		if notif == nil {
+			continue
+		}
+		if e := notif.Edit(ref.Ref, item); e != nil {
+			log.Printf("[WARN] failed to edit %s message %s in %s, %v", ref.Notifier, ref.Ref, ref.ChanID, e)
+		}
+	}
+	n.Item = item
+	if e := p.Store.SaveNotified(name, n); e != nil {
+		log.Printf("[WARN] failed to save published message reference for %s, %v", item.GUID, e)
+	}
+}
+
+// removeDeleted deletes messages published for the items removed from the source.
+// Only items within the time span of the current source's items are checked, as older ones are not expected to be there.
+func (p *Processor) removeDeleted(name, url string, items []feed.Item) {
+	if len(items) == 0 {
+		return // nothing to compare with, most likely broken source
+	}
+
+	var oldest time.Time
+	guids := make(map[string]bool, len(items))
+	for _, item := range items {
+		guids[item.GUID] = true
+		if !item.DT.IsZero() && (oldest.IsZero() || item.DT.Before(oldest)) {
+			oldest = item.DT
+		}
+	}
+	if oldest.IsZero() {
+		return // no items with valid timestamps, can't tell which items were removed
+	}
+
+	queued, err := p.Store.ListQueuedFeed(name)
+	if err != nil {
+		log.Printf("[WARN] failed to list queued messages for %s, %v", name, err)
+	}
+	for _, q := range queued {
+		if q.Source != url || guids[q.Item.GUID] || q.Item.DT.Before(oldest) {
+			continue
+		}
+		log.Printf("[INFO] item %s (%s) removed from %s, drop queued message", q.Item.GUID, q.Item.Title, url)
+		p.markRemoved(name, q.Item)
+		if e := p.Store.Dequeue(q); e != nil {
+			log.Printf("[WARN] failed to remove queued message %s for %s, %v", q.Item.GUID, q.ChanID, e)
+		}
+	}
+
+	notified, err := p.Store.ListNotified(name, url)
+	if err != nil {
+		log.Printf("[WARN] failed to list published messages for %s, %v", name, err)
+		return
+	}
+	for _, n := range notified {
+		if guids[n.Item.GUID] || n.Item.DT.Before(oldest) {
+			continue
+		}
+		log.Printf("[INFO] item %s (%s) removed from %s, remove published messages", n.Item.GUID, n.Item.Title, url)
+		p.markRemoved(name, n.Item)
+		p.deleteNotified(name, n)
+	}
+}
+
+// markRemoved marks item removed from the source as junk
+func (p *Processor) markRemoved(name string, item feed.Item) {
+	item.Junk = true
+	if _, _, e := p.Store.Update(name, item); e != nil {
+		log.Printf("[WARN] failed to mark removed %s (%s) as junk in %s, %v", item.GUID, item.PubDate, name, e)
+	}
+}
+
+// updateQueued replaces the item in its deferred notifications, or drops them if the item got filtered out
+func (p *Processor) updateQueued(name string, item feed.Item) {
+	queued, err := p.Store.ListQueuedFeed(name)
+	if err != nil {
+		log.Printf("[WARN] failed to list queued messages for %s, %v", name, err)
+		return
+	}
+	for _, q := range queued {
+		if q.Item.GUID != item.GUID {
+			continue
+		}
+		if item.Junk {
+			log.Printf("[INFO] item %s (%s) in %s filtered out, drop queued message", item.GUID, item.Title, name)
+			if e := p.Store.Dequeue(q); e != nil {
+				log.Printf("[WARN] failed to remove queued message %s for %s, %v", item.GUID, q.ChanID, e)
+			}
+			continue
+		}
+		q.Item = item
+		if e := p.Store.UpdateQueued(q); e != nil {
+			log.Printf("[WARN] failed to update queued message %s for %s, %v", item.GUID, q.ChanID, e)
+		}
+	}
+}
+
+// editDigest replaces the item in published digest, or removes it from there. The digest message is deleted
+// if no items left.
+func (p *Processor) editDigest(ref NotifRef, item feed.Item, remove bool) {
+	if p.TelegramNotif == nil {
+		return
+	}
+	d, found, err := p.Store.LoadDigest(ref.Ref)
+	if err != nil || !found {
+		log.Printf("[DEBUG] digest %s for %s not available, %v", ref.Ref, item.GUID, err)
+		return
+	}
+
+	items := make([]feed.Item, 0, len(d.Items))
+	for _, it := range d.Items {
+		switch {
+		case it.GUID != item.GUID:
+			items = append(items, it)
+		case !remove:
+			items = append(items, item)
+		}
+	}
+	d.Items = items
+
+	if len(items) == 0 {
+		if e := p.TelegramNotif.Delete(ref.Ref); e != nil {
+			log.Printf("[WARN] failed to delete digest %s in %s, %v", ref.Ref, ref.ChanID, e)
+		}
+		if e := p.Store.RemoveDigest(ref.Ref); e != nil {
+			log.Printf("[WARN] failed to remove digest %s, %v", ref.Ref, e)
+		}
+		return
+	}
+	if e := p.TelegramNotif.EditDigest(ref.Ref, items); e != nil {
+		log.Printf("[WARN] failed to edit digest %s in %s, %v", ref.Ref, ref.ChanID, e)
+	}
+	if e := p.Store.SaveDigest(d); e != nil {
+		log.Printf("[WARN] failed to save digest %s, %v", ref.Ref, e)
+	}
+}
+
+// deleteNotified deletes all messages published for the item and the references to them
+func (p *Processor) deleteNotified(name string, n Notified) {
+	for _, ref := range n.Refs {
+		if ref.Notifier == digestNotifier {
+			p.editDigest(ref, n.Item, true)
+			continue
+		}
+		notif := p.editableNotif(ref.Notifier)
+		if notif == nil {
+			continue
+		}
+		if e := notif.Delete(ref.Ref); e != nil {
+			log.Printf("[WARN] failed to delete %s message %s in %s, %v", ref.Notifier, ref.Ref, ref.ChanID, e)
+		}
+	}
+	if e := p.Store.RemoveNotified(name, n.Item); e != nil {
+		log.Printf("[WARN] failed to remove published message reference for %s, %v", n.Item.GUID, e)
+	}
+}
+
+// editableNotif returns notifier able to change published messages by its name, nil if not supported
+func (p *Processor) editableNotif(notifier string) EditableNotif {
+	if notifier == telegramNotifier && p.TelegramNotif != nil {
+		return p.TelegramNotif
+	}
+	return nil
+}
@@ -2,11 +2,14 @@ package proc
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -18,13 +21,12 @@ import (
 	"github.com/umputun/feed-master/app/config"
 	"github.com/umputun/feed-master/app/feed"
 	"github.com/umputun/feed-master/app/proc/mocks"
-	"github.com/umputun/feed-master/app/youtube"
 )
 
 func TestProcessor_DoRemoveOldItems(t *testing.T) {
 	lgr.Setup(lgr.Debug)
-	tgNotif := &mocks.TelegramNotifMock{SendFunc: func(string, feed.Item) error {
-		return nil
+	tgNotif := &mocks.TelegramNotifMock{SendFunc: func(string, feed.Item) (string, error) {
+		return "", nil
 	}}
 
 	twitterNotif := &mocks.TwitterNotifMock{SendFunc: func(feed.Item) error {
@@ -66,12 +68,12 @@ func TestProcessor_DoRemoveOldItems(t *testing.T) {
 				},
 			},
 			System: struct {
-				UpdateInterval time.Duration `yaml:"update"`
-				MaxItems       int           `yaml:"max_per_feed"`
-				MaxTotal       int           `yaml:"max_total"`
-				MaxKeepInDB    int           `yaml:"max_keep"`
-				Concurrent     int           `yaml:"concurrent"`
-				BaseURL        string        `yaml:"base_url"`
+				UpdateInterval time.Duration `yaml:"update" json:"update"`
+				MaxItems       int           `yaml:"max_per_feed" json:"max_per_feed"`
+				MaxTotal       int           `yaml:"max_total" json:"max_total"`
+				MaxKeepInDB    int           `yaml:"max_keep" json:"max_keep"`
+				Concurrent     int           `yaml:"concurrent" json:"concurrent"`
+				BaseURL        string        `yaml:"base_url" json:"base_url"`
 			}{
 				UpdateInterval: time.Second / 2,
 				MaxItems:       5,
@@ -80,19 +82,6 @@ func TestProcessor_DoRemoveOldItems(t *testing.T) {
 				Concurrent:     1,
 				BaseURL:        "baseUrl",
 			},
-			YouTube: struct {
-				DlTemplate      string             `yaml:"dl_template"`
-				BaseChanURL     string             `yaml:"base_chan_url"`
-				BasePlaylistURL string             `yaml:"base_playlist_url"`
-				Channels        []youtube.FeedInfo `yaml:"channels"`
-				BaseURL         string             `yaml:"base_url"`
-				UpdateInterval  time.Duration      `yaml:"update"`
-				MaxItems        int                `yaml:"max_per_channel"`
-				FilesLocation   string             `yaml:"files_location"`
-				RSSLocation     string             `yaml:"rss_location"`
-				SkipShorts      time.Duration      `yaml:"skip_shorts"`
-				DisableUpdates  bool               `yaml:"disable_updates"`
-			}{},
 		},
 		Store:         boltStore,
 		TelegramNotif: tgNotif,
@@ -159,8 +148,8 @@ func TestProcessor_DoRemoveOldItems(t *testing.T) {
 
 func TestProcessor_DoLoadMaxItems(t *testing.T) {
 
-	tgNotif := &mocks.TelegramNotifMock{SendFunc: func(string, feed.Item) error {
-		return nil
+	tgNotif := &mocks.TelegramNotifMock{SendFunc: func(string, feed.Item) (string, error) {
+		return "", nil
 	}}
 
 	twitterNotif := &mocks.TwitterNotifMock{SendFunc: func(feed.Item) error {
@@ -202,12 +191,12 @@ func TestProcessor_DoLoadMaxItems(t *testing.T) {
 				},
 			},
 			System: struct {
-				UpdateInterval time.Duration `yaml:"update"`
-				MaxItems       int           `yaml:"max_per_feed"`
-				MaxTotal       int           `yaml:"max_total"`
-				MaxKeepInDB    int           `yaml:"max_keep"`
-				Concurrent     int           `yaml:"concurrent"`
-				BaseURL        string        `yaml:"base_url"`
+				UpdateInterval time.Duration `yaml:"update" json:"update"`
+				MaxItems       int           `yaml:"max_per_feed" json:"max_per_feed"`
+				MaxTotal       int           `yaml:"max_total" json:"max_total"`
+				MaxKeepInDB    int           `yaml:"max_keep" json:"max_keep"`
+				Concurrent     int           `yaml:"concurrent" json:"concurrent"`
+				BaseURL        string        `yaml:"base_url" json:"base_url"`
 			}{
 				UpdateInterval: time.Second / 2,
 				MaxItems:       3,
@@ -216,19 +205,6 @@ func TestProcessor_DoLoadMaxItems(t *testing.T) {
 				Concurrent:     1,
 				BaseURL:        "baseUrl",
 			},
-			YouTube: struct {
-				DlTemplate      string             `yaml:"dl_template"`
-				BaseChanURL     string             `yaml:"base_chan_url"`
-				BasePlaylistURL string             `yaml:"base_playlist_url"`
-				Channels        []youtube.FeedInfo `yaml:"channels"`
-				BaseURL         string             `yaml:"base_url"`
-				UpdateInterval  time.Duration      `yaml:"update"`
-				MaxItems        int                `yaml:"max_per_channel"`
-				FilesLocation   string             `yaml:"files_location"`
-				RSSLocation     string             `yaml:"rss_location"`
-				SkipShorts      time.Duration      `yaml:"skip_shorts"`
-				DisableUpdates  bool               `yaml:"disable_updates"`
-			}{},
 		},
 		Store:         boltStore,
 		TelegramNotif: tgNotif,
@@ -255,8 +231,8 @@ func TestProcessor_DoLoadMaxItems(t *testing.T) {
 
 func TestProcessor_DoSkipItems(t *testing.T) {
 
-	tgNotif := &mocks.TelegramNotifMock{SendFunc: func(string, feed.Item) error {
-		return nil
+	tgNotif := &mocks.TelegramNotifMock{SendFunc: func(string, feed.Item) (string, error) {
+		return "", nil
 	}}
 
 	twitterNotif := &mocks.TwitterNotifMock{SendFunc: func(feed.Item) error {
@@ -300,12 +276,12 @@ func TestProcessor_DoSkipItems(t *testing.T) {
 				},
 			},
 			System: struct {
-				UpdateInterval time.Duration `yaml:"update"`
-				MaxItems       int           `yaml:"max_per_feed"`
-				MaxTotal       int           `yaml:"max_total"`
-				MaxKeepInDB    int           `yaml:"max_keep"`
-				Concurrent     int           `yaml:"concurrent"`
-				BaseURL        string        `yaml:"base_url"`
+				UpdateInterval time.Duration `yaml:"update" json:"update"`
+				MaxItems       int           `yaml:"max_per_feed" json:"max_per_feed"`
+				MaxTotal       int           `yaml:"max_total" json:"max_total"`
+				MaxKeepInDB    int           `yaml:"max_keep" json:"max_keep"`
+				Concurrent     int           `yaml:"concurrent" json:"concurrent"`
+				BaseURL        string        `yaml:"base_url" json:"base_url"`
 			}{
 				UpdateInterval: time.Second / 2,
 				MaxItems:       10,
@@ -314,19 +290,6 @@ func TestProcessor_DoSkipItems(t *testing.T) {
 				Concurrent:     1,
 				BaseURL:        "baseUrl",
 			},
-			YouTube: struct {
-				DlTemplate      string             `yaml:"dl_template"`
-				BaseChanURL     string             `yaml:"base_chan_url"`
-				BasePlaylistURL string             `yaml:"base_playlist_url"`
-				Channels        []youtube.FeedInfo `yaml:"channels"`
-				BaseURL         string             `yaml:"base_url"`
-				UpdateInterval  time.Duration      `yaml:"update"`
-				MaxItems        int                `yaml:"max_per_channel"`
-				FilesLocation   string             `yaml:"files_location"`
-				RSSLocation     string             `yaml:"rss_location"`
-				SkipShorts      time.Duration      `yaml:"skip_shorts"`
-				DisableUpdates  bool               `yaml:"disable_updates"`
-			}{},
 		},
 		Store:         boltStore,
 		TelegramNotif: tgNotif,
@@ -356,3 +319,316 @@ func TestProcessor_DoSkipItems(t *testing.T) {
 	assert.Equal(t, "Радио-Т 798", twitterNotif.SendCalls()[0].Item.Title)
 	assert.Equal(t, "Радио-Т 797", twitterNotif.SendCalls()[1].Item.Title)
 }
+
+func TestProcessor_processFeedUpdatesPublished(t *testing.T) {
+	tgNotif := &mocks.TelegramNotifMock{
+		SendFunc: func(_ string, item feed.Item) (string, error) {
+			return "audio:1:" + item.GUID, nil
+		},
+		EditFunc:   func(string, feed.Item) error { return nil },
+		DeleteFunc: func(string) error { return nil },
+	}
+	twitterNotif := &mocks.TwitterNotifMock{SendFunc: func(feed.Item) error { return nil }}
+
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "test.db"), 0o600, &bolt.Options{Timeout: 1 * time.Second})
+	require.NoError(t, err)
+	boltStore := &BoltDB{DB: db}
+
+	now := time.Now()
+	rssItem := func(guid, title string, dt time.Time) string {
+		return fmt.Sprintf("<item><guid>%s</guid><title>%s</title><pubDate>%s</pubDate></item>",
+			guid, title, dt.Format(time.RFC1123Z))
+	}
+	var rssItems []string
+	hidden := ""
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if hidden != "" {
+			w.Header().Set(feed.HiddenEntriesHeader, hidden)
+		}
+		_, _ = fmt.Fprintf(w, `<rss version="2.0"><channel><title>test</title>%s</channel></rss>`, strings.Join(rssItems, ""))
+	}))
+	defer ts.Close()
+
+	p := Processor{Conf: &config.Conf{}, Store: boltStore, TelegramNotif: tgNotif, TwitterNotif: twitterNotif}
+	p.Conf.System.MaxKeepInDB = 100
+
+	rssItems = []string{rssItem("g3", "title3", now), rssItem("g2", "title2", now.Add(-time.Hour)),
+		rssItem("g1", "title1", now.Add(-2*time.Hour))}
+	p.processFeed("feed1", config.Source{Name: "src", URL: ts.URL}, "chan", 10, config.Filter{Title: "junk"})
+	require.Equal(t, 3, len(tgNotif.SendCalls()))
+	assert.Equal(t, 0, len(tgNotif.EditCalls()))
+	assert.Equal(t, 0, len(tgNotif.DeleteCalls()))
+
+	// title of g3 changed, g2 got filtered, g1 is not changed
+	rssItems = []string{rssItem("g3", "title3 fixed", now), rssItem("g2", "title2 junk", now.Add(-time.Hour)),
+		rssItem("g1", "title1", now.Add(-2*time.Hour))}
+	p.processFeed("feed1", config.Source{Name: "src", URL: ts.URL}, "chan", 10, config.Filter{Title: "junk"})
+	assert.Equal(t, 3, len(tgNotif.SendCalls()), "nothing new sent")
+	require.Equal(t, 1, len(tgNotif.EditCalls()))
+	assert.Equal(t, "audio:1:g3", tgNotif.EditCalls()[0].Ref)
+	assert.Equal(t, "title3 fixed", tgNotif.EditCalls()[0].Item.Title)
+	require.Equal(t, 1, len(tgNotif.DeleteCalls()))
+	assert.Equal(t, "audio:1:g2", tgNotif.DeleteCalls()[0].Ref)
+
+	// g3 removed from the source
+	rssItems = []string{rssItem("g4", "title4", now.Add(time.Minute)), rssItem("g1", "title1", now.Add(-2*time.Hour))}
+	p.processFeed("feed1", config.Source{Name: "src", URL: ts.URL}, "chan", 10, config.Filter{Title: "junk"})
+	assert.Equal(t, 4, len(tgNotif.SendCalls()), "g4 sent")
+	assert.Equal(t, 1, len(tgNotif.EditCalls()))
+	require.Equal(t, 2, len(tgNotif.DeleteCalls()))
+	assert.Equal(t, "audio:1:g3", tgNotif.DeleteCalls()[1].Ref)
+
+	items, err := boltStore.Load("feed1", 10, true)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(items), "g2 and g3 are junk")
+	assert.Equal(t, "title4", items[0].Title)
+	assert.Equal(t, "title1", items[1].Title)
+
+	// g1 within the source's time span, but hidden by the server, is not considered as removed
+	rssItems, hidden = []string{rssItem("g4", "title4", now.Add(time.Minute)), rssItem("g0", "title0", now.Add(-3*time.Hour))}, "1"
+	p.processFeed("feed1", config.Source{Name: "src", URL: ts.URL}, "chan", 10, config.Filter{Title: "junk"})
+	assert.Equal(t, 2, len(tgNotif.DeleteCalls()))
+	items, err = boltStore.Load("feed1", 10, true)
+	require.NoError(t, err)
+	assert.Equal(t, 3, len(items), "g1 kept, g0 added")
+	hidden = ""
+
+	// g1 is out of the source's time span and not considered as removed
+	rssItems = []string{rssItem("g4", "title4", now.Add(time.Minute))}
+	p.processFeed("feed1", config.Source{Name: "src", URL: ts.URL}, "chan", 10, config.Filter{Title: "junk"})
+	assert.Equal(t, 2, len(tgNotif.DeleteCalls()))
+
+	// empty source doesn't remove anything
+	rssItems = nil
+	p.processFeed("feed1", config.Source{Name: "src", URL: ts.URL}, "chan", 10, config.Filter{Title: "junk"})
+	assert.Equal(t, 2, len(tgNotif.DeleteCalls()))
+}
+
+func TestProcessor_deliverQueued(t *testing.T) {
+	tmpfile := filepath.Join(os.TempDir(), "test-queued.db")
+	defer os.Remove(tmpfile)
+	db, err := bolt.Open(tmpfile, 0o600, &bolt.Options{Timeout: 1 * time.Second}) // nolint
+	require.NoError(t, err)
+	defer db.Close()
+	store := &BoltDB{DB: db}
+
+	tgNotif := &mocks.TelegramNotifMock{
+		SendFunc: func(chanID string, item feed.Item) (string, error) {
+			return "audio:1:" + item.GUID, nil
+		},
+		SendDigestFunc: func(chanID string, items []feed.Item) ([]string, error) {
+			refs := []string{}
+			for range items {
+				refs = append(refs, "digest:1:100")
+			}
+			return refs, nil
+		},
+		EditDigestFunc: func(string, []feed.Item) error { return nil },
+		DeleteFunc:     func(string) error { return nil },
+	}
+	now := time.Now().UTC()
+	quietHours := now.Add(-time.Hour).Format("15:04") + "-" + now.Add(time.Hour).Format("15:04")
+	conf := &config.Conf{Notify: map[string]config.NotifyLimits{
+		"limited": {MaxPerHour: 2},
+		"quiet":   {QuietHours: quietHours, Timezone: "UTC"},
+		"digest":  {Digest: true},
+	}}
+	p := Processor{Conf: conf, Store: store, TelegramNotif: tgNotif}
+
+	for _, ch := range []string{"limited", "quiet", "digest"} {
+		for i := 1; i <= 3; i++ {
+			item := feed.Item{GUID: ch + strconv.Itoa(i), Title: "title " + strconv.Itoa(i), PubDate: pubDate}
+			require.NoError(t, store.Enqueue(Queued{Feed: "feed1", Source: "src", ChanID: ch, Item: item}))
+			_, err = store.Save("feed1", item)
+			require.NoError(t, err)
+		}
+	}
+
+	p.deliverQueued(now)
+
+	// two sent to limited channel, third one deferred by rate limit
+	require.Equal(t, 2, len(tgNotif.SendCalls()))
+	assert.Equal(t, "limited", tgNotif.SendCalls()[0].ChanID)
+	assert.Equal(t, "limited1", tgNotif.SendCalls()[0].Item.GUID)
+	assert.Equal(t, "limited2", tgNotif.SendCalls()[1].Item.GUID)
+	list, err := store.ListQueued("limited")
+	require.NoError(t, err)
+	require.Equal(t, 1, len(list))
+	assert.Equal(t, "limited3", list[0].Item.GUID)
+	n, found, err := store.LoadNotified("feed1", feed.Item{GUID: "limited1", PubDate: pubDate})
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "audio:1:limited1", n.Refs[0].Ref)
+
+	// nothing sent to quiet channel
+	list, err = store.ListQueued("quiet")
+	require.NoError(t, err)
+	assert.Equal(t, 3, len(list))
+
+	// all collapsed into a single digest
+	require.Equal(t, 1, len(tgNotif.SendDigestCalls()))
+	assert.Equal(t, "digest", tgNotif.SendDigestCalls()[0].ChanID)
+	assert.Equal(t, 3, len(tgNotif.SendDigestCalls()[0].Items))
+	list, err = store.ListQueued("digest")
+	require.NoError(t, err)
+	assert.Empty(t, list)
+	n, found, err = store.LoadNotified("feed1", feed.Item{GUID: "digest2", PubDate: pubDate})
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.Equal(t, []NotifRef{{Notifier: digestNotifier, ChanID: "digest", Ref: "digest:1:100"}}, n.Refs)
+	d, found, err := store.LoadDigest("digest:1:100")
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.Equal(t, 3, len(d.Items))
+
+	// still limited within an hour, after restart as well
+	p.deliverQueued(now.Add(time.Minute))
+	assert.Equal(t, 2, len(tgNotif.SendCalls()))
+	restarted := Processor{Conf: conf, Store: store, TelegramNotif: tgNotif}
+	restarted.deliverQueued(now.Add(2 * time.Minute))
+	assert.Equal(t, 2, len(tgNotif.SendCalls()))
+
+	// changed item updated in the digest, filtered out one removed from it
+	p.updateItem("feed1", feed.Item{GUID: "digest2", Title: "title 2 fixed", PubDate: pubDate})
+	require.Equal(t, 1, len(tgNotif.EditDigestCalls()))
+	assert.Equal(t, "digest:1:100", tgNotif.EditDigestCalls()[0].Ref)
+	assert.Equal(t, "title 2 fixed", tgNotif.EditDigestCalls()[0].Items[1].Title)
+	p.updateItem("feed1", feed.Item{GUID: "digest1", Title: "title 1", PubDate: pubDate, Junk: true})
+	require.Equal(t, 2, len(tgNotif.EditDigestCalls()))
+	require.Equal(t, 2, len(tgNotif.EditDigestCalls()[1].Items))
+	assert.Equal(t, "digest2", tgNotif.EditDigestCalls()[1].Items[0].GUID)
+	assert.Equal(t, "title 2 fixed", tgNotif.EditDigestCalls()[1].Items[0].Title)
+	assert.Equal(t, 0, len(tgNotif.DeleteCalls()))
+
+	// digest deleted with the last item
+	p.updateItem("feed1", feed.Item{GUID: "digest2", Title: "title 2 fixed", PubDate: pubDate, Junk: true})
+	p.updateItem("feed1", feed.Item{GUID: "digest3", Title: "title 3", PubDate: pubDate, Junk: true})
+	assert.Equal(t, 3, len(tgNotif.EditDigestCalls()))
+	require.Equal(t, 1, len(tgNotif.DeleteCalls()))
+	assert.Equal(t, "digest:1:100", tgNotif.DeleteCalls()[0].Ref)
+	_, found, err = store.LoadDigest("digest:1:100")
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	// queued items updated or dropped
+	p.updateItem("feed1", feed.Item{GUID: "quiet1", Title: "title 1 fixed", PubDate: pubDate})
+	p.updateItem("feed1", feed.Item{GUID: "quiet2", Title: "title 2", PubDate: pubDate, Junk: true})
+	list, err = store.ListQueued("quiet")
+	require.NoError(t, err)
+	require.Equal(t, 2, len(list))
+	assert.Equal(t, "title 1 fixed", list[0].Item.Title)
+	assert.Equal(t, "quiet3", list[1].Item.GUID)
+}
+
+func TestProcessor_deliverQueuedDigestFailed(t *testing.T) {
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "test.db"), 0o600, &bolt.Options{Timeout: 1 * time.Second})
+	require.NoError(t, err)
+	defer db.Close()
+	store := &BoltDB{DB: db}
+
+	// the first message of digest sent, the second one failed
+	tgNotif := &mocks.TelegramNotifMock{
+		SendDigestFunc: func(chanID string, items []feed.Item) ([]string, error) {
+			return []string{"digest:1:100", "digest:1:100"}, errors.New("too many requests")
+		},
+	}
+	conf := &config.Conf{Notify: map[string]config.NotifyLimits{"digest": {Digest: true, MaxPerHour: 10}}}
+	p := Processor{Conf: conf, Store: store, TelegramNotif: tgNotif}
+	for i := 1; i <= 3; i++ {
+		item := feed.Item{GUID: "digest" + strconv.Itoa(i), Title: "title " + strconv.Itoa(i), PubDate: pubDate}
+		require.NoError(t, store.Enqueue(Queued{Feed: "feed1", Source: "src", ChanID: "digest", Item: item}))
+	}
+
+	now := time.Now()
+	p.deliverQueued(now)
+	require.Equal(t, 1, len(tgNotif.SendDigestCalls()))
+	list, err := store.ListQueued("digest")
+	require.NoError(t, err)
+	require.Equal(t, 1, len(list), "not sent item kept queued")
+	assert.Equal(t, "digest3", list[0].Item.GUID)
+	d, found, err := store.LoadDigest("digest:1:100")
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.Equal(t, 2, len(d.Items))
+	assert.Equal(t, now.Unix(), d.TS.Unix())
+	n, found, err := store.LoadNotified("feed1", feed.Item{GUID: "digest2", PubDate: pubDate})
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.Equal(t, "digest:1:100", n.Refs[0].Ref)
+	_, found, err = store.LoadNotified("feed1", feed.Item{GUID: "digest3", PubDate: pubDate})
+	require.NoError(t, err)
+	assert.False(t, found)
+	count, err := store.SentSince("digest", now.Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
+
+func TestProcessor_removeDeletedQueued(t *testing.T) {
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "test.db"), 0o600, &bolt.Options{Timeout: 1 * time.Second})
+	require.NoError(t, err)
+	store := &BoltDB{DB: db}
+	p := Processor{Conf: &config.Conf{}, Store: store, TelegramNotif: &mocks.TelegramNotifMock{}}
+
+	now := time.Now()
+	var items []feed.Item
+	for i := 1; i <= 3; i++ {
+		item := feed.Item{GUID: "g" + strconv.Itoa(i), PubDate: now.Add(-time.Duration(i) * time.Hour).Format(time.RFC1123Z),
+			DT: now.Add(-time.Duration(i) * time.Hour)}
+		_, err = store.Save("feed1", item)
+		require.NoError(t, err)
+		require.NoError(t, store.Enqueue(Queued{Feed: "feed1", Source: "src", ChanID: "chan", Item: item}))
+		items = append(items, item)
+	}
+
+	p.removeDeleted("feed1", "other", items[2:])
+	list, err := store.ListQueued("chan")
+	require.NoError(t, err)
+	assert.Equal(t, 3, len(list), "other source")
+
+	p.removeDeleted("feed1", "src", []feed.Item{items[0], items[2]})
+	list, err = store.ListQueued("chan")
+	require.NoError(t, err)
+	require.Equal(t, 2, len(list))
+	assert.Equal(t, "g1", list[0].Item.GUID)
+	assert.Equal(t, "g3", list[1].Item.GUID)
+	res, err := store.Load("feed1", 10, true)
+	require.NoError(t, err)
+	assert.Equal(t, 2, len(res), "removed item marked as junk")
+}
+
+func TestProcessor_LastSweep(t *testing.T) {
+	p := Processor{Conf: &config.Conf{}}
+	p.Conf.System.Concurrent = 1
+	assert.True(t, p.LastSweep().IsZero())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	p.processFeeds(ctx)
+	assert.True(t, p.LastSweep().IsZero(), "interrupted sweep is not counted")
+
+	p.processFeeds(context.Background())
+	assert.WithinDuration(t, time.Now(), p.LastSweep(), time.Second)
+}
+
+func TestCheckItem(t *testing.T) {
+	now := time.Now()
+	tbl := []struct {
+		idx    int
+		item   feed.Item
+		filter config.Filter
+		res    itemCheck
+	}{
+		{0, feed.Item{Title: "news", DT: now}, config.Filter{}, itemCheck{}},
+		{5, feed.Item{Title: "news", DT: now}, config.Filter{}, itemCheck{skip: "over max items per source"}},
+		{1, feed.Item{Title: "news", DT: now.AddDate(-1, 0, -1)}, config.Filter{}, itemCheck{skip: "older than 1 year"}},
+		{1, feed.Item{Title: "ad", DT: now}, config.Filter{Title: "^ad$"}, itemCheck{junk: true}},
+		{1, feed.Item{Title: "news", DT: now}, config.Filter{Title: "^ad$", Invert: true}, itemCheck{junk: true}},
+	}
+	for i, tt := range tbl {
+		assert.Equal(t, tt.res, checkItem(tt.idx, 5, tt.filter, tt.item), i)
+	}
+
+	res := checkItem(0, 5, config.Filter{Title: "("}, feed.Item{Title: "news", DT: now})
+	assert.Equal(t, "", res.skip)
+	assert.Error(t, res.err, "bad filter, item used as is")
+}
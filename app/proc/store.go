@@ -1,6 +1,7 @@
 package proc
 
 import (
+	"bytes"
 	"crypto/sha1"
 	"encoding/json"
 	"fmt"
@@ -9,30 +10,64 @@ import (
 	log "github.com/go-pkgz/lgr"
 	bolt "go.etcd.io/bbolt"
 
+	"github.com/umputun/feed-master/app/config"
 	"github.com/umputun/feed-master/app/feed"
 )
 
+var (
+	notifiedBkt  = []byte("notified")
+	queuedBkt    = []byte("queued")
+	digestsBkt   = []byte("digests")
+	confBkt      = []byte("config")
+	sentBkt      = []byte("sent")
+	overridesKey = []byte("overrides")
+)
+
 // BoltDB store
 type BoltDB struct {
 	DB *bolt.DB
 }
 
+// Notified keeps references to all messages published for a stored item
+type Notified struct {
+	Item   feed.Item  `json:"item"`
+	Source string     `json:"source"` // url of the source the item came from
+	Refs   []NotifRef `json:"refs"`
+}
+
+// NotifRef refers to a message published by a notifier, so it can be edited or deleted later
+type NotifRef struct {
+	Notifier string `json:"notifier"`
+	ChanID   string `json:"chan_id"`
+	Ref      string `json:"ref"` // notifier-specific message reference
+}
+
+// Queued is a notification deferred by destination's quiet hours or rate limit
+type Queued struct {
+	Key    string    `json:"-"`
+	Feed   string    `json:"feed"`
+	Source string    `json:"source"` // url of the source the item came from
+	ChanID string    `json:"chan_id"`
+	Item   feed.Item `json:"item"`
+	TS     time.Time `json:"ts"`
+}
+
+// Digest is a published message listing several items, kept to edit the message when one of them changes
+type Digest struct {
+	Ref    string      `json:"ref"` // notifier-specific message reference
+	ChanID string      `json:"chan_id"`
+	Items  []feed.Item `json:"items"`
+	TS     time.Time   `json:"ts"`
+}
+
+// digestKeep is how long digests are kept, older ones are not edited anymore
+const digestKeep = 30 * 24 * time.Hour
+
 // Save to bolt, skip if found
 func (b BoltDB) Save(fmFeed string, item feed.Item) (bool, error) {
 	var created bool
 
-	key, err := func() ([]byte, error) {
-		ts, err := time.Parse(time.RFC1123Z, item.PubDate)
-		if err != nil {
-			return nil, err
-		}
-		h := sha1.New()
-		if _, err = h.Write([]byte(item.GUID)); err != nil {
-			return nil, err
-		}
-		return []byte(fmt.Sprintf("%d-%x", ts.Unix(), h.Sum(nil))), nil
-	}()
-
+	key, err := b.key(item)
 	if err != nil {
 		return created, err
 	}
@@ -93,6 +128,448 @@ func (b BoltDB) Load(fmFeed string, max int, skipJunk bool) ([]feed.Item, error)
 	return result, err
 }
 
+// LoadPage returns up to limit items of the feed stored before cursor, newest first. Empty cursor starts
+// from the newest item, filter is optional and skips items it returns false for. Returns the cursor of the next page,
+// empty if there are no more items.
+func (b BoltDB) LoadPage(fmFeed, cursor string, limit int, filter func(feed.Item) bool) (items []feed.Item, next string, err error) {
+	err = b.DB.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(fmFeed))
+		if bucket == nil {
+			return nil
+		}
+		var last []byte
+		c := bucket.Cursor()
+		for k, v := seekBefore(c, cursor); k != nil; k, v = c.Prev() {
+			item := feed.Item{}
+			if err := json.Unmarshal(v, &item); err != nil {
+				log.Printf("[WARN] failed to unmarshal, %v", err)
+				continue
+			}
+			if filter != nil && !filter(item) {
+				continue
+			}
+			if len(items) >= limit {
+				next = string(last)
+				break
+			}
+			items = append(items, item)
+			last = k
+		}
+		return nil
+	})
+	return items, next, err
+}
+
+// Exists checks if the item is already saved to the feed, doesn't change anything
+func (b BoltDB) Exists(fmFeed string, item feed.Item) (bool, error) {
+	key, err := b.key(item)
+	if err != nil {
+		return false, err
+	}
+
+	var found bool
+	err = b.DB.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(fmFeed))
+		if bucket == nil {
+			return nil
+		}
+		found = bucket.Get(key) != nil
+		return nil
+	})
+	return found, err
+}
+
+// Update replaces stored item if its title, description or junk status changed.
+// Returns the previously stored item and true if the item was updated.
+func (b BoltDB) Update(fmFeed string, item feed.Item) (prev feed.Item, changed bool, err error) {
+	key, err := b.key(item)
+	if err != nil {
+		return prev, false, err
+	}
+	same := func(prev feed.Item) bool {
+		return prev.Title == item.Title && prev.Description == item.Description && prev.Junk == item.Junk
+	}
+
+	// most items are not changed, compare them in read-only transaction to avoid a write
+	err = b.DB.View(func(tx *bolt.Tx) (e error) {
+		prev, e = b.loadItem(tx, fmFeed, key)
+		return e
+	})
+	if err != nil || same(prev) {
+		return prev, false, err
+	}
+
+	err = b.DB.Update(func(tx *bolt.Tx) (e error) {
+		if prev, e = b.loadItem(tx, fmFeed, key); e != nil {
+			return e
+		}
+		if same(prev) { // updated in between
+			return nil
+		}
+
+		jdata, jerr := json.Marshal(&item)
+		if jerr != nil {
+			return jerr
+		}
+		log.Printf("[INFO] update %s - %s - %s - %s", string(key), fmFeed, item.Title, item.GUID)
+		if e = tx.Bucket([]byte(fmFeed)).Put(key, jdata); e != nil {
+			return e
+		}
+		changed = true
+		return nil
+	})
+
+	return prev, changed, err
+}
+
+// loadItem returns stored item by key, fails if not found
+func (b BoltDB) loadItem(tx *bolt.Tx, fmFeed string, key []byte) (res feed.Item, err error) {
+	bucket := tx.Bucket([]byte(fmFeed))
+	if bucket == nil {
+		return res, fmt.Errorf("no bucket for %s", fmFeed)
+	}
+	data := bucket.Get(key)
+	if data == nil {
+		return res, fmt.Errorf("no item %s in %s", string(key), fmFeed)
+	}
+	err = json.Unmarshal(data, &res)
+	return res, err
+}
+
+// SaveNotified stores references to the messages published for the item, replaces existing ones
+func (b BoltDB) SaveNotified(fmFeed string, n Notified) error {
+	key, err := b.notifiedKey(fmFeed, n.Item)
+	if err != nil {
+		return err
+	}
+
+	return b.DB.Update(func(tx *bolt.Tx) error {
+		bucket, e := tx.CreateBucketIfNotExists(notifiedBkt)
+		if e != nil {
+			return e
+		}
+		jdata, jerr := json.Marshal(&n)
+		if jerr != nil {
+			return jerr
+		}
+		return bucket.Put(key, jdata)
+	})
+}
+
+// LoadNotified returns references to the messages published for the item, found=false if nothing was published
+func (b BoltDB) LoadNotified(fmFeed string, item feed.Item) (n Notified, found bool, err error) {
+	key, err := b.notifiedKey(fmFeed, item)
+	if err != nil {
+		return n, false, err
+	}
+
+	err = b.DB.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(notifiedBkt)
+		if bucket == nil {
+			return nil
+		}
+		data := bucket.Get(key)
+		if data == nil {
+			return nil
+		}
+		found = true
+		return json.Unmarshal(data, &n)
+	})
+	return n, found, err
+}
+
+// ListNotified returns all published items of the feed came from the given source
+func (b BoltDB) ListNotified(fmFeed, source string) ([]Notified, error) {
+	var result []Notified
+	prefix := []byte(fmFeed + "::")
+
+	err := b.DB.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(notifiedBkt)
+		if bucket == nil {
+			return nil
+		}
+		c := bucket.Cursor()
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			n := Notified{}
+			if err := json.Unmarshal(v, &n); err != nil {
+				log.Printf("[WARN] failed to unmarshal, %v", err)
+				continue
+			}
+			if n.Source == source {
+				result = append(result, n)
+			}
+		}
+		return nil
+	})
+	return result, err
+}
+
+// RemoveNotified deletes references to the messages published for the item
+func (b BoltDB) RemoveNotified(fmFeed string, item feed.Item) error {
+	key, err := b.notifiedKey(fmFeed, item)
+	if err != nil {
+		return err
+	}
+
+	return b.DB.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(notifiedBkt)
+		if bucket == nil {
+			return nil
+		}
+		return bucket.Delete(key)
+	})
+}
+
+// Enqueue adds deferred notification to the end of destination's queue
+func (b BoltDB) Enqueue(q Queued) error {
+	return b.DB.Update(func(tx *bolt.Tx) error {
+		bucket, e := tx.CreateBucketIfNotExists(queuedBkt)
+		if e != nil {
+			return e
+		}
+		seq, e := bucket.NextSequence()
+		if e != nil {
+			return e
+		}
+		jdata, jerr := json.Marshal(&q)
+		if jerr != nil {
+			return jerr
+		}
+		return bucket.Put([]byte(fmt.Sprintf("%s::%020d", q.ChanID, seq)), jdata)
+	})
+}
+
+// ListQueued returns deferred notifications of the destination in the order they were queued
+func (b BoltDB) ListQueued(chanID string) ([]Queued, error) {
+	var result []Queued
+	prefix := []byte(chanID + "::")
+
+	err := b.DB.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(queuedBkt)
+		if bucket == nil {
+			return nil
+		}
+		c := bucket.Cursor()
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			q := Queued{}
+			if err := json.Unmarshal(v, &q); err != nil {
+				log.Printf("[WARN] failed to unmarshal, %v", err)
+				continue
+			}
+			q.Key = string(k)
+			result = append(result, q)
+		}
+		return nil
+	})
+	return result, err
+}
+
+// Dequeue removes deferred notifications from the queue
+func (b BoltDB) Dequeue(qq ...Queued) error {
+	return b.DB.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(queuedBkt)
+		if bucket == nil {
+			return nil
+		}
+		for _, q := range qq {
+			if err := bucket.Delete([]byte(q.Key)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ListQueuedFeed returns deferred notifications of the feed's items for all destinations
+func (b BoltDB) ListQueuedFeed(fmFeed string) ([]Queued, error) {
+	var result []Queued
+	err := b.DB.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(queuedBkt)
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			q := Queued{}
+			if err := json.Unmarshal(v, &q); err != nil {
+				log.Printf("[WARN] failed to unmarshal, %v", err)
+				return nil
+			}
+			if q.Feed == fmFeed {
+				q.Key = string(k)
+				result = append(result, q)
+			}
+			return nil
+		})
+	})
+	return result, err
+}
+
+// UpdateQueued replaces deferred notification in place, keeping its position in the queue
+func (b BoltDB) UpdateQueued(q Queued) error {
+	return b.DB.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(queuedBkt)
+		if bucket == nil || bucket.Get([]byte(q.Key)) == nil {
+			return fmt.Errorf("no queued message %s", q.Key)
+		}
+		jdata, err := json.Marshal(&q)
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(q.Key), jdata)
+	})
+}
+
+// SaveDigest stores published digest, replaces existing one with the same reference. Digests older than
+// digestKeep are removed.
+func (b BoltDB) SaveDigest(d Digest) error {
+	return b.DB.Update(func(tx *bolt.Tx) error {
+		bucket, e := tx.CreateBucketIfNotExists(digestsBkt)
+		if e != nil {
+			return e
+		}
+		var old [][]byte
+		_ = bucket.ForEach(func(k, v []byte) error {
+			prev := Digest{}
+			if err := json.Unmarshal(v, &prev); err == nil && time.Since(prev.TS) > digestKeep {
+				old = append(old, k)
+			}
+			return nil
+		})
+		for _, k := range old {
+			if err := bucket.Delete(k); err != nil {
+				return err
+			}
+		}
+		jdata, jerr := json.Marshal(&d)
+		if jerr != nil {
+			return jerr
+		}
+		return bucket.Put([]byte(d.Ref), jdata)
+	})
+}
+
+// LoadDigest returns published digest by its reference, found=false if not stored
+func (b BoltDB) LoadDigest(ref string) (d Digest, found bool, err error) {
+	err = b.DB.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(digestsBkt)
+		if bucket == nil {
+			return nil
+		}
+		data := bucket.Get([]byte(ref))
+		if data == nil {
+			return nil
+		}
+		found = true
+		return json.Unmarshal(data, &d)
+	})
+	return d, found, err
+}
+
+// RemoveDigest deletes published digest by its reference
+func (b BoltDB) RemoveDigest(ref string) error {
+	return b.DB.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(digestsBkt)
+		if bucket == nil {
+			return nil
+		}
+		return bucket.Delete([]byte(ref))
+	})
+}
+
+// MarkSent records sending time of a message to the destination, drops records older than an hour.
+// Records are kept for rate limiting, so the limit is respected after restart.
+func (b BoltDB) MarkSent(chanID string, ts time.Time) error {
+	return b.DB.Update(func(tx *bolt.Tx) error {
+		bucket, e := tx.CreateBucketIfNotExists(sentBkt)
+		if e != nil {
+			return e
+		}
+		sent := []time.Time{}
+		if data := bucket.Get([]byte(chanID)); data != nil {
+			if err := json.Unmarshal(data, &sent); err != nil {
+				log.Printf("[WARN] failed to unmarshal sending times of %s, reset, %v", chanID, err)
+			}
+		}
+		res := []time.Time{ts}
+		for _, t := range sent {
+			if t.After(ts.Add(-time.Hour)) {
+				res = append(res, t)
+			}
+		}
+		jdata, err := json.Marshal(res)
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(chanID), jdata)
+	})
+}
+
+// SentSince returns number of messages sent to the destination after the given time
+func (b BoltDB) SentSince(chanID string, ts time.Time) (count int, err error) {
+	err = b.DB.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(sentBkt)
+		if bucket == nil {
+			return nil
+		}
+		data := bucket.Get([]byte(chanID))
+		if data == nil {
+			return nil
+		}
+		sent := []time.Time{}
+		if e := json.Unmarshal(data, &sent); e != nil {
+			return e
+		}
+		for _, t := range sent {
+			if t.After(ts) {
+				count++
+			}
+		}
+		return nil
+	})
+	return count, err
+}
+
+// LoadOverrides returns feeds and youtube channels changed at runtime, empty if nothing stored
+func (b BoltDB) LoadOverrides() (config.Overrides, error) {
+	res := config.Overrides{}
+	err := b.DB.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(confBkt)
+		if bucket == nil {
+			return nil
+		}
+		v := bucket.Get(overridesKey)
+		if v == nil {
+			return nil
+		}
+		return json.Unmarshal(v, &res)
+	})
+	return res, err
+}
+
+// SaveOverrides stores feeds and youtube channels changed at runtime
+func (b BoltDB) SaveOverrides(o config.Overrides) error {
+	return b.DB.Update(func(tx *bolt.Tx) error {
+		bucket, e := tx.CreateBucketIfNotExists(confBkt)
+		if e != nil {
+			return e
+		}
+		jdata, jerr := json.Marshal(&o)
+		if jerr != nil {
+			return jerr
+		}
+		return bucket.Put(overridesKey, jdata)
+	})
+}
+
+// Version returns id of the last committed write transaction, it changes on any update of the db
+func (b BoltDB) Version() (res uint64, err error) {
+	err = b.DB.View(func(tx *bolt.Tx) error {
+		res = uint64(tx.ID()) // nolint
+		return nil
+	})
+	return res, err
+}
+
 func (b BoltDB) removeOld(fmFeed string, keep int) (int, error) {
 	deleted := 0
 	err := b.DB.Update(func(tx *bolt.Tx) error {
@@ -100,6 +577,7 @@ func (b BoltDB) removeOld(fmFeed string, keep int) (int, error) {
 		if bucket == nil {
 			return fmt.Errorf("no bucket for %s", fmFeed)
 		}
+		notifBucket := tx.Bucket(notifiedBkt)
 		recs := 0
 		c := bucket.Cursor()
 		var err error
@@ -109,6 +587,11 @@ func (b BoltDB) removeOld(fmFeed string, keep int) (int, error) {
 				if e := bucket.Delete(k); e != nil {
 					err = e
 				}
+				if notifBucket != nil {
+					if e := notifBucket.Delete([]byte(fmFeed + "::" + string(k))); e != nil {
+						err = e
+					}
+				}
 				deleted++
 			}
 		}
@@ -116,3 +599,37 @@ func (b BoltDB) removeOld(fmFeed string, keep int) (int, error) {
 	})
 	return deleted, err
 }
+
+// seekBefore positions cursor to the last key before the given one, or to the last key if empty
+func seekBefore(c *bolt.Cursor, before string) (k, v []byte) {
+	if before == "" {
+		return c.Last()
+	}
+	if k, v = c.Seek([]byte(before)); k == nil {
+		k, v = c.Last()
+	}
+	for k != nil && bytes.Compare(k, []byte(before)) >= 0 {
+		k, v = c.Prev()
+	}
+	return k, v
+}
+
+func (b BoltDB) key(item feed.Item) ([]byte, error) {
+	ts, err := time.Parse(time.RFC1123Z, item.PubDate)
+	if err != nil {
+		return nil, err
+	}
+	h := sha1.New()
+	if _, err = h.Write([]byte(item.GUID)); err != nil {
+		return nil, err
+	}
+	return []byte(fmt.Sprintf("%d-%x", ts.Unix(), h.Sum(nil))), nil
+}
+
+func (b BoltDB) notifiedKey(fmFeed string, item feed.Item) ([]byte, error) {
+	key, err := b.key(item)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(fmFeed + "::" + string(key)), nil
+}
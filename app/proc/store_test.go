@@ -11,7 +11,9 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/umputun/feed-master/app/config"
 	"github.com/umputun/feed-master/app/feed"
+	"github.com/umputun/feed-master/app/youtube"
 )
 
 const pubDate = "Mon, 02 Jan 2006 15:04:05 -0700"
@@ -178,3 +180,295 @@ func TestRemoveOld(t *testing.T) {
 		})
 	}
 }
+
+func TestUpdate(t *testing.T) {
+	tmpfile, _ := os.CreateTemp("", "")
+	defer os.Remove(tmpfile.Name())
+	db, err := bolt.Open(tmpfile.Name(), 0o600, &bolt.Options{Timeout: 1 * time.Second}) // nolint
+	require.NoError(t, err)
+	bdb := &BoltDB{DB: db}
+
+	_, _, err = bdb.Update("radio-t", feed.Item{PubDate: pubDate, GUID: "1"})
+	assert.EqualError(t, err, "no bucket for radio-t")
+
+	_, err = bdb.Save("radio-t", feed.Item{PubDate: pubDate, GUID: "1", Title: "title"})
+	require.NoError(t, err)
+
+	_, _, err = bdb.Update("radio-t", feed.Item{PubDate: pubDate, GUID: "2"})
+	assert.Error(t, err, "no such item")
+
+	ver, err := bdb.Version()
+	require.NoError(t, err)
+	prev, changed, err := bdb.Update("radio-t", feed.Item{PubDate: pubDate, GUID: "1", Title: "title"})
+	require.NoError(t, err)
+	assert.False(t, changed)
+	assert.Equal(t, "title", prev.Title)
+	ver2, err := bdb.Version()
+	require.NoError(t, err)
+	assert.Equal(t, ver, ver2, "no write transaction for unchanged item")
+
+	prev, changed, err = bdb.Update("radio-t", feed.Item{PubDate: pubDate, GUID: "1", Title: "new title"})
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, "title", prev.Title)
+
+	prev, changed, err = bdb.Update("radio-t", feed.Item{PubDate: pubDate, GUID: "1", Title: "new title", Junk: true})
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.False(t, prev.Junk)
+
+	items, err := bdb.Load("radio-t", 5, false)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(items))
+	assert.Equal(t, "new title", items[0].Title)
+	assert.True(t, items[0].Junk)
+}
+
+func TestNotified(t *testing.T) {
+	tmpfile, _ := os.CreateTemp("", "")
+	defer os.Remove(tmpfile.Name())
+	db, err := bolt.Open(tmpfile.Name(), 0o600, &bolt.Options{Timeout: 1 * time.Second}) // nolint
+	require.NoError(t, err)
+	bdb := &BoltDB{DB: db}
+
+	item1 := feed.Item{PubDate: pubDate, GUID: "1"}
+	item2 := feed.Item{PubDate: "Mon, 02 Jan 2006 16:04:05 -0700", GUID: "2"}
+
+	_, found, err := bdb.LoadNotified("radio-t", item1)
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	n1 := Notified{Item: item1, Source: "src1", Refs: []NotifRef{{Notifier: "telegram", ChanID: "chan", Ref: "audio:1:1"}}}
+	require.NoError(t, bdb.SaveNotified("radio-t", n1))
+	n2 := Notified{Item: item2, Source: "src2", Refs: []NotifRef{{Notifier: "telegram", ChanID: "chan", Ref: "audio:1:2"}}}
+	require.NoError(t, bdb.SaveNotified("radio-t", n2))
+	require.NoError(t, bdb.SaveNotified("other", n2))
+
+	n, found, err := bdb.LoadNotified("radio-t", item1)
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, n1, n)
+
+	list, err := bdb.ListNotified("radio-t", "src2")
+	require.NoError(t, err)
+	assert.Equal(t, []Notified{n2}, list)
+
+	require.NoError(t, bdb.RemoveNotified("radio-t", item2))
+	list, err = bdb.ListNotified("radio-t", "src2")
+	require.NoError(t, err)
+	assert.Empty(t, list)
+	list, err = bdb.ListNotified("other", "src2")
+	require.NoError(t, err)
+	assert.Equal(t, 1, len(list), "same item in other feed not removed")
+
+	// removeOld cleans references for removed items
+	_, err = bdb.Save("radio-t", item1)
+	require.NoError(t, err)
+	_, err = bdb.Save("radio-t", item2)
+	require.NoError(t, err)
+	_, err = bdb.removeOld("radio-t", 1)
+	require.NoError(t, err)
+	_, found, err = bdb.LoadNotified("radio-t", item1)
+	require.NoError(t, err)
+	assert.False(t, found)
+}
+
+func TestQueue(t *testing.T) {
+	tmpfile, _ := os.CreateTemp("", "")
+	defer os.Remove(tmpfile.Name())
+	db, err := bolt.Open(tmpfile.Name(), 0o600, &bolt.Options{Timeout: 1 * time.Second}) // nolint
+	require.NoError(t, err)
+	bdb := &BoltDB{DB: db}
+
+	list, err := bdb.ListQueued("chan1")
+	require.NoError(t, err)
+	assert.Empty(t, list)
+
+	for i := 1; i <= 3; i++ {
+		q := Queued{Feed: "radio-t", Source: "src", ChanID: "chan1", Item: feed.Item{GUID: strconv.Itoa(i)}}
+		require.NoError(t, bdb.Enqueue(q))
+	}
+	require.NoError(t, bdb.Enqueue(Queued{Feed: "radio-t", ChanID: "chan10", Item: feed.Item{GUID: "other"}}))
+
+	list, err = bdb.ListQueued("chan1")
+	require.NoError(t, err)
+	require.Equal(t, 3, len(list))
+	for i, q := range list {
+		assert.Equal(t, strconv.Itoa(i+1), q.Item.GUID, "kept in order")
+		assert.Equal(t, "radio-t", q.Feed)
+		assert.NotEmpty(t, q.Key)
+	}
+
+	require.NoError(t, bdb.Dequeue(list[0], list[2]))
+	list, err = bdb.ListQueued("chan1")
+	require.NoError(t, err)
+	require.Equal(t, 1, len(list))
+	assert.Equal(t, "2", list[0].Item.GUID)
+
+	list, err = bdb.ListQueued("chan10")
+	require.NoError(t, err)
+	assert.Equal(t, 1, len(list))
+
+	list, err = bdb.ListQueuedFeed("radio-t")
+	require.NoError(t, err)
+	require.Equal(t, 2, len(list), "all destinations")
+	q := list[0]
+	q.Item.Title = "updated"
+	require.NoError(t, bdb.UpdateQueued(q))
+	list, err = bdb.ListQueued(q.ChanID)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(list))
+	assert.Equal(t, "updated", list[0].Item.Title)
+
+	require.NoError(t, bdb.Dequeue(q))
+	assert.EqualError(t, bdb.UpdateQueued(q), "no queued message "+q.Key)
+	list, err = bdb.ListQueuedFeed("other")
+	require.NoError(t, err)
+	assert.Empty(t, list)
+}
+
+func TestDigest(t *testing.T) {
+	tmpfile, _ := os.CreateTemp("", "")
+	defer os.Remove(tmpfile.Name())
+	db, err := bolt.Open(tmpfile.Name(), 0o600, &bolt.Options{Timeout: 1 * time.Second}) // nolint
+	require.NoError(t, err)
+	bdb := &BoltDB{DB: db}
+
+	_, found, err := bdb.LoadDigest("digest:1:1")
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	old := Digest{Ref: "digest:1:1", ChanID: "chan1", Items: []feed.Item{{GUID: "1"}}, TS: time.Now().Add(-digestKeep - time.Hour)}
+	require.NoError(t, bdb.SaveDigest(old))
+	d := Digest{Ref: "digest:1:2", ChanID: "chan1", Items: []feed.Item{{GUID: "2"}, {GUID: "3"}}, TS: time.Now()}
+	require.NoError(t, bdb.SaveDigest(d))
+
+	res, found, err := bdb.LoadDigest("digest:1:2")
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.Equal(t, "chan1", res.ChanID)
+	assert.Equal(t, 2, len(res.Items))
+	_, found, err = bdb.LoadDigest("digest:1:1")
+	require.NoError(t, err)
+	assert.False(t, found, "outdated digest removed")
+
+	require.NoError(t, bdb.RemoveDigest("digest:1:2"))
+	_, found, err = bdb.LoadDigest("digest:1:2")
+	require.NoError(t, err)
+	assert.False(t, found)
+}
+
+func TestSent(t *testing.T) {
+	tmpfile, _ := os.CreateTemp("", "")
+	defer os.Remove(tmpfile.Name())
+	db, err := bolt.Open(tmpfile.Name(), 0o600, &bolt.Options{Timeout: 1 * time.Second}) // nolint
+	require.NoError(t, err)
+	bdb := &BoltDB{DB: db}
+
+	now := time.Now()
+	count, err := bdb.SentSince("chan1", now.Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	require.NoError(t, bdb.MarkSent("chan1", now.Add(-90*time.Minute)))
+	require.NoError(t, bdb.MarkSent("chan1", now.Add(-30*time.Minute)))
+	require.NoError(t, bdb.MarkSent("chan1", now))
+	require.NoError(t, bdb.MarkSent("chan2", now))
+	count, err = bdb.SentSince("chan1", now.Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+	count, err = bdb.SentSince("chan1", now.Add(-2*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 2, count, "older than an hour dropped")
+	count, err = bdb.SentSince("chan2", now.Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
+
+func TestOverrides(t *testing.T) {
+	tmpfile, _ := os.CreateTemp("", "")
+	defer os.Remove(tmpfile.Name())
+	db, err := bolt.Open(tmpfile.Name(), 0o600, &bolt.Options{Timeout: 1 * time.Second}) // nolint
+	require.NoError(t, err)
+	bdb := &BoltDB{DB: db}
+
+	o, err := bdb.LoadOverrides()
+	require.NoError(t, err)
+	assert.Equal(t, config.Overrides{}, o)
+
+	o = config.Overrides{
+		Feeds:    map[string]*config.Feed{"feed1": {Title: "title1"}, "feed2": nil},
+		Channels: map[string]*youtube.FeedInfo{"ch1": {ID: "ch1", Name: "name1"}, "ch2": nil},
+	}
+	require.NoError(t, bdb.SaveOverrides(o))
+	res, err := bdb.LoadOverrides()
+	require.NoError(t, err)
+	assert.Equal(t, o, res)
+}
+
+func TestLoadPage(t *testing.T) {
+	tmpfile, _ := os.CreateTemp("", "")
+	defer os.Remove(tmpfile.Name())
+	db, err := bolt.Open(tmpfile.Name(), 0o600, &bolt.Options{Timeout: 1 * time.Second}) // nolint
+	require.NoError(t, err)
+	bdb := &BoltDB{DB: db}
+
+	ts := time.Date(2022, time.March, 21, 16, 45, 22, 0, time.UTC)
+	for i := 0; i < 6; i++ {
+		item := feed.Item{GUID: strconv.Itoa(i), PubDate: ts.Add(time.Duration(i) * time.Hour).Format(time.RFC1123Z),
+			Source: "src1", Junk: i == 4}
+		if i%2 == 1 {
+			item.Source = "src2"
+		}
+		_, err = bdb.Save("radio-t", item)
+		require.NoError(t, err)
+	}
+	guids := func(items []feed.Item) (res []string) {
+		for _, item := range items {
+			res = append(res, item.GUID)
+		}
+		return res
+	}
+
+	items, next, err := bdb.LoadPage("radio-t", "", 2, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"5", "4"}, guids(items))
+	items, next, err = bdb.LoadPage("radio-t", next, 10, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"3", "2", "1", "0"}, guids(items))
+	assert.Empty(t, next)
+
+	src1 := func(item feed.Item) bool { return item.Source == "src1" && !item.Junk }
+	items, next, err = bdb.LoadPage("radio-t", "", 1, src1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"2"}, guids(items), "junk skipped")
+	items, next, err = bdb.LoadPage("radio-t", next, 1, src1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"0"}, guids(items))
+	assert.Empty(t, next, "no more matching items")
+
+	items, next, err = bdb.LoadPage("no-such-feed", "", 1, nil)
+	require.NoError(t, err)
+	assert.Empty(t, items)
+	assert.Empty(t, next)
+}
+
+func TestVersion(t *testing.T) {
+	tmpfile, _ := os.CreateTemp("", "")
+	defer os.Remove(tmpfile.Name())
+	db, err := bolt.Open(tmpfile.Name(), 0o600, &bolt.Options{Timeout: 1 * time.Second}) // nolint
+	require.NoError(t, err)
+	bdb := &BoltDB{DB: db}
+
+	v1, err := bdb.Version()
+	require.NoError(t, err)
+	v2, err := bdb.Version()
+	require.NoError(t, err)
+	assert.Equal(t, v1, v2, "not changed by reads")
+
+	_, err = bdb.Save("radio-t", feed.Item{GUID: "guid1", PubDate: pubDate})
+	require.NoError(t, err)
+	v2, err = bdb.Version()
+	require.NoError(t, err)
+	assert.Greater(t, v2, v1, "changed by writes")
+}
@@ -21,6 +21,18 @@ import (
 //go:generate moq -out mocks/tg_sender.go -pkg mocks -skip-ensure -fmt goimports . TelegramSender
 //go:generate moq -out mocks/duration.go -pkg mocks -skip-ensure -fmt goimports . DurationService
 
+const (
+	telegramNotifier = "telegram"
+	digestNotifier   = "telegram-digest" // message shared by several items, see Digest
+
+	// kinds of published telegram messages, audio message can be edited by caption only
+	tgAudioMsg  = "audio"
+	tgTextMsg   = "text"
+	tgDigestMsg = "digest"
+
+	tgMessageLimit = 4096 // max length of text message
+)
+
 // TelegramClient client
 type TelegramClient struct {
 	Bot             *tb.Bot
@@ -71,25 +83,183 @@ func NewTelegramClient(token, apiURL string, timeout time.Duration, ds DurationS
 	return &result, err
 }
 
-// Send message, skip if telegram token empty
-func (client TelegramClient) Send(channelID string, item feed.Item) (err error) {
+// Send message, skip if telegram token empty. Returns reference to the published message
+func (client TelegramClient) Send(channelID string, item feed.Item) (ref string, err error) {
 	if client.Bot == nil || channelID == "" {
-		return nil
+		return "", nil
 	}
 
+	kind := tgAudioMsg
 	message, err := client.sendAudio(channelID, item)
 	if err != nil && strings.Contains(err.Error(), "Request Entity Too Large") {
+		kind = tgTextMsg
 		message, err = client.sendText(channelID, item)
 	}
 
 	if err != nil {
-		return errors.Wrapf(err, "can't send to telegram for %+v", item.Enclosure)
+		notificationsTotal.Inc(telegramNotifier, "failed")
+		return "", errors.Wrapf(err, "can't send to telegram for %+v", item.Enclosure)
+	}
+	notificationsTotal.Inc(telegramNotifier, "sent")
+	if message == nil {
+		return "", nil
 	}
 
 	log.Printf("[DEBUG] telegram message sent: \n%s", message.Text)
+	var chatID int64
+	if message.Chat != nil {
+		chatID = message.Chat.ID
+	}
+	return fmt.Sprintf("%s:%d:%d", kind, chatID, message.ID), nil
+}
+
+// SendDigest sends text messages listing the items, used to collapse deferred notifications. Items are split into
+// several messages if the list doesn't fit telegram's message limit. Returns references to the published messages
+// for each of the sent items, in the same order. Items of the failed message and after it are not sent.
+func (client TelegramClient) SendDigest(channelID string, items []feed.Item) (refs []string, err error) {
+	if client.Bot == nil || channelID == "" || len(items) == 0 {
+		return nil, nil
+	}
+
+	for _, chunk := range client.digestChunks(items) {
+		message, err := client.Bot.Send(recipient{chatID: channelID}, client.getDigestHTML(chunk), tb.ModeHTML, tb.NoPreview)
+		if err != nil {
+			notificationsTotal.Inc(telegramNotifier, "failed")
+			return refs, errors.Wrapf(err, "can't send digest of %d items to telegram", len(chunk))
+		}
+		notificationsTotal.Inc(telegramNotifier, "sent")
+		ref := ""
+		if message != nil {
+			var chatID int64
+			if message.Chat != nil {
+				chatID = message.Chat.ID
+			}
+			ref = fmt.Sprintf("%s:%d:%d", tgDigestMsg, chatID, message.ID)
+		}
+		for range chunk {
+			refs = append(refs, ref)
+		}
+	}
+	return refs, nil
+}
+
+// EditDigest updates previously published digest with the new list of items. Items not fitting telegram's
+// message limit, possible with longer titles, are dropped.
+func (client TelegramClient) EditDigest(ref string, items []feed.Item) error {
+	if client.Bot == nil {
+		return nil
+	}
+
+	_, msg, err := client.parseRef(ref)
+	if err != nil {
+		return err
+	}
+	if chunks := client.digestChunks(items); len(chunks) > 1 {
+		log.Printf("[WARN] telegram digest %s is too long, %d items dropped", ref, len(items)-len(chunks[0]))
+		items = chunks[0]
+	}
+	if _, err = client.Bot.Edit(msg, client.getDigestHTML(items), tb.ModeHTML, tb.NoPreview); err != nil {
+		return errors.Wrapf(err, "can't edit telegram digest %s", ref)
+	}
+	log.Printf("[DEBUG] telegram digest %s updated, %d items", ref, len(items))
 	return nil
 }
 
+// digestChunks splits items to chunks, digest message of each chunk fits telegram's message limit.
+// Length in bytes is used, it's not less than the length in utf-16 units telegram counts.
+func (client TelegramClient) digestChunks(items []feed.Item) [][]feed.Item {
+	res := [][]feed.Item{}
+	var chunk []feed.Item
+	size := len(client.digestHeader(len(items)))
+	for _, item := range items {
+		l := len(client.digestLine(item)) + 1 // with new line
+		if len(chunk) > 0 && size+l > tgMessageLimit {
+			res = append(res, chunk)
+			chunk, size = nil, len(client.digestHeader(len(items)))
+		}
+		chunk, size = append(chunk, item), size+l
+	}
+	if len(chunk) > 0 {
+		res = append(res, chunk)
+	}
+	return res
+}
+
+// getDigestHTML generates HTML message listing titles of the items, linked if the item has a link
+func (client TelegramClient) getDigestHTML(items []feed.Item) string {
+	lines := make([]string, 0, len(items)+1)
+	lines = append(lines, client.digestHeader(len(items)))
+	for _, item := range items {
+		lines = append(lines, client.digestLine(item))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (client TelegramClient) digestHeader(count int) string {
+	return fmt.Sprintf("<b>%d new items</b>", count)
+}
+
+// digestLine makes line of the digest with the item's title, cropped to fit several lines in a message
+func (client TelegramClient) digestLine(item feed.Item) string {
+	title := html.EscapeString(CropText(item.Title, 500))
+	if item.Link != "" {
+		title = fmt.Sprintf(`<a href="%s">%s</a>`, html.EscapeString(item.Link), title)
+	}
+	return "- " + title
+}
+
+// Edit updates previously published message with the new item's title and description
+func (client TelegramClient) Edit(ref string, item feed.Item) error {
+	if client.Bot == nil {
+		return nil
+	}
+
+	kind, msg, err := client.parseRef(ref)
+	if err != nil {
+		return err
+	}
+	if kind == tgAudioMsg {
+		_, err = client.Bot.EditCaption(msg, client.getMessageHTML(item, htmlMessageParams{TrimCaption: true}), tb.ModeHTML)
+	} else {
+		_, err = client.Bot.Edit(msg, client.getMessageHTML(item, htmlMessageParams{WithMp3Link: true}), tb.ModeHTML, tb.NoPreview)
+	}
+	if err != nil {
+		return errors.Wrapf(err, "can't edit telegram message %s", ref)
+	}
+	log.Printf("[DEBUG] telegram message %s updated, %s", ref, item.Title)
+	return nil
+}
+
+// Delete removes previously published message
+func (client TelegramClient) Delete(ref string) error {
+	if client.Bot == nil {
+		return nil
+	}
+
+	_, msg, err := client.parseRef(ref)
+	if err != nil {
+		return err
+	}
+	if err := client.Bot.Delete(msg); err != nil {
+		return errors.Wrapf(err, "can't delete telegram message %s", ref)
+	}
+	log.Printf("[DEBUG] telegram message %s deleted", ref)
+	return nil
+}
+
+// parseRef parses reference made by Send, i.e. "audio:chatID:messageID"
+func (client TelegramClient) parseRef(ref string) (kind string, msg tb.StoredMessage, err error) {
+	elems := strings.Split(ref, ":")
+	if len(elems) != 3 {
+		return "", msg, fmt.Errorf("invalid telegram message reference %q", ref)
+	}
+	chatID, err := strconv.ParseInt(elems[1], 10, 64)
+	if err != nil {
+		return "", msg, errors.Wrapf(err, "invalid chat id in telegram message reference %q", ref)
+	}
+	return elems[0], tb.StoredMessage{MessageID: elems[2], ChatID: chatID}, nil
+}
+
 func (client TelegramClient) sendText(channelID string, item feed.Item) (*tb.Message, error) {
 	message, err := client.Bot.Send(
 		recipient{chatID: channelID},
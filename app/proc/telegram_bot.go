@@ -0,0 +1,263 @@
+package proc
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	log "github.com/go-pkgz/lgr"
+	tb "gopkg.in/tucnak/telebot.v2"
+
+	"github.com/umputun/feed-master/app/config"
+	"github.com/umputun/feed-master/app/feed"
+	ytfeed "github.com/umputun/feed-master/app/youtube/feed"
+)
+
+//go:generate moq -out mocks/bot_store.go -pkg mocks -skip-ensure -fmt goimports . BotStore
+//go:generate moq -out mocks/bot_youtube.go -pkg mocks -skip-ensure -fmt goimports . BotYoutubeSvc
+
+// TelegramBot answers commands sent to telegram bot by subscribers and admins
+type TelegramBot struct {
+	Bot         *tb.Bot
+	Conf        *config.Conf
+	Store       BotStore
+	Processor   BotProcessor
+	YoutubeSvc  BotYoutubeSvc // optional, nil if youtube processing is not configured
+	Admins      []int64       // telegram user ids allowed to run admin commands
+	LatestItems int           // default number of items returned by /latest
+
+	lock sync.Mutex
+}
+
+// BotStore provides access to feed items
+type BotStore interface {
+	Load(fmFeed string, max int, skipJunk bool) ([]feed.Item, error)
+}
+
+// BotProcessor provides control over feeds processing
+type BotProcessor interface {
+	Refresh()
+	Status() []SourceStatus
+}
+
+// BotYoutubeSvc provides control over youtube entries
+type BotYoutubeSvc interface {
+	RemoveEntry(entry ytfeed.Entry) error
+}
+
+// NewTelegramBot makes telegram bot receiving updates with long polling
+func NewTelegramBot(token, apiURL string, timeout time.Duration) (*TelegramBot, error) {
+	log.Printf("[INFO] create telegram bot for %s, timeout: %s", apiURL, timeout)
+	if timeout == 0 {
+		timeout = time.Second * 60
+	}
+
+	bot, err := tb.NewBot(tb.Settings{
+		URL:    apiURL,
+		Token:  token,
+		Client: &http.Client{Timeout: timeout + 10*time.Second}, // should be longer than poller's timeout
+		Poller: &tb.LongPoller{Timeout: timeout},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &TelegramBot{Bot: bot, LatestItems: 5}, nil
+}
+
+// Run registers commands and blocks until context is canceled
+func (b *TelegramBot) Run(ctx context.Context) {
+	log.Printf("[INFO] starting telegram bot, admins: %v", b.Admins)
+	commands := map[string]func(m *tb.Message) string{
+		"/start":   b.help,
+		"/help":    b.help,
+		"/feeds":   b.feeds,
+		"/latest":  b.latest,
+		"/refresh": b.adminOnly(b.refresh),
+		"/remove":  b.adminOnly(b.remove),
+		"/status":  b.adminOnly(b.status),
+	}
+	for cmd, fn := range commands {
+		fn := fn
+		b.Bot.Handle(cmd, func(m *tb.Message) {
+			if m.Sender != nil {
+				log.Printf("[INFO] telegram command %q from %s (%d)", m.Text, m.Sender.Username, m.Sender.ID)
+			}
+			if _, err := b.Bot.Send(m.Chat, fn(m), tb.ModeHTML, tb.NoPreview); err != nil {
+				log.Printf("[WARN] failed to reply to %q, %v", m.Text, err)
+			}
+		})
+	}
+
+	go func() {
+		<-ctx.Done()
+		b.Bot.Stop()
+	}()
+	b.Bot.Start()
+	log.Printf("[INFO] telegram bot stopped")
+}
+
+// SetConf replaces configuration, used on config reload
+func (b *TelegramBot) SetConf(conf *config.Conf) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.Conf = conf
+}
+
+func (b *TelegramBot) conf() *config.Conf {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.Conf
+}
+
+// help returns the list of supported commands
+func (b *TelegramBot) help(m *tb.Message) string {
+	res := "/feeds - list of feeds\n/latest <feed> [count] - latest items of the feed"
+	if b.isAdmin(m) {
+		res += "\n/refresh - refresh all feeds\n/remove <channel> <video> - remove youtube entry\n/status - sources status"
+	}
+	return res
+}
+
+// feeds returns the list of configured feeds, private feeds are not listed
+func (b *TelegramBot) feeds(*tb.Message) string {
+	conf := b.conf()
+	names := make([]string, 0, len(conf.Feeds))
+	for name, f := range conf.Feeds {
+		if f.Private {
+			continue
+		}
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		return "no feeds"
+	}
+	sort.Strings(names)
+
+	res := make([]string, 0, len(names))
+	for _, name := range names {
+		f := conf.Feeds[name]
+		line := "<b>" + html.EscapeString(name) + "</b>"
+		if f.Title != "" {
+			line += " - " + html.EscapeString(f.Title)
+		}
+		if conf.System.BaseURL != "" {
+			rss := strings.TrimSuffix(conf.System.BaseURL, "/") + "/rss/" + name
+			line += fmt.Sprintf(` (<a href="%s">rss</a>)`, html.EscapeString(rss))
+		}
+		res = append(res, line)
+	}
+	return strings.Join(res, "\n")
+}
+
+// latest returns the latest items of the feed, i.e. "/latest feed1 10", private feeds reported as not found
+func (b *TelegramBot) latest(m *tb.Message) string {
+	args := strings.Fields(m.Payload)
+	if len(args) == 0 || len(args) > 2 {
+		return "usage: /latest <feed> [count]"
+	}
+	name := args[0]
+	conf := b.conf()
+	if f, ok := conf.Feeds[name]; !ok || f.Private {
+		return fmt.Sprintf("feed %s not found", html.EscapeString(name))
+	}
+
+	count := b.LatestItems
+	if len(args) == 2 {
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n <= 0 {
+			return "count should be a positive number"
+		}
+		count = n
+	}
+	if conf.System.MaxTotal > 0 && count > conf.System.MaxTotal {
+		count = conf.System.MaxTotal
+	}
+
+	items, err := b.Store.Load(name, count, true)
+	if err != nil || len(items) == 0 {
+		return fmt.Sprintf("no items in %s", html.EscapeString(name))
+	}
+
+	res := make([]string, 0, len(items))
+	for _, item := range items {
+		title := html.EscapeString(item.Title)
+		if item.Link != "" {
+			title = fmt.Sprintf(`<a href="%s">%s</a>`, html.EscapeString(item.Link), title)
+		}
+		res = append(res, fmt.Sprintf("%s, %s", title, humanize.Time(item.DT)))
+	}
+	return strings.Join(res, "\n")
+}
+
+// refresh requests immediate processing of all feeds
+func (b *TelegramBot) refresh(*tb.Message) string {
+	b.Processor.Refresh()
+	return "refresh requested"
+}
+
+// remove deletes youtube entry, i.e. "/remove channelID videoID"
+func (b *TelegramBot) remove(m *tb.Message) string {
+	if b.YoutubeSvc == nil {
+		return "youtube processing is not configured"
+	}
+	args := strings.Fields(m.Payload)
+	if len(args) != 2 {
+		return "usage: /remove <channel> <video>"
+	}
+	if err := b.YoutubeSvc.RemoveEntry(ytfeed.Entry{ChannelID: args[0], VideoID: args[1]}); err != nil {
+		return "failed to remove entry: " + html.EscapeString(err.Error())
+	}
+	return fmt.Sprintf("removed %s from %s", html.EscapeString(args[1]), html.EscapeString(args[0]))
+}
+
+// status returns the health of all sources
+func (b *TelegramBot) status(*tb.Message) string {
+	statuses := b.Processor.Status()
+	if len(statuses) == 0 {
+		return "no sources processed yet"
+	}
+
+	res := make([]string, 0, len(statuses))
+	for _, st := range statuses {
+		line := fmt.Sprintf("%s/%s: ", html.EscapeString(st.Feed), html.EscapeString(st.Name))
+		if st.Error != "" {
+			line += "<b>failed</b> " + humanize.Time(st.LastAttempt) + ", " + html.EscapeString(st.Error)
+			if !st.LastSuccess.IsZero() {
+				line += ", last success " + humanize.Time(st.LastSuccess)
+			}
+		} else {
+			line += fmt.Sprintf("ok %s, %d items", humanize.Time(st.LastSuccess), st.Items)
+		}
+		res = append(res, line)
+	}
+	return strings.Join(res, "\n")
+}
+
+// adminOnly wraps command allowed for admins only
+func (b *TelegramBot) adminOnly(fn func(m *tb.Message) string) func(m *tb.Message) string {
+	return func(m *tb.Message) string {
+		if !b.isAdmin(m) {
+			return "not allowed"
+		}
+		return fn(m)
+	}
+}
+
+func (b *TelegramBot) isAdmin(m *tb.Message) bool {
+	if m.Sender == nil {
+		return false
+	}
+	for _, id := range b.Admins {
+		if id == m.Sender.ID {
+			return true
+		}
+	}
+	return false
+}
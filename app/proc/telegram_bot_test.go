@@ -0,0 +1,141 @@
+package proc
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	tb "gopkg.in/tucnak/telebot.v2"
+
+	"github.com/umputun/feed-master/app/config"
+	"github.com/umputun/feed-master/app/feed"
+	"github.com/umputun/feed-master/app/proc/mocks"
+	ytfeed "github.com/umputun/feed-master/app/youtube/feed"
+)
+
+func TestTelegramBot_commands(t *testing.T) {
+	store := &mocks.BotStoreMock{LoadFunc: func(fmFeed string, max int, skipJunk bool) ([]feed.Item, error) {
+		return []feed.Item{
+			{Title: "title <1>", Link: `http://example.com/1?a=1&b="x"`, DT: time.Now().Add(-time.Hour)},
+			{Title: "title 2", DT: time.Now().Add(-2 * time.Hour)},
+		}, nil
+	}}
+	proc := &Processor{}
+	proc.setStatus("feed1", config.Source{Name: "src1", URL: "http://example.com/1"}, 10, nil)
+	proc.setStatus("feed1", config.Source{Name: "src2", URL: "http://example.com/2"}, 0, errors.New("non-200 status code"))
+	yt := &mocks.BotYoutubeSvcMock{RemoveEntryFunc: func(entry ytfeed.Entry) error {
+		if entry.VideoID == "bad" {
+			return errors.New("no such entry")
+		}
+		return nil
+	}}
+
+	conf := &config.Conf{Feeds: map[string]config.Feed{"feed1": {Title: "Feed 1"}, "feed2": {},
+		"private": {Title: "Private", Private: true}}}
+	conf.System.BaseURL = "http://example.com/"
+	conf.System.MaxTotal = 10
+	bot := TelegramBot{Conf: conf, Store: store, Processor: proc, YoutubeSvc: yt, Admins: []int64{123}, LatestItems: 5}
+
+	user := &tb.User{ID: 1}
+	admin := &tb.User{ID: 123}
+
+	t.Run("help", func(t *testing.T) {
+		assert.NotContains(t, bot.help(&tb.Message{Sender: user}), "/refresh")
+		assert.Contains(t, bot.help(&tb.Message{Sender: admin}), "/refresh")
+	})
+
+	t.Run("feeds", func(t *testing.T) {
+		assert.Equal(t, "<b>feed1</b> - Feed 1 (<a href=\"http://example.com/rss/feed1\">rss</a>)\n"+
+			"<b>feed2</b> (<a href=\"http://example.com/rss/feed2\">rss</a>)", bot.feeds(&tb.Message{Sender: user}))
+	})
+
+	t.Run("latest", func(t *testing.T) {
+		assert.Equal(t, "usage: /latest <feed> [count]", bot.latest(&tb.Message{Sender: user}))
+		assert.Equal(t, "feed bad not found", bot.latest(&tb.Message{Sender: user, Payload: "bad"}))
+		assert.Equal(t, "feed private not found", bot.latest(&tb.Message{Sender: admin, Payload: "private"}))
+		assert.Equal(t, "count should be a positive number", bot.latest(&tb.Message{Sender: user, Payload: "feed1 -1"}))
+		assert.Equal(t, "<a href=\"http://example.com/1?a=1&amp;b=&#34;x&#34;\">title &lt;1&gt;</a>, 1 hour ago\ntitle 2, 2 hours ago",
+			bot.latest(&tb.Message{Sender: user, Payload: "feed1"}))
+		assert.Equal(t, 5, store.LoadCalls()[0].Max)
+		assert.True(t, store.LoadCalls()[0].SkipJunk)
+
+		bot.latest(&tb.Message{Sender: user, Payload: "feed1 100"})
+		assert.Equal(t, 10, store.LoadCalls()[1].Max, "limited by max total")
+	})
+
+	t.Run("refresh", func(t *testing.T) {
+		assert.Equal(t, "not allowed", bot.adminOnly(bot.refresh)(&tb.Message{Sender: user}))
+		assert.Equal(t, 0, len(proc.refresh()))
+		assert.Equal(t, "refresh requested", bot.adminOnly(bot.refresh)(&tb.Message{Sender: admin}))
+		assert.Equal(t, 1, len(proc.refresh()))
+	})
+
+	t.Run("remove", func(t *testing.T) {
+		remove := bot.adminOnly(bot.remove)
+		assert.Equal(t, "not allowed", remove(&tb.Message{Sender: user, Payload: "chan1 vid1"}))
+		assert.Equal(t, "not allowed", remove(&tb.Message{Payload: "chan1 vid1"}), "no sender")
+		assert.Equal(t, "usage: /remove <channel> <video>", remove(&tb.Message{Sender: admin, Payload: "chan1"}))
+		assert.Equal(t, "removed vid1 from chan1", remove(&tb.Message{Sender: admin, Payload: "chan1 vid1"}))
+		assert.Equal(t, "failed to remove entry: no such entry", remove(&tb.Message{Sender: admin, Payload: "chan1 bad"}))
+		require.Equal(t, 2, len(yt.RemoveEntryCalls()))
+		assert.Equal(t, ytfeed.Entry{ChannelID: "chan1", VideoID: "vid1"}, yt.RemoveEntryCalls()[0].Entry)
+
+		noYt := TelegramBot{Conf: conf, Admins: []int64{123}}
+		assert.Equal(t, "youtube processing is not configured", noYt.remove(&tb.Message{Sender: admin, Payload: "chan1 vid1"}))
+	})
+
+	t.Run("status", func(t *testing.T) {
+		assert.Equal(t, "not allowed", bot.adminOnly(bot.status)(&tb.Message{Sender: user}))
+		assert.Equal(t, "feed1/src1: ok now, 10 items\nfeed1/src2: <b>failed</b> now, non-200 status code",
+			bot.adminOnly(bot.status)(&tb.Message{Sender: admin}))
+	})
+}
+
+func TestProcessor_RefreshAndStatus(t *testing.T) {
+	p := Processor{}
+	p.Refresh()
+	p.Refresh() // second call doesn't block
+	select {
+	case <-p.refresh():
+	default:
+		t.Fatal("refresh not requested")
+	}
+
+	p.setStatus("feed2", config.Source{Name: "src", URL: "http://example.com/2"}, 5, nil)
+	p.setStatus("feed1", config.Source{Name: "src", URL: "http://example.com/1"}, 10, nil)
+	p.setStatus("feed1", config.Source{Name: "src", URL: "http://example.com/1"}, 0, errors.New("failed"))
+
+	st := p.Status()
+	require.Equal(t, 2, len(st))
+	assert.Equal(t, "feed1", st[0].Feed)
+	assert.Equal(t, "failed", st[0].Error)
+	assert.Equal(t, 10, st[0].Items, "keeps items from the last success")
+	assert.False(t, st[0].LastSuccess.IsZero())
+	assert.Equal(t, "feed2", st[1].Feed)
+	assert.Equal(t, "", st[1].Error)
+	assert.Equal(t, 5, st[1].Items)
+}
+
+func TestProcessor_SetConf(t *testing.T) {
+	p := Processor{Conf: &config.Conf{}}
+	p.setStatus("feed1", config.Source{Name: "src1", URL: "http://example.com/1"}, 5, nil)
+	p.setStatus("feed1", config.Source{Name: "src2", URL: "http://example.com/2"}, 5, nil)
+	p.setStatus("feed2", config.Source{Name: "src1", URL: "http://example.com/1"}, 5, nil)
+
+	conf := &config.Conf{Feeds: map[string]config.Feed{
+		"feed1": {Sources: []config.Source{{Name: "src1", URL: "http://example.com/1"}}},
+		"feed3": {Sources: []config.Source{{Name: "src3", URL: "http://example.com/3"}}},
+	}}
+	p.SetConf(conf)
+	assert.Equal(t, conf, p.conf())
+	st := p.Status()
+	require.Equal(t, 1, len(st), "statuses of removed sources dropped")
+	assert.Equal(t, "feed1", st[0].Feed)
+	assert.Equal(t, "src1", st[0].Name)
+
+	bot := TelegramBot{Conf: &config.Conf{}}
+	bot.SetConf(conf)
+	assert.Contains(t, bot.feeds(&tb.Message{}), "feed3")
+}
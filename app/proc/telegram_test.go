@@ -2,6 +2,7 @@ package proc
 
 import (
 	"errors"
+	"fmt"
 	"html/template"
 	"io"
 	"net/http"
@@ -53,7 +54,7 @@ func TestNewTelegramClientCheckTimeout(t *testing.T) {
 func TestSendIfBotIsNil(t *testing.T) {
 	client, err := NewTelegramClient("", "", 0, &duration.Service{}, &TelegramSenderImpl{})
 	require.NoError(t, err)
-	err = client.Send("@channel", feed.Item{})
+	_, err = client.Send("@channel", feed.Item{})
 	assert.NoError(t, err)
 }
 
@@ -62,7 +63,7 @@ func TestSendIfChannelIDEmpty(t *testing.T) {
 		Bot: &tb.Bot{},
 	}
 
-	err := client.Send("", feed.Item{})
+	_, err := client.Send("", feed.Item{})
 	assert.NoError(t, err)
 }
 
@@ -251,7 +252,7 @@ func TestSendIfSendAudioFailed(t *testing.T) {
 	require.NoError(t, err)
 	assert.NotNil(t, tc)
 
-	err = tc.Send("@channel", feed.Item{Enclosure: feed.Enclosure{URL: ts.URL + "/download/some.mp3"}})
+	_, err = tc.Send("@channel", feed.Item{Enclosure: feed.Enclosure{URL: ts.URL + "/download/some.mp3"}})
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "can't send to telegram for")
 
@@ -270,7 +271,7 @@ func TestSend(t *testing.T) {
 
 	snd := &mocks.TelegramSenderMock{
 		SendFunc: func(tb.Audio, *tb.Bot, tb.Recipient, *tb.SendOptions) (*tb.Message, error) {
-			return &tb.Message{Text: "Some test message"}, nil
+			return &tb.Message{ID: 42, Chat: &tb.Chat{ID: -1001484738202}, Text: "Some test message"}, nil
 		},
 	}
 
@@ -279,8 +280,9 @@ func TestSend(t *testing.T) {
 	require.NoError(t, err)
 	assert.NotNil(t, tc)
 
-	err = tc.Send("@channel", feed.Item{Enclosure: feed.Enclosure{URL: ts.URL + "/download/some.mp3"}})
+	ref, err := tc.Send("@channel", feed.Item{Enclosure: feed.Enclosure{URL: ts.URL + "/download/some.mp3"}})
 	assert.NoError(t, err)
+	assert.Equal(t, "audio:-1001484738202:42", ref)
 
 	require.Equal(t, 1, len(snd.SendCalls()))
 	assert.Equal(t, 12345, snd.SendCalls()[0].Audio.Duration)
@@ -312,8 +314,9 @@ func TestSendTextIfAudioLarge(t *testing.T) {
 	require.NoError(t, err)
 	assert.NotNil(t, tc)
 
-	err = tc.Send("@channel", feed.Item{Enclosure: feed.Enclosure{URL: ts.URL + "/download/some.mp3"}})
+	ref, err := tc.Send("@channel", feed.Item{Enclosure: feed.Enclosure{URL: ts.URL + "/download/some.mp3"}})
 	assert.NoError(t, err)
+	assert.Equal(t, "text:0:0", ref, "text message sent instead of audio")
 
 	require.Equal(t, 1, len(snd.SendCalls()))
 	assert.Equal(t, 12345, snd.SendCalls()[0].Audio.Duration)
@@ -324,6 +327,124 @@ func TestSendTextIfAudioLarge(t *testing.T) {
 	assert.Equal(t, "@channel", snd.SendCalls()[0].Recipient.Recipient())
 }
 
+func TestTelegramClient_EditAndDelete(t *testing.T) {
+	var reqs []string
+	ts := mockTelegramServer(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		reqs = append(reqs, r.URL.Path+" "+string(body))
+		if strings.HasSuffix(r.URL.Path, "/deleteMessage") {
+			_, _ = w.Write([]byte(`{"ok": true, "result": true}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"ok": true, "result": {"message_id": 42, "text": "edited"}}`))
+	})
+	defer ts.Close()
+
+	tc, err := NewTelegramClient("test-token", ts.URL, 900*time.Millisecond, &duration.Service{}, &TelegramSenderImpl{})
+	require.NoError(t, err)
+
+	item := feed.Item{Title: "new title", Link: "http://example.com/1", Enclosure: feed.Enclosure{URL: "http://example.com/1.mp3"}}
+	require.NoError(t, tc.Edit("audio:-100123:42", item))
+	require.NoError(t, tc.Edit("text:-100123:43", item))
+	require.NoError(t, tc.Delete("audio:-100123:42"))
+
+	require.Equal(t, 3, len(reqs))
+	assert.Contains(t, reqs[0], "/bottest-token/editMessageCaption")
+	assert.Contains(t, reqs[0], `"message_id":"42"`)
+	assert.Contains(t, reqs[0], `"chat_id":"-100123"`)
+	assert.Contains(t, reqs[0], "new title")
+	assert.Contains(t, reqs[1], "/bottest-token/editMessageText")
+	assert.Contains(t, reqs[1], `"message_id":"43"`)
+	assert.Contains(t, reqs[1], "http://example.com/1.mp3", "text message has mp3 link")
+	assert.Contains(t, reqs[2], "/bottest-token/deleteMessage")
+	assert.Contains(t, reqs[2], `"message_id":"42"`)
+
+	assert.EqualError(t, tc.Delete("bad-ref"), `invalid telegram message reference "bad-ref"`)
+	assert.Equal(t, 3, len(reqs), "no request for invalid reference")
+
+	client := TelegramClient{}
+	assert.NoError(t, client.Edit("audio:1:2", item), "nothing to edit without bot")
+	assert.NoError(t, client.Delete("audio:1:2"), "nothing to delete without bot")
+}
+
+func TestTelegramClient_SendDigest(t *testing.T) {
+	var reqs []string
+	ts := mockTelegramServer(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		reqs = append(reqs, r.URL.Path+" "+string(body))
+		_, _ = w.Write([]byte(`{"ok": true, "result": {"message_id": 42, "chat": {"id": -100123}, "text": "digest"}}`))
+	})
+	defer ts.Close()
+
+	tc, err := NewTelegramClient("test-token", ts.URL, 900*time.Millisecond, &duration.Service{}, &TelegramSenderImpl{})
+	require.NoError(t, err)
+
+	items := []feed.Item{{Title: "title <1>", Link: `http://example.com/1?a=1&b="x"`}, {Title: "title 2"}}
+	refs, err := tc.SendDigest("@chan", items)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"digest:-100123:42", "digest:-100123:42"}, refs)
+	require.Equal(t, 1, len(reqs))
+	assert.Contains(t, reqs[0], "/bottest-token/sendMessage")
+	assert.Contains(t, reqs[0], "2 new items")
+	assert.Contains(t, reqs[0], `title \u0026lt;1\u0026gt;`)
+	assert.Contains(t, reqs[0], `\u003ca href=\"http://example.com/1?a=1\u0026amp;b=\u0026#34;x\u0026#34;\"\u003e`)
+
+	require.NoError(t, tc.EditDigest(refs[0], items[1:]))
+	require.Equal(t, 2, len(reqs))
+	assert.Contains(t, reqs[1], "/bottest-token/editMessageText")
+	assert.Contains(t, reqs[1], `"message_id":"42"`)
+	assert.Contains(t, reqs[1], "1 new items")
+	assert.NotContains(t, reqs[1], "example.com/1")
+
+	refs, err = tc.SendDigest("@chan", nil)
+	require.NoError(t, err)
+	assert.Empty(t, refs)
+	assert.Equal(t, 2, len(reqs), "nothing sent for empty digest")
+
+	// long list split to several messages
+	items = nil
+	for i := 0; i < 100; i++ {
+		items = append(items, feed.Item{Title: strings.Repeat("long title ", 10) + strconv.Itoa(i),
+			Link: "http://example.com/" + strings.Repeat("x", 50) + "/" + strconv.Itoa(i)})
+	}
+	refs, err = tc.SendDigest("@chan", items)
+	require.NoError(t, err)
+	require.Equal(t, 100, len(refs))
+	chunks := tc.digestChunks(items)
+	require.Equal(t, 5, len(chunks))
+	require.Equal(t, 7, len(reqs), "message per chunk")
+	total := 0
+	for i, chunk := range chunks {
+		assert.Equal(t, items[total:total+len(chunk)], chunk, "items kept in order")
+		total += len(chunk)
+		assert.True(t, len(tc.getDigestHTML(chunk)) <= tgMessageLimit, i)
+		assert.Contains(t, reqs[2+i], fmt.Sprintf("%d new items", len(chunk)))
+	}
+	assert.Equal(t, 100, total)
+
+	require.NoError(t, tc.EditDigest(refs[0], items))
+	require.Equal(t, 8, len(reqs))
+	assert.Contains(t, reqs[7], fmt.Sprintf("%d new items", len(chunks[0])), "edited digest cropped")
+
+	// failed message returned with sent ones
+	failed := false
+	ts.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failed {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"ok": false, "error_code": 400, "description": "Bad Request: message is too long"}`))
+			return
+		}
+		failed = true
+		_, _ = w.Write([]byte(`{"ok": true, "result": {"message_id": 43, "chat": {"id": -100123}, "text": "digest"}}`))
+	})
+	refs, err = tc.SendDigest("@chan", items)
+	require.Error(t, err)
+	require.Equal(t, len(chunks[0]), len(refs), "items of the first message")
+	assert.Equal(t, "digest:-100123:43", refs[0])
+}
+
 func TestTelegramSenderImpl_Send(t *testing.T) {
 	ts := mockTelegramServer(nil)
 	defer ts.Close()
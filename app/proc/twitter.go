@@ -39,7 +39,7 @@ func NewTwitterClient(auth TwitterAuth, formatter func(feed.Item) string, twitte
 
 // Send formatted item to twitter
 func (t *TwitterClient) Send(item feed.Item) error {
-	if t.ConsumerKey == "" || t.ConsumerSecret == "" || t.AccessToken == "" || t.AccessSecret == "" {
+	if !t.enabled() {
 		return nil
 	}
 
@@ -49,12 +49,19 @@ func (t *TwitterClient) Send(item feed.Item) error {
 	v.Set("tweet_mode", "extended")
 	msg := t.formatter(item)
 	if _, err := t.tweetPoster.PostTweet(msg, v); err != nil {
+		notificationsTotal.Inc("twitter", "failed")
 		return errors.Wrap(err, "can't send to twitter")
 	}
+	notificationsTotal.Inc("twitter", "sent")
 	log.Printf("[DEBUG] published to twitter %s", strings.ReplaceAll(msg, "\n", " "))
 	return nil
 }
 
+// enabled checks if all credentials are set
+func (t *TwitterClient) enabled() bool {
+	return t.ConsumerKey != "" && t.ConsumerSecret != "" && t.AccessToken != "" && t.AccessSecret != ""
+}
+
 // CleanText removes html tags and shrinks result
 func CleanText(inp string, max int) string {
 	res := striphtmltags.StripTags(inp)
@@ -0,0 +1,137 @@
+package token
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	log "github.com/go-pkgz/lgr"
+)
+
+// Counter counts access of subscribers in memory and writes the counts and last access time to the store in batches,
+// see Flush and Run. Requests of the same media file by the subscriber within Window, i.e. range requests of a player,
+// are counted once.
+type Counter struct {
+	BoltDB
+	Window time.Duration
+
+	lock    sync.Mutex
+	pending map[string]*hit      // not yet stored counts, by subscriber key
+	counted map[string]time.Time // last counted media request, by subscriber key and file
+}
+
+// hit is access counts of the subscriber not written to the store yet
+type hit struct {
+	feed, name string
+	rss, media int64
+	last       time.Time
+}
+
+func (h *hit) apply(sub *Subscriber) {
+	sub.RSSHits += h.rss
+	sub.MediaHits += h.media
+	if h.last.After(sub.LastAccess) {
+		sub.LastAccess = h.last
+	}
+}
+
+// Access finds subscriber by the secret and counts the access, file is the requested media file, empty for rss.
+// Returned subscriber includes counts not written to the store yet.
+func (c *Counter) Access(secret, file string) (res Subscriber, found bool, err error) {
+	if res, found, err = c.FindSubscriber(secret); err != nil || !found {
+		return res, found, err
+	}
+
+	now := time.Now()
+	key := string(hash(secret))
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.pending == nil {
+		c.pending, c.counted = map[string]*hit{}, map[string]time.Time{}
+	}
+	h, ok := c.pending[key]
+	if !ok {
+		h = &hit{feed: res.Feed, name: res.Name}
+		c.pending[key] = h
+	}
+	h.last = now
+	switch {
+	case file == "":
+		h.rss++
+	case now.Sub(c.counted[key+"::"+file]) >= c.Window:
+		h.media++
+		c.counted[key+"::"+file] = now
+	}
+	h.apply(&res)
+	return res, true, nil
+}
+
+// Subscribers returns all subscribers of the feed sorted by name, including counts not written to the store yet
+func (c *Counter) Subscribers(fmFeed string) ([]Subscriber, error) {
+	res, err := c.BoltDB.Subscribers(fmFeed)
+	if err != nil {
+		return nil, err
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for _, h := range c.pending {
+		for i := range res {
+			if res[i].Feed == h.feed && res[i].Name == h.name {
+				h.apply(&res[i])
+			}
+		}
+	}
+	return res, nil
+}
+
+// Flush writes pending counts to the store and forgets counted media requests older than Window
+func (c *Counter) Flush() error {
+	c.lock.Lock()
+	pending := c.pending
+	c.pending = map[string]*hit{}
+	for k, ts := range c.counted {
+		if time.Since(ts) >= c.Window {
+			delete(c.counted, k)
+		}
+	}
+	c.lock.Unlock()
+
+	if len(pending) == 0 {
+		return nil
+	}
+	if err := c.addHits(pending); err != nil {
+		c.lock.Lock()
+		for k, h := range pending { // keep for the next attempt
+			if p, ok := c.pending[k]; ok {
+				h.rss, h.media = h.rss+p.rss, h.media+p.media
+				if p.last.After(h.last) {
+					h.last = p.last
+				}
+			}
+			c.pending[k] = h
+		}
+		c.lock.Unlock()
+		return err
+	}
+	log.Printf("[DEBUG] access counts of %d subscribers stored", len(pending))
+	return nil
+}
+
+// Run flushes counts periodically till context canceled, and once more on exit
+func (c *Counter) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			if err := c.Flush(); err != nil {
+				log.Printf("[WARN] failed to store access counts, %v", err)
+			}
+			return
+		case <-ticker.C:
+			if err := c.Flush(); err != nil {
+				log.Printf("[WARN] failed to store access counts, %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package token
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	log "github.com/go-pkgz/lgr"
+	bolt "go.etcd.io/bbolt"
+)
+
+const subscriberPrefix = "fms_"
+
+var subscribersBkt = []byte("subscribers")
+
+// Subscriber is a named token giving access to a private feed and its media
+type Subscriber struct {
+	Feed       string    `json:"feed"`
+	Name       string    `json:"name"`
+	Created    time.Time `json:"created"`
+	RSSHits    int64     `json:"rss_hits"`
+	MediaHits  int64     `json:"media_hits"`
+	LastAccess time.Time `json:"last_access,omitempty"`
+}
+
+// AddSubscriber makes a new subscriber token for the feed, returns the secret to embed in feed url
+func (b BoltDB) AddSubscriber(fmFeed, name string) (secret string, err error) {
+	if fmFeed == "" || name == "" {
+		return "", fmt.Errorf("empty feed or subscriber name")
+	}
+	if secret, err = newSecret(subscriberPrefix); err != nil {
+		return "", err
+	}
+
+	err = b.DB.Update(func(tx *bolt.Tx) error {
+		bucket, e := tx.CreateBucketIfNotExists(subscribersBkt)
+		if e != nil {
+			return e
+		}
+		if key, _ := b.findSubscriber(bucket, fmFeed, name); key != nil {
+			return fmt.Errorf("subscriber %q of feed %q already exists", name, fmFeed)
+		}
+		jdata, e := json.Marshal(Subscriber{Feed: fmFeed, Name: name, Created: time.Now()})
+		if e != nil {
+			return e
+		}
+		return bucket.Put(hash(secret), jdata)
+	})
+	if err != nil {
+		return "", err
+	}
+	log.Printf("[INFO] subscriber %s added to feed %s", name, fmFeed)
+	return secret, nil
+}
+
+// Subscribers returns all subscribers of the feed sorted by name
+func (b BoltDB) Subscribers(fmFeed string) ([]Subscriber, error) {
+	res := []Subscriber{}
+	err := b.DB.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(subscribersBkt)
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(_, v []byte) error {
+			sub := Subscriber{}
+			if err := json.Unmarshal(v, &sub); err != nil {
+				log.Printf("[WARN] failed to unmarshal subscriber, %v", err)
+				return nil
+			}
+			if sub.Feed == fmFeed {
+				res = append(res, sub)
+			}
+			return nil
+		})
+	})
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+	return res, err
+}
+
+// RevokeSubscriber removes subscriber of the feed by name
+func (b BoltDB) RevokeSubscriber(fmFeed, name string) error {
+	return b.DB.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(subscribersBkt)
+		if bucket == nil {
+			return fmt.Errorf("subscriber %q of feed %q not found", name, fmFeed)
+		}
+		key, _ := b.findSubscriber(bucket, fmFeed, name)
+		if key == nil {
+			return fmt.Errorf("subscriber %q of feed %q not found", name, fmFeed)
+		}
+		log.Printf("[INFO] subscriber %s of feed %s revoked", name, fmFeed)
+		return bucket.Delete(key)
+	})
+}
+
+// FindSubscriber finds subscriber by the secret
+func (b BoltDB) FindSubscriber(secret string) (res Subscriber, found bool, err error) {
+	err = b.DB.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(subscribersBkt)
+		if bucket == nil {
+			return nil
+		}
+		v := bucket.Get(hash(secret))
+		if v == nil {
+			return nil
+		}
+		found = true
+		return json.Unmarshal(v, &res)
+	})
+	return res, found, err
+}
+
+// addHits adds access counts to the stored subscribers in a single transaction, revoked subscribers are skipped
+func (b BoltDB) addHits(hits map[string]*hit) error {
+	return b.DB.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(subscribersBkt)
+		if bucket == nil {
+			return nil
+		}
+		for key, h := range hits {
+			v := bucket.Get([]byte(key))
+			if v == nil {
+				continue
+			}
+			sub := Subscriber{}
+			if e := json.Unmarshal(v, &sub); e != nil {
+				return e
+			}
+			h.apply(&sub)
+			jdata, e := json.Marshal(sub)
+			if e != nil {
+				return e
+			}
+			if e = bucket.Put([]byte(key), jdata); e != nil {
+				return e
+			}
+		}
+		return nil
+	})
+}
+
+func (b BoltDB) findSubscriber(bucket *bolt.Bucket, fmFeed, name string) (key []byte, sub Subscriber) {
+	_ = bucket.ForEach(func(k, v []byte) error {
+		s := Subscriber{}
+		if e := json.Unmarshal(v, &s); e != nil {
+			return nil
+		}
+		if s.Feed == fmFeed && s.Name == name {
+			key, sub = append([]byte(nil), k...), s
+		}
+		return nil
+	})
+	return key, sub
+}
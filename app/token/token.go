@@ -0,0 +1,184 @@
+// Package token provides named api tokens with scopes and subscriber tokens of private feeds.
+// Tokens are stored hashed, the secret is returned once on creation only.
+package token
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	log "github.com/go-pkgz/lgr"
+	"github.com/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+)
+
+// scopes of api tokens
+const (
+	ScopeRead       = "read"        // read-only admin endpoints, i.e. preview
+	ScopeFeedsAdmin = "feeds:admin" // changes of feeds and sources
+	ScopeYTAdmin    = "yt:admin"    // changes of youtube channels and entries
+)
+
+// Scopes lists all known scopes
+var Scopes = []string{ScopeRead, ScopeFeedsAdmin, ScopeYTAdmin}
+
+const secretPrefix = "fm_"
+
+var tokensBkt = []byte("tokens")
+
+// Token is a named api token with scopes
+type Token struct {
+	Name    string    `json:"name"`
+	Scopes  []string  `json:"scopes"`
+	Created time.Time `json:"created"`
+}
+
+// HasScope checks if token has the scope
+func (t Token) HasScope(scope string) bool {
+	for _, s := range t.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// BoltDB stores tokens keyed by sha256 of the secret
+type BoltDB struct {
+	DB *bolt.DB
+}
+
+// Add makes a new token with the name and scopes, returns the secret to pass as bearer token
+func (b BoltDB) Add(name string, scopes []string) (secret string, err error) {
+	if name == "" {
+		return "", errors.New("empty token name")
+	}
+	if len(scopes) == 0 {
+		return "", errors.New("no scopes for token")
+	}
+	for _, s := range scopes {
+		if !(Token{Scopes: Scopes}).HasScope(s) { // all known scopes
+			return "", fmt.Errorf("unknown scope %q, should be one of %v", s, Scopes)
+		}
+	}
+
+	if secret, err = newSecret(secretPrefix); err != nil {
+		return "", err
+	}
+
+	err = b.DB.Update(func(tx *bolt.Tx) error {
+		bucket, e := tx.CreateBucketIfNotExists(tokensBkt)
+		if e != nil {
+			return e
+		}
+		_, found, e := b.findByName(bucket, name)
+		if e != nil {
+			return e
+		}
+		if found {
+			return fmt.Errorf("token %q already exists", name)
+		}
+		jdata, e := json.Marshal(Token{Name: name, Scopes: scopes, Created: time.Now()})
+		if e != nil {
+			return e
+		}
+		return bucket.Put(hash(secret), jdata)
+	})
+	if err != nil {
+		return "", err
+	}
+	log.Printf("[INFO] api token %s added, scopes %v", name, scopes)
+	return secret, nil
+}
+
+// List returns all tokens sorted by name
+func (b BoltDB) List() ([]Token, error) {
+	res := []Token{}
+	err := b.DB.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(tokensBkt)
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(_, v []byte) error {
+			t := Token{}
+			if err := json.Unmarshal(v, &t); err != nil {
+				log.Printf("[WARN] failed to unmarshal token, %v", err)
+				return nil
+			}
+			res = append(res, t)
+			return nil
+		})
+	})
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+	return res, err
+}
+
+// Revoke removes token by name
+func (b BoltDB) Revoke(name string) error {
+	return b.DB.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(tokensBkt)
+		if bucket == nil {
+			return fmt.Errorf("token %q not found", name)
+		}
+		key, found, err := b.findByName(bucket, name)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return fmt.Errorf("token %q not found", name)
+		}
+		log.Printf("[INFO] api token %s revoked", name)
+		return bucket.Delete(key)
+	})
+}
+
+// Find returns token by the secret
+func (b BoltDB) Find(secret string) (res Token, found bool, err error) {
+	err = b.DB.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(tokensBkt)
+		if bucket == nil {
+			return nil
+		}
+		v := bucket.Get(hash(secret))
+		if v == nil {
+			return nil
+		}
+		found = true
+		return json.Unmarshal(v, &res)
+	})
+	return res, found, err
+}
+
+func (b BoltDB) findByName(bucket *bolt.Bucket, name string) (key []byte, found bool, err error) {
+	err = bucket.ForEach(func(k, v []byte) error {
+		t := Token{}
+		if e := json.Unmarshal(v, &t); e != nil {
+			return nil
+		}
+		if t.Name == name {
+			key, found = append([]byte(nil), k...), true
+		}
+		return nil
+	})
+	return key, found, err
+}
+
+// newSecret makes random url-safe secret with the prefix
+func newSecret(prefix string) (string, error) {
+	rnd := make([]byte, 32)
+	if _, err := rand.Read(rnd); err != nil {
+		return "", errors.Wrap(err, "can't make token secret")
+	}
+	return prefix + base64.RawURLEncoding.EncodeToString(rnd), nil
+}
+
+// hash returns key of the secret, sha256 is enough for random secrets
+func hash(secret string) []byte {
+	h := sha256.Sum256([]byte(secret))
+	return []byte(hex.EncodeToString(h[:]))
+}
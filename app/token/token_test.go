@@ -0,0 +1,176 @@
+package token
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestBoltDB(t *testing.T) {
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "test.db"), 0o600, &bolt.Options{Timeout: time.Second})
+	require.NoError(t, err)
+	b := BoltDB{DB: db}
+
+	list, err := b.List()
+	require.NoError(t, err)
+	assert.Empty(t, list)
+	_, found, err := b.Find("fm_something")
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	secret1, err := b.Add("ops-ui", []string{ScopeRead, ScopeFeedsAdmin})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(secret1, "fm_"))
+	secret2, err := b.Add("ci", []string{ScopeYTAdmin})
+	require.NoError(t, err)
+	assert.NotEqual(t, secret1, secret2)
+
+	_, err = b.Add("ops-ui", []string{ScopeRead})
+	assert.EqualError(t, err, `token "ops-ui" already exists`)
+	_, err = b.Add("other", []string{"admin"})
+	assert.EqualError(t, err, `unknown scope "admin", should be one of [read feeds:admin yt:admin]`)
+	_, err = b.Add("other", nil)
+	assert.EqualError(t, err, "no scopes for token")
+	_, err = b.Add("", []string{ScopeRead})
+	assert.EqualError(t, err, "empty token name")
+
+	tkn, found, err := b.Find(secret1)
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.Equal(t, "ops-ui", tkn.Name)
+	assert.True(t, tkn.HasScope(ScopeFeedsAdmin))
+	assert.False(t, tkn.HasScope(ScopeYTAdmin))
+
+	list, err = b.List()
+	require.NoError(t, err)
+	require.Equal(t, 2, len(list))
+	assert.Equal(t, "ci", list[0].Name)
+	assert.Equal(t, "ops-ui", list[1].Name)
+
+	require.NoError(t, b.Revoke("ops-ui"))
+	_, found, err = b.Find(secret1)
+	require.NoError(t, err)
+	assert.False(t, found)
+	assert.EqualError(t, b.Revoke("ops-ui"), `token "ops-ui" not found`)
+
+	// secrets are not stored
+	err = db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(tokensBkt).ForEach(func(k, v []byte) error {
+			assert.NotContains(t, string(k)+string(v), secret2)
+			return nil
+		})
+	})
+	require.NoError(t, err)
+}
+
+func TestBoltDB_Subscribers(t *testing.T) {
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "test.db"), 0o600, &bolt.Options{Timeout: time.Second})
+	require.NoError(t, err)
+	b := BoltDB{DB: db}
+
+	_, found, err := b.FindSubscriber("fms_something")
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	secret1, err := b.AddSubscriber("internal", "alice")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(secret1, "fms_"))
+	_, err = b.AddSubscriber("internal", "bob")
+	require.NoError(t, err)
+	_, err = b.AddSubscriber("other", "alice")
+	require.NoError(t, err)
+	_, err = b.AddSubscriber("internal", "alice")
+	assert.EqualError(t, err, `subscriber "alice" of feed "internal" already exists`)
+
+	sub, found, err := b.FindSubscriber(secret1)
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.Equal(t, "internal", sub.Feed)
+	assert.Equal(t, "alice", sub.Name)
+
+	subs, err := b.Subscribers("internal")
+	require.NoError(t, err)
+	require.Equal(t, 2, len(subs))
+	assert.Equal(t, "alice", subs[0].Name)
+	assert.Equal(t, "bob", subs[1].Name)
+
+	require.NoError(t, b.RevokeSubscriber("internal", "alice"))
+	_, found, err = b.FindSubscriber(secret1)
+	require.NoError(t, err)
+	assert.False(t, found)
+	assert.EqualError(t, b.RevokeSubscriber("internal", "alice"), `subscriber "alice" of feed "internal" not found`)
+	subs, err = b.Subscribers("other")
+	require.NoError(t, err)
+	assert.Equal(t, 1, len(subs))
+}
+
+func TestCounter(t *testing.T) {
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "test.db"), 0o600, &bolt.Options{Timeout: time.Second})
+	require.NoError(t, err)
+	c := &Counter{BoltDB: BoltDB{DB: db}, Window: time.Hour}
+
+	_, found, err := c.Access("fms_something", "")
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	secret1, err := c.AddSubscriber("internal", "alice")
+	require.NoError(t, err)
+	secret2, err := c.AddSubscriber("internal", "bob")
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		_, _, err = c.Access(secret1, "")
+		require.NoError(t, err)
+		_, _, err = c.Access(secret1, "v1.mp3") // range requests of the same file
+		require.NoError(t, err)
+	}
+	sub, found, err := c.Access(secret1, "v2.mp3")
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.Equal(t, "alice", sub.Name)
+	assert.Equal(t, int64(3), sub.RSSHits)
+	assert.Equal(t, int64(2), sub.MediaHits, "one hit per file")
+	assert.False(t, sub.LastAccess.IsZero())
+	_, _, err = c.Access(secret2, "v1.mp3")
+	require.NoError(t, err)
+
+	stored, _, err := c.FindSubscriber(secret1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), stored.RSSHits, "not stored before flush")
+	subs, err := c.Subscribers("internal")
+	require.NoError(t, err)
+	require.Equal(t, 2, len(subs))
+	assert.Equal(t, int64(3), subs[0].RSSHits, "pending counts included")
+	assert.Equal(t, int64(1), subs[1].MediaHits)
+
+	require.NoError(t, c.Flush())
+	stored, _, err = c.FindSubscriber(secret1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), stored.RSSHits)
+	assert.Equal(t, int64(2), stored.MediaHits)
+	assert.Equal(t, sub.LastAccess.Unix(), stored.LastAccess.Unix())
+
+	// counted media request kept within window after flush
+	sub, _, err = c.Access(secret1, "v1.mp3")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), sub.MediaHits)
+
+	// revoked subscriber skipped
+	require.NoError(t, c.RevokeSubscriber("internal", "bob"))
+	require.NoError(t, c.Flush())
+	subs, err = c.Subscribers("internal")
+	require.NoError(t, err)
+	require.Equal(t, 1, len(subs))
+	assert.Equal(t, int64(2), subs[0].MediaHits)
+
+	c.Window = 0
+	require.NoError(t, c.Flush())
+	sub, _, err = c.Access(secret1, "v1.mp3")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), sub.MediaHits, "counted again after window")
+}
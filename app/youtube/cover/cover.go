@@ -0,0 +1,157 @@
+// Package cover loads images for cover art of downloaded files, scales them down and encodes as jpeg
+package cover
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif" // register gif decoder
+	"image/jpeg"
+	_ "image/png" // register png decoder
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultMaxSize = 600
+	maxImageBytes  = 20 * 1024 * 1024
+	maxImagePixels = 25_000_000 // decoded image takes 4 bytes per pixel at least, limits small files with huge dimensions
+)
+
+// Loader loads images from remote urls or local files
+type Loader struct {
+	Client  *http.Client
+	MaxSize int // max width and height in pixels, larger images are scaled down to fit, 600 if not set
+}
+
+// Get loads the image from http(s) url or local file and returns it as jpeg, scaled down to fit MaxSize.
+// Supported source formats are jpeg, png and gif.
+func (l *Loader) Get(ctx context.Context, src string) ([]byte, error) {
+	img, err := l.load(ctx, src)
+	if err != nil {
+		return nil, err
+	}
+	maxSize := l.MaxSize
+	if maxSize <= 0 {
+		maxSize = defaultMaxSize
+	}
+	return encode(Resize(img, maxSize))
+}
+
+// Square loads the image the same way as Get and returns it as square jpeg of size x size pixels
+func (l *Loader) Square(ctx context.Context, src string, size int) ([]byte, error) {
+	img, err := l.load(ctx, src)
+	if err != nil {
+		return nil, err
+	}
+	return encode(Square(img, size))
+}
+
+func (l *Loader) load(ctx context.Context, src string) (image.Image, error) {
+	var rd io.Reader
+	if strings.HasPrefix(src, "http://") || strings.HasPrefix(src, "https://") {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, src, http.NoBody)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to make request for %s", src)
+		}
+		client := l.Client
+		if client == nil {
+			client = http.DefaultClient
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get %s", src)
+		}
+		defer resp.Body.Close() // nolint
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("failed to get %s, status %d", src, resp.StatusCode)
+		}
+		rd = resp.Body
+	} else {
+		fh, err := os.Open(src) // nolint
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to open image")
+		}
+		defer fh.Close() // nolint
+		rd = fh
+	}
+
+	data, err := io.ReadAll(io.LimitReader(rd, maxImageBytes))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read image %s", src)
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to decode image config %s", src)
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width > maxImagePixels/cfg.Height {
+		return nil, fmt.Errorf("image %s is too large, %dx%d", src, cfg.Width, cfg.Height)
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to decode image %s", src)
+	}
+	return img, nil
+}
+
+// Resize scales the image down to fit maxSize x maxSize box, keeping aspect ratio.
+// Images smaller than the box are returned as is.
+func Resize(img image.Image, maxSize int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if maxSize <= 0 || (w <= maxSize && h <= maxSize) {
+		return img
+	}
+	nw, nh := maxSize, max(1, h*maxSize/w)
+	if h > w {
+		nw, nh = max(1, w*maxSize/h), maxSize
+	}
+	return scale(img, b, nw, nh)
+}
+
+// Square crops the center square of the image and scales it to size x size, up or down.
+// Youtube thumbnails are 16:9 or 4:3 with black bars, so their sides are cropped.
+func Square(img image.Image, size int) image.Image {
+	b := img.Bounds()
+	side := min(b.Dx(), b.Dy())
+	x0, y0 := b.Min.X+(b.Dx()-side)/2, b.Min.Y+(b.Dy()-side)/2
+	return scale(img, image.Rect(x0, y0, x0+side, y0+side), size, size)
+}
+
+// scale makes nw x nh image from the rect of the source image. Downscaled pixel is an average of the source pixels
+// it covers, upscaled one is the nearest source pixel.
+func scale(img image.Image, rect image.Rectangle, nw, nh int) image.Image {
+	w, h := rect.Dx(), rect.Dy()
+	res := image.NewRGBA(image.Rect(0, 0, nw, nh))
+	for y := 0; y < nh; y++ {
+		y0 := rect.Min.Y + y*h/nh
+		y1 := max(rect.Min.Y+(y+1)*h/nh, y0+1)
+		for x := 0; x < nw; x++ {
+			x0 := rect.Min.X + x*w/nw
+			x1 := max(rect.Min.X+(x+1)*w/nw, x0+1)
+			var sr, sg, sb, sa, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := img.At(sx, sy).RGBA()
+					sr, sg, sb, sa, n = sr+uint64(cr), sg+uint64(cg), sb+uint64(cb), sa+uint64(ca), n+1
+				}
+			}
+			res.Set(x, y, color.RGBA64{R: uint16(sr / n), G: uint16(sg / n), B: uint16(sb / n), A: uint16(sa / n)})
+		}
+	}
+	return res
+}
+
+func encode(img image.Image) ([]byte, error) {
+	buf := bytes.Buffer{}
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 90}); err != nil {
+		return nil, errors.Wrap(err, "failed to encode jpeg")
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,141 @@
+package cover
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoader_Get(t *testing.T) {
+	pngData := bytes.Buffer{}
+	require.NoError(t, png.Encode(&pngData, solid(1280, 720, color.RGBA{R: 255, A: 255})))
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/img.png":
+			_, _ = w.Write(pngData.Bytes())
+		case "/bad.png":
+			_, _ = w.Write([]byte("not an image"))
+		case "/huge.gif": // gif header of 65535x65535 image without data
+			_, _ = w.Write([]byte("GIF89a\xff\xff\xff\xff\x00\x00\x00"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	l := Loader{Client: ts.Client(), MaxSize: 320}
+	res, err := l.Get(context.Background(), ts.URL+"/img.png")
+	require.NoError(t, err)
+	img, err := jpeg.Decode(bytes.NewReader(res))
+	require.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 320, 180), img.Bounds())
+	r, g, b, _ := img.At(100, 100).RGBA()
+	assert.True(t, r > 0xf000 && g < 0x1000 && b < 0x1000, "red kept")
+
+	file := filepath.Join(t.TempDir(), "cover.png")
+	require.NoError(t, os.WriteFile(file, pngData.Bytes(), 0o600))
+	res, err = (&Loader{}).Get(context.Background(), file)
+	require.NoError(t, err)
+	img, err = jpeg.Decode(bytes.NewReader(res))
+	require.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 600, 337), img.Bounds(), "default size")
+
+	_, err = l.Get(context.Background(), ts.URL+"/bad.png")
+	assert.ErrorContains(t, err, "failed to decode image")
+	_, err = l.Get(context.Background(), ts.URL+"/huge.gif")
+	assert.ErrorContains(t, err, "is too large, 65535x65535")
+	_, err = l.Get(context.Background(), ts.URL+"/not-found.png")
+	assert.ErrorContains(t, err, "status 404")
+	_, err = l.Get(context.Background(), filepath.Join(t.TempDir(), "not-found.png"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestResize(t *testing.T) {
+	tbl := []struct {
+		w, h, size int
+		res        image.Rectangle
+	}{
+		{1280, 720, 600, image.Rect(0, 0, 600, 337)},
+		{720, 1280, 600, image.Rect(0, 0, 337, 600)},
+		{1500, 1500, 1400, image.Rect(0, 0, 1400, 1400)},
+		{300, 200, 600, image.Rect(0, 0, 300, 200)},
+		{300, 200, 0, image.Rect(0, 0, 300, 200)},
+		{5000, 1, 100, image.Rect(0, 0, 100, 1)},
+	}
+	for _, tt := range tbl {
+		assert.Equal(t, tt.res, Resize(solid(tt.w, tt.h, color.White), tt.size).Bounds(), "%dx%d to %d", tt.w, tt.h, tt.size)
+	}
+
+	// stripes of black and white averaged to gray
+	img := image.NewGray(image.Rect(0, 0, 4, 2))
+	for x := 0; x < 4; x++ {
+		img.SetGray(x, 0, color.Gray{Y: 255})
+	}
+	res := Resize(img, 2)
+	assert.Equal(t, image.Rect(0, 0, 2, 1), res.Bounds())
+	r, _, _, _ := res.At(0, 0).RGBA()
+	assert.Equal(t, uint32(0x7f7f), r)
+}
+
+func TestSquare(t *testing.T) {
+	// red center with blue sides, like 4:3 thumbnail with black bars
+	img := image.NewRGBA(image.Rect(0, 0, 160, 90))
+	for y := 0; y < 90; y++ {
+		for x := 0; x < 160; x++ {
+			c := color.RGBA{B: 255, A: 255}
+			if x >= 35 && x < 125 {
+				c = color.RGBA{R: 255, A: 255}
+			}
+			img.Set(x, y, c)
+		}
+	}
+
+	res := Square(img, 200)
+	assert.Equal(t, image.Rect(0, 0, 200, 200), res.Bounds(), "upscaled")
+	for _, pt := range []image.Point{{0, 0}, {199, 0}, {100, 100}, {0, 199}, {199, 199}} {
+		r, _, b, _ := res.At(pt.X, pt.Y).RGBA()
+		assert.Equal(t, uint32(0xffff), r, "%v is red", pt)
+		assert.Equal(t, uint32(0), b, "%v is not blue", pt)
+	}
+
+	res = Square(img, 30)
+	assert.Equal(t, image.Rect(0, 0, 30, 30), res.Bounds(), "downscaled")
+
+	res = Square(solid(90, 160, color.White), 50)
+	assert.Equal(t, image.Rect(0, 0, 50, 50), res.Bounds(), "portrait")
+
+	pngData := bytes.Buffer{}
+	require.NoError(t, png.Encode(&pngData, img))
+	file := filepath.Join(t.TempDir(), "thumb.png")
+	require.NoError(t, os.WriteFile(file, pngData.Bytes(), 0o600))
+	data, err := (&Loader{}).Square(context.Background(), file, 64)
+	require.NoError(t, err)
+	jpg, err := jpeg.Decode(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 64, 64), jpg.Bounds())
+
+	_, err = (&Loader{}).Square(context.Background(), filepath.Join(t.TempDir(), "not-found.png"), 64)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func solid(w, h int, c color.Color) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
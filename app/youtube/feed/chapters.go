@@ -0,0 +1,83 @@
+package feed
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// chapterRe matches description lines like "00:00 Intro", "1:02:03 - Part two" or "[12:30] Q&A"
+var chapterRe = regexp.MustCompile(`^[\[(]?((?:\d{1,2}:)?\d{1,2}:\d{2})[\])]?\s*[-–—:|.]?\s+(\S.*)$`)
+
+// ParseChapters extracts chapters from timestamped lines of the description, the same way youtube does.
+// The first timestamp should be 00:00 and timestamps should go up, otherwise the lines are not chapters
+// and nil is returned. The last chapter ends at duration (in seconds), or has no end if duration is unknown.
+func ParseChapters(description string, duration float64) []Chapter {
+	res := []Chapter{}
+	for _, line := range strings.Split(description, "\n") {
+		m := chapterRe.FindStringSubmatch(strings.TrimSpace(line))
+		if m == nil {
+			continue
+		}
+		start := parseTimestamp(m[1])
+		if len(res) == 0 && start != 0 {
+			return nil
+		}
+		if len(res) > 0 {
+			if start <= res[len(res)-1].Start {
+				return nil
+			}
+			res[len(res)-1].End = start
+		}
+		res = append(res, Chapter{Start: start, Title: strings.TrimSpace(m[2])})
+	}
+	if len(res) < 2 {
+		return nil
+	}
+	if duration > res[len(res)-1].Start {
+		res[len(res)-1].End = duration
+	}
+	return res
+}
+
+// parseTimestamp converts [hh:]mm:ss to seconds
+func parseTimestamp(ts string) (res float64) {
+	for _, part := range strings.Split(ts, ":") {
+		v, _ := strconv.Atoi(part) // nolint, matched by regex as digits
+		res = res*60 + float64(v)
+	}
+	return res
+}
+
+// ChaptersFile returns the name of chapters file for the downloaded file
+func ChaptersFile(file string) string {
+	return strings.TrimSuffix(file, filepath.Ext(file)) + ".chapters.json"
+}
+
+// WriteChapters saves chapters of the downloaded file in podcasting 2.0 json chapters format,
+// see https://github.com/Podcastindex-org/podcast-namespace/blob/main/chapters/jsonChapters.md
+func WriteChapters(file string, chapters []Chapter) error {
+	type podcastChapter struct {
+		StartTime float64 `json:"startTime"`
+		EndTime   float64 `json:"endTime,omitempty"`
+		Title     string  `json:"title"`
+	}
+	pc := struct {
+		Version  string           `json:"version"`
+		Chapters []podcastChapter `json:"chapters"`
+	}{Version: "1.2.0", Chapters: make([]podcastChapter, 0, len(chapters))}
+	for _, c := range chapters {
+		pc.Chapters = append(pc.Chapters, podcastChapter{StartTime: c.Start, EndTime: c.End, Title: c.Title})
+	}
+
+	data, err := json.MarshalIndent(pc, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal chapters")
+	}
+	return errors.Wrapf(os.WriteFile(ChaptersFile(file), data, 0o644), "failed to write chapters") // nolint
+}
@@ -0,0 +1,55 @@
+package feed
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseChapters(t *testing.T) {
+	tbl := []struct {
+		name     string
+		descr    string
+		duration float64
+		res      []Chapter
+	}{
+		{name: "empty", descr: "", res: nil},
+		{name: "no timestamps", descr: "some text\nmore text", res: nil},
+		{name: "simple", descr: "intro text\n00:00 Intro\n01:30 Main part\n1:02:03 Outro\nthanks", duration: 4000,
+			res: []Chapter{{Start: 0, End: 90, Title: "Intro"}, {Start: 90, End: 3723, Title: "Main part"},
+				{Start: 3723, End: 4000, Title: "Outro"}}},
+		{name: "separators and brackets", descr: "[0:00] Intro\n 2:15 - Q&A: part 1 \n(10:00) | End",
+			res: []Chapter{{Start: 0, End: 135, Title: "Intro"}, {Start: 135, End: 600, Title: "Q&A: part 1"},
+				{Start: 600, Title: "End"}}},
+		{name: "not from zero", descr: "01:00 first\n02:00 second", res: nil},
+		{name: "not ordered", descr: "00:00 first\n02:00 second\n01:00 third", res: nil},
+		{name: "single", descr: "00:00 first", res: nil},
+		{name: "timestamp in text", descr: "see at 00:00 here\nand 01:00 there", res: nil},
+		{name: "duration before last start", descr: "00:00 first\n02:00 second", duration: 60,
+			res: []Chapter{{Start: 0, End: 120, Title: "first"}, {Start: 120, Title: "second"}}},
+	}
+
+	for _, tt := range tbl {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.res, ParseChapters(tt.descr, tt.duration))
+		})
+	}
+}
+
+func TestWriteChapters(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "f1.mp3")
+	assert.Equal(t, filepath.Join(filepath.Dir(file), "f1.chapters.json"), ChaptersFile(file))
+
+	err := WriteChapters(file, []Chapter{{Start: 0, End: 90, Title: "Intro"}, {Start: 90, Title: "Main part"}})
+	require.NoError(t, err)
+	data, err := os.ReadFile(ChaptersFile(file))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"version": "1.2.0", "chapters": [{"startTime": 0, "endTime": 90, "title": "Intro"},
+		{"startTime": 90, "title": "Main part"}]}`, string(data))
+
+	err = WriteChapters(filepath.Join(file, "not-a-dir", "f2.mp3"), nil)
+	assert.Error(t, err)
+}
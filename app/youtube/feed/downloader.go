@@ -8,6 +8,9 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
 	"text/template"
 
 	log "github.com/go-pkgz/lgr"
@@ -18,6 +21,49 @@ import (
 // ErrSkip is returned when the file is not downloaded
 var ErrSkip = errors.New("skip")
 
+const (
+	// DefaultFormat is the format of downloaded files if not set in DownloadOptions
+	DefaultFormat = "mp3"
+	// DefaultResolution is the max height of downloaded videos if not set in DownloadOptions
+	DefaultResolution = 720
+)
+
+var formatRe = regexp.MustCompile(`^[a-z0-9]+$`)
+
+// mediaTypes maps extensions of downloaded files to mime types
+var mediaTypes = map[string]string{
+	".mp3":  "audio/mpeg",
+	".m4a":  "audio/mp4",
+	".aac":  "audio/aac",
+	".opus": "audio/ogg",
+	".ogg":  "audio/ogg",
+	".flac": "audio/flac",
+	".wav":  "audio/wav",
+	".webm": "audio/webm",
+	".mp4":  "video/mp4",
+}
+
+// DownloadOptions overrides defaults of the downloader for the channel, empty values are not overridden
+type DownloadOptions struct {
+	Template string // command template, the same as NewDownloader's one
+	Format   string // extension of the result, i.e. mp3, m4a or opus, passed to the template as {{.Format}}
+	// max video height passed to the template as {{.Resolution}}, DefaultResolution if not set
+	Resolution int
+}
+
+// ValidFormat checks if the format can be passed to the download command, it is a part of shell command
+func ValidFormat(format string) bool {
+	return formatRe.MatchString(format)
+}
+
+// MimeType returns mime type of downloaded file by its extension, audio/mpeg if unknown
+func MimeType(file string) string {
+	if res, ok := mediaTypes[strings.ToLower(filepath.Ext(file))]; ok {
+		return res
+	}
+	return "audio/mpeg"
+}
+
 // Downloader executes an external command to download a video and extract its audio.
 type Downloader struct {
 	ytTemplate   string
@@ -26,7 +72,8 @@ type Downloader struct {
 	destination  string
 }
 
-// NewDownloader creates a new Downloader with the given template (full command with placeholders for {{.ID}} and {{.Filename}}.
+// NewDownloader creates a new Downloader with the given template (full command with placeholders for {{.ID}},
+// {{.FileName}}, optional {{.Format}} and {{.Resolution}}).
 // Destination is the directory where the audio files will be stored.
 func NewDownloader(tmpl string, logOutWriter, logErrWriter io.Writer, destination string) *Downloader {
 	return &Downloader{
@@ -38,24 +85,49 @@ func NewDownloader(tmpl string, logOutWriter, logErrWriter io.Writer, destinatio
 }
 
 // Get downloads a video from youtube and extracts audio.
-// yt-dlp --extract-audio --audio-format=mp3 --audio-quality=0 -f m4a/bestaudio "https://www.youtube.com/watch?v={{.ID}}" --no-progress -o {{.Filename}}
-func (d *Downloader) Get(ctx context.Context, id, fname string) (file string, err error) {
+// yt-dlp --extract-audio --audio-format={{.Format}} --audio-quality=0 -f m4a/bestaudio "https://www.youtube.com/watch?v={{.ID}}" --no-progress --write-info-json -o {{.FileName}}
+// With --write-info-json metadata is written next to the file, see LoadInfo.
+// The result is the file with extension of the format, or with any other known media extension
+// if the command produced a different one, i.e. the template has fixed format.
+func (d *Downloader) Get(ctx context.Context, id, fname string, opts DownloadOptions) (file string, err error) {
 
 	if err := os.MkdirAll(d.destination, 0o750); err != nil {
 		return "", errors.Wrapf(err, "failed to create directory %s", d.destination)
 	}
 
+	tmpl, format, resolution := d.ytTemplate, DefaultFormat, DefaultResolution
+	if opts.Template != "" {
+		tmpl = opts.Template
+	}
+	if opts.Format != "" {
+		format = opts.Format
+	}
+	if opts.Resolution > 0 {
+		resolution = opts.Resolution
+	}
+	if !ValidFormat(format) {
+		return "", fmt.Errorf("invalid format %q", format)
+	}
+
 	tmplParams := struct {
-		ID       string
-		FileName string
+		ID         string
+		FileName   string
+		Format     string
+		Resolution int
 	}{
-		ID:       id,
-		FileName: fname,
+		ID:         id,
+		FileName:   fname,
+		Format:     format,
+		Resolution: resolution,
 	}
 	b1 := bytes.Buffer{}
-	if err := template.Must(template.New("youtube-dl").Parse(d.ytTemplate)).Execute(&b1, tmplParams); err != nil { // nolint
+	t, err := template.New("youtube-dl").Parse(tmpl)
+	if err != nil {
 		return "", fmt.Errorf("failed to parse template: %v", err)
 	}
+	if err := t.Execute(&b1, tmplParams); err != nil {
+		return "", fmt.Errorf("failed to execute template: %v", err)
+	}
 
 	cmd := exec.CommandContext(ctx, "sh", "-c", b1.String()) // nolint
 	cmd.Stdin = os.Stdin
@@ -67,9 +139,19 @@ func (d *Downloader) Get(ctx context.Context, id, fname string) (file string, er
 		return "", fmt.Errorf("failed to execute command: %v", err)
 	}
 
-	file = filepath.Join(d.destination, fname+".mp3")
-	if _, err := os.Stat(file); os.IsNotExist(err) {
-		return file, ErrSkip
+	file = filepath.Join(d.destination, fname+"."+format)
+	if _, err := os.Stat(file); err == nil {
+		return file, nil
+	}
+	exts := make([]string, 0, len(mediaTypes))
+	for ext := range mediaTypes {
+		exts = append(exts, ext)
+	}
+	sort.Strings(exts)
+	for _, ext := range exts {
+		if _, err := os.Stat(filepath.Join(d.destination, fname+ext)); err == nil {
+			return filepath.Join(d.destination, fname+ext), nil
+		}
 	}
-	return file, nil
+	return file, ErrSkip
 }
@@ -24,7 +24,7 @@ func TestDownloader_Get(t *testing.T) {
 	fname := filepath.Base(fh.Name())
 
 	d := NewDownloader("echo {{.ID}} blah {{.FileName}}.mp3 12345", lw, lw, loc)
-	res, err := d.Get(context.Background(), "id1", strings.TrimSuffix(fname, path.Ext(fname)))
+	res, err := d.Get(context.Background(), "id1", strings.TrimSuffix(fname, path.Ext(fname)), DownloadOptions{})
 	require.NoError(t, err)
 	assert.Equal(t, fh.Name(), res)
 	l := lw.String()
@@ -41,7 +41,7 @@ func TestDownloader_GetSkip(t *testing.T) {
 
 	fname := filepath.Base(fh.Name())
 	d := NewDownloader("echo {{.ID}} blah {{.FileName}} 12345", lw, lw, loc)
-	res, err := d.Get(context.Background(), "id1", fname)
+	res, err := d.Get(context.Background(), "id1", fname, DownloadOptions{})
 	require.EqualError(t, err, "skip")
 	assert.Equal(t, fh.Name()+".mp3", res)
 }
@@ -56,7 +56,92 @@ func TestDownloader_GetFailed(t *testing.T) {
 	fname := filepath.Base(fh.Name())
 
 	d := NewDownloader("echo {{.ID}} blah {{.FileName}}.mp3 12345", lw, lw, loc)
-	res, err := d.Get(context.Background(), "id1", strings.TrimSuffix(fname, path.Ext(fname)))
+	res, err := d.Get(context.Background(), "id1", strings.TrimSuffix(fname, path.Ext(fname)), DownloadOptions{})
 	require.EqualError(t, err, "skip")
 	assert.Equal(t, fh.Name(), res)
 }
+
+func TestDownloader_GetWithInfo(t *testing.T) {
+	lw := bytes.NewBuffer(nil)
+	loc := t.TempDir()
+	// fake yt-dlp writes audio and metadata the same way as the real one with --write-info-json
+	d := NewDownloader("touch {{.FileName}}.mp3 && cp "+filepath.Join(mustAbs(t, "testdata"), "info.info.json")+
+		" {{.FileName}}.info.json", lw, lw, loc)
+	res, err := d.Get(context.Background(), "vid1", "f1", DownloadOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(loc, "f1.mp3"), res)
+
+	info, err := LoadInfo(res)
+	require.NoError(t, err)
+	assert.Equal(t, "vid1", info.ID)
+	assert.Equal(t, 3599.6, info.Duration)
+	assert.Equal(t, 2, len(info.Chapters))
+	assert.Equal(t, "was_live", info.LiveStatus)
+
+	_, err = LoadInfo(filepath.Join(loc, "f2.mp3"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func mustAbs(t *testing.T, p string) string {
+	res, err := filepath.Abs(p)
+	require.NoError(t, err)
+	return res
+}
+
+func TestDownloader_GetOptions(t *testing.T) {
+	lw := bytes.NewBuffer(nil)
+	loc := t.TempDir()
+	d := NewDownloader("echo {{.Format}} && touch {{.FileName}}.{{.Format}}", lw, lw, loc)
+
+	res, err := d.Get(context.Background(), "vid1", "f1", DownloadOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(loc, "f1.mp3"), res, "default format")
+
+	res, err = d.Get(context.Background(), "vid1", "f2", DownloadOptions{Format: "opus"})
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(loc, "f2.opus"), res)
+	assert.Equal(t, "mp3\nopus\n", lw.String())
+
+	// template with fixed format, the result found by extension
+	res, err = d.Get(context.Background(), "vid1", "f3", DownloadOptions{Template: "touch {{.FileName}}.m4a"})
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(loc, "f3.m4a"), res)
+
+	res, err = d.Get(context.Background(), "vid1", "f4", DownloadOptions{Template: "touch {{.FileName}}.info.json",
+		Format: "m4a"})
+	assert.Equal(t, ErrSkip, err, "no media file")
+	assert.Equal(t, filepath.Join(loc, "f4.m4a"), res)
+
+	_, err = d.Get(context.Background(), "vid1", "f5", DownloadOptions{Format: "mp3; rm -rf /"})
+	assert.EqualError(t, err, `invalid format "mp3; rm -rf /"`)
+	_, err = d.Get(context.Background(), "vid1", "f5", DownloadOptions{Template: "echo {{.ID"})
+	assert.ErrorContains(t, err, "failed to parse template")
+
+	// video with resolution, default 720 if not set
+	lw.Reset()
+	tmpl := "echo {{.Resolution}} && touch {{.FileName}}.{{.Format}}"
+	res, err = d.Get(context.Background(), "vid1", "f6", DownloadOptions{Template: tmpl, Format: "mp4"})
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(loc, "f6.mp4"), res)
+	_, err = d.Get(context.Background(), "vid1", "f7", DownloadOptions{Template: tmpl, Format: "mp4", Resolution: 480})
+	require.NoError(t, err)
+	assert.Equal(t, "720\n480\n", lw.String())
+}
+
+func TestMimeType(t *testing.T) {
+	tbl := []struct {
+		file, res string
+	}{
+		{"/tmp/f1.mp3", "audio/mpeg"},
+		{"/tmp/f1.MP3", "audio/mpeg"},
+		{"/tmp/f1.m4a", "audio/mp4"},
+		{"/tmp/f1.opus", "audio/ogg"},
+		{"/tmp/f1.webm", "audio/webm"},
+		{"/tmp/f1.mp4", "video/mp4"},
+		{"/tmp/f1.unknown", "audio/mpeg"},
+		{"/tmp/f1", "audio/mpeg"},
+	}
+	for _, tt := range tbl {
+		assert.Equal(t, tt.res, MimeType(tt.file), tt.file)
+	}
+}
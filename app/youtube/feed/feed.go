@@ -7,7 +7,9 @@ import (
 	"fmt"
 	"html/template"
 	"net/http"
+	"path/filepath"
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/pkg/errors"
@@ -105,8 +107,21 @@ type Entry struct {
 	} `xml:"author"`
 
 	File        string
+	Image       string // local square thumbnail, empty if not cached
 	Duration    int    // seconds
 	DurationFmt string // used for ui only
+
+	// metadata of downloaded video from yt-dlp, empty if not available
+	Chapters   []Chapter   `xml:"-"`
+	Tags       []string    `xml:"-"`
+	Thumbnails []Thumbnail `xml:"-"`
+	UploadDate time.Time   `xml:"-"`
+	LiveStatus string      `xml:"-"`
+}
+
+// ImageFile returns the name of thumbnail file cached for the downloaded file
+func ImageFile(file string) string {
+	return strings.TrimSuffix(file, filepath.Ext(file)) + ".jpg"
 }
 
 // UID returns the unique identifier of the entry.
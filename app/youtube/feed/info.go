@@ -0,0 +1,96 @@
+package feed
+
+import (
+	"encoding/json"
+	"html/template"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Info is a metadata of downloaded video written by yt-dlp with --write-info-json
+type Info struct {
+	ID          string      `json:"id"`
+	Title       string      `json:"title"`
+	Description string      `json:"description"`
+	Duration    float64     `json:"duration"`    // seconds
+	UploadDate  string      `json:"upload_date"` // YYYYMMDD
+	Timestamp   int64       `json:"timestamp"`   // unix time of upload, more precise than UploadDate if set
+	Tags        []string    `json:"tags"`
+	Chapters    []Chapter   `json:"chapters"`
+	Thumbnail   string      `json:"thumbnail"` // the best thumbnail
+	Thumbnails  []Thumbnail `json:"thumbnails"`
+	LiveStatus  string      `json:"live_status"` // not_live, is_live, is_upcoming, was_live or post_live
+}
+
+// Chapter is a part of the video with its title, start and end are in seconds
+type Chapter struct {
+	Start float64 `json:"start_time"`
+	End   float64 `json:"end_time"`
+	Title string  `json:"title"`
+}
+
+// Thumbnail is an image of the video in one of available sizes
+type Thumbnail struct {
+	URL    string `json:"url"`
+	Width  int    `json:"width,omitempty"`
+	Height int    `json:"height,omitempty"`
+}
+
+// InfoFile returns the name of metadata file written by yt-dlp for the downloaded file
+func InfoFile(file string) string {
+	return strings.TrimSuffix(file, filepath.Ext(file)) + ".info.json"
+}
+
+// LoadInfo reads metadata of the downloaded file, fails with os.ErrNotExist if it is not written
+func LoadInfo(file string) (Info, error) {
+	var info Info
+	data, err := os.ReadFile(InfoFile(file)) // nolint
+	if err != nil {
+		return info, errors.Wrap(err, "failed to read info")
+	}
+	if err := json.Unmarshal(data, &info); err != nil {
+		return info, errors.Wrapf(err, "failed to parse info %s", InfoFile(file))
+	}
+	return info, nil
+}
+
+// Uploaded returns upload time of the video, zero if unknown
+func (i Info) Uploaded() time.Time {
+	if i.Timestamp > 0 {
+		return time.Unix(i.Timestamp, 0).UTC()
+	}
+	ts, err := time.Parse("20060102", i.UploadDate)
+	if err != nil {
+		return time.Time{}
+	}
+	return ts
+}
+
+// Apply sets entry fields from metadata, fields missing in metadata are kept as is.
+// The feed's description is truncated, so it is replaced with the full one.
+func (i Info) Apply(entry Entry) Entry {
+	if i.Description != "" {
+		entry.Media.Description = template.HTML(i.Description) // nolint
+	}
+	if i.Duration > 0 {
+		entry.Duration = int(math.Round(i.Duration))
+	}
+	if i.Thumbnail != "" {
+		entry.Media.Thumbnail.URL = i.Thumbnail
+	}
+	for _, th := range i.Thumbnails {
+		if th.URL != "" && th.Width > 0 && th.Height > 0 { // skip thumbnails of unknown size, yt-dlp lists all possible
+			entry.Thumbnails = append(entry.Thumbnails, th)
+		}
+	}
+	entry.Chapters = i.Chapters
+	entry.Tags = i.Tags
+	entry.UploadDate = i.Uploaded()
+	entry.LiveStatus = i.LiveStatus
+	return entry
+}
@@ -0,0 +1,63 @@
+package feed
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadInfo(t *testing.T) {
+	info, err := LoadInfo("testdata/info.mp3")
+	require.NoError(t, err)
+	assert.Equal(t, "vid1", info.ID)
+	assert.Equal(t, "Some title", info.Title)
+	assert.Contains(t, info.Description, "\n00:00 Intro\n01:30 Main part")
+	assert.Equal(t, []string{"news", "podcast"}, info.Tags)
+	assert.Equal(t, []Chapter{{Start: 0, End: 90, Title: "Intro"}, {Start: 90, End: 3599.6, Title: "Main part"}}, info.Chapters)
+	assert.Equal(t, 3, len(info.Thumbnails))
+	assert.Equal(t, time.Date(2024, 3, 15, 14, 0, 0, 0, time.UTC), info.Uploaded())
+
+	fname := filepath.Join(t.TempDir(), "bad.mp3")
+	require.NoError(t, os.WriteFile(InfoFile(fname), []byte("not json"), 0o600))
+	_, err = LoadInfo(fname)
+	assert.Error(t, err)
+}
+
+func TestInfo_Uploaded(t *testing.T) {
+	assert.Equal(t, time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC), Info{UploadDate: "20240315"}.Uploaded())
+	assert.Equal(t, time.Unix(1710511200, 0).UTC(), Info{UploadDate: "20240315", Timestamp: 1710511200}.Uploaded())
+	assert.True(t, Info{}.Uploaded().IsZero())
+}
+
+func TestInfo_Apply(t *testing.T) {
+	entry := Entry{VideoID: "vid1", Title: "title", Duration: 10}
+	entry.Media.Description = "truncated"
+	entry.Media.Thumbnail.URL = "https://i.ytimg.com/vi/vid1/hqdefault.jpg"
+
+	res := Info{}.Apply(entry)
+	assert.Equal(t, entry, res, "nothing changed by empty info")
+
+	info, err := LoadInfo("testdata/info.mp3")
+	require.NoError(t, err)
+	res = info.Apply(entry)
+	assert.Equal(t, "title", res.Title, "title kept")
+	assert.Equal(t, info.Description, string(res.Media.Description))
+	assert.Equal(t, 3600, res.Duration)
+	assert.Equal(t, "https://i.ytimg.com/vi/vid1/maxresdefault.jpg", res.Media.Thumbnail.URL)
+	assert.Equal(t, []Thumbnail{{URL: "https://i.ytimg.com/vi/vid1/hqdefault.jpg", Width: 480, Height: 360},
+		{URL: "https://i.ytimg.com/vi/vid1/maxresdefault.jpg", Width: 1920, Height: 1080}}, res.Thumbnails,
+		"thumbnails of unknown size skipped")
+	assert.Equal(t, info.Chapters, res.Chapters)
+	assert.Equal(t, info.Tags, res.Tags)
+	assert.Equal(t, info.Uploaded(), res.UploadDate)
+	assert.Equal(t, "was_live", res.LiveStatus)
+}
+
+func TestInfoFile(t *testing.T) {
+	assert.Equal(t, "/tmp/abc.info.json", InfoFile("/tmp/abc.mp3"))
+	assert.Equal(t, "abc.info.json", InfoFile("abc"))
+}
@@ -0,0 +1,37 @@
+package feed
+
+import "time"
+
+// QueueState is a state of the entry download
+type QueueState string
+
+// enum of download states
+const (
+	QueuePending     QueueState = "pending"     // waiting for download, i.e. requested to retry
+	QueueDownloading QueueState = "downloading" // download in progress or interrupted
+	QueueFailed      QueueState = "failed"      // download failed, retried after NextTry unless attempts are exhausted
+	QueueDone        QueueState = "done"        // downloaded and saved
+	QueueSkipped     QueueState = "skipped"     // skipped by downloader, too short or given up by admin
+)
+
+// QueueItem keeps download state of the entry with the number of attempts and the last error
+type QueueItem struct {
+	Entry    Entry      `json:"entry"`
+	State    QueueState `json:"state"`
+	Attempts int        `json:"attempts"`
+	Error    string     `json:"error,omitempty"`
+	NextTry  time.Time  `json:"next_try,omitempty"`
+	Updated  time.Time  `json:"updated"`
+}
+
+// Due returns true if the item should be downloaded at the given time, i.e. it is pending, interrupted
+// or failed with retry time passed. Failed items without NextTry have no attempts left.
+func (q QueueItem) Due(now time.Time) bool {
+	switch q.State {
+	case QueuePending, QueueDownloading:
+		return true
+	case QueueFailed:
+		return !q.NextTry.IsZero() && !now.Before(q.NextTry)
+	}
+	return false
+}
@@ -0,0 +1,28 @@
+package feed
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueueItem_Due(t *testing.T) {
+	now := time.Now()
+	tbl := []struct {
+		item QueueItem
+		due  bool
+	}{
+		{QueueItem{State: QueuePending}, true},
+		{QueueItem{State: QueueDownloading}, true},
+		{QueueItem{State: QueueFailed, NextTry: now.Add(-time.Minute)}, true},
+		{QueueItem{State: QueueFailed, NextTry: now}, true},
+		{QueueItem{State: QueueFailed, NextTry: now.Add(time.Minute)}, false},
+		{QueueItem{State: QueueFailed}, false},
+		{QueueItem{State: QueueDone}, false},
+		{QueueItem{State: QueueSkipped}, false},
+	}
+	for i, tt := range tbl {
+		assert.Equal(t, tt.due, tt.item.Due(now), "case #%d, %+v", i, tt.item)
+	}
+}
@@ -0,0 +1,48 @@
+// Package ffmpeg runs ffmpeg to rewrite downloaded media files in place
+package ffmpeg
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	log "github.com/go-pkgz/lgr"
+	"github.com/pkg/errors"
+)
+
+// Runner executes ffmpeg writing to a temporary file next to the original one, which replaces the original on success
+type Runner struct {
+	Cmd string // ffmpeg command, "ffmpeg" if not set
+}
+
+// Replace runs ffmpeg with args followed by the temporary output, the file name with suffix, i.e. "video.cut.mp4".
+// Rename of the output is atomic as it is in the same directory, the output is removed on error.
+func (r Runner) Replace(ctx context.Context, file, suffix string, args ...string) error {
+	ext := filepath.Ext(file)
+	out := strings.TrimSuffix(file, ext) + "." + suffix + ext
+	args = append(append([]string{"-y", "-v", "error"}, args...), out)
+
+	cmd := r.Cmd
+	if cmd == "" {
+		cmd = "ffmpeg"
+	}
+	stderr := bytes.Buffer{}
+	c := exec.CommandContext(ctx, cmd, args...) // nolint
+	c.Stderr = &stderr
+	log.Printf("[DEBUG] executing command: %s %s", cmd, strings.Join(args, " "))
+	if err := c.Run(); err != nil {
+		_ = os.Remove(out)
+		return fmt.Errorf("failed to execute %s: %v, %s", cmd, err, strings.TrimSpace(stderr.String()))
+	}
+	return errors.Wrapf(os.Rename(out, file), "failed to replace %s", file)
+}
+
+// IsVideo checks if the file is a video by extension. Video stream of mp4 files is processed or copied,
+// other files are treated as audio and their video streams, i.e. embedded cover art, are dropped.
+func IsVideo(file string) bool {
+	return strings.EqualFold(filepath.Ext(file), ".mp4")
+}
@@ -0,0 +1,48 @@
+package ffmpeg
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunner_Replace(t *testing.T) {
+	tmp := t.TempDir()
+	// fake ffmpeg saves its arguments and writes the output file, the last argument
+	ffmpeg := filepath.Join(tmp, "ffmpeg")
+	script := "#!/bin/sh\necho \"$@\" > " + tmp + "/args\nfor out; do :; done\necho processed > \"$out\"\n"
+	require.NoError(t, os.WriteFile(ffmpeg, []byte(script), 0o700)) // nolint
+	file := filepath.Join(tmp, "audio.mp3")
+	require.NoError(t, os.WriteFile(file, []byte("audio"), 0o600))
+
+	r := Runner{Cmd: ffmpeg}
+	require.NoError(t, r.Replace(context.Background(), file, "step", "-i", file, "-ac", "1"))
+	data, err := os.ReadFile(file) // nolint
+	require.NoError(t, err)
+	assert.Equal(t, "processed\n", string(data), "file replaced")
+	args, err := os.ReadFile(filepath.Join(tmp, "args")) // nolint
+	require.NoError(t, err)
+	assert.Equal(t, "-y -v error -i "+file+" -ac 1 "+filepath.Join(tmp, "audio.step.mp3")+"\n", string(args))
+
+	require.NoError(t, os.WriteFile(ffmpeg, []byte("#!/bin/sh\nfor out; do :; done\necho x > \"$out\"\n"+
+		"echo bad input >&2\nexit 1\n"), 0o700)) // nolint
+	err = r.Replace(context.Background(), file, "step", "-i", file)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "bad input")
+	data, err = os.ReadFile(file) // nolint
+	require.NoError(t, err)
+	assert.Equal(t, "processed\n", string(data), "file kept on error")
+	_, err = os.Stat(filepath.Join(tmp, "audio.step.mp3"))
+	assert.True(t, os.IsNotExist(err), "output removed on error")
+}
+
+func TestIsVideo(t *testing.T) {
+	assert.True(t, IsVideo("/srv/video.mp4"))
+	assert.True(t, IsVideo("/srv/video.MP4"))
+	assert.False(t, IsVideo("/srv/audio.mp3"))
+	assert.False(t, IsVideo("/srv/audio.m4a"))
+}
@@ -6,26 +6,28 @@ package mocks
 import (
 	"context"
 	"sync"
+
+	ytfeed "github.com/umputun/feed-master/app/youtube/feed"
 )
 
 // DownloaderServiceMock is a mock implementation of youtube.DownloaderService.
 //
-// 	func TestSomethingThatUsesDownloaderService(t *testing.T) {
+//	func TestSomethingThatUsesDownloaderService(t *testing.T) {
 //
-// 		// make and configure a mocked youtube.DownloaderService
-// 		mockedDownloaderService := &DownloaderServiceMock{
-// 			GetFunc: func(ctx context.Context, id string, fname string) (string, error) {
-// 				panic("mock out the Get method")
-// 			},
-// 		}
+//		// make and configure a mocked youtube.DownloaderService
+//		mockedDownloaderService := &DownloaderServiceMock{
+//			GetFunc: func(ctx context.Context, id string, fname string, opts ytfeed.DownloadOptions) (string, error) {
+//				panic("mock out the Get method")
+//			},
+//		}
 //
-// 		// use mockedDownloaderService in code that requires youtube.DownloaderService
-// 		// and then make assertions.
+//		// use mockedDownloaderService in code that requires youtube.DownloaderService
+//		// and then make assertions.
 //
-// 	}
+//	}
 type DownloaderServiceMock struct {
 	// GetFunc mocks the Get method.
-	GetFunc func(ctx context.Context, id string, fname string) (string, error)
+	GetFunc func(ctx context.Context, id string, fname string, opts ytfeed.DownloadOptions) (string, error)
 
 	// calls tracks calls to the methods.
 	calls struct {
@@ -37,13 +39,15 @@ type DownloaderServiceMock struct {
 			ID string
 			// Fname is the fname argument value.
 			Fname string
+			// Opts is the opts argument value.
+			Opts ytfeed.DownloadOptions
 		}
 	}
 	lockGet sync.RWMutex
 }
 
 // Get calls GetFunc.
-func (mock *DownloaderServiceMock) Get(ctx context.Context, id string, fname string) (string, error) {
+func (mock *DownloaderServiceMock) Get(ctx context.Context, id string, fname string, opts ytfeed.DownloadOptions) (string, error) {
 	if mock.GetFunc == nil {
 		panic("DownloaderServiceMock.GetFunc: method is nil but DownloaderService.Get was just called")
 	}
@@ -51,29 +55,34 @@ func (mock *DownloaderServiceMock) Get(ctx context.Context, id string, fname str
 		Ctx   context.Context
 		ID    string
 		Fname string
+		Opts  ytfeed.DownloadOptions
 	}{
 		Ctx:   ctx,
 		ID:    id,
 		Fname: fname,
+		Opts:  opts,
 	}
 	mock.lockGet.Lock()
 	mock.calls.Get = append(mock.calls.Get, callInfo)
 	mock.lockGet.Unlock()
-	return mock.GetFunc(ctx, id, fname)
+	return mock.GetFunc(ctx, id, fname, opts)
 }
 
 // GetCalls gets all the calls that were made to Get.
 // Check the length with:
-//     len(mockedDownloaderService.GetCalls())
+//
+//	len(mockedDownloaderService.GetCalls())
 func (mock *DownloaderServiceMock) GetCalls() []struct {
 	Ctx   context.Context
 	ID    string
 	Fname string
+	Opts  ytfeed.DownloadOptions
 } {
 	var calls []struct {
 		Ctx   context.Context
 		ID    string
 		Fname string
+		Opts  ytfeed.DownloadOptions
 	}
 	mock.lockGet.RLock()
 	calls = mock.calls.Get
@@ -12,43 +12,55 @@ import (
 
 // StoreServiceMock is a mock implementation of youtube.StoreService.
 //
-// 	func TestSomethingThatUsesStoreService(t *testing.T) {
+//	func TestSomethingThatUsesStoreService(t *testing.T) {
 //
-// 		// make and configure a mocked youtube.StoreService
-// 		mockedStoreService := &StoreServiceMock{
-// 			CheckProcessedFunc: func(entry ytfeed.Entry) (bool, time.Time, error) {
-// 				panic("mock out the CheckProcessed method")
-// 			},
-// 			CountProcessedFunc: func() int {
-// 				panic("mock out the CountProcessed method")
-// 			},
-// 			ExistFunc: func(entry ytfeed.Entry) (bool, error) {
-// 				panic("mock out the Exist method")
-// 			},
-// 			LoadFunc: func(channelID string, max int) ([]ytfeed.Entry, error) {
-// 				panic("mock out the Load method")
-// 			},
-// 			RemoveFunc: func(entry ytfeed.Entry) error {
-// 				panic("mock out the Remove method")
-// 			},
-// 			RemoveOldFunc: func(channelID string, keep int) ([]string, error) {
-// 				panic("mock out the RemoveOld method")
-// 			},
-// 			ResetProcessedFunc: func(entry ytfeed.Entry) error {
-// 				panic("mock out the ResetProcessed method")
-// 			},
-// 			SaveFunc: func(entry ytfeed.Entry) (bool, error) {
-// 				panic("mock out the Save method")
-// 			},
-// 			SetProcessedFunc: func(entry ytfeed.Entry) error {
-// 				panic("mock out the SetProcessed method")
-// 			},
-// 		}
+//		// make and configure a mocked youtube.StoreService
+//		mockedStoreService := &StoreServiceMock{
+//			CheckProcessedFunc: func(entry ytfeed.Entry) (bool, time.Time, error) {
+//				panic("mock out the CheckProcessed method")
+//			},
+//			CountProcessedFunc: func() int {
+//				panic("mock out the CountProcessed method")
+//			},
+//			ExistFunc: func(entry ytfeed.Entry) (bool, error) {
+//				panic("mock out the Exist method")
+//			},
+//			ListQueuedFunc: func(channelID string) ([]ytfeed.QueueItem, error) {
+//				panic("mock out the ListQueued method")
+//			},
+//			LoadFunc: func(channelID string, max int) ([]ytfeed.Entry, error) {
+//				panic("mock out the Load method")
+//			},
+//			QueuedFunc: func(entry ytfeed.Entry) (ytfeed.QueueItem, bool, error) {
+//				panic("mock out the Queued method")
+//			},
+//			RemoveFunc: func(entry ytfeed.Entry) error {
+//				panic("mock out the Remove method")
+//			},
+//			RemoveOldFunc: func(channelID string, keep int) ([]string, error) {
+//				panic("mock out the RemoveOld method")
+//			},
+//			RemoveQueuedFunc: func(before time.Time) (int, error) {
+//				panic("mock out the RemoveQueued method")
+//			},
+//			ResetProcessedFunc: func(entry ytfeed.Entry) error {
+//				panic("mock out the ResetProcessed method")
+//			},
+//			SaveFunc: func(entry ytfeed.Entry) (bool, error) {
+//				panic("mock out the Save method")
+//			},
+//			SetProcessedFunc: func(entry ytfeed.Entry) error {
+//				panic("mock out the SetProcessed method")
+//			},
+//			SetQueuedFunc: func(item ytfeed.QueueItem) error {
+//				panic("mock out the SetQueued method")
+//			},
+//		}
 //
-// 		// use mockedStoreService in code that requires youtube.StoreService
-// 		// and then make assertions.
+//		// use mockedStoreService in code that requires youtube.StoreService
+//		// and then make assertions.
 //
-// 	}
+//	}
 type StoreServiceMock struct {
 	// CheckProcessedFunc mocks the CheckProcessed method.
 	CheckProcessedFunc func(entry ytfeed.Entry) (bool, time.Time, error)
@@ -59,15 +71,24 @@ type StoreServiceMock struct {
 	// ExistFunc mocks the Exist method.
 	ExistFunc func(entry ytfeed.Entry) (bool, error)
 
+	// ListQueuedFunc mocks the ListQueued method.
+	ListQueuedFunc func(channelID string) ([]ytfeed.QueueItem, error)
+
 	// LoadFunc mocks the Load method.
 	LoadFunc func(channelID string, max int) ([]ytfeed.Entry, error)
 
+	// QueuedFunc mocks the Queued method.
+	QueuedFunc func(entry ytfeed.Entry) (ytfeed.QueueItem, bool, error)
+
 	// RemoveFunc mocks the Remove method.
 	RemoveFunc func(entry ytfeed.Entry) error
 
 	// RemoveOldFunc mocks the RemoveOld method.
 	RemoveOldFunc func(channelID string, keep int) ([]string, error)
 
+	// RemoveQueuedFunc mocks the RemoveQueued method.
+	RemoveQueuedFunc func(before time.Time) (int, error)
+
 	// ResetProcessedFunc mocks the ResetProcessed method.
 	ResetProcessedFunc func(entry ytfeed.Entry) error
 
@@ -77,6 +98,9 @@ type StoreServiceMock struct {
 	// SetProcessedFunc mocks the SetProcessed method.
 	SetProcessedFunc func(entry ytfeed.Entry) error
 
+	// SetQueuedFunc mocks the SetQueued method.
+	SetQueuedFunc func(item ytfeed.QueueItem) error
+
 	// calls tracks calls to the methods.
 	calls struct {
 		// CheckProcessed holds details about calls to the CheckProcessed method.
@@ -92,6 +116,11 @@ type StoreServiceMock struct {
 			// Entry is the entry argument value.
 			Entry ytfeed.Entry
 		}
+		// ListQueued holds details about calls to the ListQueued method.
+		ListQueued []struct {
+			// ChannelID is the channelID argument value.
+			ChannelID string
+		}
 		// Load holds details about calls to the Load method.
 		Load []struct {
 			// ChannelID is the channelID argument value.
@@ -99,6 +128,11 @@ type StoreServiceMock struct {
 			// Max is the max argument value.
 			Max int
 		}
+		// Queued holds details about calls to the Queued method.
+		Queued []struct {
+			// Entry is the entry argument value.
+			Entry ytfeed.Entry
+		}
 		// Remove holds details about calls to the Remove method.
 		Remove []struct {
 			// Entry is the entry argument value.
@@ -111,6 +145,11 @@ type StoreServiceMock struct {
 			// Keep is the keep argument value.
 			Keep int
 		}
+		// RemoveQueued holds details about calls to the RemoveQueued method.
+		RemoveQueued []struct {
+			// Before is the before argument value.
+			Before time.Time
+		}
 		// ResetProcessed holds details about calls to the ResetProcessed method.
 		ResetProcessed []struct {
 			// Entry is the entry argument value.
@@ -126,16 +165,25 @@ type StoreServiceMock struct {
 			// Entry is the entry argument value.
 			Entry ytfeed.Entry
 		}
+		// SetQueued holds details about calls to the SetQueued method.
+		SetQueued []struct {
+			// Item is the item argument value.
+			Item ytfeed.QueueItem
+		}
 	}
 	lockCheckProcessed sync.RWMutex
 	lockCountProcessed sync.RWMutex
 	lockExist          sync.RWMutex
+	lockListQueued     sync.RWMutex
 	lockLoad           sync.RWMutex
+	lockQueued         sync.RWMutex
 	lockRemove         sync.RWMutex
 	lockRemoveOld      sync.RWMutex
+	lockRemoveQueued   sync.RWMutex
 	lockResetProcessed sync.RWMutex
 	lockSave           sync.RWMutex
 	lockSetProcessed   sync.RWMutex
+	lockSetQueued      sync.RWMutex
 }
 
 // CheckProcessed calls CheckProcessedFunc.
@@ -156,7 +204,8 @@ func (mock *StoreServiceMock) CheckProcessed(entry ytfeed.Entry) (bool, time.Tim
 
 // CheckProcessedCalls gets all the calls that were made to CheckProcessed.
 // Check the length with:
-//     len(mockedStoreService.CheckProcessedCalls())
+//
+//	len(mockedStoreService.CheckProcessedCalls())
 func (mock *StoreServiceMock) CheckProcessedCalls() []struct {
 	Entry ytfeed.Entry
 } {
@@ -184,7 +233,8 @@ func (mock *StoreServiceMock) CountProcessed() int {
 
 // CountProcessedCalls gets all the calls that were made to CountProcessed.
 // Check the length with:
-//     len(mockedStoreService.CountProcessedCalls())
+//
+//	len(mockedStoreService.CountProcessedCalls())
 func (mock *StoreServiceMock) CountProcessedCalls() []struct {
 } {
 	var calls []struct {
@@ -213,7 +263,8 @@ func (mock *StoreServiceMock) Exist(entry ytfeed.Entry) (bool, error) {
 
 // ExistCalls gets all the calls that were made to Exist.
 // Check the length with:
-//     len(mockedStoreService.ExistCalls())
+//
+//	len(mockedStoreService.ExistCalls())
 func (mock *StoreServiceMock) ExistCalls() []struct {
 	Entry ytfeed.Entry
 } {
@@ -226,6 +277,38 @@ func (mock *StoreServiceMock) ExistCalls() []struct {
 	return calls
 }
 
+// ListQueued calls ListQueuedFunc.
+func (mock *StoreServiceMock) ListQueued(channelID string) ([]ytfeed.QueueItem, error) {
+	if mock.ListQueuedFunc == nil {
+		panic("StoreServiceMock.ListQueuedFunc: method is nil but StoreService.ListQueued was just called")
+	}
+	callInfo := struct {
+		ChannelID string
+	}{
+		ChannelID: channelID,
+	}
+	mock.lockListQueued.Lock()
+	mock.calls.ListQueued = append(mock.calls.ListQueued, callInfo)
+	mock.lockListQueued.Unlock()
+	return mock.ListQueuedFunc(channelID)
+}
+
+// ListQueuedCalls gets all the calls that were made to ListQueued.
+// Check the length with:
+//
+//	len(mockedStoreService.ListQueuedCalls())
+func (mock *StoreServiceMock) ListQueuedCalls() []struct {
+	ChannelID string
+} {
+	var calls []struct {
+		ChannelID string
+	}
+	mock.lockListQueued.RLock()
+	calls = mock.calls.ListQueued
+	mock.lockListQueued.RUnlock()
+	return calls
+}
+
 // Load calls LoadFunc.
 func (mock *StoreServiceMock) Load(channelID string, max int) ([]ytfeed.Entry, error) {
 	if mock.LoadFunc == nil {
@@ -246,7 +329,8 @@ func (mock *StoreServiceMock) Load(channelID string, max int) ([]ytfeed.Entry, e
 
 // LoadCalls gets all the calls that were made to Load.
 // Check the length with:
-//     len(mockedStoreService.LoadCalls())
+//
+//	len(mockedStoreService.LoadCalls())
 func (mock *StoreServiceMock) LoadCalls() []struct {
 	ChannelID string
 	Max       int
@@ -261,6 +345,38 @@ func (mock *StoreServiceMock) LoadCalls() []struct {
 	return calls
 }
 
+// Queued calls QueuedFunc.
+func (mock *StoreServiceMock) Queued(entry ytfeed.Entry) (ytfeed.QueueItem, bool, error) {
+	if mock.QueuedFunc == nil {
+		panic("StoreServiceMock.QueuedFunc: method is nil but StoreService.Queued was just called")
+	}
+	callInfo := struct {
+		Entry ytfeed.Entry
+	}{
+		Entry: entry,
+	}
+	mock.lockQueued.Lock()
+	mock.calls.Queued = append(mock.calls.Queued, callInfo)
+	mock.lockQueued.Unlock()
+	return mock.QueuedFunc(entry)
+}
+
+// QueuedCalls gets all the calls that were made to Queued.
+// Check the length with:
+//
+//	len(mockedStoreService.QueuedCalls())
+func (mock *StoreServiceMock) QueuedCalls() []struct {
+	Entry ytfeed.Entry
+} {
+	var calls []struct {
+		Entry ytfeed.Entry
+	}
+	mock.lockQueued.RLock()
+	calls = mock.calls.Queued
+	mock.lockQueued.RUnlock()
+	return calls
+}
+
 // Remove calls RemoveFunc.
 func (mock *StoreServiceMock) Remove(entry ytfeed.Entry) error {
 	if mock.RemoveFunc == nil {
@@ -279,7 +395,8 @@ func (mock *StoreServiceMock) Remove(entry ytfeed.Entry) error {
 
 // RemoveCalls gets all the calls that were made to Remove.
 // Check the length with:
-//     len(mockedStoreService.RemoveCalls())
+//
+//	len(mockedStoreService.RemoveCalls())
 func (mock *StoreServiceMock) RemoveCalls() []struct {
 	Entry ytfeed.Entry
 } {
@@ -312,7 +429,8 @@ func (mock *StoreServiceMock) RemoveOld(channelID string, keep int) ([]string, e
 
 // RemoveOldCalls gets all the calls that were made to RemoveOld.
 // Check the length with:
-//     len(mockedStoreService.RemoveOldCalls())
+//
+//	len(mockedStoreService.RemoveOldCalls())
 func (mock *StoreServiceMock) RemoveOldCalls() []struct {
 	ChannelID string
 	Keep      int
@@ -327,6 +445,38 @@ func (mock *StoreServiceMock) RemoveOldCalls() []struct {
 	return calls
 }
 
+// RemoveQueued calls RemoveQueuedFunc.
+func (mock *StoreServiceMock) RemoveQueued(before time.Time) (int, error) {
+	if mock.RemoveQueuedFunc == nil {
+		panic("StoreServiceMock.RemoveQueuedFunc: method is nil but StoreService.RemoveQueued was just called")
+	}
+	callInfo := struct {
+		Before time.Time
+	}{
+		Before: before,
+	}
+	mock.lockRemoveQueued.Lock()
+	mock.calls.RemoveQueued = append(mock.calls.RemoveQueued, callInfo)
+	mock.lockRemoveQueued.Unlock()
+	return mock.RemoveQueuedFunc(before)
+}
+
+// RemoveQueuedCalls gets all the calls that were made to RemoveQueued.
+// Check the length with:
+//
+//	len(mockedStoreService.RemoveQueuedCalls())
+func (mock *StoreServiceMock) RemoveQueuedCalls() []struct {
+	Before time.Time
+} {
+	var calls []struct {
+		Before time.Time
+	}
+	mock.lockRemoveQueued.RLock()
+	calls = mock.calls.RemoveQueued
+	mock.lockRemoveQueued.RUnlock()
+	return calls
+}
+
 // ResetProcessed calls ResetProcessedFunc.
 func (mock *StoreServiceMock) ResetProcessed(entry ytfeed.Entry) error {
 	if mock.ResetProcessedFunc == nil {
@@ -345,7 +495,8 @@ func (mock *StoreServiceMock) ResetProcessed(entry ytfeed.Entry) error {
 
 // ResetProcessedCalls gets all the calls that were made to ResetProcessed.
 // Check the length with:
-//     len(mockedStoreService.ResetProcessedCalls())
+//
+//	len(mockedStoreService.ResetProcessedCalls())
 func (mock *StoreServiceMock) ResetProcessedCalls() []struct {
 	Entry ytfeed.Entry
 } {
@@ -376,7 +527,8 @@ func (mock *StoreServiceMock) Save(entry ytfeed.Entry) (bool, error) {
 
 // SaveCalls gets all the calls that were made to Save.
 // Check the length with:
-//     len(mockedStoreService.SaveCalls())
+//
+//	len(mockedStoreService.SaveCalls())
 func (mock *StoreServiceMock) SaveCalls() []struct {
 	Entry ytfeed.Entry
 } {
@@ -407,7 +559,8 @@ func (mock *StoreServiceMock) SetProcessed(entry ytfeed.Entry) error {
 
 // SetProcessedCalls gets all the calls that were made to SetProcessed.
 // Check the length with:
-//     len(mockedStoreService.SetProcessedCalls())
+//
+//	len(mockedStoreService.SetProcessedCalls())
 func (mock *StoreServiceMock) SetProcessedCalls() []struct {
 	Entry ytfeed.Entry
 } {
@@ -419,3 +572,35 @@ func (mock *StoreServiceMock) SetProcessedCalls() []struct {
 	mock.lockSetProcessed.RUnlock()
 	return calls
 }
+
+// SetQueued calls SetQueuedFunc.
+func (mock *StoreServiceMock) SetQueued(item ytfeed.QueueItem) error {
+	if mock.SetQueuedFunc == nil {
+		panic("StoreServiceMock.SetQueuedFunc: method is nil but StoreService.SetQueued was just called")
+	}
+	callInfo := struct {
+		Item ytfeed.QueueItem
+	}{
+		Item: item,
+	}
+	mock.lockSetQueued.Lock()
+	mock.calls.SetQueued = append(mock.calls.SetQueued, callInfo)
+	mock.lockSetQueued.Unlock()
+	return mock.SetQueuedFunc(item)
+}
+
+// SetQueuedCalls gets all the calls that were made to SetQueued.
+// Check the length with:
+//
+//	len(mockedStoreService.SetQueuedCalls())
+func (mock *StoreServiceMock) SetQueuedCalls() []struct {
+	Item ytfeed.QueueItem
+} {
+	var calls []struct {
+		Item ytfeed.QueueItem
+	}
+	mock.lockSetQueued.RLock()
+	calls = mock.calls.SetQueued
+	mock.lockSetQueued.RUnlock()
+	return calls
+}
@@ -0,0 +1,84 @@
+// Package mp4 sets metadata of mp4 files, i.e. downloaded videos, with ffmpeg
+package mp4
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	ytfeed "github.com/umputun/feed-master/app/youtube/feed"
+	"github.com/umputun/feed-master/app/youtube/ffmpeg"
+)
+
+// Tags is metadata of mp4 file, empty values are not set
+type Tags struct {
+	Title    string
+	Artist   string
+	Album    string
+	Genre    string
+	Date     string
+	Comment  string
+	Chapters []ytfeed.Chapter
+	Cover    []byte // jpeg image
+}
+
+// Tagger sets mp4 metadata atoms, chapters and cover art with ffmpeg, streams are copied as is
+type Tagger struct {
+	FFmpeg string // ffmpeg command, "ffmpeg" if not set
+}
+
+// Set replaces metadata of the file. The file is re-muxed to a temporary one next to it, which replaces
+// the original on success.
+func (t *Tagger) Set(ctx context.Context, file string, tags Tags) error {
+	tmp, err := os.MkdirTemp("", "feed-master-mp4")
+	if err != nil {
+		return errors.Wrap(err, "failed to make temp dir")
+	}
+	defer os.RemoveAll(tmp) // nolint
+
+	meta := tmp + "/meta.txt"
+	if err = os.WriteFile(meta, []byte(ffMetadata(tags)), 0o600); err != nil {
+		return errors.Wrap(err, "failed to write metadata")
+	}
+	args := []string{"-i", file, "-i", meta}
+	maps := []string{"-map", "0", "-map_metadata", "1", "-map_chapters", "1", "-c", "copy"}
+	if len(tags.Cover) > 0 {
+		cover := tmp + "/cover.jpg"
+		if err = os.WriteFile(cover, tags.Cover, 0o600); err != nil {
+			return errors.Wrap(err, "failed to write cover")
+		}
+		args = append(args, "-i", cover)
+		maps = append(maps, "-map", "2", "-disposition:v:1", "attached_pic")
+	}
+	args = append(append(args, maps...), "-movflags", "+faststart")
+	return ffmpeg.Runner{Cmd: t.FFmpeg}.Replace(ctx, file, "tags", args...)
+}
+
+// ffMetadata makes ffmpeg metadata file, see https://ffmpeg.org/ffmpeg-formats.html#Metadata-1
+func ffMetadata(tags Tags) string {
+	res := strings.Builder{}
+	res.WriteString(";FFMETADATA1\n")
+	for _, kv := range [][2]string{{"title", tags.Title}, {"artist", tags.Artist}, {"album", tags.Album},
+		{"genre", tags.Genre}, {"date", tags.Date}, {"comment", tags.Comment}} {
+		if kv[1] != "" {
+			res.WriteString(kv[0] + "=" + escape(kv[1]) + "\n")
+		}
+	}
+	for _, c := range tags.Chapters {
+		end := c.End
+		if end < c.Start {
+			end = c.Start
+		}
+		res.WriteString(fmt.Sprintf("[CHAPTER]\nTIMEBASE=1/1000\nSTART=%d\nEND=%d\ntitle=%s\n",
+			int64(c.Start*1000), int64(end*1000), escape(c.Title)))
+	}
+	return res.String()
+}
+
+// escape special characters of ffmpeg metadata value with backslash
+func escape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "=", `\=`, ";", `\;`, "#", `\#`, "\n", "\\\n").Replace(s)
+}
@@ -0,0 +1,59 @@
+package mp4
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ytfeed "github.com/umputun/feed-master/app/youtube/feed"
+)
+
+func TestTagger_Set(t *testing.T) {
+	tmp := t.TempDir()
+	// fake ffmpeg saves its arguments and writes the output file, the last argument
+	ffmpeg := filepath.Join(tmp, "ffmpeg")
+	script := "#!/bin/sh\necho \"$@\" > " + tmp + "/args\nfor out; do :; done\necho tagged > \"$out\"\n"
+	require.NoError(t, os.WriteFile(ffmpeg, []byte(script), 0o700)) // nolint
+	file := filepath.Join(tmp, "video.mp4")
+	require.NoError(t, os.WriteFile(file, []byte("video"), 0o600))
+
+	tg := Tagger{FFmpeg: ffmpeg}
+	require.NoError(t, tg.Set(context.Background(), file, Tags{Title: "title1", Cover: []byte("jpeg")}))
+	data, err := os.ReadFile(file) // nolint
+	require.NoError(t, err)
+	assert.Equal(t, "tagged\n", string(data), "file replaced")
+	args, err := os.ReadFile(filepath.Join(tmp, "args")) // nolint
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(args), "-y -v error -i "+file+" -i "), string(args))
+	assert.Contains(t, string(args), "-map 0 -map_metadata 1 -map_chapters 1 -c copy -map 2 -disposition:v:1 attached_pic")
+	assert.Contains(t, string(args), "-movflags +faststart "+filepath.Join(tmp, "video.tags.mp4"), "temp output next to the file")
+
+	require.NoError(t, tg.Set(context.Background(), file, Tags{Title: "title1"}))
+	args, err = os.ReadFile(filepath.Join(tmp, "args")) // nolint
+	require.NoError(t, err)
+	assert.NotContains(t, string(args), "attached_pic", "no cover")
+
+	require.NoError(t, os.WriteFile(ffmpeg, []byte("#!/bin/sh\necho bad input >&2\nexit 1\n"), 0o700)) // nolint
+	err = tg.Set(context.Background(), file, Tags{Title: "title1"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "bad input")
+	data, err = os.ReadFile(file) // nolint
+	require.NoError(t, err)
+	assert.Equal(t, "tagged\n", string(data), "file kept on error")
+	files, err := os.ReadDir(tmp)
+	require.NoError(t, err)
+	assert.Equal(t, 3, len(files), "no temp files left: args, ffmpeg and video.mp4")
+}
+
+func TestFFMetadata(t *testing.T) {
+	res := ffMetadata(Tags{Title: "a=b; #1", Artist: "author", Comment: "line1\nline2\\",
+		Chapters: []ytfeed.Chapter{{Start: 0, End: 10.5, Title: "Intro"}, {Start: 10.5, Title: "Main"}}})
+	assert.Equal(t, ";FFMETADATA1\ntitle=a\\=b\\; \\#1\nartist=author\ncomment=line1\\\nline2\\\\\n"+
+		"[CHAPTER]\nTIMEBASE=1/1000\nSTART=0\nEND=10500\ntitle=Intro\n"+
+		"[CHAPTER]\nTIMEBASE=1/1000\nSTART=10500\nEND=10500\ntitle=Main\n", res)
+}
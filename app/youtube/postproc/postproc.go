@@ -0,0 +1,172 @@
+// Package postproc runs post-processing steps on downloaded files, i.e. loudness normalization, with ffmpeg
+package postproc
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+
+	log "github.com/go-pkgz/lgr"
+	"github.com/pkg/errors"
+
+	"github.com/umputun/feed-master/app/youtube/ffmpeg"
+)
+
+// Defaults has ffmpeg arguments of predefined steps, used if the step has no args
+var Defaults = map[string]string{
+	"loudnorm": "-af loudnorm=I=-16:TP=-1.5:LRA=11", // EBU R128, -16 LUFS recommended for podcasts
+	"trim_silence": "-af silenceremove=start_periods=1:start_silence=0.5:start_threshold=-50dB,areverse," +
+		"silenceremove=start_periods=1:start_silence=0.5:start_threshold=-50dB,areverse", // from both ends
+	"mono":    "-ac 1",
+	"bitrate": "-b:a 96k",
+}
+
+// Step is a named post-processing step. Args is a template of ffmpeg arguments placed between input and output,
+// with {{.ID}} and {{.Format}} parameters. Arguments with spaces should be quoted. Audio filter set with -af
+// is joined with filters of other steps.
+type Step struct {
+	Name string `yaml:"name" json:"name"`
+	Args string `yaml:"args" json:"args"` // default args of the predefined step with this name if not set
+}
+
+// Template returns arguments template of the step, own or the default one
+func (s Step) Template() string {
+	if s.Args != "" {
+		return s.Args
+	}
+	return Defaults[s.Name]
+}
+
+// Service runs steps with ffmpeg
+type Service struct {
+	FFmpeg  string // ffmpeg command, "ffmpeg" if not set
+	FFProbe string // ffprobe command to get bitrate of the source, "ffprobe" if not set
+}
+
+// Process runs the steps on the file with a single ffmpeg invocation, so the file is re-encoded once.
+// Audio filters of all steps are joined to one chain in order of steps, other arguments are kept as is.
+// Bitrate of the source audio is kept unless steps set their own. The result is written to a temporary file
+// which replaces the original, video stream is copied, see ffmpeg.IsVideo.
+func (s *Service) Process(ctx context.Context, file, videoID string, steps []Step) error {
+	if len(steps) == 0 {
+		return nil
+	}
+	format := strings.TrimPrefix(filepath.Ext(file), ".")
+	args, filters := []string{"-i", file, "-map_metadata", "0"}, []string{}
+	for _, step := range steps {
+		stepArgs, err := Args(step, videoID, format)
+		if err != nil {
+			return err
+		}
+		for i := 0; i < len(stepArgs); i++ {
+			if (stepArgs[i] == "-af" || stepArgs[i] == "-filter:a") && i+1 < len(stepArgs) {
+				filters = append(filters, stepArgs[i+1])
+				i++
+				continue
+			}
+			args = append(args, stepArgs[i])
+		}
+	}
+	if len(filters) > 0 {
+		args = append(args, "-af", strings.Join(filters, ","))
+	}
+	if !hasBitrate(args) {
+		if br := s.bitrate(ctx, file); br != "" {
+			args = append(args, "-b:a", br)
+		}
+	}
+	if ffmpeg.IsVideo(file) {
+		args = append(args, "-c:v", "copy")
+	} else {
+		args = append(args, "-vn")
+	}
+	return ffmpeg.Runner{Cmd: s.FFmpeg}.Replace(ctx, file, "post", args...)
+}
+
+// bitrate returns bitrate of the file's audio stream in bits per second, empty if it can't be detected
+func (s *Service) bitrate(ctx context.Context, file string) string {
+	cmd := s.FFProbe
+	if cmd == "" {
+		cmd = "ffprobe"
+	}
+	out, err := exec.CommandContext(ctx, cmd, "-v", "error", "-select_streams", "a:0", // nolint
+		"-show_entries", "stream=bit_rate", "-of", "default=noprint_wrappers=1:nokey=1", file).Output()
+	if err != nil {
+		log.Printf("[WARN] can't get bitrate of %s with %s: %v", file, cmd, err)
+		return ""
+	}
+	res := strings.TrimSpace(string(out))
+	if _, err = strconv.Atoi(res); err != nil {
+		log.Printf("[DEBUG] unknown bitrate of %s, %q", file, res)
+		return ""
+	}
+	return res
+}
+
+// hasBitrate checks if args set audio bitrate or quality
+func hasBitrate(args []string) bool {
+	for _, a := range args {
+		if a == "-b:a" || a == "-ab" || a == "-q:a" || a == "-aq" {
+			return true
+		}
+	}
+	return false
+}
+
+// Args makes ffmpeg arguments of the step for the video and format of the file
+func Args(step Step, videoID, format string) ([]string, error) {
+	tmpl := step.Template()
+	if tmpl == "" {
+		return nil, fmt.Errorf("no args for step %q", step.Name)
+	}
+	t, err := template.New(step.Name).Parse(tmpl)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse args of step %q", step.Name)
+	}
+	buf := bytes.Buffer{}
+	params := struct {
+		ID     string
+		Format string
+	}{ID: videoID, Format: format}
+	if err = t.Execute(&buf, params); err != nil {
+		return nil, errors.Wrapf(err, "failed to execute args of step %q", step.Name)
+	}
+	return split(buf.String())
+}
+
+// split splits arguments by spaces, single or double quoted parts are kept as is
+func split(s string) ([]string, error) {
+	res := []string{}
+	arg, inArg, quote := strings.Builder{}, false, rune(0)
+	for _, r := range s {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			arg.WriteRune(r)
+		case r == '"' || r == '\'':
+			quote, inArg = r, true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inArg {
+				res = append(res, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		default:
+			arg.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unclosed quote in %q", s)
+	}
+	if inArg {
+		res = append(res, arg.String())
+	}
+	return res, nil
+}
@@ -0,0 +1,89 @@
+package postproc
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_Process(t *testing.T) {
+	tmp := t.TempDir()
+	// fake ffmpeg saves its arguments and writes the output file, the last argument
+	ffmpeg := filepath.Join(tmp, "ffmpeg")
+	script := "#!/bin/sh\necho \"$@\" > " + tmp + "/args\nfor out; do :; done\necho processed > \"$out\"\n"
+	require.NoError(t, os.WriteFile(ffmpeg, []byte(script), 0o700)) // nolint
+	ffprobe := filepath.Join(tmp, "ffprobe")
+	require.NoError(t, os.WriteFile(ffprobe, []byte("#!/bin/sh\necho 128000\n"), 0o700)) // nolint
+	file := filepath.Join(tmp, "audio.m4a")
+	require.NoError(t, os.WriteFile(file, []byte("audio"), 0o600))
+
+	svc := Service{FFmpeg: ffmpeg, FFProbe: ffprobe}
+	readArgs := func() string {
+		args, err := os.ReadFile(filepath.Join(tmp, "args")) // nolint
+		require.NoError(t, err)
+		return strings.TrimSpace(string(args))
+	}
+
+	require.NoError(t, svc.Process(context.Background(), file, "vid1", []Step{{Name: "loudnorm"}, {Name: "mono"},
+		{Name: "volume", Args: "-af volume=2"}}))
+	data, err := os.ReadFile(file) // nolint
+	require.NoError(t, err)
+	assert.Equal(t, "processed\n", string(data), "file replaced")
+	assert.Equal(t, "-y -v error -i "+file+" -map_metadata 0 -ac 1 -af loudnorm=I=-16:TP=-1.5:LRA=11,volume=2 "+
+		"-b:a 128000 -vn "+filepath.Join(tmp, "audio.post.m4a"), readArgs(), "filters joined, source bitrate kept")
+
+	require.NoError(t, svc.Process(context.Background(), file, "vid1", []Step{{Name: "bitrate"}, {Name: "loudnorm"}}))
+	assert.Equal(t, "-y -v error -i "+file+" -map_metadata 0 -b:a 96k -af loudnorm=I=-16:TP=-1.5:LRA=11 -vn "+
+		filepath.Join(tmp, "audio.post.m4a"), readArgs(), "bitrate of the step")
+
+	require.NoError(t, os.WriteFile(ffprobe, []byte("#!/bin/sh\necho N/A\n"), 0o700)) // nolint
+	video := filepath.Join(tmp, "video.mp4")
+	require.NoError(t, os.WriteFile(video, []byte("video"), 0o600))
+	require.NoError(t, svc.Process(context.Background(), video, "vid1", []Step{{Name: "mono"}}))
+	assert.Equal(t, "-y -v error -i "+video+" -map_metadata 0 -ac 1 -c:v copy "+
+		filepath.Join(tmp, "video.post.mp4"), readArgs(), "unknown bitrate not set")
+
+	err = svc.Process(context.Background(), file, "vid1", []Step{{Name: "mono"}, {Name: "unknown"}})
+	assert.EqualError(t, err, `no args for step "unknown"`)
+	require.NoError(t, svc.Process(context.Background(), file, "vid1", nil), "nothing to do")
+
+	require.NoError(t, os.WriteFile(ffmpeg, []byte("#!/bin/sh\necho bad filter >&2\nexit 1\n"), 0o700)) // nolint
+	err = svc.Process(context.Background(), file, "vid1", []Step{{Name: "mono"}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "bad filter")
+	data, err = os.ReadFile(file) // nolint
+	require.NoError(t, err)
+	assert.Equal(t, "processed\n", string(data), "file kept on error")
+}
+
+func TestArgs(t *testing.T) {
+	tbl := []struct {
+		step Step
+		res  []string
+		err  string
+	}{
+		{Step{Name: "bitrate"}, []string{"-b:a", "96k"}, ""},
+		{Step{Name: "bitrate", Args: `-b:a {{if eq .Format "mp3"}}128k{{else}}64k{{end}}`}, []string{"-b:a", "128k"}, ""},
+		{Step{Name: "meta", Args: `-metadata "comment=processed {{.ID}}" -metadata 'title=a "b"'`},
+			[]string{"-metadata", "comment=processed vid1", "-metadata", `title=a "b"`}, ""},
+		{Step{Name: "bad", Args: "-af {{.ID"}, nil, `failed to parse args of step "bad"`},
+		{Step{Name: "bad", Args: "-af {{.Unknown}}"}, nil, `failed to execute args of step "bad"`},
+		{Step{Name: "bad", Args: `-af "volume=2`}, nil, `unclosed quote in "-af \"volume=2"`},
+		{Step{Name: "nothing"}, nil, `no args for step "nothing"`},
+	}
+	for i, tt := range tbl {
+		res, err := Args(tt.step, "vid1", "mp3")
+		if tt.err != "" {
+			require.Error(t, err, i)
+			assert.Contains(t, err.Error(), tt.err, i)
+			continue
+		}
+		require.NoError(t, err, i)
+		assert.Equal(t, tt.res, res, i)
+	}
+}
@@ -8,24 +8,35 @@ import (
 	"fmt"
 	"os"
 	"path"
+	"path/filepath"
 	"regexp"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/bogem/id3v2/v2"
 	log "github.com/go-pkgz/lgr"
+	"github.com/go-pkgz/syncs"
 	"github.com/google/uuid"
 	"github.com/pkg/errors"
 
 	rssfeed "github.com/umputun/feed-master/app/feed"
+	"github.com/umputun/feed-master/app/metrics"
 	ytfeed "github.com/umputun/feed-master/app/youtube/feed"
+	"github.com/umputun/feed-master/app/youtube/mp4"
+	"github.com/umputun/feed-master/app/youtube/postproc"
+	"github.com/umputun/feed-master/app/youtube/sponsor"
 )
 
 //go:generate moq -out mocks/downloader.go -pkg mocks -skip-ensure -fmt goimports . DownloaderService
 //go:generate moq -out mocks/channel.go -pkg mocks -skip-ensure -fmt goimports . ChannelService
 //go:generate moq -out mocks/store.go -pkg mocks -skip-ensure -fmt goimports . StoreService
 //go:generate moq -out mocks/duration.go -pkg mocks -skip-ensure -fmt goimports . DurationService
+//go:generate moq -out mocks/cover.go -pkg mocks -skip-ensure -fmt goimports . CoverService
+//go:generate moq -out mocks/mp4tags.go -pkg mocks -skip-ensure -fmt goimports . MP4TagsService
+//go:generate moq -out mocks/sponsor.go -pkg mocks -skip-ensure -fmt goimports . SponsorService
+//go:generate moq -out mocks/postproc.go -pkg mocks -skip-ensure -fmt goimports . PostProcService
 
 // Service loads audio from youtube channels
 type Service struct {
@@ -36,30 +47,78 @@ type Service struct {
 	CheckDuration   time.Duration
 	RSSFileStore    RSSFileStore
 	DurationService DurationService
+	CoverService    CoverService   // loads cover art embedded into mp3 files, no cover art if not set
+	MP4TagsService  MP4TagsService // sets metadata of downloaded videos, not set if nil
+	VideoTemplate   string         // download command template for channels in video mode
+	SponsorService  SponsorService // removes skip segments of channels with SkipSegments, not removed if nil
+	PostProcService PostProcService
+	PostProcess     []postproc.Step // steps applied to each downloaded file in order, except skipped by channel
 	KeepPerChannel  int
 	RootURL         string
 	SkipShorts      time.Duration
+	Concurrent      int           // number of channels processed in parallel, 1 if not set
+	RetryAttempts   int           // max download attempts of the entry, failed downloads are not retried if not set
+	RetryDelay      time.Duration // delay before the first retry of failed download, doubled for each next one
+
+	lock         sync.Mutex
+	lastProgress time.Time // time of the last progress, start or end of entry processing or end of channel processing
 }
 
+const (
+	maxRetryDelay = 24 * time.Hour
+	queueKeep     = 7 * 24 * time.Hour // done and skipped queue items are removed after this period
+	imageSize     = 1400               // size of cached square thumbnails, the minimal artwork size for apple podcasts
+)
+
+var (
+	// ErrNotQueued is returned for entries never queued for download
+	ErrNotQueued = errors.New("not queued")
+	// ErrQueueState is returned if the queued entry can't be changed in its current state
+	ErrQueueState = errors.New("not allowed in the current state")
+)
+
+var (
+	downloadsTotal = metrics.NewCounter("feed_master_youtube_downloads_total",
+		"Youtube downloads by result, ok, skip or failed", "result")
+	downloadDuration = metrics.NewHistogram("feed_master_youtube_download_duration_seconds",
+		"Youtube download duration", []float64{5, 10, 30, 60, 120, 300, 600, 1200, 1800, 3600})
+	downloadSize = metrics.NewHistogram("feed_master_youtube_download_size_bytes", "Size of downloaded youtube files",
+		[]float64{1 << 20, 5 << 20, 10 << 20, 25 << 20, 50 << 20, 100 << 20, 250 << 20, 500 << 20})
+	runEntries = metrics.NewGauge("feed_master_youtube_run_entries",
+		"Entries of the last youtube channels processing by state", "state")
+)
+
 // FeedInfo contains channel or feed ID, readable name and other per-feed info
 type FeedInfo struct {
-	Name     string      `yaml:"name"`
-	ID       string      `yaml:"id"`
-	Type     ytfeed.Type `yaml:"type"`
-	Keep     int         `yaml:"keep"`
-	Language string      `yaml:"lang"`
-	Filter   FeedFilter  `yaml:"filter"`
+	Name     string      `yaml:"name" json:"name"`
+	ID       string      `yaml:"id" json:"id"`
+	Type     ytfeed.Type `yaml:"type" json:"type"`
+	Keep     int         `yaml:"keep" json:"keep"`
+	Language string      `yaml:"lang" json:"lang"`
+	Filter   FeedFilter  `yaml:"filter" json:"filter"`
+	Cover    string      `yaml:"cover" json:"cover"` // url or local file of cover art for all entries, video thumbnail is used if not set
+
+	DlTemplate string `yaml:"dl_template" json:"dl_template" redact:"true"` // download command template, overrides the global one
+	Format     string `yaml:"format" json:"format"`                         // format of downloaded files, i.e. mp3, m4a or opus
+	Mode       string `yaml:"mode" json:"mode"`                             // audio (default) or video
+	Resolution int    `yaml:"resolution" json:"resolution"`                 // max height of downloaded videos, 720 if not set
+
+	SkipSegments    []string `yaml:"skip_segments" json:"skip_segments"`       // sponsorblock categories removed from downloaded files, i.e. sponsor
+	SkipPostProcess []string `yaml:"skip_postprocess" json:"skip_postprocess"` // names of post-processing steps not applied, "all" to skip all
 }
 
+// ModeVideo is FeedInfo.Mode of channels downloaded as mp4 videos instead of audio
+const ModeVideo = "video"
+
 // FeedFilter contains filter criteria for the feed
 type FeedFilter struct {
-	Include string `yaml:"include"`
-	Exclude string `yaml:"exclude"`
+	Include string `yaml:"include" json:"include"`
+	Exclude string `yaml:"exclude" json:"exclude"`
 }
 
 // DownloaderService is an interface for downloading audio from youtube
 type DownloaderService interface {
-	Get(ctx context.Context, id string, fname string) (file string, err error)
+	Get(ctx context.Context, id string, fname string, opts ytfeed.DownloadOptions) (file string, err error)
 }
 
 // ChannelService is an interface for getting channel entries, i.e. the list of videos
@@ -78,6 +137,10 @@ type StoreService interface {
 	ResetProcessed(entry ytfeed.Entry) error
 	CheckProcessed(entry ytfeed.Entry) (found bool, ts time.Time, err error)
 	CountProcessed() (count int)
+	SetQueued(item ytfeed.QueueItem) error
+	Queued(entry ytfeed.Entry) (item ytfeed.QueueItem, found bool, err error)
+	ListQueued(channelID string) ([]ytfeed.QueueItem, error)
+	RemoveQueued(before time.Time) (count int, err error)
 }
 
 // DurationService is an interface for getting duration of audio file
@@ -85,6 +148,28 @@ type DurationService interface {
 	File(fname string) int
 }
 
+// CoverService is an interface for loading cover art and thumbnails as jpeg from url or local file
+type CoverService interface {
+	Get(ctx context.Context, src string) ([]byte, error)
+	Square(ctx context.Context, src string, size int) ([]byte, error)
+}
+
+// MP4TagsService is an interface for setting metadata of mp4 files
+type MP4TagsService interface {
+	Set(ctx context.Context, file string, tags mp4.Tags) error
+}
+
+// SponsorService is an interface for loading skip segments of the video and cutting them out of the file
+type SponsorService interface {
+	Segments(ctx context.Context, videoID string, categories []string) ([]sponsor.Segment, error)
+	Cut(ctx context.Context, file string, segments []sponsor.Segment) error
+}
+
+// PostProcService is an interface for running post-processing steps on downloaded file
+type PostProcService interface {
+	Process(ctx context.Context, file, videoID string, steps []postproc.Step) error
+}
+
 // Do is a blocking function that downloads audio from youtube channels and updates metadata
 func (s *Service) Do(ctx context.Context) error {
 	log.Printf("[INFO] starting youtube service")
@@ -92,7 +177,7 @@ func (s *Service) Do(ctx context.Context) error {
 	if s.SkipShorts > 0 {
 		log.Printf("[DEBUG] skip youtube episodes shorter than %v", s.SkipShorts)
 	}
-	for _, f := range s.Feeds {
+	for _, f := range s.feeds() {
 		log.Printf("[INFO] youtube feed %+v", f)
 	}
 
@@ -115,18 +200,69 @@ func (s *Service) Do(ctx context.Context) error {
 	}
 }
 
+// SetFeeds replaces the list of channels, used on config reload. Applied starting from the next update,
+// downloads in progress are not interrupted
+func (s *Service) SetFeeds(feeds []FeedInfo) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.Feeds = feeds
+}
+
+// LastProgress returns time of the last progress of processing, start or end of entry download and processing,
+// or end of channel processing. Zero if nothing processed yet. Long initial load of many entries keeps it fresh,
+// as well as a long download as it's started.
+func (s *Service) LastProgress() time.Time {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.lastProgress
+}
+
+// progress marks the progress of processing, see LastProgress
+func (s *Service) progress() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.lastProgress = time.Now()
+}
+
+func (s *Service) concurrent() int {
+	if s.Concurrent < 1 {
+		return 1
+	}
+	return s.Concurrent
+}
+
+func (s *Service) feeds() []FeedInfo {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.Feeds
+}
+
 // RSSFeed generates RSS feed for given channel
 func (s *Service) RSSFeed(fi FeedInfo) (string, error) {
+	return s.FilteredRSSFeed(fi, nil)
+}
+
+// FilteredRSSFeed generates RSS feed for given channel without entries skip func returns true for, skip is optional
+func (s *Service) FilteredRSSFeed(fi FeedInfo, skip func(entry ytfeed.Entry) bool) (string, error) {
 	entries, err := s.Store.Load(fi.ID, s.keep(fi))
 	if err != nil {
 		return "", errors.Wrap(err, "failed to get channel entries")
 	}
+	if skip != nil {
+		res := make([]ytfeed.Entry, 0, len(entries))
+		for _, entry := range entries {
+			if !skip(entry) {
+				res = append(res, entry)
+			}
+		}
+		entries = res
+	}
 
 	if len(entries) == 0 {
 		return "", nil
 	}
 
-	items := []rssfeed.Item{}
+	items, hasChapters := []rssfeed.Item{}, false
 	for _, entry := range entries {
 
 		fileURL := s.RootURL + "/" + path.Base(entry.File)
@@ -143,6 +279,18 @@ func (s *Service) RSSFeed(fi FeedInfo) (string, error) {
 			duration = fmt.Sprintf("%d", entry.Duration)
 		}
 
+		var chapters *rssfeed.PodcastChapters
+		if len(entry.Chapters) > 0 {
+			chapters = &rssfeed.PodcastChapters{URL: s.RootURL + "/" + path.Base(ytfeed.ChaptersFile(entry.File)),
+				Type: "application/json+chapters"}
+			hasChapters = true
+		}
+
+		var image *rssfeed.ItemImage
+		if entry.Image != "" {
+			image = &rssfeed.ItemImage{URL: s.RootURL + "/" + path.Base(entry.Image)}
+		}
+
 		items = append(items, rssfeed.Item{
 			Title:       entry.Title,
 			Description: entry.Media.Description,
@@ -152,10 +300,12 @@ func (s *Service) RSSFeed(fi FeedInfo) (string, error) {
 			Author:      entry.Author.Name,
 			Enclosure: rssfeed.Enclosure{
 				URL:    fileURL,
-				Type:   "audio/mpeg",
+				Type:   ytfeed.MimeType(entry.File),
 				Length: fileSize,
 			},
 			Duration: duration,
+			Chapters: chapters,
+			Image:    image,
 			DT:       time.Now(),
 		})
 	}
@@ -175,13 +325,20 @@ func (s *Service) RSSFeed(fi FeedInfo) (string, error) {
 		ItunesExplicit: "no",
 	}
 
-	// set image from channel as rss thumbnail
-	// TODO: we may want to load it locally in case if youtube doesn't like such remote usage of images
-	if image := entries[0].Media.Thumbnail.URL; image != "" {
+	// set image of the last entry as rss thumbnail, cached one if available
+	image := entries[0].Media.Thumbnail.URL
+	if entries[0].Image != "" {
+		image = s.RootURL + "/" + path.Base(entries[0].Image)
+	}
+	if image != "" {
 		rss.ItunesImage = &rssfeed.ItunesImg{URL: image}
 		rss.MediaThumbnail = &rssfeed.MediaThumbnail{URL: image}
 	}
 
+	if hasChapters {
+		rss.NsPodcast = "https://podcastindex.org/namespace/1.0"
+	}
+
 	if fi.Type == ytfeed.FTPlaylist {
 		rss.Link = "https://www.youtube.com/playlist?list=" + fi.ID
 	}
@@ -195,151 +352,389 @@ func (s *Service) RSSFeed(fi FeedInfo) (string, error) {
 	// this hack to avoid having different items for marshal and unmarshal due to "itunes" namespace
 	res = strings.Replace(res, "<duration>", "<itunes:duration>", -1)
 	res = strings.Replace(res, "</duration>", "</itunes:duration>", -1)
+	res = strings.Replace(res, "<chapters ", "<podcast:chapters ", -1)
+	res = strings.Replace(res, "</chapters>", "</podcast:chapters>", -1)
+	res = strings.Replace(res, "<image href=", "<itunes:image href=", -1)
+	res = strings.Replace(res, "</image>", "</itunes:image>", -1)
 	return res, nil
 }
 
-// procChannels processes all channels, downloads audio, updates metadata and stores RSS
+// procChannels processes all channels, downloads audio, updates metadata and stores RSS.
+// Channels are processed by the pool of s.Concurrent workers, entries of each channel are processed in order
 func (s *Service) procChannels(ctx context.Context) error {
-
 	var allStats stats
+	var firstErr error
+	lock := sync.Mutex{}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	ewg := syncs.NewErrSizedGroup(s.concurrent(), syncs.Preemptive, syncs.TermOnErr, syncs.Context(ctx))
+	for _, feedInfo := range s.feeds() {
+		feedInfo := feedInfo
+		ewg.Go(func() error {
+			st, err := s.procChannel(ctx, feedInfo)
+			lock.Lock()
+			defer lock.Unlock()
+			allStats.add(st)
+			if err != nil && firstErr == nil {
+				firstErr = err
+				cancel() // stop other workers, the first error is reported
+			}
+			return err
+		})
+	}
+	if err := ewg.Wait(); err != nil {
+		if firstErr == nil { // context canceled before any channel processed
+			firstErr = ctx.Err()
+		}
+		return firstErr
+	}
+
+	log.Printf("[INFO] all channels processed - channels: %d, %s, lifetime: %d, feed size: %d",
+		len(s.feeds()), allStats.String(), s.Store.CountProcessed(), s.countAllEntries())
+	allStats.report()
 
-	for _, feedInfo := range s.Feeds {
-		entries, err := s.ChannelService.Get(ctx, feedInfo.ID, feedInfo.Type)
+	if n, err := s.Store.RemoveQueued(time.Now().Add(-queueKeep)); err != nil {
+		log.Printf("[WARN] failed to remove old queue items, %v", err)
+	} else if n > 0 {
+		log.Printf("[DEBUG] removed %d old queue items", n)
+	}
+
+	newestEntry := s.newestEntry()
+	log.Printf("[INFO] last entry: %s", newestEntry.String())
+	return nil
+}
+
+// procChannel processes entries of the channel and retries its failed downloads,
+// stops on the first error of the store or context cancellation
+func (s *Service) procChannel(ctx context.Context, feedInfo FeedInfo) (st stats, err error) {
+	entries, err := s.ChannelService.Get(ctx, feedInfo.ID, feedInfo.Type)
+	if err != nil {
+		log.Printf("[WARN] failed to get channel entries for %s: %s", feedInfo.ID, err)
+		return st, nil
+	}
+	log.Printf("[INFO] got %d entries for %s, limit to %d", len(entries), feedInfo.Name, s.keep(feedInfo))
+	changed, processed := false, 0
+	seen := map[string]bool{}
+	for i, entry := range entries {
+
+		// exit right away if context is done
+		select {
+		case <-ctx.Done():
+			return st, ctx.Err()
+		default:
+		}
+
+		st.entries++
+		if processed >= s.keep(feedInfo) {
+			break
+		}
+		seen[entry.VideoID] = true
+		isAllowed, err := s.isAllowed(entry, feedInfo)
 		if err != nil {
-			log.Printf("[WARN] failed to get channel entries for %s: %s", feedInfo.ID, err)
+			return st, errors.Wrapf(err, "failed to check if entry %s is relevant", entry.VideoID)
+		}
+		if !isAllowed {
+			log.Printf("[DEBUG] skipping filtered %s", entry.String())
+			st.ignored++
 			continue
 		}
-		log.Printf("[INFO] got %d entries for %s, limit to %d", len(entries), feedInfo.Name, s.keep(feedInfo))
-		changed, processed := false, 0
-		for i, entry := range entries {
 
-			// exit right away if context is done
-			select {
-			case <-ctx.Done():
-				return ctx.Err()
-			default:
-			}
+		ok, err := s.isNew(entry, feedInfo)
+		if err != nil {
+			return st, errors.Wrapf(err, "failed to check if entry %s exists", entry.VideoID)
+		}
+		if !ok {
+			st.skipped++
+			processed++
+			continue
+		}
 
-			allStats.entries++
-			if processed >= s.keep(feedInfo) {
-				break
-			}
-			isAllowed, err := s.isAllowed(entry, feedInfo)
-			if err != nil {
-				return errors.Wrapf(err, "failed to check if entry %s is relevant", entry.VideoID)
-			}
-			if !isAllowed {
-				log.Printf("[DEBUG] skipping filtered %s", entry.String())
-				allStats.ignored++
-				continue
+		// got new entry, but with very old timestamp. skip it if we have already reached max capacity
+		// (this is to eliminate the initial load) and this entry is older than the oldest one we have.
+		// Also marks it as processed as we don't want to process it again
+		oldestEntry := s.oldestEntry()
+		if entry.Published.Before(oldestEntry.Published) && s.countAllEntries() >= s.totalEntriesToKeep() {
+			st.ignored++
+			log.Printf("[INFO] skipping entry %s as it is older than the oldest one we have %s",
+				entry.String(), oldestEntry.String())
+			if procErr := s.Store.SetProcessed(entry); procErr != nil {
+				log.Printf("[WARN] failed to set processed status for %s: %v", entry.VideoID, procErr)
 			}
+			continue
+		}
 
-			ok, err := s.isNew(entry, feedInfo)
-			if err != nil {
-				return errors.Wrapf(err, "failed to check if entry %s exists", entry.VideoID)
-			}
-			if !ok {
-				allStats.skipped++
-				processed++
-				continue
-			}
+		// failed entry is retried after the delay, even if still in the channel's feed
+		if item, found, qErr := s.Store.Queued(entry); qErr != nil {
+			log.Printf("[WARN] can't get queue state for %s, %v", entry.VideoID, qErr)
+		} else if found && item.State == ytfeed.QueueFailed && !item.Due(time.Now()) {
+			log.Printf("[DEBUG] skipping %s, failed %d times, next try at %s", entry.VideoID, item.Attempts,
+				item.NextTry.Format(time.RFC3339))
+			st.skipped++
+			continue
+		}
 
-			// got new entry, but with very old timestamp. skip it if we have already reached max capacity
-			// (this is to eliminate the initial load) and this entry is older than the oldest one we have.
-			// Also marks it as processed as we don't want to process it again
-			oldestEntry := s.oldestEntry()
-			if entry.Published.Before(oldestEntry.Published) && s.countAllEntries() >= s.totalEntriesToKeep() {
-				allStats.ignored++
-				log.Printf("[INFO] skipping entry %s as it is older than the oldest one we have %s",
-					entry.String(), oldestEntry.String())
-				if procErr := s.Store.SetProcessed(entry); procErr != nil {
-					log.Printf("[WARN] failed to set processed status for %s: %v", entry.VideoID, procErr)
-				}
-				continue
-			}
+		log.Printf("[INFO] new entry [%d] %s, %s, %s, %s", i+1, entry.VideoID, entry.Title, feedInfo.Name, entry.String())
+		saved, err := s.procEntry(ctx, entry, feedInfo, &st)
+		if err != nil {
+			return st, err
+		}
+		if saved {
+			processed++
+			changed = true
+		}
+	}
+	st.processed += processed
 
-			log.Printf("[INFO] new entry [%d] %s, %s, %s, %s", i+1, entry.VideoID, entry.Title, feedInfo.Name, entry.String())
-
-			file, downErr := s.Downloader.Get(ctx, entry.VideoID, s.makeFileName(entry))
-			if downErr != nil {
-				allStats.ignored++
-				if downErr == ytfeed.ErrSkip { // downloader decided to skip this entry
-					log.Printf("[INFO] skipping %s", entry.String())
-					continue
-				}
-				log.Printf("[WARN] failed to download %s: %s", entry.VideoID, downErr)
-				continue
-			}
+	// retry queued entries which are not in the channel's feed anymore
+	retried, err := s.procQueued(ctx, feedInfo, seen, &st)
+	if err != nil {
+		return st, err
+	}
+	changed = changed || retried
 
-			if short, duration := s.isShort(file); short {
-				allStats.ignored++
-				log.Printf("[INFO] skip short file %s (%v): %s, %s", file, duration, entry.VideoID, entry.String())
-				if procErr := s.Store.SetProcessed(entry); procErr != nil {
-					log.Printf("[WARN] failed to set processed status for %s: %v", entry.VideoID, procErr)
-				}
-				if errRm := os.Remove(file); errRm != nil {
-					log.Printf("[WARN] failed to remove short video's %s file %s: %v", entry.VideoID, file, errRm)
-				}
-				continue
-			}
+	if changed {
+		removed := s.removeOld(feedInfo)
+		st.removed += removed
 
-			// update metadata
-			if tagsErr := s.updateMp3Tags(file, entry, feedInfo); tagsErr != nil {
-				log.Printf("[WARN] failed to update metadata for %s: %s", entry.VideoID, tagsErr)
+		// save rss feed to fs if there are new entries
+		rss, rssErr := s.RSSFeed(feedInfo)
+		if rssErr != nil {
+			log.Printf("[WARN] failed to generate rss for %s: %s", feedInfo.Name, rssErr)
+		} else {
+			if err := s.RSSFileStore.Save(feedInfo.ID, rss); err != nil {
+				log.Printf("[WARN] failed to save rss for %s: %s", feedInfo.Name, err)
 			}
+		}
+	}
+	s.progress()
+	return st, nil
+}
 
-			processed++
+// procQueued downloads due queue items of the channel not in seen, i.e. failed entries not in the channel's feed anymore
+// or requested to retry. Returns true if any entry saved.
+func (s *Service) procQueued(ctx context.Context, feedInfo FeedInfo, seen map[string]bool, st *stats) (changed bool, err error) {
+	items, err := s.Store.ListQueued(feedInfo.ID)
+	if err != nil {
+		log.Printf("[WARN] can't get queued entries for %s, %v", feedInfo.ID, err)
+		return false, nil
+	}
+	now := time.Now()
+	for _, item := range items {
+		if seen[item.Entry.VideoID] || !item.Due(now) {
+			continue
+		}
+		if ctx.Err() != nil {
+			return changed, ctx.Err()
+		}
+		log.Printf("[INFO] retry queued entry %s, %s, %s, attempts: %d", item.Entry.VideoID, item.Entry.Title,
+			feedInfo.Name, item.Attempts)
+		saved, err := s.procEntry(ctx, item.Entry, feedInfo, st)
+		if err != nil {
+			return changed, err
+		}
+		if saved {
+			st.processed++
+			changed = true
+		}
+	}
+	return changed, nil
+}
 
-			fsize := 0
-			if fi, err := os.Stat(file); err == nil {
-				fsize = int(fi.Size())
-			} else {
-				log.Printf("[WARN] failed to get file size for %s: %v", file, err)
-			}
+// procEntry downloads the entry, updates metadata and saves it. Download state is kept in the queue, failed downloads
+// are retried with increasing delay till attempts exhausted. Returns true if the entry saved.
+func (s *Service) procEntry(ctx context.Context, entry ytfeed.Entry, feedInfo FeedInfo, st *stats) (saved bool, err error) {
+	s.progress()
+	defer s.progress()
+	item, _, qErr := s.Store.Queued(entry) // keeps attempts of the previous failures
+	if qErr != nil {
+		log.Printf("[WARN] can't get queue state for %s, %v", entry.VideoID, qErr)
+	}
+	item.Entry = entry
+	item.Attempts++
+	item = s.setQueued(item, ytfeed.QueueDownloading, "")
+
+	downStart := time.Now()
+	file, downErr := s.Downloader.Get(ctx, entry.VideoID, s.makeFileName(entry), s.downloadOptions(feedInfo))
+	downloadDuration.Observe(time.Since(downStart).Seconds())
+	if downErr != nil {
+		st.ignored++
+		if downErr == ytfeed.ErrSkip { // downloader decided to skip this entry
+			downloadsTotal.Inc("skip")
+			log.Printf("[INFO] skipping %s", entry.String())
+			s.setQueued(item, ytfeed.QueueSkipped, "skipped by downloader")
+			return false, nil
+		}
+		if ctx.Err() != nil { // interrupted, the attempt is not counted
+			item.Attempts--
+			s.setQueued(item, ytfeed.QueuePending, "")
+			return false, nil
+		}
+		downloadsTotal.Inc("failed")
+		item = s.setQueued(item, ytfeed.QueueFailed, downErr.Error())
+		if !item.NextTry.IsZero() {
+			log.Printf("[WARN] failed to download %s, attempt %d, next try at %s: %s", entry.VideoID, item.Attempts,
+				item.NextTry.Format(time.RFC3339), downErr)
+			return false, nil
+		}
+		log.Printf("[WARN] failed to download %s, giving up after %d attempts: %s", entry.VideoID, item.Attempts, downErr)
+		if procErr := s.Store.SetProcessed(entry); procErr != nil {
+			log.Printf("[WARN] failed to set processed status for %s: %v", entry.VideoID, procErr)
+		}
+		return false, nil
+	}
+	downloadsTotal.Inc("ok")
 
-			log.Printf("[INFO] downloaded %s (%s) to %s, size: %d, channel: %+v", entry.VideoID, entry.Title, file, fsize, feedInfo)
+	info := s.loadInfo(file)
+	entry = info.Apply(entry)
+	if len(entry.Chapters) == 0 { // no chapters in metadata, try timestamps in description
+		entry.Chapters = ytfeed.ParseChapters(string(entry.Media.Description), info.Duration)
+	}
 
-			entry = s.update(entry, file, feedInfo)
+	if short, duration := s.isShort(file, info); short {
+		st.ignored++
+		log.Printf("[INFO] skip short file %s (%v): %s, %s", file, duration, entry.VideoID, entry.String())
+		s.setQueued(item, ytfeed.QueueSkipped, fmt.Sprintf("too short, %v", duration))
+		if procErr := s.Store.SetProcessed(entry); procErr != nil {
+			log.Printf("[WARN] failed to set processed status for %s: %v", entry.VideoID, procErr)
+		}
+		if errRm := os.Remove(file); errRm != nil {
+			log.Printf("[WARN] failed to remove short video's %s file %s: %v", entry.VideoID, file, errRm)
+		}
+		return false, nil
+	}
 
-			ok, saveErr := s.Store.Save(entry)
-			if saveErr != nil {
-				return errors.Wrapf(saveErr, "failed to save entry %+v", entry)
-			}
-			if !ok {
-				log.Printf("[WARN] attempt to save dup entry %+v", entry)
-			}
-			changed = true
-			if procErr := s.Store.SetProcessed(entry); procErr != nil {
-				log.Printf("[WARN] failed to set processed status for %s: %v", entry.VideoID, procErr)
-			}
-			allStats.added++
-			log.Printf("[INFO] saved %s (%s) to %s, channel: %+v", entry.VideoID, entry.Title, file, feedInfo)
-		}
-		allStats.processed += processed
-
-		if changed {
-			removed := s.removeOld(feedInfo)
-			allStats.removed += removed
-
-			// save rss feed to fs if there are new entries
-			rss, rssErr := s.RSSFeed(feedInfo)
-			if rssErr != nil {
-				log.Printf("[WARN] failed to generate rss for %s: %s", feedInfo.Name, rssErr)
-			} else {
-				if err := s.RSSFileStore.Save(feedInfo.ID, rss); err != nil {
-					log.Printf("[WARN] failed to save rss for %s: %s", feedInfo.Name, err)
-				}
-			}
+	entry, info = s.removeSegments(ctx, entry, file, feedInfo, info)
+	info = s.postProcess(ctx, entry, file, feedInfo, info)
+	entry.Image = s.cacheImage(ctx, entry, file)
+
+	// update metadata, id3 tags for mp3 and metadata atoms for mp4
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".mp3":
+		if tagsErr := s.updateMp3Tags(ctx, file, entry, feedInfo); tagsErr != nil {
+			log.Printf("[WARN] failed to update metadata for %s: %s", entry.VideoID, tagsErr)
+		}
+	case ".mp4":
+		if tagsErr := s.updateMp4Tags(ctx, file, entry, feedInfo); tagsErr != nil {
+			log.Printf("[WARN] failed to update metadata for %s: %s", entry.VideoID, tagsErr)
 		}
 	}
 
-	log.Printf("[INFO] all channels processed - channels: %d, %s, lifetime: %d, feed size: %d",
-		len(s.Feeds), allStats.String(), s.Store.CountProcessed(), s.countAllEntries())
+	fsize := 0
+	if fi, err := os.Stat(file); err == nil {
+		fsize = int(fi.Size())
+		downloadSize.Observe(float64(fsize))
+	} else {
+		log.Printf("[WARN] failed to get file size for %s: %v", file, err)
+	}
 
-	newestEntry := s.newestEntry()
-	log.Printf("[INFO] last entry: %s", newestEntry.String())
+	log.Printf("[INFO] downloaded %s (%s) to %s, size: %d, channel: %+v", entry.VideoID, entry.Title, file, fsize, feedInfo)
 
-	return nil
+	entry = s.update(entry, file, feedInfo, info)
+	if len(entry.Chapters) > 0 {
+		if chapErr := ytfeed.WriteChapters(file, entry.Chapters); chapErr != nil {
+			log.Printf("[WARN] failed to write chapters for %s: %v", entry.VideoID, chapErr)
+		}
+	}
+
+	ok, saveErr := s.Store.Save(entry)
+	if saveErr != nil {
+		return false, errors.Wrapf(saveErr, "failed to save entry %+v", entry)
+	}
+	if !ok {
+		log.Printf("[WARN] attempt to save dup entry %+v", entry)
+	}
+	if procErr := s.Store.SetProcessed(entry); procErr != nil {
+		log.Printf("[WARN] failed to set processed status for %s: %v", entry.VideoID, procErr)
+	}
+	s.setQueued(item, ytfeed.QueueDone, "")
+	st.added++
+	log.Printf("[INFO] saved %s (%s) to %s, channel: %+v", entry.VideoID, entry.Title, file, feedInfo)
+	return true, nil
+}
+
+// setQueued saves the item with the given state and error. Failed item gets the next try time
+// unless attempts are exhausted, the delay is doubled for each attempt.
+func (s *Service) setQueued(item ytfeed.QueueItem, state ytfeed.QueueState, errMsg string) ytfeed.QueueItem {
+	item.State, item.Error, item.Updated, item.NextTry = state, errMsg, time.Now(), time.Time{}
+	if state == ytfeed.QueueFailed && item.Attempts < s.RetryAttempts {
+		delay := s.RetryDelay
+		for i := 1; i < item.Attempts && delay < maxRetryDelay; i++ {
+			delay *= 2
+		}
+		if delay > maxRetryDelay {
+			delay = maxRetryDelay
+		}
+		item.NextTry = item.Updated.Add(delay)
+	}
+	if err := s.Store.SetQueued(item); err != nil {
+		log.Printf("[WARN] failed to save queue state %s for %s: %v", state, item.Entry.VideoID, err)
+	}
+	return item
+}
+
+// Queue returns download states of entries of all channels, only with the given state if it is not empty
+func (s *Service) Queue(state ytfeed.QueueState) ([]ytfeed.QueueItem, error) {
+	items, err := s.Store.ListQueued("")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list queue")
+	}
+	if state == "" {
+		return items, nil
+	}
+	res := []ytfeed.QueueItem{}
+	for _, item := range items {
+		if item.State == state {
+			res = append(res, item)
+		}
+	}
+	return res, nil
+}
+
+// RetryQueued resets failed or skipped entry to pending with no attempts made. It is downloaded on the next update,
+// even if it is not in the channel's feed anymore.
+func (s *Service) RetryQueued(chanID, videoID string) (ytfeed.QueueItem, error) {
+	item, err := s.queued(chanID, videoID, ytfeed.QueueFailed, ytfeed.QueueSkipped)
+	if err != nil {
+		return item, err
+	}
+	if err = s.Store.ResetProcessed(item.Entry); err != nil {
+		return item, errors.Wrapf(err, "failed to reset processed status for %s", videoID)
+	}
+	item.Attempts = 0
+	log.Printf("[INFO] retry requested for %s, %s", videoID, item.Entry.Title)
+	return s.setQueued(item, ytfeed.QueuePending, ""), nil
+}
+
+// GiveUpQueued marks failed or pending entry as skipped and processed, it won't be downloaded anymore
+func (s *Service) GiveUpQueued(chanID, videoID string) (ytfeed.QueueItem, error) {
+	item, err := s.queued(chanID, videoID, ytfeed.QueueFailed, ytfeed.QueuePending)
+	if err != nil {
+		return item, err
+	}
+	if err = s.Store.SetProcessed(item.Entry); err != nil {
+		return item, errors.Wrapf(err, "failed to set processed status for %s", videoID)
+	}
+	log.Printf("[INFO] gave up on %s, %s", videoID, item.Entry.Title)
+	return s.setQueued(item, ytfeed.QueueSkipped, "given up"), nil
+}
+
+// queued returns queue item of the entry, fails with ErrNotQueued if not found and ErrQueueState if its state
+// is not one of allowed
+func (s *Service) queued(chanID, videoID string, allowed ...ytfeed.QueueState) (ytfeed.QueueItem, error) {
+	item, found, err := s.Store.Queued(ytfeed.Entry{ChannelID: chanID, VideoID: videoID})
+	if err != nil {
+		return item, errors.Wrapf(err, "failed to get queue state for %s", videoID)
+	}
+	if !found {
+		return item, errors.Wrapf(ErrNotQueued, "entry %s of %s", videoID, chanID)
+	}
+	for _, st := range allowed {
+		if item.State == st {
+			return item, nil
+		}
+	}
+	return item, errors.Wrapf(ErrQueueState, "entry %s is %s", videoID, item.State)
 }
 
 // StoreRSS saves RSS feed to file
@@ -408,10 +803,13 @@ func (s *Service) isAllowed(entry ytfeed.Entry, fi FeedInfo) (ok bool, err error
 	return matchedIncludeFilter && !matchedExcludeFilter, nil
 }
 
-func (s *Service) isShort(file string) (bool, time.Duration) {
+func (s *Service) isShort(file string, info ytfeed.Info) (bool, time.Duration) {
 	if s.SkipShorts.Seconds() > 0 {
 		// skip shorts if duration is less than SkipShorts
-		duration := s.DurationService.File(file)
+		duration := int(info.Duration)
+		if duration == 0 {
+			duration = s.DurationService.File(file)
+		}
 		if duration > 0 && duration < int(s.SkipShorts.Seconds()) {
 			return true, time.Duration(duration) * time.Second
 		}
@@ -420,7 +818,7 @@ func (s *Service) isShort(file string) (bool, time.Duration) {
 }
 
 // update sets entry file name and reset published ts
-func (s *Service) update(entry ytfeed.Entry, file string, fi FeedInfo) ytfeed.Entry {
+func (s *Service) update(entry ytfeed.Entry, file string, fi FeedInfo, info ytfeed.Info) ytfeed.Entry {
 	entry.File = file
 
 	// only reset time if published not too long ago
@@ -438,7 +836,12 @@ func (s *Service) update(entry ytfeed.Entry, file string, fi FeedInfo) ytfeed.En
 		entry.Title = fi.Name + ": " + entry.Title
 	}
 
-	entry.Duration = s.DurationService.File(file)
+	if info.Duration == 0 { // duration from metadata is set already
+		entry.Duration = s.DurationService.File(file)
+	}
+	if n := len(entry.Chapters); n > 0 && entry.Chapters[n-1].End == 0 { // the last chapter ends with the file
+		entry.Chapters[n-1].End = float64(entry.Duration)
+	}
 	log.Printf("[DEBUG] updated entry: %s", entry.String())
 	return entry
 }
@@ -459,6 +862,11 @@ func (s *Service) removeOld(fi FeedInfo) int {
 		}
 		removed++
 		log.Printf("[INFO] removed %s for %s (%s)", f, fi.ID, fi.Name)
+		for _, extra := range []string{ytfeed.ChaptersFile(f), ytfeed.ImageFile(f)} {
+			if e := os.Remove(extra); e != nil && !errors.Is(e, os.ErrNotExist) {
+				log.Printf("[WARN] failed to remove %s: %v", extra, e)
+			}
+		}
 	}
 	return removed
 }
@@ -481,7 +889,7 @@ func (s *Service) makeFileName(entry ytfeed.Entry) string {
 
 // totalEntriesToKeep returns total number of entries to keep, summing all channels' keep values
 func (s *Service) totalEntriesToKeep() (res int) {
-	for _, fi := range s.Feeds {
+	for _, fi := range s.feeds() {
 		res += s.keep(fi)
 	}
 	return res
@@ -490,7 +898,7 @@ func (s *Service) totalEntriesToKeep() (res int) {
 // countAllEntries returns total number of entries across all channels, respects keep settings
 func (s *Service) countAllEntries() int {
 	var result int
-	for _, fi := range s.Feeds {
+	for _, fi := range s.feeds() {
 		if entries, err := s.Store.Load(fi.ID, s.keep(fi)); err == nil {
 			result += len(entries)
 		}
@@ -501,7 +909,7 @@ func (s *Service) countAllEntries() int {
 // newestEntry returns the newest entry across all channels, respects keep settings
 func (s *Service) newestEntry() ytfeed.Entry {
 	entries := []ytfeed.Entry{}
-	for _, fi := range s.Feeds {
+	for _, fi := range s.feeds() {
 		if recs, err := s.Store.Load(fi.ID, 1); err == nil {
 			entries = append(entries, recs...)
 		}
@@ -518,7 +926,7 @@ func (s *Service) newestEntry() ytfeed.Entry {
 // oldestEntry returns the oldest entry from all channels, respecting keep settings
 func (s *Service) oldestEntry() ytfeed.Entry {
 	entries := []ytfeed.Entry{}
-	for _, fi := range s.Feeds {
+	for _, fi := range s.feeds() {
 		if recs, err := s.Store.Load(fi.ID, s.keep(fi)); err == nil {
 			entries = append(entries, recs...)
 		}
@@ -532,7 +940,97 @@ func (s *Service) oldestEntry() ytfeed.Entry {
 	return entries[0]
 }
 
-func (s *Service) updateMp3Tags(file string, entry ytfeed.Entry, fi FeedInfo) error {
+// loadInfo loads metadata written by downloader next to the file and removes it, as it is large and not needed anymore.
+// Returns empty info if metadata is not available, i.e. not enabled in download template.
+func (s *Service) loadInfo(file string) ytfeed.Info {
+	info, err := ytfeed.LoadInfo(file)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Printf("[WARN] failed to load metadata for %s: %v", file, err)
+		}
+		return ytfeed.Info{}
+	}
+	if err := os.Remove(ytfeed.InfoFile(file)); err != nil {
+		log.Printf("[WARN] failed to remove metadata file %s: %v", ytfeed.InfoFile(file), err)
+	}
+	log.Printf("[DEBUG] loaded metadata for %s, duration: %v, chapters: %d, tags: %d, live status: %s",
+		info.ID, info.Duration, len(info.Chapters), len(info.Tags), info.LiveStatus)
+	return info
+}
+
+// removeSegments cuts skip segments of the channel's categories out of the file and moves chapters accordingly.
+// Duration from metadata is reset on success, so it is detected from the cut file.
+func (s *Service) removeSegments(ctx context.Context, entry ytfeed.Entry, file string, fi FeedInfo,
+	info ytfeed.Info) (ytfeed.Entry, ytfeed.Info) {
+	if s.SponsorService == nil || len(fi.SkipSegments) == 0 {
+		return entry, info
+	}
+	segments, err := s.SponsorService.Segments(ctx, entry.VideoID, fi.SkipSegments)
+	if err != nil {
+		log.Printf("[WARN] failed to get skip segments for %s: %v", entry.VideoID, err)
+		return entry, info
+	}
+	if len(segments) == 0 {
+		return entry, info
+	}
+	if err = s.SponsorService.Cut(ctx, file, segments); err != nil {
+		log.Printf("[WARN] failed to remove skip segments of %s: %v", entry.VideoID, err)
+		return entry, info
+	}
+	log.Printf("[INFO] removed %d skip segments (%.0fs) of %s", len(segments), sponsor.Total(segments), entry.VideoID)
+	entry.Chapters = sponsor.AdjustChapters(entry.Chapters, segments)
+	info.Duration = 0
+	return entry, info
+}
+
+// postProcess runs post-processing steps on the file, except skipped by the channel. Failure is reported
+// and the file is kept as it was. Duration from metadata is reset on success, as steps like silence trimming change it.
+func (s *Service) postProcess(ctx context.Context, entry ytfeed.Entry, file string, fi FeedInfo,
+	info ytfeed.Info) ytfeed.Info {
+	if s.PostProcService == nil {
+		return info
+	}
+	skip := map[string]bool{}
+	for _, name := range fi.SkipPostProcess {
+		skip[name] = true
+	}
+	if skip["all"] {
+		return info
+	}
+	steps, names := []postproc.Step{}, []string{}
+	for _, step := range s.PostProcess {
+		if !skip[step.Name] {
+			steps, names = append(steps, step), append(names, step.Name)
+		}
+	}
+	if len(steps) == 0 {
+		return info
+	}
+	if err := s.PostProcService.Process(ctx, file, entry.VideoID, steps); err != nil {
+		log.Printf("[WARN] post-processing %v failed for %s: %v", names, entry.VideoID, err)
+		return info
+	}
+	log.Printf("[DEBUG] post-processing %v done for %s", names, entry.VideoID)
+	info.Duration = 0
+	return info
+}
+
+// downloadOptions returns download options of the channel, video mode uses VideoTemplate and mp4 format by default
+func (s *Service) downloadOptions(fi FeedInfo) ytfeed.DownloadOptions {
+	res := ytfeed.DownloadOptions{Template: fi.DlTemplate, Format: fi.Format, Resolution: fi.Resolution}
+	if fi.Mode != ModeVideo {
+		return res
+	}
+	if res.Template == "" {
+		res.Template = s.VideoTemplate
+	}
+	if res.Format == "" {
+		res.Format = "mp4"
+	}
+	return res
+}
+
+func (s *Service) updateMp3Tags(ctx context.Context, file string, entry ytfeed.Entry, fi FeedInfo) error {
 	fh, err := id3v2.Open(file, id3v2.Options{Parse: false})
 	if err != nil {
 		return errors.Wrapf(err, "failed to open file %s", file)
@@ -545,6 +1043,15 @@ func (s *Service) updateMp3Tags(file string, entry ytfeed.Entry, fi FeedInfo) er
 	fh.SetGenre("podcast")
 	fh.SetYear(entry.Published.Format("2006"))
 	fh.AddTextFrame(fh.CommonID("Recording time"), fh.DefaultEncoding(), entry.Published.Format("20060102T150405"))
+	if entry.Media.Description != "" {
+		fh.AddCommentFrame(id3v2.CommentFrame{Encoding: id3v2.EncodingUTF8, Language: "eng",
+			Text: string(entry.Media.Description)})
+	}
+	s.addMp3Chapters(fh, file, entry.Chapters)
+	if cover := s.cover(ctx, entry, fi); cover != nil {
+		fh.AddAttachedPicture(id3v2.PictureFrame{Encoding: id3v2.EncodingUTF8, MimeType: "image/jpeg",
+			PictureType: id3v2.PTFrontCover, Description: "Front cover", Picture: cover})
+	}
 
 	if err = fh.Save(); err != nil {
 		return errors.Wrapf(err, "failed to close file %s", file)
@@ -552,6 +1059,114 @@ func (s *Service) updateMp3Tags(file string, entry ytfeed.Entry, fi FeedInfo) er
 	return nil
 }
 
+func (s *Service) updateMp4Tags(ctx context.Context, file string, entry ytfeed.Entry, fi FeedInfo) error {
+	if s.MP4TagsService == nil {
+		return nil
+	}
+	chapters := append([]ytfeed.Chapter{}, entry.Chapters...)
+	if n := len(chapters); n > 0 && chapters[n-1].End == 0 { // the last chapter with unknown end
+		chapters[n-1].End = float64(s.DurationService.File(file))
+	}
+	tags := mp4.Tags{
+		Title:    entry.Title,
+		Artist:   entry.Author.Name,
+		Album:    fi.Name,
+		Genre:    "podcast",
+		Date:     entry.Published.Format("2006-01-02"),
+		Comment:  string(entry.Media.Description),
+		Chapters: chapters,
+		Cover:    s.cover(ctx, entry, fi),
+	}
+	return errors.Wrapf(s.MP4TagsService.Set(ctx, file, tags), "failed to set mp4 tags of %s", file)
+}
+
+// cover returns cover art of the entry as jpeg, nil if not available. Fixed cover of the channel is used if set,
+// otherwise the cached thumbnail or the largest of video thumbnails which can be loaded.
+func (s *Service) cover(ctx context.Context, entry ytfeed.Entry, fi FeedInfo) []byte {
+	if s.CoverService == nil {
+		return nil
+	}
+
+	srcs := []string{fi.Cover}
+	if fi.Cover == "" {
+		srcs = s.thumbnails(entry)
+		if entry.Image != "" {
+			srcs = append([]string{entry.Image}, srcs...)
+		}
+	}
+
+	for _, src := range srcs {
+		res, err := s.CoverService.Get(ctx, src)
+		if err != nil {
+			log.Printf("[WARN] failed to load cover art for %s from %s: %v", entry.VideoID, src, err)
+			continue
+		}
+		return res
+	}
+	return nil
+}
+
+// cacheImage saves square thumbnail of the entry next to the downloaded file, so feeds don't refer to youtube images.
+// Returns the name of saved file, empty if no thumbnail can be loaded.
+func (s *Service) cacheImage(ctx context.Context, entry ytfeed.Entry, file string) string {
+	if s.CoverService == nil {
+		return ""
+	}
+	for _, src := range s.thumbnails(entry) {
+		data, err := s.CoverService.Square(ctx, src, imageSize)
+		if err != nil {
+			log.Printf("[WARN] failed to load thumbnail for %s from %s: %v", entry.VideoID, src, err)
+			continue
+		}
+		if err = os.WriteFile(ytfeed.ImageFile(file), data, 0o644); err != nil { // nolint
+			log.Printf("[WARN] failed to save thumbnail for %s: %v", entry.VideoID, err)
+			return ""
+		}
+		return ytfeed.ImageFile(file)
+	}
+	return ""
+}
+
+// thumbnails returns up to 3 largest thumbnails of the entry, webp thumbnails are skipped as not supported
+func (s *Service) thumbnails(entry ytfeed.Entry) []string {
+	thumbs := append([]ytfeed.Thumbnail{}, entry.Thumbnails...)
+	sort.SliceStable(thumbs, func(i, j int) bool { return thumbs[i].Width > thumbs[j].Width })
+	res := []string{}
+	for _, th := range append(thumbs, ytfeed.Thumbnail{URL: entry.Media.Thumbnail.URL}) {
+		if th.URL != "" && !strings.Contains(th.URL, ".webp") && len(res) < 3 {
+			res = append(res, th.URL)
+		}
+	}
+	return res
+}
+
+// addMp3Chapters adds CHAP frame for each chapter and CTOC frame listing them in order,
+// see https://id3.org/id3v2-chapters-1.0
+func (s *Service) addMp3Chapters(fh *id3v2.Tag, file string, chapters []ytfeed.Chapter) {
+	if len(chapters) == 0 || len(chapters) > 255 { // CTOC can't list more than 255 entries
+		return
+	}
+	toc := []byte("toc\x00")
+	toc = append(toc, 0x03, byte(len(chapters))) // flags: top-level and ordered, number of entries
+	for i, c := range chapters {
+		end := c.End
+		if end == 0 { // the last chapter with unknown end
+			end = float64(s.DurationService.File(file))
+		}
+		id := fmt.Sprintf("chp%d", i)
+		fh.AddChapterFrame(id3v2.ChapterFrame{
+			ElementID:   id,
+			StartTime:   time.Duration(c.Start * float64(time.Second)),
+			EndTime:     time.Duration(end * float64(time.Second)),
+			StartOffset: id3v2.IgnoredOffset,
+			EndOffset:   id3v2.IgnoredOffset,
+			Title:       &id3v2.TextFrame{Encoding: id3v2.EncodingUTF8, Text: c.Title},
+		})
+		toc = append(append(toc, id...), 0)
+	}
+	fh.AddFrame("CTOC", id3v2.UnknownFrame{Body: toc})
+}
+
 type stats struct {
 	entries   int
 	processed int
@@ -561,6 +1176,24 @@ type stats struct {
 	skipped   int
 }
 
+// add merges stats of a single channel processing
+func (st *stats) add(other stats) {
+	st.entries += other.entries
+	st.processed += other.processed
+	st.added += other.added
+	st.removed += other.removed
+	st.ignored += other.ignored
+	st.skipped += other.skipped
+}
+
+// report sets metrics of the last processing run
+func (st stats) report() {
+	for state, v := range map[string]int{"entries": st.entries, "processed": st.processed, "added": st.added,
+		"removed": st.removed, "ignored": st.ignored, "skipped": st.skipped} {
+		runEntries.Set(float64(v), state)
+	}
+}
+
 func (st stats) String() string {
 	return fmt.Sprintf("entries: %d, processed: %d, updated: %d, removed: %d, ignored: %d, skipped: %d",
 		st.entries, st.processed, st.added, st.removed, st.ignored, st.skipped)
@@ -2,17 +2,24 @@ package youtube
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/bogem/id3v2/v2"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	bolt "go.etcd.io/bbolt"
 
 	ytfeed "github.com/umputun/feed-master/app/youtube/feed"
+	"github.com/umputun/feed-master/app/youtube/mp4"
+	"github.com/umputun/feed-master/app/youtube/postproc"
+	"github.com/umputun/feed-master/app/youtube/sponsor"
 	"github.com/umputun/feed-master/app/youtube/store"
 
 	"github.com/umputun/feed-master/app/youtube/mocks"
@@ -33,7 +40,7 @@ func TestService_Do(t *testing.T) {
 		},
 	}
 	downloader := &mocks.DownloaderServiceMock{
-		GetFunc: func(_ context.Context, _ string, fname string) (string, error) {
+		GetFunc: func(_ context.Context, _ string, fname string, _ ytfeed.DownloadOptions) (string, error) {
 			fpath := filepath.Join(tempDir, fname+".mp3")
 			_, err := os.Create(fpath) // nolint
 			require.NoError(t, err)
@@ -127,6 +134,421 @@ func TestService_Do(t *testing.T) {
 	assert.FileExists(t, filepath.Join(tempDir, "e4650bb3d770eed60faad7ffbed5f33ffb1b89fa.mp3"), "non short video should exist")
 }
 
+func TestService_procChannelsConcurrent(t *testing.T) {
+	f := newProcFixture(t, []FeedInfo{{ID: "slow", Name: "name1"}, {ID: "fast", Name: "name2"}},
+		func(chanID string) []ytfeed.Entry {
+			return []ytfeed.Entry{
+				{ChannelID: chanID, VideoID: chanID + "-vid1", Title: "title1", Published: time.Now().Add(-time.Hour)},
+				{ChannelID: chanID, VideoID: chanID + "-vid2", Title: "title2", Published: time.Now()},
+			}
+		}, "mp3", "")
+	slowStarted, fastDone := make(chan struct{}), make(chan struct{})
+	get := f.downloader.GetFunc
+	f.downloader.GetFunc = func(ctx context.Context, id, fname string, opts ytfeed.DownloadOptions) (string, error) {
+		switch id {
+		case "slow-vid1": // blocks the slow channel until the fast one is done
+			close(slowStarted)
+			select {
+			case <-fastDone:
+			case <-time.After(5 * time.Second):
+				return "", errors.New("fast channel is not processed in parallel")
+			}
+		case "fast-vid2":
+			<-slowStarted
+			defer close(fastDone)
+		}
+		return get(ctx, id, fname, opts)
+	}
+	svc, boltStore, downloader := f.svc, f.store, f.downloader
+	svc.Concurrent = 2
+	assert.True(t, svc.LastProgress().IsZero())
+	start := time.Now()
+	require.NoError(t, svc.procChannels(context.Background()))
+	assert.Equal(t, 4, len(downloader.GetCalls()))
+
+	for _, ch := range []string{"slow", "fast"} {
+		res, err := boltStore.Load(ch, 10)
+		require.NoError(t, err)
+		require.Equal(t, 2, len(res))
+		assert.Equal(t, ch+"-vid2", res[0].VideoID, "ordered by published time")
+		assert.Equal(t, ch+"-vid1", res[1].VideoID)
+		assert.FileExists(t, filepath.Join(f.dir, ch+".xml"))
+	}
+	assert.False(t, svc.LastProgress().Before(start))
+
+	// canceled context stops all workers
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, svc.procChannels(ctx), context.Canceled)
+	assert.Equal(t, 4, len(downloader.GetCalls()), "nothing downloaded")
+}
+
+func TestService_procChannelsInfo(t *testing.T) {
+	f := newProcFixture(t, []FeedInfo{{ID: "chan1", Name: "name1"}}, func(chanID string) []ytfeed.Entry {
+		e := ytfeed.Entry{ChannelID: chanID, VideoID: "vid1", Title: "title1", Published: time.Now()}
+		e.Media.Description = "truncated description"
+		return []ytfeed.Entry{e}
+	}, "mp3", "")
+	info := `{"id": "vid1", "description": "full description", "duration": 3599.6, "upload_date": "20240315",
+		"tags": ["news"], "chapters": [{"start_time": 0, "end_time": 90, "title": "Intro"}]}`
+	get := f.downloader.GetFunc
+	f.downloader.GetFunc = func(ctx context.Context, id, fname string, opts ytfeed.DownloadOptions) (string, error) {
+		fpath, err := get(ctx, id, fname, opts)
+		require.NoError(t, os.WriteFile(ytfeed.InfoFile(fpath), []byte(info), 0o600))
+		return fpath, err
+	}
+	duration := &mocks.DurationServiceMock{FileFunc: func(string) int { return 1234 }}
+	svc, boltStore := f.svc, f.store
+	svc.DurationService = duration
+	svc.SkipShorts = time.Minute
+	require.NoError(t, svc.procChannels(context.Background()))
+	assert.Equal(t, 0, len(duration.FileCalls()), "duration taken from metadata")
+
+	res, err := boltStore.Load("chan1", 10)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(res))
+	assert.Equal(t, "full description", string(res[0].Media.Description))
+	assert.Equal(t, 3600, res[0].Duration)
+	assert.Equal(t, []string{"news"}, res[0].Tags)
+	assert.Equal(t, []ytfeed.Chapter{{Start: 0, End: 90, Title: "Intro"}}, res[0].Chapters)
+	assert.Equal(t, time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC), res[0].UploadDate.UTC())
+	assert.FileExists(t, res[0].File)
+	assert.NoFileExists(t, ytfeed.InfoFile(res[0].File), "metadata removed after use")
+
+	rssData, err := os.ReadFile(filepath.Join(f.dir, "chan1.xml")) // nolint
+	require.NoError(t, err)
+	assert.Contains(t, string(rssData), "full description")
+	assert.Contains(t, string(rssData), "<itunes:duration>3600</itunes:duration>")
+}
+
+func TestService_procChannelsChapters(t *testing.T) {
+	f := newProcFixture(t, []FeedInfo{{ID: "chan1", Name: "name1"}}, func(chanID string) []ytfeed.Entry {
+		e := ytfeed.Entry{ChannelID: chanID, VideoID: "vid1", Title: "title1", Published: time.Now()}
+		e.Media.Description = "some description\n00:00 Intro\n01:30 Main part\n20:00 Outro"
+		return []ytfeed.Entry{e}
+	}, "mp3", "")
+	svc, boltStore := f.svc, f.store
+	svc.DurationService = &mocks.DurationServiceMock{FileFunc: func(string) int { return 1800 }}
+	require.NoError(t, svc.procChannels(context.Background()))
+
+	res, err := boltStore.Load("chan1", 10)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(res))
+	chapters := []ytfeed.Chapter{{Start: 0, End: 90, Title: "Intro"}, {Start: 90, End: 1200, Title: "Main part"},
+		{Start: 1200, End: 1800, Title: "Outro"}}
+	assert.Equal(t, chapters, res[0].Chapters, "parsed from description, the last one ends with the file")
+
+	data, err := os.ReadFile(ytfeed.ChaptersFile(res[0].File))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"startTime": 1200`)
+	assert.Contains(t, string(data), `"endTime": 1800`)
+
+	tag, err := id3v2.Open(res[0].File, id3v2.Options{Parse: true})
+	require.NoError(t, err)
+	defer tag.Close()
+	chapFrames := tag.GetFrames("CHAP")
+	require.Equal(t, 3, len(chapFrames))
+	chap, ok := chapFrames[1].(id3v2.ChapterFrame)
+	require.True(t, ok)
+	assert.Equal(t, "chp1", chap.ElementID)
+	assert.Equal(t, 90*time.Second, chap.StartTime)
+	assert.Equal(t, 1200*time.Second, chap.EndTime)
+	assert.Equal(t, "Main part", chap.Title.Text)
+	toc := tag.GetFrames("CTOC")
+	require.Equal(t, 1, len(toc))
+	assert.Equal(t, "toc\x00\x03\x03chp0\x00chp1\x00chp2\x00", string(toc[0].(id3v2.UnknownFrame).Body))
+
+	rssData, err := os.ReadFile(filepath.Join(f.dir, "chan1.xml")) // nolint
+	require.NoError(t, err)
+	assert.Contains(t, string(rssData), `xmlns:podcast="https://podcastindex.org/namespace/1.0"`)
+	assert.Contains(t, string(rssData), `<podcast:chapters url="http://localhost/yt/media/`+
+		filepath.Base(ytfeed.ChaptersFile(res[0].File))+`" type="application/json+chapters"></podcast:chapters>`)
+
+	// chapters file removed with the old entry
+	_, err = boltStore.Save(ytfeed.Entry{ChannelID: "chan1", VideoID: "vid2", Published: time.Now().Add(time.Hour)})
+	require.NoError(t, err)
+	svc.KeepPerChannel = 0
+	assert.Equal(t, 1, svc.removeOld(FeedInfo{ID: "chan1"}))
+	assert.NoFileExists(t, res[0].File)
+	assert.NoFileExists(t, ytfeed.ChaptersFile(res[0].File))
+}
+
+func TestService_procChannelsFormat(t *testing.T) {
+	f := newProcFixture(t, []FeedInfo{{ID: "chan1", Name: "name1"},
+		{ID: "chan2", Name: "name2", Format: "m4a", DlTemplate: "yt-dlp -f m4a {{.ID}} -o {{.FileName}}"}},
+		oneEntry, ytfeed.DefaultFormat, "not mp3 data")
+	svc, boltStore, downloader := f.svc, f.store, f.downloader
+	require.NoError(t, svc.procChannels(context.Background()))
+
+	require.Equal(t, 2, len(downloader.GetCalls()))
+	calls := map[string]ytfeed.DownloadOptions{}
+	for _, c := range downloader.GetCalls() {
+		calls[c.ID] = c.Opts
+	}
+	assert.Equal(t, ytfeed.DownloadOptions{}, calls["chan1-vid1"], "global template and default format")
+	assert.Equal(t, ytfeed.DownloadOptions{Template: "yt-dlp -f m4a {{.ID}} -o {{.FileName}}", Format: "m4a"},
+		calls["chan2-vid1"])
+
+	res, err := boltStore.Load("chan2", 10)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(res))
+	assert.Equal(t, ".m4a", filepath.Ext(res[0].File))
+	data, err := os.ReadFile(res[0].File)
+	require.NoError(t, err)
+	assert.Equal(t, "not mp3 data", string(data), "no id3 tags added to m4a")
+
+	rssData, err := os.ReadFile(filepath.Join(f.dir, "chan2.xml")) // nolint
+	require.NoError(t, err)
+	assert.Contains(t, string(rssData), `<enclosure url="http://localhost/yt/media/`+filepath.Base(res[0].File)+
+		`" length="12" type="audio/mp4"></enclosure>`)
+	rssData, err = os.ReadFile(filepath.Join(f.dir, "chan1.xml")) // nolint
+	require.NoError(t, err)
+	assert.Contains(t, string(rssData), `type="audio/mpeg"`)
+}
+
+func TestService_procChannelsVideo(t *testing.T) {
+	f := newProcFixture(t, []FeedInfo{{ID: "chan1", Name: "name1", Mode: ModeVideo},
+		{ID: "chan2", Name: "name2", Mode: ModeVideo, Resolution: 480, DlTemplate: "yt-dlp {{.ID}}", Format: "mkv"}},
+		func(chanID string) []ytfeed.Entry {
+			e := ytfeed.Entry{ChannelID: chanID, VideoID: chanID + "-vid1", Title: "title1", Published: time.Now()}
+			e.Author.Name = "author1"
+			e.Media.Description = "00:00 Intro\n00:10 Main"
+			return []ytfeed.Entry{e}
+		}, "mp4", "mp4 data")
+	tagger := &mocks.MP4TagsServiceMock{SetFunc: func(context.Context, string, mp4.Tags) error { return nil }}
+	svc, boltStore, downloader := f.svc, f.store, f.downloader
+	svc.MP4TagsService = tagger
+	svc.VideoTemplate = "yt-dlp -f mp4 {{.ID}}"
+	require.NoError(t, svc.procChannels(context.Background()))
+
+	calls := map[string]ytfeed.DownloadOptions{}
+	for _, c := range downloader.GetCalls() {
+		calls[c.ID] = c.Opts
+	}
+	assert.Equal(t, ytfeed.DownloadOptions{Template: "yt-dlp -f mp4 {{.ID}}", Format: "mp4"}, calls["chan1-vid1"],
+		"video template and mp4 format by default")
+	assert.Equal(t, ytfeed.DownloadOptions{Template: "yt-dlp {{.ID}}", Format: "mkv", Resolution: 480},
+		calls["chan2-vid1"])
+
+	require.Equal(t, 1, len(tagger.SetCalls()), "tags set for mp4 only")
+	call := tagger.SetCalls()[0]
+	assert.Equal(t, ".mp4", filepath.Ext(call.File))
+	assert.Equal(t, "title1", call.Tags.Title)
+	assert.Equal(t, "author1", call.Tags.Artist)
+	assert.Equal(t, "name1", call.Tags.Album)
+	assert.Equal(t, "00:00 Intro\n00:10 Main", call.Tags.Comment)
+	assert.Equal(t, []ytfeed.Chapter{{Start: 0, End: 10, Title: "Intro"}, {Start: 10, End: 1234, Title: "Main"}},
+		call.Tags.Chapters, "the last chapter ends at duration")
+
+	res, err := boltStore.Load("chan1", 10)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(res))
+	assert.Equal(t, 1234, res[0].Duration)
+	rssData, err := os.ReadFile(filepath.Join(f.dir, "chan1.xml")) // nolint
+	require.NoError(t, err)
+	assert.Contains(t, string(rssData), `<enclosure url="http://localhost/yt/media/`+filepath.Base(res[0].File)+
+		`" length="8" type="video/mp4"></enclosure>`)
+}
+
+func TestService_procChannelsSegments(t *testing.T) {
+	f := newProcFixture(t, []FeedInfo{{ID: "chan1", Name: "name1", SkipSegments: []string{"sponsor", "intro"}},
+		{ID: "chan2", Name: "name2", SkipSegments: []string{"sponsor"}}, {ID: "chan3", Name: "name3"}},
+		func(chanID string) []ytfeed.Entry {
+			e := ytfeed.Entry{ChannelID: chanID, VideoID: chanID + "-vid1", Title: "title1", Published: time.Now()}
+			e.Media.Description = "00:00 Intro\n00:30 Main\n10:00 Outro"
+			return []ytfeed.Entry{e}
+		}, "m4a", "audio data")
+	sponsorSvc := &mocks.SponsorServiceMock{
+		SegmentsFunc: func(_ context.Context, videoID string, _ []string) ([]sponsor.Segment, error) {
+			if videoID == "chan2-vid1" {
+				return nil, errors.New("api error")
+			}
+			return []sponsor.Segment{{Start: 0, End: 30, Category: "intro"}, {Start: 120, End: 180, Category: "sponsor"}}, nil
+		},
+		CutFunc: func(context.Context, string, []sponsor.Segment) error { return nil },
+	}
+	svc, boltStore := f.svc, f.store
+	svc.DurationService = &mocks.DurationServiceMock{FileFunc: func(string) int { return 900 }}
+	svc.SponsorService = sponsorSvc
+	require.NoError(t, svc.procChannels(context.Background()))
+
+	require.Equal(t, 2, len(sponsorSvc.SegmentsCalls()), "not called for channel without categories")
+	require.Equal(t, 1, len(sponsorSvc.CutCalls()), "not cut on api error")
+	assert.Equal(t, []string{"sponsor", "intro"}, sponsorSvc.SegmentsCalls()[0].Categories)
+
+	res, err := boltStore.Load("chan1", 10)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(res))
+	assert.Equal(t, sponsorSvc.CutCalls()[0].File, res[0].File)
+	assert.Equal(t, 900, res[0].Duration)
+	assert.Equal(t, []ytfeed.Chapter{{Start: 0, End: 510, Title: "Main"}, {Start: 510, End: 900, Title: "Outro"}},
+		res[0].Chapters, "intro removed, chapters moved")
+
+	res, err = boltStore.Load("chan2", 10)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(res))
+	assert.Equal(t, 3, len(res[0].Chapters), "chapters kept")
+}
+
+func TestService_procChannelsPostProcess(t *testing.T) {
+	f := newProcFixture(t, []FeedInfo{{ID: "chan1", Name: "name1"},
+		{ID: "chan2", Name: "name2", SkipPostProcess: []string{"loudnorm"}},
+		{ID: "chan3", Name: "name3", SkipPostProcess: []string{"all"}}}, oneEntry, "m4a", "audio data")
+	pp := &mocks.PostProcServiceMock{
+		ProcessFunc: func(_ context.Context, _, videoID string, _ []postproc.Step) error {
+			if videoID == "chan2-vid1" {
+				return errors.New("ffmpeg failed")
+			}
+			return nil
+		},
+	}
+	svc, boltStore := f.svc, f.store
+	svc.PostProcService = pp
+	svc.PostProcess = []postproc.Step{{Name: "loudnorm"}, {Name: "mono"}, {Name: "bitrate", Args: "-b:a 64k"}}
+	require.NoError(t, svc.procChannels(context.Background()))
+
+	steps := map[string][]string{}
+	for _, c := range pp.ProcessCalls() {
+		for _, step := range c.Steps {
+			steps[c.VideoID] = append(steps[c.VideoID], step.Name)
+		}
+	}
+	assert.Equal(t, map[string][]string{"chan1-vid1": {"loudnorm", "mono", "bitrate"}, "chan2-vid1": {"mono", "bitrate"}},
+		steps, "all steps in one call, skipped steps not passed")
+
+	for _, ch := range []string{"chan1", "chan2", "chan3"} {
+		res, err := boltStore.Load(ch, 10)
+		require.NoError(t, err)
+		require.Equal(t, 1, len(res), "saved regardless of post-processing failure, %s", ch)
+		assert.Equal(t, 1234, res[0].Duration)
+	}
+}
+
+func TestService_procChannelsQueue(t *testing.T) {
+	inFeed := []ytfeed.Entry{{ChannelID: "chan1", VideoID: "vid1", Title: "title1", Published: time.Now()}}
+	f := newProcFixture(t, []FeedInfo{{ID: "chan1", Name: "name1"}}, func(string) []ytfeed.Entry { return inFeed },
+		"mp3", "")
+	failed := true
+	get := f.downloader.GetFunc
+	f.downloader.GetFunc = func(ctx context.Context, id, fname string, opts ytfeed.DownloadOptions) (string, error) {
+		if failed {
+			return "", errors.New("network error")
+		}
+		return get(ctx, id, fname, opts)
+	}
+	svc, boltStore, downloader := f.svc, f.store, f.downloader
+	svc.RetryAttempts = 3
+	svc.RetryDelay = time.Hour
+
+	queued := func() ytfeed.QueueItem {
+		items, e := svc.Queue("")
+		require.NoError(t, e)
+		require.Equal(t, 1, len(items))
+		return items[0]
+	}
+
+	require.NoError(t, svc.procChannels(context.Background()))
+	assert.Equal(t, 1, len(downloader.GetCalls()))
+	item := queued()
+	assert.Equal(t, ytfeed.QueueFailed, item.State)
+	assert.Equal(t, 1, item.Attempts)
+	assert.Equal(t, "network error", item.Error)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), item.NextTry, time.Second)
+
+	require.NoError(t, svc.procChannels(context.Background()))
+	assert.Equal(t, 1, len(downloader.GetCalls()), "not retried before the next try time")
+
+	// retried without the entry in feed, with doubled delay
+	svc.RetryDelay = time.Millisecond
+	inFeed = nil
+	require.NoError(t, boltStore.SetQueued(ytfeed.QueueItem{Entry: item.Entry, State: ytfeed.QueueFailed, Attempts: 1,
+		NextTry: time.Now()}))
+	require.NoError(t, svc.procChannels(context.Background()))
+	assert.Equal(t, 2, len(downloader.GetCalls()))
+	item = queued()
+	assert.Equal(t, ytfeed.QueueFailed, item.State)
+	assert.Equal(t, 2, item.Attempts)
+	assert.WithinDuration(t, time.Now().Add(2*time.Millisecond), item.NextTry, time.Second)
+
+	// the last attempt, gives up
+	time.Sleep(5 * time.Millisecond)
+	require.NoError(t, svc.procChannels(context.Background()))
+	assert.Equal(t, 3, len(downloader.GetCalls()))
+	item = queued()
+	assert.Equal(t, ytfeed.QueueFailed, item.State)
+	assert.Equal(t, 3, item.Attempts)
+	assert.True(t, item.NextTry.IsZero())
+	processed, _, err := boltStore.CheckProcessed(item.Entry)
+	require.NoError(t, err)
+	assert.True(t, processed)
+
+	time.Sleep(5 * time.Millisecond)
+	require.NoError(t, svc.procChannels(context.Background()))
+	assert.Equal(t, 3, len(downloader.GetCalls()), "no more attempts")
+
+	_, err = svc.GiveUpQueued("chan1", "vid2")
+	assert.ErrorIs(t, err, ErrNotQueued)
+
+	// retry requested by admin
+	item, err = svc.RetryQueued("chan1", "vid1")
+	require.NoError(t, err)
+	assert.Equal(t, ytfeed.QueuePending, item.State)
+	assert.Equal(t, 0, item.Attempts)
+	failed = false
+	require.NoError(t, svc.procChannels(context.Background()))
+	assert.Equal(t, 4, len(downloader.GetCalls()))
+	item = queued()
+	assert.Equal(t, ytfeed.QueueDone, item.State)
+	assert.Equal(t, 1, item.Attempts)
+	assert.Empty(t, item.Error)
+	res, err := boltStore.Load("chan1", 10)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(res))
+	assert.Equal(t, "vid1", res[0].VideoID)
+	assert.FileExists(t, filepath.Join(f.dir, "chan1.xml"))
+
+	_, err = svc.RetryQueued("chan1", "vid1")
+	assert.ErrorIs(t, err, ErrQueueState, "done can't be retried")
+	_, err = svc.GiveUpQueued("chan1", "vid1")
+	assert.ErrorIs(t, err, ErrQueueState, "done can't be given up")
+
+	items, err := svc.Queue(ytfeed.QueueFailed)
+	require.NoError(t, err)
+	assert.Empty(t, items)
+}
+
+func TestService_GiveUpQueued(t *testing.T) {
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "test.db"), 0o600, &bolt.Options{Timeout: 1 * time.Second})
+	require.NoError(t, err)
+	boltStore := &store.BoltDB{DB: db}
+	svc := Service{Store: boltStore}
+	entry := ytfeed.Entry{ChannelID: "chan1", VideoID: "vid1", Published: time.Now()}
+	require.NoError(t, boltStore.SetQueued(ytfeed.QueueItem{Entry: entry, State: ytfeed.QueueFailed, Attempts: 2,
+		NextTry: time.Now().Add(time.Hour)}))
+
+	item, err := svc.GiveUpQueued("chan1", "vid1")
+	require.NoError(t, err)
+	assert.Equal(t, ytfeed.QueueSkipped, item.State)
+	assert.Equal(t, "given up", item.Error)
+	assert.True(t, item.NextTry.IsZero())
+	processed, _, err := boltStore.CheckProcessed(entry)
+	require.NoError(t, err)
+	assert.True(t, processed)
+
+	items, err := svc.Queue(ytfeed.QueueSkipped)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(items))
+	assert.Equal(t, "vid1", items[0].Entry.VideoID)
+
+	// skipped can be retried
+	item, err = svc.RetryQueued("chan1", "vid1")
+	require.NoError(t, err)
+	assert.Equal(t, ytfeed.QueuePending, item.State)
+	processed, _, err = boltStore.CheckProcessed(entry)
+	require.NoError(t, err)
+	assert.False(t, processed)
+}
+
 // nolint:dupl // test if very similar to TestService_RSSFeed
 func TestService_DoIsAllowedFilter(t *testing.T) {
 
@@ -140,7 +562,7 @@ func TestService_DoIsAllowedFilter(t *testing.T) {
 		},
 	}
 	downloader := &mocks.DownloaderServiceMock{
-		GetFunc: func(_ context.Context, _ string, fname string) (string, error) {
+		GetFunc: func(_ context.Context, _ string, fname string, _ ytfeed.DownloadOptions) (string, error) {
 			return "/tmp/" + fname + ".mp3", nil
 		},
 	}
@@ -264,6 +686,37 @@ func TestService_RSSFeed(t *testing.T) {
 	assert.Contains(t, res, `<itunes:image href="http://example.com/thumb.jpg"></itunes:image>`)
 	assert.Contains(t, res, `<media:thumbnail url="http://example.com/thumb.jpg"></media:thumbnail>`)
 
+	res, err = svc.FilteredRSSFeed(FeedInfo{ID: "channel1", Name: "name1", Type: ytfeed.FTChannel},
+		func(entry ytfeed.Entry) bool { return entry.VideoID == "vid1" })
+	require.NoError(t, err)
+	assert.NotContains(t, res, `<guid>channel1::vid1</guid>`, "skipped by filter")
+	assert.Contains(t, res, `<guid>channel1::vid2</guid>`)
+}
+
+func TestService_RSSFeedCachedImages(t *testing.T) {
+	storeSvc := &mocks.StoreServiceMock{
+		LoadFunc: func(string, int) ([]ytfeed.Entry, error) {
+			res := []ytfeed.Entry{
+				{ChannelID: "channel1", VideoID: "vid1", Title: "title1", File: "/tmp/file1.mp3", Image: "/tmp/file1.jpg"},
+				{ChannelID: "channel1", VideoID: "vid2", Title: "title2", File: "/tmp/file2.mp3"}, // downloaded before caching
+			}
+			res[0].Media.Thumbnail.URL = "http://example.com/thumb1.jpg"
+			res[1].Media.Thumbnail.URL = "http://example.com/thumb2.jpg"
+			return res, nil
+		},
+	}
+	svc := Service{Store: storeSvc, RootURL: "http://localhost:8080/yt", KeepPerChannel: 10}
+
+	res, err := svc.RSSFeed(FeedInfo{ID: "channel1", Name: "name1", Type: ytfeed.FTChannel})
+	require.NoError(t, err)
+	t.Logf("%v", res)
+	assert.Contains(t, res, "<channel>\n    <title>name1</title>")
+	assert.Contains(t, res, `<itunes:image href="http://localhost:8080/yt/file1.jpg"></itunes:image>`+"\n    <media:thumbnail",
+		"channel image is the cached image of the last entry")
+	assert.Contains(t, res, `<media:thumbnail url="http://localhost:8080/yt/file1.jpg"></media:thumbnail>`)
+	assert.Equal(t, 2, strings.Count(res, `<itunes:image href="http://localhost:8080/yt/file1.jpg"></itunes:image>`),
+		"channel and item images")
+	assert.NotContains(t, res, "thumb2.jpg", "no image for item without cached one")
 }
 
 // nolint:dupl // test if very similar to TestService_RSSFeed
@@ -360,7 +813,7 @@ func TestService_update(t *testing.T) {
 			Title:     "something",
 		}
 
-		res := svc.update(inpEntry, "/tmp/audio.mp3", FeedInfo{ID: "f1", Name: "feed1"})
+		res := svc.update(inpEntry, "/tmp/audio.mp3", FeedInfo{ID: "f1", Name: "feed1"}, ytfeed.Info{})
 		t.Logf("%+v", res)
 		assert.Equal(t, 1234, res.Duration)
 		assert.True(t, time.Since(res.Published) < time.Second, "published time was reset")
@@ -375,7 +828,7 @@ func TestService_update(t *testing.T) {
 			Published: time.Now().Add(time.Hour * -1),
 			Title:     `Сергей Пархоменко на канале “Живой Гвоздь” в программме “Персонально ваш”. 06.04.2022`,
 		}
-		res := svc.update(inpEntry, "/tmp/audio.mp3", FeedInfo{ID: "f1", Name: "Сергей Пархоменко"})
+		res := svc.update(inpEntry, "/tmp/audio.mp3", FeedInfo{ID: "f1", Name: "Сергей Пархоменко"}, ytfeed.Info{})
 		t.Logf("%+v", res)
 		assert.Equal(t, 1234, res.Duration)
 		assert.True(t, time.Since(res.Published) < time.Second, "published time was reset")
@@ -384,6 +837,88 @@ func TestService_update(t *testing.T) {
 
 }
 
+func TestService_cover(t *testing.T) {
+	covers := &mocks.CoverServiceMock{
+		GetFunc: func(_ context.Context, src string) ([]byte, error) {
+			if src == "https://i.ytimg.com/vi/vid1/maxresdefault.jpg" {
+				return nil, errors.New("not found")
+			}
+			return []byte("jpeg of " + src), nil
+		},
+	}
+	entry := ytfeed.Entry{VideoID: "vid1", Thumbnails: []ytfeed.Thumbnail{
+		{URL: "https://i.ytimg.com/vi/vid1/hqdefault.jpg?sqp=abc", Width: 480, Height: 360},
+		{URL: "https://i.ytimg.com/vi_webp/vid1/maxresdefault.webp", Width: 1920, Height: 1080},
+		{URL: "https://i.ytimg.com/vi/vid1/maxresdefault.jpg", Width: 1920, Height: 1080},
+	}}
+	entry.Media.Thumbnail.URL = "https://i.ytimg.com/vi/vid1/hqdefault.jpg"
+
+	svc := Service{}
+	assert.Nil(t, svc.cover(context.Background(), entry, FeedInfo{}), "no cover service")
+
+	svc.CoverService = covers
+	res := svc.cover(context.Background(), entry, FeedInfo{})
+	assert.Equal(t, "jpeg of https://i.ytimg.com/vi/vid1/hqdefault.jpg?sqp=abc", string(res))
+	require.Equal(t, 2, len(covers.GetCalls()), "the largest failed, webp skipped")
+	assert.Equal(t, "https://i.ytimg.com/vi/vid1/maxresdefault.jpg", covers.GetCalls()[0].Src)
+
+	res = svc.cover(context.Background(), entry, FeedInfo{Cover: "/srv/covers/chan1.png"})
+	assert.Equal(t, "jpeg of /srv/covers/chan1.png", string(res), "fixed channel cover")
+
+	res = svc.cover(context.Background(), ytfeed.Entry{VideoID: "vid2"}, FeedInfo{Cover: "https://i.ytimg.com/vi/vid1/maxresdefault.jpg"})
+	assert.Nil(t, res, "fixed cover failed, no fallback to thumbnails")
+
+	// cover embedded into mp3
+	file := filepath.Join(t.TempDir(), "audio.mp3")
+	require.NoError(t, os.WriteFile(file, nil, 0o600))
+	require.NoError(t, svc.updateMp3Tags(context.Background(), file, entry, FeedInfo{Name: "feed1"}))
+	tag, err := id3v2.Open(file, id3v2.Options{Parse: true})
+	require.NoError(t, err)
+	defer tag.Close()
+	pics := tag.GetFrames(tag.CommonID("Attached picture"))
+	require.Equal(t, 1, len(pics))
+	pic, ok := pics[0].(id3v2.PictureFrame)
+	require.True(t, ok)
+	assert.Equal(t, "image/jpeg", pic.MimeType)
+	assert.Equal(t, byte(id3v2.PTFrontCover), pic.PictureType)
+	assert.Equal(t, "jpeg of https://i.ytimg.com/vi/vid1/hqdefault.jpg?sqp=abc", string(pic.Picture))
+}
+
+func TestService_cacheImage(t *testing.T) {
+	covers := &mocks.CoverServiceMock{
+		SquareFunc: func(_ context.Context, src string, size int) ([]byte, error) {
+			if strings.Contains(src, "maxres") {
+				return nil, errors.New("not found")
+			}
+			return []byte(fmt.Sprintf("%dpx of %s", size, src)), nil
+		},
+		GetFunc: func(_ context.Context, src string) ([]byte, error) {
+			return []byte("cover of " + src), nil
+		},
+	}
+	entry := ytfeed.Entry{VideoID: "vid1", Thumbnails: []ytfeed.Thumbnail{
+		{URL: "https://i.ytimg.com/vi/vid1/maxresdefault.jpg", Width: 1920, Height: 1080}}}
+	entry.Media.Thumbnail.URL = "https://i.ytimg.com/vi/vid1/hqdefault.jpg"
+	file := filepath.Join(t.TempDir(), "audio.mp3")
+
+	svc := Service{}
+	assert.Equal(t, "", svc.cacheImage(context.Background(), entry, file), "no cover service")
+
+	svc.CoverService = covers
+	res := svc.cacheImage(context.Background(), entry, file)
+	assert.Equal(t, ytfeed.ImageFile(file), res)
+	data, err := os.ReadFile(res)
+	require.NoError(t, err)
+	assert.Equal(t, "1400px of https://i.ytimg.com/vi/vid1/hqdefault.jpg", string(data))
+
+	entry.Image = res
+	assert.Equal(t, "cover of "+res, string(svc.cover(context.Background(), entry, FeedInfo{})), "cached image used for cover")
+
+	assert.Equal(t, "", svc.cacheImage(context.Background(), ytfeed.Entry{VideoID: "vid2"}, file), "no thumbnails")
+	assert.Equal(t, "", svc.cacheImage(context.Background(), entry, filepath.Join(file, "not-a-dir", "audio.mp3")),
+		"failed to save")
+}
+
 func TestService_totalEntriesToKeep(t *testing.T) {
 	svc := Service{
 		Feeds: []FeedInfo{
@@ -515,3 +1050,53 @@ func TestService_newestEntry(t *testing.T) {
 	assert.Equal(t, 1, storeSvc.LoadCalls()[0].Max)
 	assert.Equal(t, 1, storeSvc.LoadCalls()[1].Max)
 }
+
+// procFixture is a service with bolt store in temp dir and mocked channel and downloader services
+type procFixture struct {
+	svc        *Service
+	store      *store.BoltDB
+	downloader *mocks.DownloaderServiceMock
+	dir        string
+}
+
+// newProcFixture makes service for feeds with entries returned by the given func. Downloader writes data to the file
+// with extension of requested format, ext if format not set. The store is closed on test cleanup.
+func newProcFixture(t *testing.T, feeds []FeedInfo, entries func(chanID string) []ytfeed.Entry, ext, data string) procFixture {
+	dir := t.TempDir()
+	db, err := bolt.Open(filepath.Join(dir, "test.db"), 0o600, &bolt.Options{Timeout: 1 * time.Second})
+	require.NoError(t, err)
+	t.Cleanup(func() { assert.NoError(t, db.Close()) })
+
+	downloader := &mocks.DownloaderServiceMock{
+		GetFunc: func(_ context.Context, _, fname string, opts ytfeed.DownloadOptions) (string, error) {
+			format := opts.Format
+			if format == "" {
+				format = ext
+			}
+			fpath := filepath.Join(dir, fname+"."+format)
+			require.NoError(t, os.WriteFile(fpath, []byte(data), 0o600))
+			return fpath, nil
+		},
+	}
+	boltStore := &store.BoltDB{DB: db}
+	svc := &Service{
+		Feeds:      feeds,
+		Downloader: downloader,
+		ChannelService: &mocks.ChannelServiceMock{
+			GetFunc: func(_ context.Context, chanID string, _ ytfeed.Type) ([]ytfeed.Entry, error) {
+				return entries(chanID), nil
+			},
+		},
+		Store:           boltStore,
+		KeepPerChannel:  10,
+		RootURL:         "http://localhost/yt/media",
+		RSSFileStore:    RSSFileStore{Enabled: true, Location: dir},
+		DurationService: &mocks.DurationServiceMock{FileFunc: func(string) int { return 1234 }},
+	}
+	return procFixture{svc: svc, store: boltStore, downloader: downloader, dir: dir}
+}
+
+// oneEntry returns a single fresh entry of the channel
+func oneEntry(chanID string) []ytfeed.Entry {
+	return []ytfeed.Entry{{ChannelID: chanID, VideoID: chanID + "-vid1", Title: "title1", Published: time.Now()}}
+}
@@ -0,0 +1,183 @@
+// Package sponsor removes sponsored and other skippable segments from downloaded files.
+// Segments are loaded from SponsorBlock compatible api, see https://wiki.sponsor.ajay.app/w/API_Docs
+package sponsor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	ytfeed "github.com/umputun/feed-master/app/youtube/feed"
+	"github.com/umputun/feed-master/app/youtube/ffmpeg"
+)
+
+// DefaultURL is the public SponsorBlock api
+const DefaultURL = "https://sponsor.ajay.app"
+
+// Categories lists known segment categories
+var Categories = []string{"sponsor", "selfpromo", "interaction", "intro", "outro", "preview", "music_offtopic", "filler"}
+
+// Segment is a time range of the video in seconds
+type Segment struct {
+	Start    float64
+	End      float64
+	Category string
+}
+
+// Service loads segments from the api and cuts them out of files with ffmpeg
+type Service struct {
+	URL    string // base url of the api, DefaultURL if not set
+	Client *http.Client
+	FFmpeg string // ffmpeg command, "ffmpeg" if not set
+}
+
+// Segments returns skip segments of the video in the given categories, sorted by start and merged if overlapped.
+// Returns nil if the video has no segments.
+func (s *Service) Segments(ctx context.Context, videoID string, categories []string) ([]Segment, error) {
+	base := s.URL
+	if base == "" {
+		base = DefaultURL
+	}
+	cats, err := json.Marshal(categories)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal categories")
+	}
+	u := fmt.Sprintf("%s/api/skipSegments?videoID=%s&categories=%s", strings.TrimSuffix(base, "/"),
+		url.QueryEscape(videoID), url.QueryEscape(string(cats)))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, http.NoBody)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to make request for %s", videoID)
+	}
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get segments for %s", videoID)
+	}
+	defer resp.Body.Close() // nolint
+
+	if resp.StatusCode == http.StatusNotFound { // api responds with 404 if no segments
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to get segments for %s, status %d", videoID, resp.StatusCode)
+	}
+
+	var items []struct {
+		Segment    []float64 `json:"segment"`
+		Category   string    `json:"category"`
+		ActionType string    `json:"actionType"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&items); err != nil {
+		return nil, errors.Wrapf(err, "failed to decode segments for %s", videoID)
+	}
+	res := []Segment{}
+	for _, it := range items {
+		if (it.ActionType != "" && it.ActionType != "skip") || len(it.Segment) != 2 || it.Segment[1] <= it.Segment[0] {
+			continue
+		}
+		res = append(res, Segment{Start: it.Segment[0], End: it.Segment[1], Category: it.Category})
+	}
+	return Merge(res), nil
+}
+
+// Cut removes segments from the file, the file is re-encoded to a temporary one which replaces the original.
+// Video stream is cut as well, see ffmpeg.IsVideo.
+func (s *Service) Cut(ctx context.Context, file string, segments []Segment) error {
+	if len(segments) == 0 {
+		return nil
+	}
+	ranges := make([]string, 0, len(segments))
+	for _, sg := range segments {
+		ranges = append(ranges, fmt.Sprintf("between(t,%.3f,%.3f)", sg.Start, sg.End))
+	}
+	keep := fmt.Sprintf("'not(%s)'", strings.Join(ranges, "+"))
+
+	args := []string{"-i", file, "-map_metadata", "0", "-af", "aselect=" + keep + ",asetpts=N/SR/TB"}
+	if ffmpeg.IsVideo(file) {
+		args = append(args, "-vf", "select="+keep+",setpts=N/FRAME_RATE/TB")
+	} else {
+		args = append(args, "-vn")
+	}
+	return ffmpeg.Runner{Cmd: s.FFmpeg}.Replace(ctx, file, "cut", args...)
+}
+
+// Merge sorts segments by start and joins overlapped ones
+func Merge(segments []Segment) []Segment {
+	sorted := append([]Segment{}, segments...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Start < sorted[j].Start })
+	res := []Segment{}
+	for _, sg := range sorted {
+		if n := len(res); n > 0 && sg.Start <= res[n-1].End {
+			if sg.End > res[n-1].End {
+				res[n-1].End = sg.End
+			}
+			continue
+		}
+		res = append(res, sg)
+	}
+	return res
+}
+
+// Total returns total length of merged segments in seconds
+func Total(segments []Segment) (res float64) {
+	for _, sg := range segments {
+		res += sg.End - sg.Start
+	}
+	return res
+}
+
+// Adjust returns the time of the cut file for the time t of the original one. Time inside removed segment
+// moves to the segment's start.
+func Adjust(t float64, segments []Segment) float64 {
+	res := t
+	for _, sg := range segments {
+		switch {
+		case t >= sg.End:
+			res -= sg.End - sg.Start
+		case t > sg.Start:
+			res -= t - sg.Start
+		}
+	}
+	return res
+}
+
+// AdjustChapters moves chapters to times of the cut file, chapters removed completely are dropped
+func AdjustChapters(chapters []ytfeed.Chapter, segments []Segment) []ytfeed.Chapter {
+	if len(chapters) == 0 || len(segments) == 0 {
+		return chapters
+	}
+	res := make([]ytfeed.Chapter, 0, len(chapters))
+	for _, c := range chapters {
+		adj := ytfeed.Chapter{Start: Adjust(c.Start, segments), Title: c.Title}
+		if c.End > 0 {
+			adj.End = Adjust(c.End, segments)
+			if adj.End <= adj.Start {
+				continue
+			}
+		}
+		if c.End == 0 && inside(c.Start, segments) {
+			continue // chapter with unknown end, most likely removed up to the end of file
+		}
+		res = append(res, adj)
+	}
+	return res
+}
+
+// inside checks if t is inside one of segments
+func inside(t float64, segments []Segment) bool {
+	for _, sg := range segments {
+		if t >= sg.Start && t < sg.End {
+			return true
+		}
+	}
+	return false
+}
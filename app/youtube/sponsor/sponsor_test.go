@@ -0,0 +1,121 @@
+package sponsor
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ytfeed "github.com/umputun/feed-master/app/youtube/feed"
+)
+
+func TestService_Segments(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/skipSegments", r.URL.Path)
+		assert.Equal(t, `["sponsor","intro"]`, r.URL.Query().Get("categories"))
+		switch r.URL.Query().Get("videoID") {
+		case "vid1":
+			_, _ = w.Write([]byte(`[{"segment":[100.5,130],"category":"sponsor","actionType":"skip","UUID":"u1"},
+				{"segment":[0,12],"category":"intro","actionType":"skip","UUID":"u2"},
+				{"segment":[120,140],"category":"sponsor","actionType":"skip","UUID":"u3"},
+				{"segment":[200,210],"category":"sponsor","actionType":"mute","UUID":"u4"}]`))
+		case "vid2":
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte("Not Found"))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer ts.Close()
+
+	svc := Service{URL: ts.URL + "/"}
+	res, err := svc.Segments(context.Background(), "vid1", []string{"sponsor", "intro"})
+	require.NoError(t, err)
+	assert.Equal(t, []Segment{{Start: 0, End: 12, Category: "intro"}, {Start: 100.5, End: 140, Category: "sponsor"}},
+		res, "sorted and merged, mute skipped")
+
+	res, err = svc.Segments(context.Background(), "vid2", []string{"sponsor", "intro"})
+	require.NoError(t, err)
+	assert.Nil(t, res)
+
+	_, err = svc.Segments(context.Background(), "vid3", []string{"sponsor", "intro"})
+	assert.EqualError(t, err, "failed to get segments for vid3, status 400")
+}
+
+func TestService_Cut(t *testing.T) {
+	tmp := t.TempDir()
+	// fake ffmpeg saves its arguments and writes the output file, the last argument
+	ffmpeg := filepath.Join(tmp, "ffmpeg")
+	script := "#!/bin/sh\necho \"$@\" > " + tmp + "/args\nfor out; do :; done\necho cut > \"$out\"\n"
+	require.NoError(t, os.WriteFile(ffmpeg, []byte(script), 0o700)) // nolint
+	file := filepath.Join(tmp, "audio.mp3")
+	require.NoError(t, os.WriteFile(file, []byte("audio"), 0o600))
+
+	svc := Service{FFmpeg: ffmpeg}
+	require.NoError(t, svc.Cut(context.Background(), file, nil), "nothing to cut")
+	_, err := os.Stat(filepath.Join(tmp, "args"))
+	assert.True(t, os.IsNotExist(err), "ffmpeg not called")
+
+	segments := []Segment{{Start: 0, End: 12}, {Start: 100.5, End: 140}}
+	require.NoError(t, svc.Cut(context.Background(), file, segments))
+	data, err := os.ReadFile(file) // nolint
+	require.NoError(t, err)
+	assert.Equal(t, "cut\n", string(data), "file replaced")
+	args, err := os.ReadFile(filepath.Join(tmp, "args")) // nolint
+	require.NoError(t, err)
+	assert.Equal(t, "-y -v error -i "+file+" -map_metadata 0 -af aselect='not(between(t,0.000,12.000)+"+
+		"between(t,100.500,140.000))',asetpts=N/SR/TB -vn "+filepath.Join(tmp, "audio.cut.mp3")+"\n", string(args))
+
+	video := filepath.Join(tmp, "video.mp4")
+	require.NoError(t, os.WriteFile(video, []byte("video"), 0o600))
+	require.NoError(t, svc.Cut(context.Background(), video, segments))
+	args, err = os.ReadFile(filepath.Join(tmp, "args")) // nolint
+	require.NoError(t, err)
+	assert.Contains(t, string(args),
+		" -vf select='not(between(t,0.000,12.000)+between(t,100.500,140.000))',setpts=N/FRAME_RATE/TB ")
+
+	require.NoError(t, os.WriteFile(ffmpeg, []byte("#!/bin/sh\necho bad input >&2\nexit 1\n"), 0o700)) // nolint
+	err = svc.Cut(context.Background(), file, segments)
+	require.Error(t, err)
+	assert.True(t, strings.HasSuffix(err.Error(), "bad input"), err.Error())
+	data, err = os.ReadFile(file) // nolint
+	require.NoError(t, err)
+	assert.Equal(t, "cut\n", string(data), "file kept on error")
+}
+
+func TestAdjust(t *testing.T) {
+	segments := []Segment{{Start: 0, End: 10}, {Start: 100, End: 130}}
+	tbl := []struct {
+		in, out float64
+	}{
+		{0, 0}, {5, 0}, {10, 0}, {50, 40}, {100, 90}, {115, 90}, {130, 90}, {200, 160},
+	}
+	for _, tt := range tbl {
+		assert.Equal(t, tt.out, Adjust(tt.in, segments), tt.in)
+	}
+	assert.Equal(t, 40.0, Total(segments))
+}
+
+func TestAdjustChapters(t *testing.T) {
+	segments := []Segment{{Start: 0, End: 10}, {Start: 100, End: 130}, {Start: 250, End: 300}}
+	chapters := []ytfeed.Chapter{
+		{Start: 0, End: 10, Title: "intro"},
+		{Start: 10, End: 100, Title: "part1"},
+		{Start: 100, End: 120, Title: "ad"},
+		{Start: 120, End: 250, Title: "part2"},
+		{Start: 250, Title: "outro"},
+	}
+	res := AdjustChapters(chapters, segments)
+	assert.Equal(t, []ytfeed.Chapter{
+		{Start: 0, End: 90, Title: "part1"},
+		{Start: 90, End: 210, Title: "part2"},
+	}, res)
+
+	assert.Equal(t, chapters, AdjustChapters(chapters, nil))
+}
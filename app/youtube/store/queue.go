@@ -0,0 +1,114 @@
+package store
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/umputun/feed-master/app/youtube/feed"
+)
+
+var queueBkt = []byte("queue")
+
+// SetQueued saves download state of the entry, replaces the previous one
+func (s *BoltDB) SetQueued(item feed.QueueItem) error {
+	key, keyErr := s.procKey(item.Entry)
+	if keyErr != nil {
+		return errors.Wrapf(keyErr, "failed to generate key for %s", item.Entry.VideoID)
+	}
+
+	return s.DB.Update(func(tx *bolt.Tx) error {
+		bucket, e := tx.CreateBucketIfNotExists(queueBkt)
+		if e != nil {
+			return errors.Wrapf(e, "create bucket %s", queueBkt)
+		}
+		jdata, e := json.Marshal(&item)
+		if e != nil {
+			return errors.Wrapf(e, "marshal queue item %s", item.Entry.VideoID)
+		}
+		return errors.Wrapf(bucket.Put(key, jdata), "save queue item %s", item.Entry.VideoID)
+	})
+}
+
+// Queued returns download state of the entry, found is false if the entry was never queued.
+// Only ChannelID and VideoID of the entry are used.
+func (s *BoltDB) Queued(entry feed.Entry) (item feed.QueueItem, found bool, err error) {
+	key, keyErr := s.procKey(entry)
+	if keyErr != nil {
+		return item, false, errors.Wrapf(keyErr, "failed to generate key for %s", entry.VideoID)
+	}
+
+	err = s.DB.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(queueBkt)
+		if bucket == nil {
+			return nil
+		}
+		v := bucket.Get(key)
+		if v == nil {
+			return nil
+		}
+		found = true
+		return errors.Wrapf(json.Unmarshal(v, &item), "unmarshal queue item %s", entry.VideoID)
+	})
+	return item, found, err
+}
+
+// ListQueued returns download states of all entries of the channel, or of all channels if channelID is empty.
+// Items are sorted by update time, the most recent first.
+func (s *BoltDB) ListQueued(channelID string) ([]feed.QueueItem, error) {
+	res := []feed.QueueItem{}
+	err := s.DB.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(queueBkt)
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			var item feed.QueueItem
+			if err := json.Unmarshal(v, &item); err != nil {
+				return errors.Wrapf(err, "unmarshal queue item %s", string(k))
+			}
+			if channelID == "" || item.Entry.ChannelID == channelID {
+				res = append(res, item)
+			}
+			return nil
+		})
+	})
+	sort.Slice(res, func(i, j int) bool { return res[i].Updated.After(res[j].Updated) })
+	return res, err
+}
+
+// RemoveQueued removes done and skipped items updated before the given time, returns the number of removed items.
+// Failed items are kept till retried or given up.
+func (s *BoltDB) RemoveQueued(before time.Time) (count int, err error) {
+	err = s.DB.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(queueBkt)
+		if bucket == nil {
+			return nil
+		}
+		keys := [][]byte{}
+		err := bucket.ForEach(func(k, v []byte) error {
+			var item feed.QueueItem
+			if err := json.Unmarshal(v, &item); err != nil {
+				return errors.Wrapf(err, "unmarshal queue item %s", string(k))
+			}
+			if (item.State == feed.QueueDone || item.State == feed.QueueSkipped) && item.Updated.Before(before) {
+				keys = append(keys, k)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, k := range keys {
+			if err := bucket.Delete(k); err != nil {
+				return errors.Wrapf(err, "remove queue item %s", string(k))
+			}
+		}
+		count = len(keys)
+		return nil
+	})
+	return count, err
+}
@@ -0,0 +1,73 @@
+package store
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/umputun/feed-master/app/youtube/feed"
+)
+
+func TestStore_Queue(t *testing.T) {
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "test.db"), 0o600, &bolt.Options{Timeout: 5 * time.Second})
+	require.NoError(t, err)
+	s := BoltDB{DB: db}
+
+	items, err := s.ListQueued("")
+	require.NoError(t, err)
+	assert.Empty(t, items, "no queue bucket yet")
+	_, found, err := s.Queued(feed.Entry{ChannelID: "chan1", VideoID: "vid1"})
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	now := time.Now().UTC().Truncate(time.Second)
+	entry1 := feed.Entry{ChannelID: "chan1", VideoID: "vid1", Title: "title1"}
+	entry2 := feed.Entry{ChannelID: "chan2", VideoID: "vid1", Title: "title2"} // same video in another channel
+	entry3 := feed.Entry{ChannelID: "chan1", VideoID: "vid3", Title: "title3"}
+	require.NoError(t, s.SetQueued(feed.QueueItem{Entry: entry1, State: feed.QueueDownloading, Attempts: 1, Updated: now}))
+	require.NoError(t, s.SetQueued(feed.QueueItem{Entry: entry2, State: feed.QueueDone, Attempts: 1,
+		Updated: now.Add(-10 * 24 * time.Hour)}))
+	require.NoError(t, s.SetQueued(feed.QueueItem{Entry: entry3, State: feed.QueueSkipped, Updated: now.Add(-time.Hour)}))
+
+	item, found, err := s.Queued(feed.Entry{ChannelID: "chan1", VideoID: "vid1"})
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.Equal(t, feed.QueueDownloading, item.State)
+	assert.Equal(t, "title1", item.Entry.Title)
+
+	// replace
+	require.NoError(t, s.SetQueued(feed.QueueItem{Entry: entry1, State: feed.QueueFailed, Attempts: 2, Error: "err",
+		NextTry: now.Add(time.Hour), Updated: now}))
+	item, found, err = s.Queued(entry1)
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.Equal(t, feed.QueueItem{Entry: entry1, State: feed.QueueFailed, Attempts: 2, Error: "err",
+		NextTry: now.Add(time.Hour), Updated: now}, item)
+
+	items, err = s.ListQueued("")
+	require.NoError(t, err)
+	require.Equal(t, 3, len(items))
+	assert.Equal(t, "title1", items[0].Entry.Title, "the most recent first")
+	assert.Equal(t, "title3", items[1].Entry.Title)
+	assert.Equal(t, "title2", items[2].Entry.Title)
+
+	items, err = s.ListQueued("chan1")
+	require.NoError(t, err)
+	require.Equal(t, 2, len(items))
+
+	count, err := s.RemoveQueued(now.Add(-7 * 24 * time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 1, count, "old done item removed")
+	count, err = s.RemoveQueued(now.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 1, count, "skipped item removed, failed one kept")
+
+	items, err = s.ListQueued("")
+	require.NoError(t, err)
+	require.Equal(t, 1, len(items))
+	assert.Equal(t, feed.QueueFailed, items[0].State)
+}
@@ -2,10 +2,12 @@
 package store
 
 import (
+	"bytes"
 	"crypto/sha1"
 	"encoding/json"
 	"fmt"
 	"sort"
+	"sync"
 	"time"
 
 	log "github.com/go-pkgz/lgr"
@@ -22,6 +24,29 @@ var processedBkt = []byte("processed")
 type BoltDB struct {
 	*bolt.DB
 	Channels []string // the list of configured channels ids
+
+	lock sync.Mutex
+}
+
+// SetChannels replaces the list of configured channels, used on config reload.
+// Buckets for added channels are created, data of removed channels is kept in case they are added back.
+func (s *BoltDB) SetChannels(channels []string) error {
+	err := s.DB.Update(func(tx *bolt.Tx) error {
+		for _, ch := range channels {
+			if _, e := tx.CreateBucketIfNotExists([]byte(ch)); e != nil {
+				return errors.Wrapf(e, "create bucket %s", ch)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.Channels = channels
+	return nil
 }
 
 // Save to bolt, skip if found
@@ -112,10 +137,50 @@ func (s *BoltDB) Load(channelID string, max int) ([]feed.Entry, error) {
 	return result, err
 }
 
+// LoadPage returns up to limit entries of the channel stored before cursor, newest first. Empty cursor starts
+// from the newest entry. Returns the cursor of the next page, empty if there are no more entries.
+func (s *BoltDB) LoadPage(channelID, cursor string, limit int) (entries []feed.Entry, next string, err error) {
+	err = s.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(channelID))
+		if bucket == nil {
+			return nil
+		}
+		var last []byte
+		c := bucket.Cursor()
+		k, v := c.Last()
+		if cursor != "" {
+			if k, v = c.Seek([]byte(cursor)); k == nil {
+				k, v = c.Last()
+			}
+			for k != nil && bytes.Compare(k, []byte(cursor)) >= 0 {
+				k, v = c.Prev()
+			}
+		}
+		for ; k != nil; k, v = c.Prev() {
+			var entry feed.Entry
+			if err := json.Unmarshal(v, &entry); err != nil {
+				log.Printf("[WARN] failed to unmarshal %s, %q: %v", channelID, string(v), err)
+				continue
+			}
+			if len(entries) >= limit {
+				next = string(last)
+				break
+			}
+			entries = append(entries, entry)
+			last = k
+		}
+		return nil
+	})
+	return entries, next, err
+}
+
 // Last returns last (newest) entry across all channels
 func (s *BoltDB) Last() (feed.Entry, error) {
 	entries := []feed.Entry{}
-	for _, channel := range s.Channels {
+	s.lock.Lock()
+	channels := s.Channels
+	s.lock.Unlock()
+	for _, channel := range channels {
 		last, err := s.Load(channel, 1)
 		if err != nil {
 			return feed.Entry{}, errors.Wrapf(err, "can't load last entry for %s", channel)
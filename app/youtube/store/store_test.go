@@ -1,6 +1,7 @@
 package store
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
@@ -313,3 +314,75 @@ func TestBoltDB_Last(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, "vid3", res.VideoID)
 }
+
+func TestBoltDB_SetChannels(t *testing.T) {
+	tmpfile := filepath.Join(os.TempDir(), "test-channels.db")
+	defer os.Remove(tmpfile)
+
+	db, err := bolt.Open(tmpfile, 0o600, &bolt.Options{Timeout: 1 * time.Second})
+	require.NoError(t, err)
+
+	s := BoltDB{DB: db, Channels: []string{"chan1"}}
+	_, err = s.Save(feed.Entry{ChannelID: "chan1", VideoID: "vid1", Published: time.Now(), File: "f1"})
+	require.NoError(t, err)
+
+	require.NoError(t, s.SetChannels([]string{"chan2"}))
+	assert.Equal(t, []string{"chan2"}, s.Channels)
+	res, err := s.Load("chan2", 10)
+	require.NoError(t, err, "bucket created for added channel")
+	assert.Empty(t, res)
+	_, err = s.Last()
+	assert.EqualError(t, err, "no entries", "removed channel not used")
+
+	res, err = s.Load("chan1", 10)
+	require.NoError(t, err)
+	assert.Equal(t, 1, len(res), "data of removed channel kept")
+}
+
+func TestStore_LoadPage(t *testing.T) {
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "test.db"), 0o600, &bolt.Options{Timeout: 5 * time.Second})
+	require.NoError(t, err)
+	s := BoltDB{DB: db}
+
+	for i := 0; i < 5; i++ {
+		_, err = s.Save(feed.Entry{ChannelID: "chan1", VideoID: fmt.Sprintf("vid%d", i),
+			Published: time.Date(2022, time.March, 21, 16+i, 45, 22, 0, time.UTC)})
+		require.NoError(t, err)
+	}
+
+	ids := func(entries []feed.Entry) (res []string) {
+		for _, e := range entries {
+			res = append(res, e.VideoID)
+		}
+		return res
+	}
+
+	res, next, err := s.LoadPage("chan1", "", 2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"vid4", "vid3"}, ids(res))
+	require.NotEmpty(t, next)
+
+	res, next, err = s.LoadPage("chan1", next, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"vid2", "vid1"}, ids(res))
+	require.NotEmpty(t, next)
+
+	res, next, err = s.LoadPage("chan1", next, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"vid0"}, ids(res))
+	assert.Empty(t, next, "last page")
+
+	res, next, err = s.LoadPage("chan1", "", 5)
+	require.NoError(t, err)
+	assert.Equal(t, 5, len(res))
+	assert.Empty(t, next, "exactly one page")
+
+	res, _, err = s.LoadPage("chan1", "9999999999", 1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"vid4"}, ids(res), "cursor after all keys")
+
+	res, next, err = s.LoadPage("no-such-chan", "", 2)
+	require.NoError(t, err)
+	assert.Empty(t, res)
+	assert.Empty(t, next)
+}